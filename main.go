@@ -1,55 +1,224 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
+	"gosynth/pkg/engine"
 	"gosynth/pkg/synth"
 	"gosynth/pkg/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"gitlab.com/gomidi/midi/v2"
-	_ "gitlab.com/gomidi/midi/v2/drivers/rtmididrv" // autoregisters driver
 )
 
 func main() {
+	// The config file gives the defaults of the startup flags
+	cfg, cfgErr := synth.LoadConfig()
+	fileCfg := cfg // As read from the file, for spotting later edits to it
+
+	preview := flag.String("preview", "", "note or chord to play on startup and preset load, e.g. C3 or C4,E4,G4")
+	previewLength := flag.Duration("preview-length", synth.PreviewLength, "how long the preview chord is held")
+	previewVelocity := flag.Uint("preview-velocity", synth.PreviewVelocity, "velocity of the preview chord, 1 to 127")
+	render := flag.String("render", "", "render the sequencer pattern to this WAV file and exit, without audio or UI")
+	renderPreset := flag.String("render-preset", "", "preset to load before rendering")
+	renderLoops := flag.Int("render-loops", 1, "times the pattern is played in the render")
+	renderTail := flag.Duration("render-tail", synth.RenderTail, "longest effect tail captured after the pattern ends")
+	renderSilence := flag.Float64("render-silence", synth.RenderSilence, "dB level that ends the tail once the output stays below it; 0 keeps the whole tail")
+	fresh := flag.Bool("fresh", false, "start from the defaults instead of restoring the last session")
+	flag.StringVar(&cfg.AudioDevice, "audio-device", cfg.AudioDevice, "audio output device, the first whose name contains this; the system default when empty")
+	flag.StringVar(&cfg.AudioInput, "audio-input", cfg.AudioInput, "audio input to run through the effects, the first whose name contains this or \"default\"; none when empty")
+	flag.IntVar(&cfg.SampleRate, "sample-rate", cfg.SampleRate, "audio sample rate in Hz; only the engine's own rate is supported")
+	flag.IntVar(&cfg.BufferSize, "buffer-size", cfg.BufferSize, "frames per audio buffer")
+	flag.StringVar(&cfg.MIDIIn, "midi-in", cfg.MIDIIn, "MIDI input to play from, the first whose name contains this")
+	flag.StringVar(&cfg.MIDIOut, "midi-out", cfg.MIDIOut, "MIDI output for split notes and clock, the first whose name contains this")
+	flag.StringVar(&cfg.VirtualIn, "virtual-in", cfg.VirtualIn, "name of the virtual MIDI input other programs can play the synth through; none when empty")
+	flag.StringVar(&cfg.Preset, "preset", cfg.Preset, "preset to load at startup, when no session is restored or when given here")
+	flag.StringVar(&cfg.Theme, "theme", cfg.Theme, "colour theme of the UI: green, amber, ice or mono")
+	flag.StringVar(&cfg.OSC, "osc", cfg.OSC, "UDP address to listen for OSC messages on, e.g. :9000; off when empty")
+	flag.StringVar(&cfg.HTTP, "http", cfg.HTTP, "TCP address to serve the JSON API on, e.g. :8080 for this machine only or 0.0.0.0:8080 for the network; off when empty")
+	flag.Float64Var(&cfg.Reference, "reference-pitch", cfg.Reference, "frequency of A4 in Hz every note is tuned from, e.g. 432 or 442")
+	flag.IntVar(&cfg.FirstProgram, "first-program", cfg.FirstProgram, "MIDI program number that selects the first preset, e.g. 1 for controllers counting from 1")
+	flag.IntVar(&cfg.BankSize, "bank-size", cfg.BankSize, "presets per bank selected with MIDI bank select, 1 to 128")
+	carrier := flag.Float64("carrier", 440, "carrier frequency in Hz to start with")
+	volume := flag.Float64("volume", engine.InitialVolume, "master volume to start with, 0 to 1")
+	var params paramFlags
+	flag.Var(&params, "set", "preset parameter to start with as name=value, e.g. reverbMix=0.4; may be repeated")
+	noMIDI := flag.Bool("no-midi", false, "don't open any MIDI ports")
+	flag.Parse()
+	if cfgErr != nil {
+		log.Printf("Reading the config file failed, using the defaults: %v", cfgErr)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *render != "" {
+		renderPattern(*render, *renderPreset, cfg.Reference, synth.RenderOptions{Loops: *renderLoops, Tail: *renderTail, Silence: *renderSilence})
+		return
+	}
+
 	// Initialize MIDI
 	defer midi.CloseDriver()
 
-	// Create a new synthesizer
+	// Create a new synthesizer, keeping the parameters locked in earlier sessions
 	s := synth.NewSynth()
+	s.Config = cfg
+	s.SetReference(cfg.Reference)
+	s.Programs.SetNumbering(cfg.FirstProgram, cfg.BankSize)
+	s.WatchConfig(fileCfg)
+	if err := s.Locks.Load(); err != nil {
+		log.Printf("Loading parameter locks failed: %v", err)
+	}
+	if err := s.Stats.Load(); err != nil {
+		log.Printf("Loading statistics failed: %v", err)
+	}
+
+	// Come back as the last session was left, unless a fresh start was asked for
+	var session synth.Session
+	if !*fresh {
+		var err error
+		if session, err = s.LoadSession(); err != nil {
+			log.Printf("Restoring the last session failed: %v", err)
+		}
+	}
+
+	// The startup preset applies over defaults, or over the session when given as a flag
+	if cfg.Preset != "" && (session.State.Params == nil || flagSet("preset")) {
+		fade := s.PresetFade.Get()
+		s.PresetFade.Set(0)
+		if err := s.LoadPreset(cfg.Preset); err != nil {
+			log.Printf("Loading preset %s failed: %v", cfg.Preset, err)
+		}
+		s.PresetFade.Set(fade)
+	}
+
+	// Parameters given as flags apply over the preset or session
+	if flagSet("carrier") {
+		params = append(params, fmt.Sprintf("carrierFreq=%g", *carrier))
+	}
+	if flagSet("volume") {
+		params = append(params, fmt.Sprintf("volume=%g", *volume))
+	}
+	for _, p := range params {
+		name, value, _ := strings.Cut(p, "=")
+		v, err := strconv.ParseFloat(value, 64)
+		if err == nil && (math.IsNaN(v) || math.IsInf(v, 0)) {
+			err = fmt.Errorf("%s isn't a finite number", value)
+		}
+		if err == nil {
+			err = s.SetParam(name, v)
+		}
+		if err != nil {
+			log.Fatalf("-set %s: %v", p, err)
+		}
+	}
+	s.NoMIDI = *noMIDI
+	if *preview != "" {
+		notes, err := synth.ParseChord(*preview)
+		if err != nil {
+			log.Fatalf("-preview: %v", err)
+		}
+		s.Preview.Set(notes, uint8(max(1, min(*previewVelocity, 127))), *previewLength)
+	}
 
 	// Start the synthesizer
 	if err := s.Start(); err != nil {
 		log.Fatal(err)
 	}
 	defer s.Stop()
+	if err := s.StartOSC(cfg.OSC); err != nil {
+		log.Printf("Starting the OSC server failed: %v", err)
+	}
+	if err := s.StartHTTP(cfg.HTTP); err != nil {
+		log.Printf("Starting the HTTP server failed: %v", err)
+	}
 
 	// Create and start the UI with proper terminal options
 	p := tea.NewProgram(
-		ui.NewModel(s),
+		ui.NewModel(s).WithPage(session.Page),
 		tea.WithAltScreen(),       // Use alternate screen buffer
 		tea.WithMouseCellMotion(), // Enable mouse support
 	)
 
-	// Handle OS signals for graceful shutdown
+	// Handle OS signals for graceful shutdown: quitting the UI restores the terminal and
+	// lets the session and statistics be saved below before the synth stops
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigChan
-		s.Stop()
-		// Ensure terminal is in a good state before exiting
-		fmt.Print("\033[?1049l") // Exit alternate screen mode
-		fmt.Print("\033[?25h")   // Show cursor
-		os.Exit(0)
+		p.Quit()
 	}()
 
-	// Run the UI
-	if _, err := p.Run(); err != nil {
+	// Run the UI, then save the session to come back to, the statistics and the history
+	final, err := p.Run()
+	if err != nil {
 		fmt.Printf("Error running program: %v\n", err)
 		os.Exit(1)
 	}
+	if err := s.Stats.Save(); err != nil {
+		log.Printf("Saving statistics failed: %v", err)
+	}
+	if err := s.History.Save(); err != nil {
+		log.Printf("Saving the undo history failed: %v", err)
+	}
+	if m, ok := final.(ui.Model); ok {
+		if err := s.SaveSession(m.Page()); err != nil {
+			log.Printf("Saving the session failed: %v", err)
+		}
+	}
+}
+
+// paramFlags collects the repeated -set flags
+type paramFlags []string
+
+func (p *paramFlags) String() string {
+	return strings.Join(*p, " ")
+}
+
+func (p *paramFlags) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return errors.New("expected name=value")
+	}
+	*p = append(*p, value)
+	return nil
+}
+
+// flagSet reports whether a flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// renderPattern renders the sequencer pattern of a preset, or the default pattern, to a WAV file
+func renderPattern(path, preset string, reference float64, opts synth.RenderOptions) {
+	s := synth.NewSynth()
+	s.SetReference(reference)
+	if preset != "" {
+		// Apply the preset at once rather than crossfading into it
+		s.PresetFade.Set(0)
+		var issues synth.PresetIssues
+		if err := s.LoadPreset(preset); errors.As(err, &issues) {
+			log.Printf("-render-preset: %v", err)
+		} else if err != nil {
+			log.Fatalf("-render-preset: %v", err)
+		}
+	}
+	result, err := s.RenderPattern(path, opts)
+	if err != nil {
+		log.Fatalf("-render: %v", err)
+	}
+	fmt.Printf("Rendered %s: %s, including a %s tail\n", path, result.Length.Round(time.Millisecond), result.Tail.Round(time.Millisecond))
 }
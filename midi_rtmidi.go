@@ -0,0 +1,5 @@
+//go:build !nortmidi
+
+package main
+
+import _ "gitlab.com/gomidi/midi/v2/drivers/rtmididrv" // autoregisters driver
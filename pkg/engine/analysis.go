@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Analysis summarizes the last block sent to the output
+type Analysis struct {
+	RMS      float64 // Root mean square level of the mono sum
+	Peak     float64 // Largest absolute sample on either channel
+	Centroid float64 // Spectral centroid estimate in Hz, 0 when silent
+	Clipped  bool    // A sample went past full scale before the soft clipper
+}
+
+// analysisTap measures output blocks from the audio callback for the UI to read
+type analysisTap struct {
+	rms      atomic.Uint64 // float64 bits
+	peak     atomic.Uint64 // float64 bits
+	centroid atomic.Uint64 // float64 bits
+	clipped  atomic.Bool
+}
+
+// measure analyzes an interleaved block. The centroid is estimated from the ratio of
+// the first difference's level to the signal's level, which is exact for a sine and
+// tracks the brightness of richer sounds without needing an FFT.
+func (a *analysisTap) measure(buf []float32, channels int) {
+	var sum, diffSum, peak, last float64
+	frames := len(buf) / channels
+	for i := 0; i < frames; i++ {
+		mono := 0.0
+		for c := 0; c < channels; c++ {
+			sample := float64(buf[i*channels+c])
+			peak = math.Max(peak, math.Abs(sample))
+			mono += sample
+		}
+		mono /= float64(channels)
+		sum += mono * mono
+		if i > 0 {
+			diffSum += (mono - last) * (mono - last)
+		}
+		last = mono
+	}
+	if frames == 0 {
+		return
+	}
+
+	rms := math.Sqrt(sum / float64(frames))
+	centroid := 0.0
+	if rms > 1e-5 && frames > 1 {
+		ratio := math.Sqrt(diffSum/float64(frames-1)) / rms
+		centroid = SampleRate / math.Pi * math.Asin(math.Min(1, ratio/2))
+	}
+	a.rms.Store(math.Float64bits(rms))
+	a.peak.Store(math.Float64bits(peak))
+	a.centroid.Store(math.Float64bits(centroid))
+}
+
+// Analysis returns the level and brightness of the last output block
+func (e *Engine) Analysis() Analysis {
+	return Analysis{
+		RMS:      math.Float64frombits(e.analysis.rms.Load()),
+		Peak:     math.Float64frombits(e.analysis.peak.Load()),
+		Centroid: math.Float64frombits(e.analysis.centroid.Load()),
+		Clipped:  e.analysis.clipped.Load(),
+	}
+}
@@ -0,0 +1,72 @@
+package engine
+
+// Audition is a module soloed to hear its contribution on its own
+type Audition int
+
+const (
+	AuditionOff    Audition = iota // Everything plays
+	AuditionVoices                 // The carrier or played voices, dry
+	AuditionLayer                  // The drone layer, dry
+	AuditionChorus                 // The chorus's wet signal alone
+	AuditionDelay                  // The delay's echoes alone
+	AuditionReverb                 // The reverb's tail alone
+	auditionCount
+)
+
+func (a Audition) String() string {
+	switch a {
+	case AuditionOff:
+		return "off"
+	case AuditionVoices:
+		return "voices"
+	case AuditionLayer:
+		return "drone layer"
+	case AuditionChorus:
+		return "chorus"
+	case AuditionDelay:
+		return "delay"
+	case AuditionReverb:
+		return "reverb"
+	}
+	return "unknown"
+}
+
+// Effect returns the name of the auditioned effect in the chain, or "" for a source
+func (a Audition) Effect() string {
+	switch a {
+	case AuditionChorus:
+		return EffectChorus
+	case AuditionDelay:
+		return EffectDelay
+	case AuditionReverb:
+		return EffectReverb
+	}
+	return ""
+}
+
+// auditionRamp fades a module's paths as auditioning starts and stops, reusing the
+// kill switches' ramp so switching doesn't click
+type auditionRamp struct {
+	wet killRamp // The module's own sound, faded while another module is auditioned
+	dry killRamp // The signal an effect passes through, faded while it is auditioned
+}
+
+// next moves both gains one sample towards their targets and returns them
+func (r *auditionRamp) next() (wet, dry float64) {
+	return r.wet.next(), r.dry.next()
+}
+
+// Audition returns the module being auditioned
+func (e *Engine) Audition() Audition {
+	return Audition(e.audition.Load())
+}
+
+// SetAudition solos a module, muting the other sources and bypassing the other effects;
+// an auditioned effect plays only its wet signal. AuditionOff plays everything again.
+func (e *Engine) SetAudition(a Audition) {
+	e.audition.Store(int32(a))
+	for i := range e.auditions {
+		e.auditions[i].wet.on.Store(a != AuditionOff && a != Audition(i))
+		e.auditions[i].dry.on.Store(a != AuditionOff && a == Audition(i))
+	}
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const EventBuffer = 256 // Events a subscriber can fall behind by before new ones are dropped for it
+
+// EventKind identifies what an Event reports
+type EventKind int
+
+const (
+	EventNoteOn    EventKind = iota // A note started sounding
+	EventNoteOff                    // A note was released
+	EventParam                      // A named parameter changed value
+	EventTransport                  // Playback started or stopped
+	EventMeter                      // Levels of an output block
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventNoteOn:
+		return "note on"
+	case EventNoteOff:
+		return "note off"
+	case EventParam:
+		return "param"
+	case EventTransport:
+		return "transport"
+	case EventMeter:
+		return "meter"
+	}
+	return "unknown"
+}
+
+// Event is a message on the bus; the fields used depend on the kind
+type Event struct {
+	Kind     EventKind
+	Note     uint8    // Note events
+	Velocity uint8    // Note on
+	Drum     bool     // Note events from the drum kit
+	Param    string   // Parameter name, as saved in presets
+	Value    float64  // New parameter value
+	Playing  bool     // Transport state
+	Meter    Analysis // Meter frames
+}
+
+// Bus delivers engine events to any number of subscribers. Publishing never blocks and
+// takes no lock, so the audio callback can publish; a subscriber that falls more than
+// EventBuffer events behind misses the newest until it catches up.
+type Bus struct {
+	mu   sync.Mutex                   // Serializes changes to the subscriber list
+	subs atomic.Pointer[[]chan Event] // Copied on change, read without locking
+}
+
+// Subscribe returns a channel of events published from now on and a function that ends
+// the subscription. The channel is left open, as a publish may be under way.
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, EventBuffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.store(append(append([]chan Event(nil), b.list()...), ch))
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		var subs []chan Event
+		for _, sub := range b.list() {
+			if sub != ch {
+				subs = append(subs, sub)
+			}
+		}
+		b.store(subs)
+	}
+}
+
+// Publish sends an event to every subscriber with room for it
+func (b *Bus) Publish(ev Event) {
+	for _, ch := range b.list() {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// list returns the current subscribers
+func (b *Bus) list() []chan Event {
+	if subs := b.subs.Load(); subs != nil {
+		return *subs
+	}
+	return nil
+}
+
+// store replaces the subscriber list; b.mu must be held
+func (b *Bus) store(subs []chan Event) {
+	b.subs.Store(&subs)
+}
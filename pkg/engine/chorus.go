@@ -0,0 +1,66 @@
+package engine
+
+import "gosynth/pkg/fx"
+
+const (
+	ChorusRate    = 0.8 // Default LFO rate in Hz
+	ChorusDepth   = 0.5 // Default sweep depth
+	ChorusMix     = 0.5 // Default wet/dry balance
+	ChorusSyncDiv = "1/4."
+)
+
+// Chorus thickens the single-oscillator sound through fx.Chorus, its LFO optionally
+// synced to the tempo
+type Chorus struct {
+	Rate      SmoothValue // LFO rate in Hz when not synced
+	Depth     SmoothValue // Sweep depth, 0 to 1
+	Mix       SmoothValue // Wet/dry balance, 0 (dry) to 1 (wet)
+	TempoSync             // Take one LFO cycle from a division at the tempo
+
+	tempo    Tempo
+	channels []*fx.Chorus
+	audition *auditionRamp // Fades for auditioning, if any
+}
+
+// NewChorus creates a chorus with an engine per channel, a quarter cycle apart
+func NewChorus(tempo Tempo, channels int) *Chorus {
+	c := &Chorus{tempo: tempo}
+	c.SetDiv(DivisionIndex(ChorusSyncDiv))
+	c.Rate.Set(ChorusRate)
+	c.Depth.Set(ChorusDepth)
+	c.Mix.Set(ChorusMix)
+	for i := 0; i < channels; i++ {
+		c.channels = append(c.channels, fx.NewChorus(SampleRate, float64(i)*0.25))
+	}
+	return c
+}
+
+// Hz returns the current LFO rate, following the tempo when synced
+func (c *Chorus) Hz() float64 {
+	if c.Synced() {
+		return 1 / (Divisions[c.Div()].Beats * c.tempo.BeatDuration().Seconds())
+	}
+	return c.Rate.Get()
+}
+
+// Process runs a block of interleaved channels through the chorus
+func (c *Chorus) Process(in, out []float32) {
+	rate := c.Hz()
+	for _, engine := range c.channels {
+		engine.Rate = rate
+		engine.Depth = c.Depth.Get()
+	}
+	mix, wetGain, dryGain := 0.0, 1.0, 1.0
+	for i, x := range in {
+		// Once per frame, shared by its channels
+		if i%len(c.channels) == 0 {
+			mix = c.Mix.Update()
+			if c.audition != nil {
+				wetGain, dryGain = c.audition.next()
+			}
+		}
+		dry := float64(x)
+		wet := c.channels[i%len(c.channels)].Process(dry)
+		out[i] = float32(dry*(1-mix*wetGain)*dryGain + wet*mix*wetGain)
+	}
+}
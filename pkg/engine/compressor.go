@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+
+	"gosynth/pkg/fx"
+)
+
+const (
+	CompThreshold = -12.0 // Default threshold in dBFS
+	CompRatio     = 4.0   // Default compression ratio
+	CompAttack    = 0.005 // Default attack in seconds
+	CompRelease   = 0.1   // Default release in seconds
+	CompMakeup    = 0.0   // Default makeup gain in dB
+	MaxCompRatio  = 20.0  // Ratio at which the compressor acts as a limiter
+)
+
+// Compressor controls the dynamics of the master bus through fx.Compressor
+type Compressor struct {
+	enabled   atomic.Bool
+	Threshold SmoothValue // dBFS
+	Ratio     SmoothValue // 1 (off) to MaxCompRatio (limiting)
+	Attack    SmoothValue // Seconds
+	Release   SmoothValue // Seconds
+	Makeup    SmoothValue // dB
+
+	engine    *fx.Compressor
+	reduction atomic.Uint64 // Peak gain reduction of the last block in dB, as float64 bits
+}
+
+// NewCompressor creates a disabled master compressor
+func NewCompressor() *Compressor {
+	// The engine sees interleaved samples, so its detector links the channels
+	engine := fx.NewCompressor(SampleRate * OutputChannels)
+	c := &Compressor{
+		engine: engine,
+	}
+	c.Threshold.Set(CompThreshold)
+	c.Ratio.Set(CompRatio)
+	c.Attack.Set(CompAttack)
+	c.Release.Set(CompRelease)
+	c.Makeup.Set(CompMakeup)
+	return c
+}
+
+// Enabled reports whether the compressor is in the master bus
+func (c *Compressor) Enabled() bool {
+	return c.enabled.Load()
+}
+
+// SetEnabled puts the compressor in the master bus or takes it out
+func (c *Compressor) SetEnabled(on bool) {
+	c.enabled.Store(on)
+}
+
+// Process compresses a block in place and records its peak gain reduction
+func (c *Compressor) Process(buf []float32) {
+	if !c.Enabled() {
+		c.reduction.Store(0)
+		return
+	}
+	c.engine.Threshold = c.Threshold.Get()
+	c.engine.Ratio = c.Ratio.Get()
+	c.engine.Attack = c.Attack.Get()
+	c.engine.Release = c.Release.Get()
+	c.engine.Makeup = c.Makeup.Get()
+
+	peak := 0.0
+	for i, x := range buf {
+		buf[i] = float32(c.engine.Process(float64(x)))
+		peak = max(peak, c.engine.GainReduction())
+	}
+	c.reduction.Store(math.Float64bits(peak))
+}
+
+// GainReduction returns the peak gain reduction of the last block in dB
+func (c *Compressor) GainReduction() float64 {
+	return math.Float64frombits(c.reduction.Load())
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultCPUBudget = 0.7 // Default fraction of each block's duration the callback may spend rendering
+	cpuAverage       = 0.1 // Weight of the newest block in the running cost averages
+)
+
+// cpuMeter times the audio callback to estimate the cost of the current patch per voice
+type cpuMeter struct {
+	load     atomic.Uint64 // Fraction of the last block's duration spent rendering, as float64 bits
+	idle     atomic.Uint64 // Average seconds the frame loop takes with no voices, as float64 bits
+	perVoice atomic.Uint64 // Average seconds each sounding voice adds to a block, as float64 bits
+	fixed    atomic.Uint64 // Average seconds spent outside the voices per block, as float64 bits
+}
+
+// average folds a new measurement into a running average stored as float64 bits
+func average(value *atomic.Uint64, sample float64) {
+	old := math.Float64frombits(value.Load())
+	if old == 0 {
+		value.Store(math.Float64bits(sample))
+		return
+	}
+	value.Store(math.Float64bits(old + (sample-old)*cpuAverage))
+}
+
+// measure records a block's timing: the whole callback, the frame loop and the voices it rendered
+func (c *cpuMeter) measure(frames, voices int, loop, total time.Duration) {
+	if frames == 0 {
+		return
+	}
+	c.load.Store(math.Float64bits(total.Seconds() * SampleRate / float64(frames)))
+
+	// Scale to a full-size block so averages stay comparable when the host changes block size
+	scale := float64(AudioBufferSize) / float64(frames)
+	idle := math.Float64frombits(c.idle.Load())
+	if voices == 0 {
+		average(&c.idle, loop.Seconds()*scale)
+		idle = math.Float64frombits(c.idle.Load())
+	} else {
+		average(&c.perVoice, math.Max(0, loop.Seconds()*scale-idle)/float64(voices))
+	}
+	average(&c.fixed, (total-loop).Seconds()*scale+idle)
+}
+
+// activeVoices counts the sounding voices
+func (e *Engine) activeVoices() int {
+	count := 0
+	for i := range e.voices {
+		if e.voices[i].Active() {
+			count++
+		}
+	}
+	return count
+}
+
+// CPULoad returns the fraction of the last block's duration spent rendering it
+func (e *Engine) CPULoad() float64 {
+	return math.Float64frombits(e.cpu.load.Load())
+}
+
+// VoiceCost returns the estimated rendering time each voice of the current patch adds
+// to a block of AudioBufferSize frames
+func (e *Engine) VoiceCost() time.Duration {
+	return time.Duration(math.Float64frombits(e.cpu.perVoice.Load()) * float64(time.Second))
+}
+
+// MaxPolyphony estimates how many voices of the current patch fit in the CPU budget,
+// or -1 before any voice has been measured. The result may exceed MaxVoices.
+func (e *Engine) MaxPolyphony() int {
+	perVoice := math.Float64frombits(e.cpu.perVoice.Load())
+	if perVoice == 0 {
+		return -1
+	}
+	budget := e.CPUBudget.Get()*AudioBufferSize/SampleRate - math.Float64frombits(e.cpu.fixed.Load())
+	return max(0, int(budget/perVoice))
+}
@@ -0,0 +1,91 @@
+package engine
+
+const (
+	MaxDelayTime  = 2.0   // Longest delay in seconds
+	DelayTime     = 0.375 // Default delay time in seconds
+	DelayFeedback = 0.4   // Default fraction of the echo fed back
+	DelayMix      = 0.3   // Default wet level
+	DelaySyncDiv  = "1/8."
+)
+
+// delayLine is a circular buffer for one audio channel
+type delayLine struct {
+	buffer []float64
+	pos    int
+}
+
+// process writes a sample and returns the sample from the given number of frames ago,
+// interpolated between neighbouring frames
+func (l *delayLine) process(in, frames, feedback float64) float64 {
+	size := len(l.buffer)
+	frames = clampFloat(frames, 1, float64(size-1))
+	whole := int(frames)
+	frac := frames - float64(whole)
+
+	a := l.buffer[(l.pos-whole+size)%size]
+	b := l.buffer[(l.pos-whole-1+size)%size]
+	out := a*(1-frac) + b*frac
+
+	l.buffer[l.pos] = in + out*feedback
+	l.pos = (l.pos + 1) % size
+	return out
+}
+
+// Delay is a feedback echo applied to the voice mix, optionally synced to the tempo
+type Delay struct {
+	Time      SmoothValue // Delay time in seconds when not synced
+	Feedback  SmoothValue // Fraction of each echo fed back into the line
+	Mix       SmoothValue // Wet level added to the dry signal
+	TempoSync             // Take the time from a division at the tempo
+
+	tempo    Tempo
+	lines    []delayLine   // One per output channel
+	kill     *killRamp     // Kill switch cutting the echoes, if any
+	audition *auditionRamp // Fades for auditioning, if any
+}
+
+// NewDelay creates a delay with a line for each channel
+func NewDelay(tempo Tempo, channels int) *Delay {
+	d := &Delay{
+		tempo: tempo,
+		lines: make([]delayLine, channels),
+	}
+	d.SetDiv(DivisionIndex(DelaySyncDiv))
+	d.Time.Set(DelayTime)
+	d.Feedback.Set(DelayFeedback)
+	d.Mix.Set(DelayMix)
+	for i := range d.lines {
+		d.lines[i].buffer = make([]float64, int(MaxDelayTime*SampleRate)+1)
+	}
+	return d
+}
+
+// Seconds returns the current delay time, following the tempo when synced
+func (d *Delay) Seconds() float64 {
+	if d.Synced() {
+		return Divisions[d.Div()].Beats * d.tempo.BeatDuration().Seconds()
+	}
+	return d.Time.Get()
+}
+
+// Process runs a block of interleaved channels through the delay
+func (d *Delay) Process(in, out []float32) {
+	frames := d.Seconds() * SampleRate
+	feedback, mix, kill, wetGain, dryGain := 0.0, 0.0, 1.0, 1.0, 1.0
+	for i, x := range in {
+		// Once per frame, shared by its channels
+		if i%len(d.lines) == 0 {
+			feedback = d.Feedback.Update()
+			mix = d.Mix.Update()
+			if d.kill != nil {
+				kill = d.kill.next()
+			}
+			if d.audition != nil {
+				wetGain, dryGain = d.audition.next()
+			}
+		}
+		dry := float64(x)
+		wet := d.lines[i%len(d.lines)].process(dry, frames, feedback)
+		out[i] = float32(dry*dryGain + wet*kill*mix*wetGain)
+	}
+}
@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	DroneLowNote  = 24  // Lowest drone root (C1)
+	DroneHighNote = 60  // Highest drone root (C4), keeping the layer under played parts
+	DroneRoot     = 36  // Default drone root (C2)
+	DroneLevel    = 0.3 // Default drone layer level
+	DroneDetune   = 4.0 // Default detune in cents between chord tones
+	DroneFade     = 2.0 // Default seconds to fade the layer in and out
+)
+
+// DroneChord is a set of intervals the drone layer sustains above its root
+type DroneChord struct {
+	Name      string
+	Intervals []int // Semitones above the root
+}
+
+// DroneChords lists the selectable drone chords
+var DroneChords = []DroneChord{
+	{Name: "root", Intervals: []int{0}},
+	{Name: "fifth", Intervals: []int{0, 7}},
+	{Name: "octave", Intervals: []int{0, 12}},
+	{Name: "fifth+octave", Intervals: []int{0, 7, 12}},
+	{Name: "major", Intervals: []int{0, 4, 7}},
+	{Name: "minor", Intervals: []int{0, 3, 7}},
+	{Name: "sus2", Intervals: []int{0, 2, 7}},
+	{Name: "sus4", Intervals: []int{0, 5, 7}},
+}
+
+// DroneWave is the oscillator shape of the drone layer
+type DroneWave int
+
+const (
+	DroneSine DroneWave = iota
+	DroneTriangle
+	DroneSaw
+	droneWaveCount
+)
+
+func (w DroneWave) String() string {
+	switch w {
+	case DroneSine:
+		return "sine"
+	case DroneTriangle:
+		return "triangle"
+	case DroneSaw:
+		return "saw"
+	}
+	return "unknown"
+}
+
+// Next returns the following wave, wrapping around
+func (w DroneWave) Next(dir int) DroneWave {
+	return DroneWave((int(w) + dir + int(droneWaveCount)) % int(droneWaveCount))
+}
+
+// DroneLayer sustains a chord with its own patch, independent of played notes
+type DroneLayer struct {
+	Level  SmoothValue
+	Detune SmoothValue // Cents the chord tones are spread apart
+	Fade   SmoothValue // Seconds to fade in when enabled and out when disabled
+
+	mu      sync.Mutex
+	enabled bool
+	root    uint8
+	chord   int // Index into DroneChords
+	wave    DroneWave
+	tuning  *Tuning
+
+	phases []float64 // Oscillator phase per chord tone; only touched by the audio callback
+	gain   float64   // Current fade gain; only touched by the audio callback
+}
+
+// NewDroneLayer creates a silent drone layer on a fifth above C2
+func NewDroneLayer(tuning *Tuning) *DroneLayer {
+	d := &DroneLayer{
+		root:   DroneRoot,
+		chord:  1,
+		tuning: tuning,
+	}
+	d.Level.Set(DroneLevel)
+	d.Detune.Set(DroneDetune)
+	d.Fade.Set(DroneFade)
+	return d
+}
+
+// Enabled reports whether the layer is sounding or fading in
+func (d *DroneLayer) Enabled() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.enabled
+}
+
+// SetEnabled fades the layer in or out
+func (d *DroneLayer) SetEnabled(enabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.enabled = enabled
+}
+
+// Root returns the root note of the chord
+func (d *DroneLayer) Root() uint8 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.root
+}
+
+// SetRoot sets the root note, limited to the drone range
+func (d *DroneLayer) SetRoot(note int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.root = uint8(max(DroneLowNote, min(DroneHighNote, note)))
+}
+
+// Chord returns the index of the sustained chord in DroneChords
+func (d *DroneLayer) Chord() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.chord
+}
+
+// SetChord selects the sustained chord from DroneChords
+func (d *DroneLayer) SetChord(index int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if index >= 0 && index < len(DroneChords) {
+		d.chord = index
+	}
+}
+
+// Wave returns the oscillator shape
+func (d *DroneLayer) Wave() DroneWave {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.wave
+}
+
+// SetWave selects the oscillator shape
+func (d *DroneLayer) SetWave(wave DroneWave) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.wave = wave
+}
+
+// Render fills left and right with the layer for a block of frames, adding to their contents
+func (d *DroneLayer) Render(left, right []float64) {
+	d.mu.Lock()
+	enabled, root, intervals, wave := d.enabled, d.root, DroneChords[d.chord].Intervals, d.wave
+	d.mu.Unlock()
+
+	if !enabled && d.gain == 0 {
+		return
+	}
+	for len(d.phases) < len(intervals) {
+		d.phases = append(d.phases, 0)
+	}
+
+	// Spread the tones around the chord pitch and across the stereo field
+	freqs := make([]float64, len(intervals))
+	pans := make([]float64, len(intervals))
+	detune := d.Detune.Get()
+	for i, interval := range intervals {
+		cents := detune * (float64(i) - float64(len(intervals)-1)/2)
+		freqs[i] = d.tuning.NoteFreq(root+uint8(interval)) * math.Pow(2, cents/1200)
+		if len(intervals) > 1 {
+			pans[i] = float64(i)/float64(len(intervals)-1)*1.2 - 0.6
+		}
+	}
+
+	step := 1 / (math.Max(d.Fade.Get(), 0.001) * SampleRate)
+	for frame := range left {
+		level := d.Level.Update() / float64(len(intervals))
+		if enabled {
+			d.gain = math.Min(1, d.gain+step)
+		} else {
+			d.gain = math.Max(0, d.gain-step)
+		}
+		for i := range freqs {
+			value := droneOscillator(wave, d.phases[i]) * level * d.gain
+			gainL, gainR := panGains(pans[i])
+			left[frame] += value * gainL
+			right[frame] += value * gainR
+
+			d.phases[i] += freqs[i] / SampleRate
+			if d.phases[i] >= 1 {
+				d.phases[i] -= 1
+			}
+		}
+	}
+}
+
+// droneOscillator returns one sample of a wave at a phase from 0 to 1
+func droneOscillator(wave DroneWave, phase float64) float64 {
+	switch wave {
+	case DroneTriangle:
+		return 1 - 4*math.Abs(phase-0.5)
+	case DroneSaw:
+		return softSawTable.at(phase)
+	}
+	return sineTable.at(phase)
+}
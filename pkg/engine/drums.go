@@ -0,0 +1,138 @@
+package engine
+
+import "math"
+
+const (
+	DrumLevel       = 0.8   // Default level of the drum voices
+	KickPitch       = 50.0  // Default pitch the kick settles to, in Hz
+	MinKickPitch    = 30.0  // Lowest kick pitch in Hz
+	MaxKickPitch    = 120.0 // Highest kick pitch in Hz
+	MinDrumDecay    = 0.01  // Shortest drum decay in seconds
+	MaxDrumDecay    = 2.0   // Longest drum decay in seconds
+	kickSweep       = 3.0   // Kick start pitch as a multiple of its settled pitch
+	kickSweepTime   = 0.03  // Seconds the kick pitch takes to fall most of the way
+	snareBodyFreq   = 185.0 // Pitch of the snare's tone under its noise, in Hz
+	hatCutoff       = 7000  // Highpass cutoff of the hi-hat noise, in Hz
+	snareCutoff     = 1500  // Highpass cutoff of the snare noise, in Hz
+	drumSilentLevel = 1e-4  // Envelope level below which a hit stops
+)
+
+// DrumKind is one of the drum voices
+type DrumKind int
+
+const (
+	DrumKick  DrumKind = iota // Sine swept down in pitch
+	DrumSnare                 // Noise burst over a short tone
+	DrumHat                   // Short burst of highpassed noise
+	DrumKinds                 // Number of drum voices
+)
+
+func (d DrumKind) String() string {
+	switch d {
+	case DrumKick:
+		return "kick"
+	case DrumSnare:
+		return "snare"
+	case DrumHat:
+		return "hat"
+	}
+	return "unknown"
+}
+
+// drumDecays are the default decay times of the drum voices, in seconds
+var drumDecays = [DrumKinds]float64{DrumKick: 0.4, DrumSnare: 0.18, DrumHat: 0.05}
+
+// drumHit is a sounding drum; only touched by the audio callback
+type drumHit struct {
+	active bool
+	age    int     // Frames since the hit
+	env    float64 // Level of the decay envelope, from the hit's velocity
+	phase  float64 // Phase of the kick or snare tone, 0 to 1
+	lastIn float64 // Previous noise sample, for the highpass
+	hp     float64 // Previous highpass output
+}
+
+// DrumSynth synthesizes kick, snare and hi-hat from oscillators and noise, for a drum
+// track without samples. Like the one-shots it plays outside the voices; each drum plays
+// one hit at a time, a new hit restarting it.
+type DrumSynth struct {
+	Level     SmoothValue
+	KickPitch SmoothValue
+	Decay     [DrumKinds]SmoothValue // Seconds each drum takes to die away
+
+	hits  [DrumKinds]drumHit
+	noise uint32 // Noise generator state
+}
+
+// NewDrumSynth creates silent drum voices
+func NewDrumSynth() *DrumSynth {
+	d := &DrumSynth{noise: 0x9e3779b9}
+	d.Level.Set(DrumLevel)
+	d.KickPitch.Set(KickPitch)
+	for kind, decay := range drumDecays {
+		d.Decay[kind].Set(decay)
+	}
+	return d
+}
+
+// QueueDrum asks the audio callback to play a drum voice
+func (e *Engine) QueueDrum(kind DrumKind, velocity uint8) {
+	e.queueEvent(noteEvent{kind: drumEvent, note: uint8(kind), velocity: velocity})
+}
+
+// trigger restarts a drum voice at a velocity
+func (d *DrumSynth) trigger(kind DrumKind, velocity uint8) {
+	if kind < 0 || kind >= DrumKinds {
+		return
+	}
+	d.hits[kind] = drumHit{active: true, env: float64(velocity) / 127}
+}
+
+// nextFrame returns the next sample of every sounding drum, mixed at the drum level
+func (d *DrumSynth) nextFrame() float64 {
+	level := d.Level.Update()
+	pitch := d.KickPitch.Update()
+	var out float64
+	for kind := range d.hits {
+		decay := d.Decay[kind].Update()
+		h := &d.hits[kind]
+		if !h.active {
+			continue
+		}
+		t := float64(h.age) / SampleRate
+		switch DrumKind(kind) {
+		case DrumKick:
+			freq := pitch * (1 + (kickSweep-1)*math.Exp(-t/kickSweepTime))
+			out += sineTable.at(h.phase) * h.env
+			h.phase = math.Mod(h.phase+freq/SampleRate, 1)
+		case DrumSnare:
+			tone := sineTable.at(h.phase) * math.Exp(-t/(decay/2))
+			h.phase = math.Mod(h.phase+snareBodyFreq/SampleRate, 1)
+			out += (0.4*tone + 0.7*h.highpass(d.nextNoise(), snareCutoff)) * h.env
+		case DrumHat:
+			out += h.highpass(d.nextNoise(), hatCutoff) * h.env
+		}
+		h.env *= math.Exp(-1 / (decay * SampleRate))
+		h.age++
+		if h.env < drumSilentLevel {
+			h.active = false
+		}
+	}
+	return out * level
+}
+
+// highpass runs noise through a one-pole highpass at a cutoff
+func (h *drumHit) highpass(in, cutoff float64) float64 {
+	a := 1 / (1 + 2*math.Pi*cutoff/SampleRate)
+	h.hp = a * (h.hp + in - h.lastIn)
+	h.lastIn = in
+	return h.hp
+}
+
+// nextNoise returns a white noise sample from -1 to 1
+func (d *DrumSynth) nextNoise() float64 {
+	d.noise ^= d.noise << 13
+	d.noise ^= d.noise >> 17
+	d.noise ^= d.noise << 5
+	return float64(d.noise)/math.MaxUint32*2 - 1
+}
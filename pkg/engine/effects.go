@@ -0,0 +1,19 @@
+package engine
+
+import "gosynth/pkg/fx"
+
+// Names of the effects in the chain, as saved in presets
+const (
+	EffectChorus = "chorus"
+	EffectDelay  = "delay"
+	EffectReverb = "reverb"
+)
+
+// newEffectsChain builds the output chain in its default order, all effects off
+func (e *Engine) newEffectsChain() *fx.Chain {
+	chain := &fx.Chain{}
+	chain.Add(EffectChorus, e.Chorus)
+	chain.Add(EffectDelay, e.Delay)
+	chain.Add(EffectReverb, e.Reverb)
+	return chain
+}
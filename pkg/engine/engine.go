@@ -0,0 +1,409 @@
+// Package engine is the sound engine of gosynth: voices, modulation, effects and the
+// master bus, rendered into interleaved buffers by Render. It has no audio or MIDI
+// device dependencies, so it can be driven by PortAudio, rendered offline or tested.
+package engine
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"gosynth/pkg/fx"
+)
+
+const (
+	SampleRate      = 44100
+	MinModFreq      = 100.0 // Minimum modulation frequency in Hz
+	MaxModFreq      = 600.0 // Maximum modulation frequency in Hz
+	FreqSweepTime   = .300  // Time to finish one cycle of the modulator sweep
+	ModulationIndex = 0.5   // Modulation intensity
+	FeedbackDepth   = 0.5   // Phase shift in cycles an oscillator feeds back into itself at full feedback
+	MaxBinauralBeat = 40.0  // Widest binaural beat in Hz, the top of the gamma band
+	ClipThreshold   = 0.6   // Threshold where soft clipping begins
+	ClipHardLimit   = 0.85  // Maximum amplitude after clipping
+	InitialVolume   = 0.75  // Initial volume level
+	AudioBufferSize = 2048  // Increased buffer size for more stability
+	OutputChannels  = 2     // Channels of the output stream, interleaved left/right
+	AttackTime      = 0.01  // Default envelope attack in seconds
+	DecayTime       = 0.2   // Default envelope decay in seconds
+	SustainLevel    = 0.7   // Default envelope sustain level
+	ReleaseTime     = 0.3   // Default envelope release in seconds
+	ParamSmoothTime = 0.01  // Default time constant of parameter smoothing in seconds
+	smoothSnap      = 1e-6  // Distance from the target at which smoothing jumps the rest of the way
+)
+
+// Engine holds the sound-generating state and renders audio blocks
+type Engine struct {
+	CarrierFreq SmoothValue
+	MinModFreq  SmoothValue
+	MaxModFreq  SmoothValue
+	SweepTime   SmoothValue
+	ModIndex    SmoothValue
+	ModFeedback SmoothValue // Modulator output fed back into its own phase, 0 to 1
+	Volume      SmoothValue
+	Attack      SmoothValue
+	Decay       SmoothValue
+	Sustain     SmoothValue
+	Release     SmoothValue
+	EnvDelay    SmoothValue // Seconds the voice envelope waits before its attack
+	EnvHold     SmoothValue // Seconds the voice envelope holds full level before its decay
+	EnvCurves   CurveSet    // Curves of the voice envelope's attack, decay and release
+	Pan         SmoothValue // Master pan, -1 (left) to 1 (right)
+	PanSpread   SmoothValue // How far voices are spread across the stereo field, 0 to 1
+	PresetFade  SmoothValue // Seconds over which preset loads crossfade
+	CPUBudget   SmoothValue // Fraction of each block's duration voices may use, for MaxPolyphony
+	MixTrim     SmoothValue // Gain of the source mix into the effects, in dB
+	FXTrim      SmoothValue // Gain of the effects chain output into the compressor, in dB
+	Binaural    SmoothValue // Beat in Hz between the drone carrier's left and right channels, 0 for one centred carrier
+	Sampler     *Sampler
+	Tuning      *Tuning        // Frequency of each MIDI note, equal-tempered or from a Scala scale
+	DroneLayer  *DroneLayer    // Sustained chord independent of played notes
+	OneShots    *OneShots      // Samples triggered from the sequencer outside the voices
+	Drums       *DrumSynth     // Kick, snare and hi-hat synthesized outside the voices
+	Mod         *ModMatrix     // Per-voice modulation routings
+	VoiceFX     *VoiceFX       // Effects run inside each voice before summing
+	Sweep       *Sweep         // Breakpoints the modulator frequency follows
+	Polyphony   *Polyphony     // Voices reserved for and allowed to each part
+	Mono        *Mono          // Single-voice legato playing of the keys
+	Sub         *SubOsc        // Octave-down oscillator under the carrier
+	Noise       *Noise         // White or pink noise mixed into each voice
+	FM          *FM            // Operators played by the voices in place of the sine
+	PitchEnv    *PitchEnvelope // Pitch bend of each voice from note-on
+	Filter      *VoiceFilter   // Resonant lowpass in each voice with its own envelope
+	LFO         *LFO           // Low-frequency oscillator for the mod matrix
+	Chorus      *Chorus
+	Delay       *Delay
+	Reverb      *Reverb
+	FX          *fx.Chain
+	Comp        *Compressor      // Master bus compressor/limiter
+	Generator   *SignalGenerator // Test and calibration signals played in place of the synth
+	Input       *AudioInput      // Microphone or line input run through the effects
+	Bus         *Bus             // Notes, parameter and transport changes and meters for the UI and extensions
+	buffer      []float32        // Add audio buffer
+	layerL      []float64        // Drone layer block, left channel
+	layerR      []float64        // Drone layer block, right channel
+	timeIndex   float64          // Move timeIndex into the struct
+
+	drone         atomic.Bool                 // Free-running carrier instead of enveloped voices
+	output        atomic.Pointer[OutputUtils] // Master output summing, swapping and polarity
+	voices        [MaxVoices]Voice
+	voiceCounter  uint64
+	carrierPhase  float64    // Phase of the free-running carrier, 0 to 1
+	binauralPhase float64    // Phase of the free-running carrier's right channel in binaural mode, 0 to 1
+	subPhase      float64    // Phase of the free-running carrier's sub-oscillator, 0 to 1
+	modLast       [2]float64 // Modulator's last two outputs, averaged for a steady feedback
+	events        chan noteEvent
+	sustainDown   bool
+	morph         atomic.Pointer[presetMorph] // Running preset crossfade
+	kills         [killCount]killRamp
+	recorder      atomic.Pointer[Recorder] // Active WAV recording, if any
+	analysis      analysisTap              // Level and brightness of the output for the UI
+	modulation    modulationTap            // Modulation sources for the UI
+	envLevel      float64                  // Highest parts envelope level of the last rendered sample
+	cpu           cpuMeter                 // Callback timing for the voice cost estimate
+	modRoutes     [ModSlots]ModRouting     // Mod matrix routings for the current block
+	stages        stageMeter               // Held peaks through the chain, for gain staging
+	scope         scopeTap                 // Recent output for the oscilloscope
+	rand          *rand.Rand               // Per-note randomness; only used by the audio callback
+
+	audition  atomic.Int32                // Module being auditioned
+	auditions [auditionCount]auditionRamp // Fades of each module's paths for auditioning
+	sleep     atomic.Pointer[sleepFade]   // Running sleep timer, if any
+
+	sweepShape  sweepShape // Sweep breakpoints for the current block
+	sweepOrigin float64    // Time of the latest note-on, where a sweep played once starts
+	sweepLevel  float64    // Sweep level of the frame being rendered, for the mod matrix
+	lfoLevel    float64    // LFO level of the frame being rendered, for the mod matrix
+
+	polyphony polyphonyFrame            // Voice limits and stealing policy for the current block
+	sounding  atomic.Int32              // Voices sounding at the end of the last block, for the UI
+	held      []uint8                   // Keys held in mono mode, oldest first
+	timbres   atomic.Pointer[[]*Timbre] // Other engines mixed into the master bus, for multitimbral playing
+}
+
+// NewEngine creates an engine whose synced effects follow tempo
+func NewEngine(tempo Tempo) *Engine {
+	e := &Engine{
+		buffer:    make([]float32, AudioBufferSize*OutputChannels),
+		layerL:    make([]float64, AudioBufferSize),
+		layerR:    make([]float64, AudioBufferSize),
+		timeIndex: 0,
+		events:    make(chan noteEvent, NoteEventBuffer),
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	e.drone.Store(true)
+	e.CarrierFreq.Set(440.0) // Start with A4 note
+	e.MinModFreq.Set(MinModFreq)
+	e.MaxModFreq.Set(MaxModFreq)
+	e.SweepTime.Set(FreqSweepTime)
+	e.ModIndex.Set(ModulationIndex)
+	e.Volume.Set(InitialVolume)
+	e.Attack.Set(AttackTime)
+	e.Decay.Set(DecayTime)
+	e.Sustain.Set(SustainLevel)
+	e.Release.Set(ReleaseTime)
+	e.PresetFade.Set(PresetFadeTime)
+	e.CPUBudget.Set(DefaultCPUBudget)
+	e.Sampler = &Sampler{}
+	e.Tuning = &Tuning{}
+	e.DroneLayer = NewDroneLayer(e.Tuning)
+	e.OneShots = NewOneShots()
+	e.Drums = NewDrumSynth()
+	e.Mod = NewModMatrix()
+	e.Sweep = NewSweep()
+	e.VoiceFX = NewVoiceFX()
+	e.Polyphony = NewPolyphony()
+	e.Mono = NewMono()
+	e.Sub = NewSubOsc()
+	e.Noise = NewNoise()
+	e.FM = NewFM()
+	e.PitchEnv = NewPitchEnvelope()
+	e.Filter = NewVoiceFilter()
+	e.LFO = NewLFO(tempo)
+	e.held = make([]uint8, 0, 128)
+	e.sweepShape = e.Sweep.snapshot()
+	e.Chorus = NewChorus(tempo, OutputChannels)
+	e.Delay = NewDelay(tempo, OutputChannels)
+	e.Delay.kill = &e.kills[KillDelay]
+	e.Reverb = NewReverb(OutputChannels)
+	e.Reverb.kill = &e.kills[KillReverb]
+	e.Chorus.audition = &e.auditions[AuditionChorus]
+	e.Delay.audition = &e.auditions[AuditionDelay]
+	e.Reverb.audition = &e.auditions[AuditionReverb]
+	for i := range e.kills {
+		e.kills[i].gain = 1
+	}
+	for i := range e.auditions {
+		e.auditions[i].wet.gain = 1
+		e.auditions[i].dry.gain = 1
+	}
+	e.FX = e.newEffectsChain()
+	e.Comp = NewCompressor()
+	e.Generator = NewSignalGenerator()
+	e.Input = NewAudioInput()
+	e.Bus = &Bus{}
+	return e
+}
+
+// MIDINoteToFreq converts a MIDI note number to its equal-tempered frequency at A4 =
+// 440 Hz; notes played through the engine follow its Tuning
+func MIDINoteToFreq(note uint8) float64 {
+	return ReferencePitch * math.Pow(2, (float64(note)-69.0)/12.0)
+}
+
+// CalculateModulatorFreq returns the modulator frequency at a time, following the sweep
+// from the minimum to the maximum frequency
+func (e *Engine) CalculateModulatorFreq(t float64) float64 {
+	e.sweepLevel = e.sweepLevelAt(t)
+	return e.MinModFreq.Get() + (e.MaxModFreq.Get()-e.MinModFreq.Get())*e.sweepLevel
+}
+
+// panGains returns the left and right gains for a pan position, keeping the
+// centre at full level on both sides so mono patches sound as before
+func panGains(pan float64) (float64, float64) {
+	pan = clampFloat(pan, -1, 1)
+	return math.Min(1, 1-pan), math.Min(1, 1+pan)
+}
+
+// SoftClip applies soft clipping to prevent harsh distortion
+func SoftClip(sample float64) float64 {
+	// Apply a hyperbolic tangent-based soft clipper
+	if math.Abs(sample) > ClipThreshold {
+		// Calculate how much the signal exceeds the threshold
+		excess := math.Abs(sample) - ClipThreshold
+
+		// Apply progressively stronger compression as the signal gets louder
+		compressionFactor := 1.0 - math.Min(1.0, excess/(ClipHardLimit-ClipThreshold))
+
+		// Determine the sign of the original sample
+		sign := 1.0
+		if sample < 0 {
+			sign = -1.0
+		}
+
+		// Apply the compression
+		return sign * (ClipThreshold + excess*compressionFactor)
+	}
+	return sample
+}
+
+// Render fills out with the next block of interleaved audio
+func (e *Engine) Render(out []float32) {
+	start := time.Now()
+
+	// Apply note and pedal events queued since the last block
+	e.polyphony = e.Polyphony.snapshot()
+	e.processEvents()
+	voices := 0
+	drone := e.Drone()
+	if !drone {
+		voices = e.ActiveVoices()
+	}
+
+	// Render the drone layer for the block ahead of the per-frame mix
+	frames := len(out) / OutputChannels
+	layerL, layerR := e.layerL[:frames], e.layerR[:frames]
+	clear(layerL)
+	clear(layerR)
+	e.DroneLayer.Render(layerL, layerR)
+
+	// Process audio, one interleaved left/right frame at a time
+	var peaks [stageCount]float64
+	e.modRoutes = e.Mod.Routings()
+	e.sweepShape = e.Sweep.snapshot()
+	lfoSynced := e.LFO.syncedHz()
+	loopStart := time.Now()
+	for frame := 0; frame < frames; frame++ {
+		t := e.timeIndex + float64(frame)/SampleRate
+
+		// Step any preset crossfade in small blocks
+		if frame%morphBlockSize == 0 {
+			e.advanceMorph(morphBlockSize)
+		}
+
+		// The parts kill fades everything but the drum kit
+		partsKill := e.kills[KillParts].next()
+
+		// Calculate modulator wave, feeding some of its output back into its phase
+		modFreq := e.CalculateModulatorFreq(t)
+		modPhase := modFreq * t
+		if feedback := e.ModFeedback.Update(); feedback > 0 {
+			modPhase += (e.modLast[0] + e.modLast[1]) / 2 * feedback * FeedbackDepth
+		}
+		modulator := sineTable.at(modPhase)
+		e.modLast[1], e.modLast[0] = e.modLast[0], modulator
+		e.lfoLevel = e.LFO.next(lfoSynced)
+
+		// Generate carrier signal, either the free-running drone or the played voices.
+		// A running phase lets the carrier glide to a new frequency without jumping.
+		var left, right float64
+		carrierFreq := e.CarrierFreq.Update()
+		e.envLevel = 0
+		if drone {
+			// A binaural beat splits the carrier into two hard-panned sines, half the
+			// beat under and over its frequency, heard beating only on headphones
+			var sub float64
+			if subLevel := e.Sub.Level.Update(); subLevel > 0 {
+				subTable, subRatio := e.Sub.wave()
+				sub = subTable.at(e.subPhase) * subLevel
+				e.subPhase = math.Mod(e.subPhase+carrierFreq*subRatio/SampleRate, 1)
+			}
+			beat := e.Binaural.Update()
+			left = (sineTable.at(e.carrierPhase) + sub) * partsKill
+			right = left
+			if beat > 0 {
+				right = (sineTable.at(e.binauralPhase) + sub) * partsKill
+			}
+			e.carrierPhase = math.Mod(e.carrierPhase+(carrierFreq-beat/2)/SampleRate, 1)
+			e.binauralPhase = math.Mod(e.binauralPhase+(carrierFreq+beat/2)/SampleRate, 1)
+			if beat <= 0 {
+				e.binauralPhase = e.carrierPhase // Start a beat in phase, without a click
+			}
+		} else {
+			left, right = e.renderVoices(partsKill, modulator)
+		}
+
+		// Apply amplitude modulation, add the unmodulated drone layer, one-shots, drums and audio input and place the
+		// mix with the master pan. Auditioning another module fades each source out; the drums are part of the drum kit.
+		am := 1 + e.ModIndex.Update()*modulator
+		voicesGain := e.auditions[AuditionVoices].wet.next()
+		layerGain := e.auditions[AuditionLayer].wet.next() * partsKill
+		shotL, shotR := e.OneShots.nextFrame()
+		drum := e.Drums.nextFrame()
+		inL, inR := e.Input.frame(frame)
+		inputGain := e.Input.Level.Update()
+		left = left*am*voicesGain + layerL[frame]*layerGain + shotL*partsKill + drum + inL*inputGain
+		right = right*am*voicesGain + layerR[frame]*layerGain + shotR*partsKill + drum + inR*inputGain
+		gainL, gainR := panGains(e.Pan.Update())
+		trim := dbToGain(e.MixTrim.Update())
+		left, right = left*gainL*trim, right*gainR*trim
+		peaks[StageMix] = math.Max(peaks[StageMix], math.Max(math.Abs(left), math.Abs(right)))
+		e.buffer[frame*OutputChannels] = float32(left)
+		e.buffer[frame*OutputChannels+1] = float32(right)
+
+		if frame == frames-1 {
+			e.modulation.store(Modulation{ModFreq: modFreq, Modulator: modulator, Envelope: e.envLevel, Sweep: e.sweepLevel})
+		}
+	}
+
+	loop := time.Since(loopStart)
+
+	// Run the block through the effects chain and trim its output
+	e.FX.Process(e.buffer[:len(out)])
+	var trim float64
+	for i := range out {
+		if i%OutputChannels == 0 {
+			trim = dbToGain(e.FXTrim.Update())
+		}
+		e.buffer[i] *= float32(trim)
+		peaks[StageFX] = math.Max(peaks[StageFX], math.Abs(float64(e.buffer[i])))
+	}
+
+	// Mix in the other parts, then control the dynamics of the master bus
+	e.mixTimbres(e.buffer[:len(out)])
+	e.Comp.Process(e.buffer[:len(out)])
+
+	var volume float64
+	sleep := e.sleep.Load()
+	for i := range out {
+		// Apply soft clipping to prevent distortion
+		peaks[StageMaster] = math.Max(peaks[StageMaster], math.Abs(float64(e.buffer[i])))
+		sample := SoftClip(float64(e.buffer[i]))
+
+		// Apply volume control and store in buffer, smoothing once per frame
+		// and fading with any sleep timer
+		if i%OutputChannels == 0 {
+			volume = e.Volume.Update()
+			if sleep != nil {
+				volume *= sleep.next()
+			}
+		}
+		e.buffer[i] = float32(sample * volume)
+	}
+
+	e.stages.hold(peaks)
+	e.analysis.clipped.Store(peaks[StageMaster] > 1)
+
+	// A test signal takes over past the volume and clipping, so it leaves at its own level
+	e.Generator.render(e.buffer[:len(out)], OutputChannels)
+
+	// Fix up the channels for the output device
+	output := e.Output()
+	output.Process(e.buffer[:len(out)], OutputChannels)
+
+	// Copy buffer to output
+	copy(out, e.buffer[:len(out)])
+	e.analysis.measure(out, OutputChannels)
+	e.Bus.Publish(Event{Kind: EventMeter, Meter: e.Analysis()})
+	e.scope.write(out, OutputChannels)
+
+	if r := e.recorder.Load(); r != nil {
+		r.record(out)
+	}
+
+	e.sounding.Store(int32(e.ActiveVoices()))
+	e.timeIndex += float64(len(out)/OutputChannels) / SampleRate
+	e.cpu.measure(frames, voices, loop, time.Since(start))
+}
+
+// Drone reports whether the engine plays the free-running carrier instead of voices
+func (e *Engine) Drone() bool {
+	return e.drone.Load()
+}
+
+// SetDrone switches between the free-running carrier and the enveloped voices
+func (e *Engine) SetDrone(on bool) {
+	e.drone.Store(on)
+}
+
+// GetTimeIndex returns the current time index
+func (e *Engine) GetTimeIndex() float64 {
+	return e.timeIndex
+}
+
+// Seed makes the per-note randomness repeat for the same seed, so the same events render
+// the same samples; call it before rendering starts
+func (e *Engine) Seed(seed int64) {
+	e.rand.Seed(seed)
+}
@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+const envCurveBend = 5 // Bend of the exponential and logarithmic envelope curves
+
+// EnvelopeStage identifies the current segment of an envelope
+type EnvelopeStage int
+
+const (
+	EnvIdle EnvelopeStage = iota
+	EnvAttack
+	EnvDecay
+	EnvSustain
+	EnvRelease
+	EnvDelay // Waiting at the current level before the attack
+	EnvHold  // Holding full level between the attack and the decay
+)
+
+// EnvCurve is the shape of a moving envelope segment
+type EnvCurve int
+
+const (
+	EnvLinear      EnvCurve = iota // A straight line
+	EnvExponential                 // Fast at first and easing into the target, like an analog envelope
+	EnvLogarithmic                 // Slow at first and speeding up towards the target
+	envCurveCount
+)
+
+func (c EnvCurve) String() string {
+	switch c {
+	case EnvLinear:
+		return "linear"
+	case EnvExponential:
+		return "exponential"
+	case EnvLogarithmic:
+		return "logarithmic"
+	}
+	return "unknown"
+}
+
+// Next returns the following curve, wrapping around
+func (c EnvCurve) Next(dir int) EnvCurve {
+	return EnvCurve((int(c) + dir + int(envCurveCount)) % int(envCurveCount))
+}
+
+// shape maps progress through a segment, 0 to 1, to the fraction of the way to its target
+func (c EnvCurve) shape(pos float64) float64 {
+	switch c {
+	case EnvExponential:
+		return (1 - math.Exp(-envCurveBend*pos)) / (1 - math.Exp(-envCurveBend))
+	case EnvLogarithmic:
+		return (math.Exp(envCurveBend*pos) - 1) / (math.Exp(envCurveBend) - 1)
+	}
+	return pos
+}
+
+// EnvelopeCurves is the curve of each moving stage of an envelope as saved with presets
+type EnvelopeCurves struct {
+	Attack  EnvCurve `json:"attack"`
+	Decay   EnvCurve `json:"decay"`
+	Release EnvCurve `json:"release"`
+}
+
+// CurveSet holds the curves of the voice envelope, read by the audio callback
+type CurveSet struct {
+	attack, decay, release atomic.Int32
+}
+
+// State returns the curves for saving with presets
+func (c *CurveSet) State() EnvelopeCurves {
+	return EnvelopeCurves{
+		Attack:  EnvCurve(c.attack.Load()),
+		Decay:   EnvCurve(c.decay.Load()),
+		Release: EnvCurve(c.release.Load()),
+	}
+}
+
+// SetState sets every curve; unknown curves are made linear
+func (c *CurveSet) SetState(st EnvelopeCurves) {
+	c.attack.Store(int32(st.Attack.Next(0)))
+	c.decay.Store(int32(st.Decay.Next(0)))
+	c.release.Store(int32(st.Release.Next(0)))
+}
+
+// EnvelopeShape is the times in seconds, sustain level and curves of an envelope
+type EnvelopeShape struct {
+	Delay, Attack, Hold, Decay, Sustain, Release float64
+	Curves                                       EnvelopeCurves
+}
+
+// Envelope is a DAHDSR envelope: an optional delay, the attack, an optional hold at full
+// level, then decay, sustain and release, each moving stage with a curve of its own
+type Envelope struct {
+	stage EnvelopeStage
+	level float64
+	from  float64 // Level the current stage started from
+	pos   float64 // Progress through the current stage, 0 to 1
+}
+
+// Trigger restarts the envelope from its current level
+func (e *Envelope) Trigger() {
+	e.start(EnvDelay)
+}
+
+// Release moves the envelope into its release segment
+func (e *Envelope) Release() {
+	if e.stage != EnvIdle {
+		e.start(EnvRelease)
+	}
+}
+
+// start enters a stage from the current level
+func (e *Envelope) start(stage EnvelopeStage) {
+	e.stage = stage
+	e.from = e.level
+	e.pos = 0
+}
+
+// Active reports whether the envelope is still producing output
+func (e *Envelope) Active() bool {
+	return e.stage != EnvIdle
+}
+
+// Stage returns the current envelope segment
+func (e *Envelope) Stage() EnvelopeStage {
+	return e.stage
+}
+
+// Next advances a linear ADSR envelope by one sample and returns its level.
+// Times are in seconds, sustain is a level between 0 and 1.
+func (e *Envelope) Next(attack, decay, sustain, release float64) float64 {
+	return e.NextShape(&EnvelopeShape{Attack: attack, Decay: decay, Sustain: sustain, Release: release})
+}
+
+// NextShape advances the envelope by one sample and returns its level. A full-scale
+// segment takes its time, so a retriggered or early release takes only the time its
+// share of the way needs. Zero delay and hold times skip their stages.
+func (e *Envelope) NextShape(s *EnvelopeShape) float64 {
+	if e.stage == EnvDelay {
+		if s.Delay > 0 {
+			e.pos += segmentStep(s.Delay)
+			if e.pos < 1 {
+				return e.level
+			}
+		}
+		e.start(EnvAttack)
+	}
+	switch e.stage {
+	case EnvAttack:
+		if e.move(1, s.Attack, s.Curves.Attack) {
+			if s.Hold > 0 {
+				e.start(EnvHold)
+			} else {
+				e.start(EnvDecay)
+			}
+		}
+	case EnvHold:
+		e.pos += segmentStep(s.Hold)
+		if e.pos >= 1 {
+			e.start(EnvDecay)
+		}
+	case EnvDecay:
+		if e.move(s.Sustain, s.Decay, s.Curves.Decay) {
+			e.stage = EnvSustain
+		}
+	case EnvSustain:
+		e.level = s.Sustain
+	case EnvRelease:
+		if e.move(0, s.Release, s.Curves.Release) {
+			e.stage = EnvIdle
+		}
+	}
+	return e.level
+}
+
+// move steps the level through the current stage towards a target and reports whether
+// it has arrived
+func (e *Envelope) move(target, seconds float64, curve EnvCurve) bool {
+	span := math.Abs(target - e.from)
+	if span > 0 {
+		e.pos += segmentStep(seconds) / span
+	}
+	if span == 0 || e.pos >= 1 {
+		e.level = target
+		return true
+	}
+	e.level = e.from + (target-e.from)*curve.shape(e.pos)
+	return false
+}
+
+// segmentStep returns the per-sample change for a full-scale segment of the given length
+func segmentStep(seconds float64) float64 {
+	if seconds <= 0 {
+		return 1
+	}
+	return 1 / (seconds * SampleRate)
+}
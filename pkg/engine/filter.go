@@ -0,0 +1,111 @@
+package engine
+
+import "math"
+
+const (
+	FilterMinCutoff  = 20.0    // Lowest cutoff in Hz
+	FilterMaxCutoff  = 20000.0 // Highest cutoff in Hz, where the filter is open
+	FilterMaxRes     = 0.95    // Highest resonance, short of self-oscillation
+	FilterEnvOctaves = 8.0     // Most octaves the filter envelope moves the cutoff either way
+	filterKeyCentre  = 60      // Note at which key tracking leaves the cutoff alone (middle C)
+)
+
+// VoiceFilter is a resonant lowpass run in each voice after its oscillator, with an ADSR
+// of its own moving the cutoff by EnvAmount octaves at full level and key tracking moving
+// it with the note: at a KeyTrack of 1 the cutoff follows the keyboard an octave per
+// octave around middle C, so higher notes open the filter more. It's bypassed while
+// fully open with no envelope or tracking.
+type VoiceFilter struct {
+	Cutoff    SmoothValue // Hz, FilterMinCutoff to FilterMaxCutoff
+	Resonance SmoothValue // 0 to FilterMaxRes
+	EnvAmount SmoothValue // Octaves the envelope moves the cutoff at full level, negative to close it
+	KeyTrack  SmoothValue // How far the cutoff follows the note, 0 to 1
+	Attack    SmoothValue
+	Decay     SmoothValue
+	Sustain   SmoothValue
+	Release   SmoothValue
+}
+
+// NewVoiceFilter creates an open filter with the default envelope times
+func NewVoiceFilter() *VoiceFilter {
+	f := &VoiceFilter{}
+	f.Cutoff.Set(FilterMaxCutoff)
+	f.Attack.Set(AttackTime)
+	f.Decay.Set(DecayTime)
+	f.Sustain.Set(SustainLevel)
+	f.Release.Set(ReleaseTime)
+	return f
+}
+
+// Active reports whether the filter changes the sound
+func (f *VoiceFilter) Active() bool {
+	return f.Cutoff.Get() < FilterMaxCutoff || f.EnvAmount.Get() != 0 || f.KeyTrack.Get() != 0
+}
+
+// Response returns the filter's gain at a frequency in dB, for the set cutoff and
+// resonance before the envelope and key tracking move them: 0 dB throughout while it's
+// bypassed
+func (f *VoiceFilter) Response(freq float64) float64 {
+	if !f.Active() {
+		return 0
+	}
+	cutoff := clampFloat(f.Cutoff.Get(), FilterMinCutoff, SampleRate*0.45)
+	damping := 2 - 2*clampFloat(f.Resonance.Get(), 0, FilterMaxRes)
+
+	// The state-variable lowpass is the analog 1/(s² + ks + 1) through the bilinear
+	// transform prewarped at the cutoff, so its response is the analog one at the
+	// warped frequency
+	w := math.Tan(math.Pi*math.Min(freq, SampleRate*0.4999)/SampleRate) / math.Tan(math.Pi*cutoff/SampleRate)
+	magnitude := 1 / math.Hypot(1-w*w, damping*w)
+	return 20 * math.Log10(magnitude+1e-12)
+}
+
+// filterFrame holds the filter settings for one sample, read once for every voice
+type filterFrame struct {
+	cutoff, damping, envAmount, keyTrack float64
+	adsr                                 [4]float64
+}
+
+// frame reads and smooths the filter settings for the next sample
+func (f *VoiceFilter) frame() filterFrame {
+	return filterFrame{
+		cutoff:    f.Cutoff.Update(),
+		damping:   2 - 2*clampFloat(f.Resonance.Update(), 0, FilterMaxRes),
+		envAmount: f.EnvAmount.Update(),
+		keyTrack:  f.KeyTrack.Update(),
+		adsr:      [4]float64{f.Attack.Get(), f.Decay.Get(), f.Sustain.Get(), f.Release.Get()},
+	}
+}
+
+// filterState is a voice's own filter; only touched by the audio callback
+type filterState struct {
+	env      Envelope
+	ic1, ic2 float64 // Integrator states of the state-variable filter
+}
+
+// trigger starts the filter envelope, clearing the integrators for a new note
+func (s *filterState) trigger(fresh bool) {
+	if fresh {
+		*s = filterState{}
+	}
+	s.env.Trigger()
+}
+
+// process filters one sample of a voice playing a note, stepping its envelope
+func (s *filterState) process(x float64, note uint8, fr *filterFrame) float64 {
+	level := s.env.Next(fr.adsr[0], fr.adsr[1], fr.adsr[2], fr.adsr[3])
+	octaves := fr.envAmount*level + fr.keyTrack*float64(int(note)-filterKeyCentre)/12
+	cutoff := clampFloat(fr.cutoff*math.Exp2(octaves), FilterMinCutoff, SampleRate*0.45)
+
+	// Topology-preserving state-variable lowpass, stable as the cutoff moves
+	g := math.Tan(math.Pi * cutoff / SampleRate)
+	a1 := 1 / (1 + g*(g+fr.damping))
+	a2 := g * a1
+	a3 := g * a2
+	v3 := x - s.ic2
+	v1 := a1*s.ic1 + a2*v3
+	v2 := s.ic2 + a2*s.ic1 + a3*v3
+	s.ic1 = 2*v1 - s.ic1
+	s.ic2 = 2*v2 - s.ic2
+	return v2
+}
@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+// TestFilterResponse checks the plotted response against the level of sines run through
+// a voice's filter with its envelope shut
+func TestFilterResponse(t *testing.T) {
+	f := NewVoiceFilter()
+	f.Cutoff.Set(1000)
+	f.Resonance.Set(0.8)
+	fr := f.frame()
+	fr.adsr = [4]float64{}
+	for _, freq := range []float64{100, 1000, 2000, 8000} {
+		var s filterState
+		peak := 0.0
+		for i := 0; i < SampleRate; i++ {
+			y := s.process(math.Sin(2*math.Pi*freq*float64(i)/SampleRate), filterKeyCentre, &fr)
+			if i > SampleRate/2 {
+				peak = math.Max(peak, math.Abs(y))
+			}
+		}
+		if measured, plotted := 20*math.Log10(peak), f.Response(freq); math.Abs(measured-plotted) > 0.1 {
+			t.Errorf("%.0f Hz is plotted at %.2f dB but filtered to %.2f dB", freq, plotted, measured)
+		}
+	}
+	if gain := NewVoiceFilter().Response(5000); gain != 0 {
+		t.Errorf("open filter plotted at %.2f dB, want 0", gain)
+	}
+}
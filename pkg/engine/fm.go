@@ -0,0 +1,223 @@
+package engine
+
+import "sync/atomic"
+
+const (
+	FMOperators = 4    // Operators of each FM voice
+	FMMaxRatio  = 16.0 // Highest operator frequency as a multiple of the note's
+	FMMinRatio  = 0.5  // Lowest operator frequency as a multiple of the note's
+	fmDepth     = 2.0  // Phase shift in cycles a modulator at full level gives its target
+)
+
+// FMAlgorithm is the routing of the operators: which modulate which and which are heard.
+// Operators only modulate lower-numbered ones, and operator 4 has the feedback.
+type FMAlgorithm int
+
+const (
+	FMStack      FMAlgorithm = iota // 4→3→2→1
+	FMTwoOnTwo                      // (3+4)→2→1
+	FMStackPlus                     // (4+(3→2))→1
+	FMBranch                        // ((4→3)+2)→1
+	FMTwoPairs                      // 4→3, 2→1
+	FMOneToThree                    // 4→1, 4→2, 4→3
+	FMPairPlus                      // 4→3, 2, 1
+	FMAdditive                      // 4, 3, 2, 1 all heard
+	fmAlgorithmCount
+)
+
+// fmRoute is an algorithm as bitmasks of operators, bit 0 for operator 1
+type fmRoute struct {
+	mods     [FMOperators]uint8 // Operators modulating each operator
+	carriers uint8              // Operators heard
+}
+
+var fmRoutes = [fmAlgorithmCount]fmRoute{
+	FMStack:      {mods: [FMOperators]uint8{0b0010, 0b0100, 0b1000, 0}, carriers: 0b0001},
+	FMTwoOnTwo:   {mods: [FMOperators]uint8{0b0010, 0b1100, 0, 0}, carriers: 0b0001},
+	FMStackPlus:  {mods: [FMOperators]uint8{0b1010, 0b0100, 0, 0}, carriers: 0b0001},
+	FMBranch:     {mods: [FMOperators]uint8{0b0110, 0, 0b1000, 0}, carriers: 0b0001},
+	FMTwoPairs:   {mods: [FMOperators]uint8{0b0010, 0, 0b1000, 0}, carriers: 0b0101},
+	FMOneToThree: {mods: [FMOperators]uint8{0b1000, 0b1000, 0b1000, 0}, carriers: 0b0111},
+	FMPairPlus:   {mods: [FMOperators]uint8{0, 0, 0b1000, 0}, carriers: 0b0111},
+	FMAdditive:   {carriers: 0b1111},
+}
+
+func (a FMAlgorithm) String() string {
+	switch a {
+	case FMStack:
+		return "4→3→2→1"
+	case FMTwoOnTwo:
+		return "(3+4)→2→1"
+	case FMStackPlus:
+		return "(4+3→2)→1"
+	case FMBranch:
+		return "(4→3+2)→1"
+	case FMTwoPairs:
+		return "4→3, 2→1"
+	case FMOneToThree:
+		return "4→(1,2,3)"
+	case FMPairPlus:
+		return "4→3, 2, 1"
+	case FMAdditive:
+		return "4, 3, 2, 1"
+	}
+	return "unknown"
+}
+
+// Next returns the following algorithm, wrapping around
+func (a FMAlgorithm) Next(dir int) FMAlgorithm {
+	return FMAlgorithm((int(a) + dir + int(fmAlgorithmCount)) % int(fmAlgorithmCount))
+}
+
+// FMState is the FM mode and algorithm as saved with presets
+type FMState struct {
+	Enabled   bool        `json:"enabled"`
+	Algorithm FMAlgorithm `json:"algorithm"`
+}
+
+// FMOperator is one sine operator: its frequency ratio to the note, its output level
+// and its own envelope. A modulator's level sets how far it bends its targets' phase.
+type FMOperator struct {
+	Ratio   SmoothValue // Frequency as a multiple of the note's, FMMinRatio to FMMaxRatio
+	Level   SmoothValue // Output level, 0 to 1
+	Attack  SmoothValue
+	Decay   SmoothValue
+	Sustain SmoothValue
+	Release SmoothValue
+}
+
+// FM replaces the single sine of each oscillator voice with FMOperators operators routed
+// by an algorithm, in the manner of the DX7. The voice envelope still shapes the whole
+// note; each operator's envelope shapes its level within it, so a modulator with a short
+// decay gives the bright attack of an electric piano or a bell.
+type FM struct {
+	Operators [FMOperators]FMOperator
+	Feedback  SmoothValue // Operator 4's output fed back into its own phase, 0 to 1
+	enabled   atomic.Bool
+	algorithm atomic.Int32
+}
+
+// NewFM creates FM voices that are off. Operator 1 starts at full level and the others
+// silent, every operator with a ratio of 1 and an envelope held at full level, so turning
+// FM on sounds the plain sine until the modulators are raised.
+func NewFM() *FM {
+	f := &FM{}
+	for i := range f.Operators {
+		op := &f.Operators[i]
+		op.Ratio.Set(1)
+		op.Sustain.Set(1)
+		op.Release.Set(ReleaseTime)
+	}
+	f.Operators[0].Level.Set(1)
+	return f
+}
+
+// Enabled reports whether oscillator voices play the operators
+func (f *FM) Enabled() bool {
+	return f.enabled.Load()
+}
+
+// SetEnabled switches oscillator voices between the operators and the plain sine
+func (f *FM) SetEnabled(on bool) {
+	f.enabled.Store(on)
+}
+
+// Algorithm returns the routing of the operators
+func (f *FM) Algorithm() FMAlgorithm {
+	return FMAlgorithm(f.algorithm.Load())
+}
+
+// SetAlgorithm sets the routing of the operators
+func (f *FM) SetAlgorithm(a FMAlgorithm) {
+	f.algorithm.Store(int32(a.Next(0)))
+}
+
+// State returns the mode and algorithm for saving with presets
+func (f *FM) State() FMState {
+	return FMState{Enabled: f.Enabled(), Algorithm: f.Algorithm()}
+}
+
+// SetState restores a saved mode and algorithm
+func (f *FM) SetState(st FMState) {
+	f.SetEnabled(st.Enabled)
+	f.SetAlgorithm(st.Algorithm)
+}
+
+// fmFrame holds the operator settings for one sample, read once for every voice
+type fmFrame struct {
+	route    fmRoute
+	ratio    [FMOperators]float64
+	level    [FMOperators]float64
+	adsr     [FMOperators][4]float64
+	feedback float64
+	gain     float64 // Scales the sum of the carriers to one carrier's level
+}
+
+// frame reads and smooths the operator settings for the next sample
+func (f *FM) frame() fmFrame {
+	fr := fmFrame{route: fmRoutes[f.Algorithm()], feedback: f.Feedback.Update()}
+	for i := range f.Operators {
+		op := &f.Operators[i]
+		fr.ratio[i] = op.Ratio.Update()
+		fr.level[i] = op.Level.Update()
+		fr.adsr[i] = [4]float64{op.Attack.Get(), op.Decay.Get(), op.Sustain.Get(), op.Release.Get()}
+	}
+	carriers := 0
+	for c := fr.route.carriers; c != 0; c &= c - 1 {
+		carriers++
+	}
+	fr.gain = 1 / float64(carriers)
+	return fr
+}
+
+// fmVoice is a voice's own operator state; only touched by the audio callback
+type fmVoice struct {
+	phase [FMOperators]float64
+	env   [FMOperators]Envelope
+	out   [FMOperators]float64 // Each operator's output this sample
+	last  [2]float64           // Operator 4's last two outputs, averaged for a steady feedback
+}
+
+// trigger starts the operator envelopes, resetting the phases of a new note
+func (s *fmVoice) trigger(fresh bool) {
+	for i := range s.env {
+		if fresh {
+			s.phase[i] = 0
+			s.env[i] = Envelope{}
+		}
+		s.env[i].Trigger()
+	}
+}
+
+// release releases the operator envelopes
+func (s *fmVoice) release() {
+	for i := range s.env {
+		s.env[i].Release()
+	}
+}
+
+// next renders one sample of the operators at a frequency, from operator 4 down so
+// every modulator is ready before its targets
+func (s *fmVoice) next(fr *fmFrame, freq float64) float64 {
+	var sum float64
+	for i := FMOperators - 1; i >= 0; i-- {
+		level := fr.level[i] * s.env[i].Next(fr.adsr[i][0], fr.adsr[i][1], fr.adsr[i][2], fr.adsr[i][3])
+		shift := 0.0
+		for m := i + 1; m < FMOperators; m++ {
+			if fr.route.mods[i]&(1<<m) != 0 {
+				shift += s.out[m] * fmDepth
+			}
+		}
+		if i == FMOperators-1 && fr.feedback > 0 {
+			shift += (s.last[0] + s.last[1]) / 2 * fr.feedback * FeedbackDepth
+		}
+		s.out[i] = sineTable.at(s.phase[i]+shift) * level
+		s.phase[i] += freq * fr.ratio[i] / SampleRate
+		s.phase[i] -= float64(int(s.phase[i]))
+		if fr.route.carriers&(1<<i) != 0 {
+			sum += s.out[i]
+		}
+	}
+	s.last[1], s.last[0] = s.last[0], s.out[FMOperators-1]
+	return sum * fr.gain
+}
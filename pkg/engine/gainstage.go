@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+const (
+	StageTarget = 0.5 // Peak each stage is trimmed toward: 6 dB of headroom, below the soft clipper
+	MaxTrim     = 24  // Largest stage trim either way, in dB
+)
+
+// Stage is a point in the signal chain whose headroom is measured
+type Stage int
+
+const (
+	StageMix    Stage = iota // The sources after the mix trim, into the effects
+	StageFX                  // The effects chain output after the effects trim, into the compressor
+	StageMaster              // The compressor output, into the soft clipper
+	stageCount
+)
+
+func (st Stage) String() string {
+	switch st {
+	case StageMix:
+		return "mix"
+	case StageFX:
+		return "effects"
+	case StageMaster:
+		return "master"
+	}
+	return "unknown"
+}
+
+// stageMeter holds the peak of each stage since the measurement was last reset
+type stageMeter struct {
+	peaks [stageCount]atomic.Uint64 // float64 bits
+	reset atomic.Bool               // Set by the UI, cleared by the audio callback
+}
+
+// hold raises the held peaks with a block's peaks; called from the audio callback
+func (m *stageMeter) hold(block [stageCount]float64) {
+	reset := m.reset.Swap(false)
+	for st, peak := range block {
+		if !reset {
+			peak = math.Max(peak, math.Float64frombits(m.peaks[st].Load()))
+		}
+		m.peaks[st].Store(math.Float64bits(peak))
+	}
+}
+
+// dbToGain converts decibels to a linear gain
+func dbToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// StagePeak returns the highest level a stage has reached since the last reset
+func (e *Engine) StagePeak(st Stage) float64 {
+	return math.Float64frombits(e.stages.peaks[st].Load())
+}
+
+// ResetStagePeaks starts a new headroom measurement
+func (e *Engine) ResetStagePeaks() {
+	e.stages.reset.Store(true)
+}
+
+// SuggestedTrims returns the changes to the mix and effects trims, in dB, that would
+// bring the peaks measured so far to StageTarget. The effects stage is judged as if the
+// mix change were already made, since the chain scales with its input. ok is false until
+// something has been heard.
+func (e *Engine) SuggestedTrims() (mix, fx float64, ok bool) {
+	mixPeak, fxPeak := e.StagePeak(StageMix), e.StagePeak(StageFX)
+	if mixPeak <= 0 || fxPeak <= 0 {
+		return 0, 0, false
+	}
+	mix = 20 * math.Log10(StageTarget/mixPeak)
+	fx = 20*math.Log10(StageTarget/fxPeak) - mix
+	return mix, fx, true
+}
+
+// ApplyTrims moves the mix and effects trims by the suggested amounts and starts a new
+// measurement, returning false when nothing has been measured yet
+func (e *Engine) ApplyTrims() bool {
+	mix, fx, ok := e.SuggestedTrims()
+	if !ok {
+		return false
+	}
+	e.MixTrim.Set(clampFloat(e.MixTrim.Get()+mix, -MaxTrim, MaxTrim))
+	e.FXTrim.Set(clampFloat(e.FXTrim.Get()+fx, -MaxTrim, MaxTrim))
+	e.ResetStagePeaks()
+	return true
+}
@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"encoding/binary"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Rerun with -update after an intended change to the sound to rewrite the snapshots
+var updateGolden = flag.Bool("update", false, "rewrite the golden render snapshots")
+
+const (
+	goldenBlocks    = 4    // Blocks rendered per case, each AudioBufferSize frames
+	goldenTolerance = 1e-4 // Largest sample difference accepted, for floating point differences between platforms
+	goldenSeed      = 1
+)
+
+// goldenCase is a patch and a sequence of events rendered to a snapshot
+type goldenCase struct {
+	name  string
+	setup func(e *Engine)
+	block func(e *Engine, n int) // Called before rendering block n, to queue events
+}
+
+var goldenCases = []goldenCase{
+	{
+		name:  "drone",
+		setup: func(e *Engine) {},
+	},
+	{
+		name: "voices",
+		setup: func(e *Engine) {
+			e.SetDrone(false)
+		},
+		block: playChord(60, 64, 67),
+	},
+	{
+		name: "effects",
+		setup: func(e *Engine) {
+			e.SetDrone(false)
+			e.Delay.Time.Set(0.05)
+			for _, name := range []string{EffectChorus, EffectDelay, EffectReverb} {
+				e.FX.SetEnabled(name, true)
+			}
+			e.Comp.SetEnabled(true)
+		},
+		block: playChord(57, 60, 64),
+	},
+	{
+		name: "modmatrix",
+		setup: func(e *Engine) {
+			e.SetDrone(false)
+			e.Mod.SetRoutings([]ModRouting{
+				{Source: ModRandom, Dest: ModPitch, Curve: CurveSteps, Steps: ModSteps, Amount: 0.5},
+				{Source: ModVelocity, Dest: ModLevel, Curve: CurveExp, Amount: 1},
+				{Source: ModKeyTrack, Dest: ModPan, Curve: CurveLinear, Amount: -0.5},
+			})
+		},
+		block: playChord(48, 55, 62, 69),
+	},
+	{
+		name: "dronelayer",
+		setup: func(e *Engine) {
+			e.SetDrone(false)
+			e.DroneLayer.Fade.Set(0.05)
+			e.DroneLayer.SetWave(DroneSaw)
+			e.DroneLayer.SetChord(4)
+			e.DroneLayer.SetEnabled(true)
+		},
+	},
+	{
+		name: "voicefx",
+		setup: func(e *Engine) {
+			e.SetDrone(false)
+			e.VoiceFX.Drive.Set(0.6)
+			e.VoiceFX.Drift.Set(0.5)
+			e.VoiceFX.Chorus.Set(0.8)
+		},
+		block: playChord(45, 52, 57, 61),
+	},
+}
+
+// playChord holds the notes for the first half of the render and releases them for the second
+func playChord(notes ...uint8) func(e *Engine, n int) {
+	return func(e *Engine, n int) {
+		for i, note := range notes {
+			switch n {
+			case 0:
+				e.QueueNoteOn(note, uint8(80+i*10), false)
+			case goldenBlocks / 2:
+				e.QueueNoteOff(note, false)
+			}
+		}
+	}
+}
+
+// render plays a case through a fresh engine and returns the interleaved output
+func (c goldenCase) render() []float32 {
+	e := NewEngine(BPM(120))
+	e.Seed(goldenSeed)
+	c.setup(e)
+	out := make([]float32, 0, goldenBlocks*AudioBufferSize*OutputChannels)
+	block := make([]float32, AudioBufferSize*OutputChannels)
+	for n := 0; n < goldenBlocks; n++ {
+		if c.block != nil {
+			c.block(e, n)
+		}
+		e.Render(block)
+		out = append(out, block...)
+	}
+	return out
+}
+
+func TestGoldenRenders(t *testing.T) {
+	for _, c := range goldenCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got := c.render()
+			path := filepath.Join("testdata", "golden", c.name+".f32")
+			if *updateGolden {
+				if err := writeSnapshot(path, got); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := readSnapshot(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -run TestGoldenRenders -update to create it)", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("rendered %d samples, snapshot has %d", len(got), len(want))
+			}
+			for i := range got {
+				if diff := math.Abs(float64(got[i] - want[i])); diff > goldenTolerance {
+					t.Fatalf("frame %d channel %d: got %v, want %v", i/OutputChannels, i%OutputChannels, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestRenderDeterministic checks that rendering a case twice gives identical samples,
+// which the golden snapshots rely on
+func TestRenderDeterministic(t *testing.T) {
+	for _, c := range goldenCases {
+		a, b := c.render(), c.render()
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("%s: sample %d differs between renders: %v and %v", c.name, i, a[i], b[i])
+			}
+		}
+	}
+}
+
+// writeSnapshot stores samples as little-endian float32
+func writeSnapshot(path string, samples []float32) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data := make([]byte, 4*len(samples))
+	for i, x := range samples {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(x))
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// readSnapshot loads samples written by writeSnapshot
+func readSnapshot(path string) ([]float32, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]float32, len(data)/4)
+	for i := range samples {
+		samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return samples, nil
+}
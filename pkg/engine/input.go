@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// AudioInput is a microphone or line input mixed into the source mix ahead of the
+// master pan and the effects chain, so live sound runs through the same effects,
+// compressor, meters and scope as the synth's own. A mono input plays on both sides.
+type AudioInput struct {
+	Level    SmoothValue   // Gain of the input into the mix, 0 (muted) to 1
+	channels atomic.Int32  // Interleaved channels of the last input block; 0 without an input
+	peak     atomic.Uint64 // Peak of the last input block, for the UI
+	tap      scopeTap      // Recent input, mono, for the tuner
+
+	// Only touched by the audio callback
+	block []float32 // Input of the block being rendered
+	width int       // Channels of block
+}
+
+// NewAudioInput creates an input at full level, silent until blocks arrive
+func NewAudioInput() *AudioInput {
+	in := &AudioInput{}
+	in.Level.Set(1)
+	return in
+}
+
+// Channels returns the channels of the open input, 0 when there is none
+func (in *AudioInput) Channels() int {
+	return int(in.channels.Load())
+}
+
+// Peak returns the highest input sample of the last block, before the level
+func (in *AudioInput) Peak() float64 {
+	return math.Float64frombits(in.peak.Load())
+}
+
+// frame returns the left and right input of one frame, silence without an input
+func (in *AudioInput) frame(i int) (float64, float64) {
+	if in.width == 0 || (i+1)*in.width > len(in.block) {
+		return 0, 0
+	}
+	left := float64(in.block[i*in.width])
+	if in.width == 1 {
+		return left, left
+	}
+	return left, float64(in.block[i*in.width+1])
+}
+
+// RenderInput fills out with the next block like Render, mixing in a block of
+// interleaved input with as many frames; a nil input renders without one
+func (e *Engine) RenderInput(in, out []float32) {
+	width := 0
+	if frames := len(out) / OutputChannels; frames > 0 {
+		width = len(in) / frames
+	}
+	var peak float64
+	for _, sample := range in {
+		peak = math.Max(peak, math.Abs(float64(sample)))
+	}
+	e.Input.block, e.Input.width = in, width
+	e.Input.channels.Store(int32(width))
+	e.Input.peak.Store(math.Float64bits(peak))
+	if width > 0 {
+		e.Input.tap.write(in, width)
+	}
+	e.Render(out)
+	e.Input.block, e.Input.width = nil, 0
+}
@@ -0,0 +1,54 @@
+package engine
+
+import "sync/atomic"
+
+const KillRampTime = 0.005 // Seconds a kill switch takes to fade out or back in
+
+// Kill identifies a performance kill switch
+type Kill int
+
+const (
+	KillDelay  Kill = iota // Cut the delay's echoes
+	KillReverb             // Cut the reverb's tail
+	KillParts              // Mute everything but the drums
+	killCount
+)
+
+func (k Kill) String() string {
+	switch k {
+	case KillDelay:
+		return "delay"
+	case KillReverb:
+		return "reverb"
+	case KillParts:
+		return "parts"
+	}
+	return "unknown"
+}
+
+// killRamp is a mute that fades instead of cutting, to avoid clicks
+type killRamp struct {
+	on   atomic.Bool
+	gain float64 // Current gain; only touched by the audio callback
+}
+
+// next moves the gain one sample towards its target and returns it
+func (k *killRamp) next() float64 {
+	step := 1 / (KillRampTime * SampleRate)
+	if k.on.Load() {
+		k.gain = max(0, k.gain-step)
+	} else {
+		k.gain = min(1, k.gain+step)
+	}
+	return k.gain
+}
+
+// Killed reports whether a kill switch is engaged
+func (e *Engine) Killed(k Kill) bool {
+	return e.kills[k].on.Load()
+}
+
+// SetKill engages or releases a kill switch
+func (e *Engine) SetKill(k Kill, on bool) {
+	e.kills[k].on.Store(on)
+}
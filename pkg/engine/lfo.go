@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+const (
+	LFORate    = 2.0   // Default LFO rate in Hz
+	LFOMinRate = 0.01  // Slowest LFO rate in Hz
+	LFOMaxRate = 20.0  // Fastest LFO rate in Hz
+	LFOSyncDiv = "1/4" // Default cycle length when synced
+)
+
+// LFOShape is the wave of the LFO
+type LFOShape int
+
+const (
+	LFOSine LFOShape = iota
+	LFOTriangle
+	LFOSaw
+	LFOSquare
+	LFOSampleHold // A new random level each cycle, held until the next
+	LFORandom     // Glides smoothly from one random level to the next each cycle
+	lfoShapeCount
+)
+
+func (s LFOShape) String() string {
+	switch s {
+	case LFOSine:
+		return "sine"
+	case LFOTriangle:
+		return "triangle"
+	case LFOSaw:
+		return "saw"
+	case LFOSquare:
+		return "square"
+	case LFOSampleHold:
+		return "sample & hold"
+	case LFORandom:
+		return "smooth random"
+	}
+	return "unknown"
+}
+
+// Next returns the following shape, wrapping around
+func (s LFOShape) Next(dir int) LFOShape {
+	return LFOShape((int(s) + dir + int(lfoShapeCount)) % int(lfoShapeCount))
+}
+
+// LFOState is the LFO's shape and sync as saved with presets
+type LFOState struct {
+	Shape LFOShape `json:"shape"`
+	Sync  bool     `json:"sync"`
+	Div   int      `json:"div"` // Index into Divisions used when synced
+}
+
+// LFO is a low-frequency oscillator shared by every voice and read through the mod
+// matrix, from -1 to 1. Synced, one cycle lasts a note division at the tempo.
+type LFO struct {
+	Rate  SmoothValue // Hz when not synced
+	shape atomic.Int32
+	sync  atomic.Bool
+	div   atomic.Int32
+	tempo Tempo
+
+	// Only touched by the audio callback
+	phase    float64
+	from, to float64 // Random levels at the start and end of the cycle
+	seed     uint32  // Xorshift state of the random shapes, never 0
+}
+
+// NewLFO creates a sine LFO at LFORate that syncs to a tempo when asked
+func NewLFO(tempo Tempo) *LFO {
+	l := &LFO{tempo: tempo, seed: 0x9e3779b9}
+	l.to = l.random()
+	l.Rate.Set(LFORate)
+	l.div.Store(int32(DivisionIndex(LFOSyncDiv)))
+	return l
+}
+
+// Shape returns the wave of the LFO
+func (l *LFO) Shape() LFOShape {
+	return LFOShape(l.shape.Load())
+}
+
+// SetShape sets the wave of the LFO
+func (l *LFO) SetShape(s LFOShape) {
+	l.shape.Store(int32(s.Next(0)))
+}
+
+// Synced reports whether the rate follows the tempo
+func (l *LFO) Synced() bool {
+	return l.sync.Load()
+}
+
+// SetSynced switches between the rate in Hz and a division at the tempo
+func (l *LFO) SetSynced(on bool) {
+	l.sync.Store(on)
+}
+
+// Div returns the index into Divisions of the synced cycle
+func (l *LFO) Div() int {
+	return int(l.div.Load())
+}
+
+// SetDiv sets the synced cycle to a division, limited to those there are
+func (l *LFO) SetDiv(div int) {
+	l.div.Store(int32(max(0, min(div, len(Divisions)-1))))
+}
+
+// State returns the shape and sync for saving with presets
+func (l *LFO) State() LFOState {
+	return LFOState{Shape: l.Shape(), Sync: l.Synced(), Div: l.Div()}
+}
+
+// SetState sets the shape and sync, limiting the division to those there are
+func (l *LFO) SetState(st LFOState) {
+	l.SetShape(st.Shape)
+	l.SetSynced(st.Sync)
+	l.SetDiv(st.Div)
+}
+
+// Hz returns the LFO rate, following the tempo when synced
+func (l *LFO) Hz() float64 {
+	if l.Synced() {
+		return 1 / (Divisions[l.div.Load()].Beats * l.tempo.BeatDuration().Seconds())
+	}
+	return l.Rate.Get()
+}
+
+// syncedHz returns the rate while synced and 0 otherwise, read once a block so the
+// tempo isn't asked for every sample
+func (l *LFO) syncedHz() float64 {
+	if l.Synced() {
+		return l.Hz()
+	}
+	return 0
+}
+
+// next advances the LFO by one sample, at a synced rate from syncedHz when not 0, and
+// returns its level
+func (l *LFO) next(synced float64) float64 {
+	rate := l.Rate.Update()
+	if synced > 0 {
+		rate = synced
+	}
+	l.phase += rate / SampleRate
+	if l.phase >= 1 {
+		l.phase -= math.Floor(l.phase)
+		l.from, l.to = l.to, l.random()
+	}
+	p := l.phase
+	switch l.Shape() {
+	case LFOTriangle:
+		return 1 - 4*math.Abs(p-0.5)
+	case LFOSaw:
+		return 2*p - 1
+	case LFOSquare:
+		if p < 0.5 {
+			return 1
+		}
+		return -1
+	case LFOSampleHold:
+		return l.to
+	case LFORandom:
+		return l.from + (l.to-l.from)*p*p*(3-2*p)
+	}
+	return sineTable.at(p)
+}
+
+// random returns the next random level from -1 to 1
+func (l *LFO) random() float64 {
+	l.seed ^= l.seed << 13
+	l.seed ^= l.seed >> 17
+	l.seed ^= l.seed << 5
+	return float64(l.seed)/math.MaxUint32*2 - 1
+}
@@ -0,0 +1,262 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	ModSlots     = 4  // Routings in the modulation matrix
+	ModPitchMax  = 12 // Semitones of pitch modulation at full amount
+	ModSteps     = 12 // Default levels of the stepped curve, semitones at full pitch amount
+	ModMaxSteps  = 24 // Most levels the stepped curve can have
+	modCurveBend = 4  // Bend of the exponential and logarithmic curves
+)
+
+// ModSource is where a modulation routing takes its value from
+type ModSource int
+
+const (
+	ModOff       ModSource = iota // Routing disabled
+	ModVelocity                   // Note velocity, 0 to 1
+	ModEnvelope                   // The voice's amplitude envelope, 0 to 1
+	ModModulator                  // The swept modulator, -1 to 1
+	ModRandom                     // A random value drawn at each note-on, 0 to 1
+	ModKeyTrack                   // Note number across the MIDI range, 0 to 1
+	ModSweep                      // Level of the modulator sweep, 0 to 1
+	ModPitchEnv                   // The voice's pitch envelope, 0 to 1
+	ModLFO                        // The LFO, -1 to 1
+	modSourceCount
+)
+
+func (s ModSource) String() string {
+	switch s {
+	case ModOff:
+		return "off"
+	case ModVelocity:
+		return "velocity"
+	case ModEnvelope:
+		return "envelope"
+	case ModModulator:
+		return "modulator"
+	case ModRandom:
+		return "random"
+	case ModKeyTrack:
+		return "key track"
+	case ModSweep:
+		return "sweep"
+	case ModPitchEnv:
+		return "pitch env"
+	case ModLFO:
+		return "LFO"
+	}
+	return "unknown"
+}
+
+// Next returns the following source, wrapping around
+func (s ModSource) Next(dir int) ModSource {
+	return ModSource((int(s) + dir + int(modSourceCount)) % int(modSourceCount))
+}
+
+// ModDest is the voice property a modulation routing changes
+type ModDest int
+
+const (
+	ModPitch ModDest = iota // Up to ModPitchMax semitones either way
+	ModLevel                // Voice gain, scaled toward the source at full amount
+	ModPan                  // Stereo position offset
+	modDestCount
+)
+
+func (d ModDest) String() string {
+	switch d {
+	case ModPitch:
+		return "pitch"
+	case ModLevel:
+		return "level"
+	case ModPan:
+		return "pan"
+	}
+	return "unknown"
+}
+
+// Next returns the following destination, wrapping around
+func (d ModDest) Next(dir int) ModDest {
+	return ModDest((int(d) + dir + int(modDestCount)) % int(modDestCount))
+}
+
+// ModCurve is the transfer curve a routing applies to its source before scaling
+type ModCurve int
+
+const (
+	CurveLinear ModCurve = iota
+	CurveExp             // Slow start, fast finish
+	CurveLog             // Fast start, slow finish
+	CurveS               // Eased at both ends
+	CurveSteps           // Quantized to a number of levels
+	modCurveCount
+)
+
+func (c ModCurve) String() string {
+	switch c {
+	case CurveLinear:
+		return "linear"
+	case CurveExp:
+		return "exp"
+	case CurveLog:
+		return "log"
+	case CurveS:
+		return "s-curve"
+	case CurveSteps:
+		return "steps"
+	}
+	return "unknown"
+}
+
+// Next returns the following curve, wrapping around
+func (c ModCurve) Next(dir int) ModCurve {
+	return ModCurve((int(c) + dir + int(modCurveCount)) % int(modCurveCount))
+}
+
+// shape applies the curve to a value from 0 to 1
+func (c ModCurve) shape(x float64, steps int) float64 {
+	x = clampFloat(x, 0, 1)
+	switch c {
+	case CurveExp:
+		return (math.Exp(modCurveBend*x) - 1) / (math.Exp(modCurveBend) - 1)
+	case CurveLog:
+		return math.Log(1+(math.Exp(modCurveBend)-1)*x) / modCurveBend
+	case CurveS:
+		return x * x * (3 - 2*x)
+	case CurveSteps:
+		steps = max(2, steps)
+		return math.Min(math.Floor(x*float64(steps)), float64(steps-1)) / float64(steps-1)
+	}
+	return x
+}
+
+// Apply shapes a source value; bipolar values are shaped by magnitude, keeping their sign
+func (c ModCurve) Apply(x float64, steps int) float64 {
+	if x < 0 {
+		return -c.shape(-x, steps)
+	}
+	return c.shape(x, steps)
+}
+
+// ModRouting connects a source to a destination through a curve
+type ModRouting struct {
+	Source ModSource `json:"source"`
+	Dest   ModDest   `json:"dest"`
+	Curve  ModCurve  `json:"curve"`
+	Steps  int       `json:"steps,omitempty"` // Levels of the stepped curve
+	Amount float64   `json:"amount"`          // Depth, -1 to 1
+}
+
+// String describes the routing for display
+func (r ModRouting) String() string {
+	if r.Source == ModOff {
+		return "off"
+	}
+	curve := r.Curve.String()
+	if r.Curve == CurveSteps {
+		curve = fmt.Sprintf("%d steps", r.Steps)
+	}
+	return fmt.Sprintf("%s → %s (%s) %+.0f%%", r.Source, r.Dest, curve, r.Amount*100)
+}
+
+// ModMatrix holds the modulation routings, edited from the UI and read once per block
+// by the audio callback
+type ModMatrix struct {
+	mu    sync.Mutex
+	slots [ModSlots]ModRouting
+}
+
+// NewModMatrix creates a matrix with every routing off
+func NewModMatrix() *ModMatrix {
+	m := &ModMatrix{}
+	for i := range m.slots {
+		m.slots[i] = ModRouting{Source: ModOff, Dest: ModPitch, Curve: CurveLinear, Steps: ModSteps}
+	}
+	return m
+}
+
+// Routing returns one slot's routing
+func (m *ModMatrix) Routing(slot int) ModRouting {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.slots[slot]
+}
+
+// EditRouting applies a change to one slot's routing, keeping it in range
+func (m *ModMatrix) EditRouting(slot int, edit func(r *ModRouting)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := &m.slots[slot]
+	edit(r)
+	r.Amount = clampFloat(r.Amount, -1, 1)
+	r.Steps = max(2, min(ModMaxSteps, r.Steps))
+}
+
+// Routings returns a copy of every slot
+func (m *ModMatrix) Routings() [ModSlots]ModRouting {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.slots
+}
+
+// SetRoutings replaces the slots, as when loading a preset; missing slots are turned off
+func (m *ModMatrix) SetRoutings(routings []ModRouting) {
+	fresh := NewModMatrix()
+	for i := range fresh.slots {
+		if i < len(routings) {
+			fresh.slots[i] = routings[i]
+			fresh.slots[i].Amount = clampFloat(fresh.slots[i].Amount, -1, 1)
+			fresh.slots[i].Steps = max(2, min(ModMaxSteps, fresh.slots[i].Steps))
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.slots = fresh.slots
+}
+
+// modulateVoice evaluates the routings for a voice, returning its pitch offset in
+// semitones, a gain factor and a pan offset
+func (e *Engine) modulateVoice(v *Voice, envelope, modulator float64) (pitch, gain, pan float64) {
+	gain = 1
+	for i := range e.modRoutes {
+		r := &e.modRoutes[i]
+		var x float64
+		switch r.Source {
+		case ModOff:
+			continue
+		case ModVelocity:
+			x = v.velocity
+		case ModEnvelope:
+			x = envelope
+		case ModModulator:
+			x = modulator
+		case ModRandom:
+			x = v.random
+		case ModKeyTrack:
+			x = float64(v.Note) / 127
+		case ModSweep:
+			x = e.sweepLevel
+		case ModPitchEnv:
+			x = v.pitchLevel
+		case ModLFO:
+			x = e.lfoLevel
+		}
+		x = r.Curve.Apply(x, r.Steps)
+
+		switch r.Dest {
+		case ModPitch:
+			pitch += x * r.Amount * ModPitchMax
+		case ModLevel:
+			gain *= clampFloat(1+r.Amount*(x-1), 0, 2)
+		case ModPan:
+			pan += x * r.Amount
+		}
+	}
+	return pitch, gain, pan
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Modulation is a snapshot of the modulation sources acting on the parameters
+type Modulation struct {
+	ModFreq   float64 // Frequency of the swept modulator in Hz
+	Modulator float64 // Output of the modulator, -1 to 1
+	Envelope  float64 // Highest envelope level among the sounding parts, 0 to 1
+	Sweep     float64 // Level of the modulator sweep, 0 to 1
+}
+
+// modulationTap holds the modulation at the end of the last block for the UI to read
+type modulationTap struct {
+	modFreq   atomic.Uint64 // float64 bits
+	modulator atomic.Uint64 // float64 bits
+	envelope  atomic.Uint64 // float64 bits
+	sweep     atomic.Uint64 // float64 bits
+}
+
+// store publishes the modulation reached at the end of a block
+func (t *modulationTap) store(m Modulation) {
+	t.modFreq.Store(math.Float64bits(m.ModFreq))
+	t.modulator.Store(math.Float64bits(m.Modulator))
+	t.envelope.Store(math.Float64bits(m.Envelope))
+	t.sweep.Store(math.Float64bits(m.Sweep))
+}
+
+// Modulation returns the modulation sources as of the last audio block
+func (e *Engine) Modulation() Modulation {
+	return Modulation{
+		ModFreq:   math.Float64frombits(e.modulation.modFreq.Load()),
+		Modulator: math.Float64frombits(e.modulation.modulator.Load()),
+		Envelope:  math.Float64frombits(e.modulation.envelope.Load()),
+		Sweep:     math.Float64frombits(e.modulation.sweep.Load()),
+	}
+}
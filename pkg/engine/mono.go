@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"slices"
+	"sync/atomic"
+)
+
+const MaxPortamento = 2.0 // Longest portamento time in seconds
+
+// NotePriority picks which of the held keys a mono voice plays
+type NotePriority int
+
+const (
+	PriorityLast NotePriority = iota // The key pressed most recently
+	PriorityLow                      // The lowest key held
+	PriorityHigh                     // The highest key held
+	notePriorityCount
+)
+
+func (p NotePriority) String() string {
+	switch p {
+	case PriorityLast:
+		return "last"
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	}
+	return "unknown"
+}
+
+// Next returns the following priority, wrapping around
+func (p NotePriority) Next(dir int) NotePriority {
+	return NotePriority((int(p) + dir + int(notePriorityCount)) % int(notePriorityCount))
+}
+
+// MonoState is the mono mode as saved with presets
+type MonoState struct {
+	Enabled  bool         `json:"enabled"`
+	Priority NotePriority `json:"priority"`
+}
+
+// Mono plays the keys on a single voice when enabled. A key pressed while another is held
+// slides the sounding voice to the new pitch without restarting its envelope, and
+// releasing it slides back to the key still held with priority. Drum notes stay polyphonic.
+type Mono struct {
+	Portamento SmoothValue // Time constant of the slide between legato notes in seconds, 0 to jump
+	enabled    atomic.Bool
+	priority   atomic.Int32
+}
+
+// NewMono creates a mono mode that is off, with last-note priority
+func NewMono() *Mono {
+	return &Mono{}
+}
+
+// Enabled reports whether the keys play on a single voice
+func (m *Mono) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// SetEnabled switches between mono and polyphonic playing
+func (m *Mono) SetEnabled(on bool) {
+	m.enabled.Store(on)
+}
+
+// Priority returns which held key the voice plays
+func (m *Mono) Priority() NotePriority {
+	return NotePriority(m.priority.Load())
+}
+
+// SetPriority sets which held key the voice plays
+func (m *Mono) SetPriority(p NotePriority) {
+	m.priority.Store(int32(p.Next(0)))
+}
+
+// State returns the mode and priority for saving with presets
+func (m *Mono) State() MonoState {
+	return MonoState{Enabled: m.Enabled(), Priority: m.Priority()}
+}
+
+// SetState restores a saved mode and priority
+func (m *Mono) SetState(st MonoState) {
+	m.SetEnabled(st.Enabled)
+	m.SetPriority(st.Priority)
+}
+
+// monoNoteOn holds a key and plays the held key with priority
+func (e *Engine) monoNoteOn(note, velocity uint8) {
+	e.unhold(note)
+	e.held = append(e.held, note)
+	e.monoPlay(velocity)
+}
+
+// monoNoteOff lets go of a key, sliding back to another held key or releasing the voice
+// when none is left
+func (e *Engine) monoNoteOff(note uint8) {
+	e.unhold(note)
+	if len(e.held) > 0 {
+		e.monoPlay(0)
+		return
+	}
+	e.releaseVoice(note, false)
+}
+
+// unhold removes a key from the keys held in mono mode
+func (e *Engine) unhold(note uint8) {
+	e.held = slices.DeleteFunc(e.held, func(n uint8) bool { return n == note })
+}
+
+// monoPlay sounds the held key with priority. A voice still sounding slides to its pitch,
+// taking the velocity unless it is 0; otherwise a new voice starts.
+func (e *Engine) monoPlay(velocity uint8) {
+	note := e.priorityNote()
+	v := e.monoVoice()
+	if v == nil {
+		if velocity > 0 {
+			e.startVoice(note, velocity, false)
+		}
+		return
+	}
+	if velocity > 0 {
+		v.velocity = float64(velocity) / 127
+	}
+	if v.Note == note {
+		return
+	}
+	v.Note = note
+	v.sustained = false
+	glide := e.Mono.Portamento.Get()
+	if glide <= 0 {
+		glide = -1 // SmoothValue takes 0 as its default time, so jump explicitly
+	}
+	v.pitch.SetSmoothing(glide)
+	v.pitch.Set(e.Tuning.NoteFreq(note))
+}
+
+// priorityNote returns the held key the mono voice plays; e.held must not be empty
+func (e *Engine) priorityNote() uint8 {
+	switch e.Mono.Priority() {
+	case PriorityLow:
+		return slices.Min(e.held)
+	case PriorityHigh:
+		return slices.Max(e.held)
+	}
+	return e.held[len(e.held)-1]
+}
+
+// monoVoice returns the held or sustained keys voice, which mono mode slides between notes
+func (e *Engine) monoVoice() *Voice {
+	for i := range e.voices {
+		v := &e.voices[i]
+		if v.Active() && !v.drum && v.env.Stage() != EnvRelease {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package engine
+
+import "slices"
+
+const (
+	PresetFadeTime = 0.3 // Default preset crossfade in seconds
+	MaxPresetFade  = 5.0 // Longest preset crossfade in seconds
+	morphBlockSize = 64  // Samples between crossfade steps
+)
+
+// presetMorph glides parameters from one preset's values to another's
+type presetMorph struct {
+	params  []Param
+	from    []float64 // Values when the crossfade began, read on its first step
+	to      []float64
+	last    []float64 // Values the crossfade last set
+	dropped []bool    // Parameters set from elsewhere, which the crossfade leaves alone
+	elapsed int       // Samples since the crossfade began
+	length  int       // Samples the crossfade lasts
+}
+
+// StartMorph begins gliding the given parameters to their target values over the preset fade time,
+// returning false when the fade is off and the values should be set directly. A parameter set
+// from elsewhere while the fade runs keeps that value and stops gliding.
+func (e *Engine) StartMorph(params []Param, to []float64) bool {
+	length := int(e.PresetFade.Get() * SampleRate)
+	if length <= 0 {
+		return false
+	}
+	e.morph.Store(&presetMorph{params: params, to: to, dropped: make([]bool, len(params)), length: length})
+	return true
+}
+
+// advanceMorph moves the running crossfade on by a number of samples; called from the audio callback
+func (e *Engine) advanceMorph(samples int) {
+	m := e.morph.Load()
+	if m == nil {
+		return
+	}
+	// The starting values are read here rather than in StartMorph, so that a crossfade
+	// replacing another starts from where the audio thread left the last one
+	if m.from == nil {
+		for _, param := range m.params {
+			m.from = append(m.from, param.Value.Get())
+		}
+		m.last = slices.Clone(m.from)
+	}
+	m.elapsed += samples
+	progress := min(float64(m.elapsed)/float64(m.length), 1)
+	for i, param := range m.params {
+		if m.dropped[i] {
+			continue
+		}
+		value := m.from[i] + (m.to[i]-m.from[i])*progress
+		if !param.Value.CompareAndSet(m.last[i], value) {
+			m.dropped[i] = true
+			continue
+		}
+		m.last[i] = value
+	}
+	if progress >= 1 {
+		e.morph.CompareAndSwap(m, nil)
+	}
+}
@@ -0,0 +1,33 @@
+package engine
+
+import "testing"
+
+// TestMorphSetDuring checks that a crossfade glides its parameters to their targets, but
+// leaves one set from elsewhere while it runs at that value
+func TestMorphSetDuring(t *testing.T) {
+	e := NewEngine(BPM(120))
+	e.PresetFade.Set(0.1)
+	var cutoff, resonance SmoothValue
+	cutoff.Set(100)
+	resonance.Set(0)
+	params := []Param{{Name: "cutoff", Value: &cutoff, Max: 1000}, {Name: "resonance", Value: &resonance, Max: 1}}
+	if !e.StartMorph(params, []float64{500, 1}) {
+		t.Fatal("crossfade refused")
+	}
+
+	e.advanceMorph(SampleRate / 20)
+	if v := cutoff.Get(); v <= 100 || v >= 500 {
+		t.Errorf("cutoff %g halfway through, want between 100 and 500", v)
+	}
+	resonance.Set(0.25)
+	e.advanceMorph(SampleRate / 10)
+	if v := cutoff.Get(); v != 500 {
+		t.Errorf("cutoff %g after the crossfade, want 500", v)
+	}
+	if v := resonance.Get(); v != 0.25 {
+		t.Errorf("resonance %g after being set during the crossfade, want 0.25", v)
+	}
+	if e.morph.Load() != nil {
+		t.Error("crossfade still running after its length")
+	}
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+const (
+	NoiseDecay    = 0.05 // Default seconds the noise burst of a note takes to fall by 60 dB
+	MaxNoiseDecay = 2.0  // Longest noise burst decay in seconds
+)
+
+// NoiseColor is the spectrum of the noise source
+type NoiseColor int
+
+const (
+	NoiseWhite NoiseColor = iota // Equal energy per hertz, bright and hissy
+	NoisePink                    // Equal energy per octave, softer and breathier
+	noiseColorCount
+)
+
+func (c NoiseColor) String() string {
+	switch c {
+	case NoiseWhite:
+		return "white"
+	case NoisePink:
+		return "pink"
+	}
+	return "unknown"
+}
+
+// Next returns the following color, wrapping around
+func (c NoiseColor) Next(dir int) NoiseColor {
+	return NoiseColor((int(c) + dir + int(noiseColorCount)) % int(noiseColorCount))
+}
+
+// NoiseState is the noise color as saved with presets
+type NoiseState struct {
+	Color NoiseColor `json:"color"`
+}
+
+// Noise is a noise generator mixed into each oscillator voice under its envelope. Its own
+// envelope is a burst at note-on falling over Decay; Envelope sets how much of the noise
+// follows that burst rather than sounding for the whole note, so at 1 the noise only
+// colours the attack, as for percussion or the breath of a flute. It's silent at level 0.
+type Noise struct {
+	Level    SmoothValue // Mix of the noise into the voice, 0 to 1
+	Decay    SmoothValue // Seconds the burst takes to fall by 60 dB
+	Envelope SmoothValue // How much of the noise follows the burst, 0 (steady) to 1 (burst only)
+	color    atomic.Int32
+}
+
+// NewNoise creates a silent white noise source
+func NewNoise() *Noise {
+	n := &Noise{}
+	n.Decay.Set(NoiseDecay)
+	return n
+}
+
+// Color returns the spectrum of the noise
+func (n *Noise) Color() NoiseColor {
+	return NoiseColor(n.color.Load())
+}
+
+// SetColor sets the spectrum of the noise
+func (n *Noise) SetColor(c NoiseColor) {
+	n.color.Store(int32(c.Next(0)))
+}
+
+// State returns the color for saving with presets
+func (n *Noise) State() NoiseState {
+	return NoiseState{Color: n.Color()}
+}
+
+// SetState restores a saved color
+func (n *Noise) SetState(st NoiseState) {
+	n.SetColor(st.Color)
+}
+
+// noiseState is a voice's own noise generator; only touched by the audio callback
+type noiseState struct {
+	seed  uint32     // Xorshift state, never 0
+	pink  [3]float64 // Pinking filter stages
+	burst float64    // Level of the note-on burst, falling from 1
+}
+
+// reset starts a note's noise from a seed of its own, so voices don't hiss in unison
+func (s *noiseState) reset(random float64) {
+	*s = noiseState{seed: uint32(random*math.MaxUint32) | 1, burst: 1}
+}
+
+// next returns one sample of noise at a color, shaped by the burst, and moves the burst on
+// by its per-sample fall
+func (s *noiseState) next(color NoiseColor, envelope, fall float64) float64 {
+	s.seed ^= s.seed << 13
+	s.seed ^= s.seed >> 17
+	s.seed ^= s.seed << 5
+	x := float64(s.seed)/math.MaxUint32*2 - 1
+	if color == NoisePink {
+		// Paul Kellet's economy filter: three one-pole stages approximating -3 dB per octave
+		s.pink[0] = 0.99765*s.pink[0] + x*0.0990460
+		s.pink[1] = 0.96300*s.pink[1] + x*0.2965164
+		s.pink[2] = 0.57000*s.pink[2] + x*1.0526913
+		x = (s.pink[0] + s.pink[1] + s.pink[2] + x*0.1848) * 0.25
+	}
+	x *= 1 - envelope + envelope*s.burst
+	s.burst *= fall
+	return x
+}
+
+// noiseFall returns the per-sample factor of a burst falling by 60 dB over a decay time
+func noiseFall(decay float64) float64 {
+	return math.Pow(0.001, 1/(math.Max(decay, 1e-3)*SampleRate))
+}
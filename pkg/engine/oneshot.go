@@ -0,0 +1,268 @@
+package engine
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	OneShotSlots   = 8        // Sample slots the sequencer can trigger
+	MaxOneShots    = 8        // One-shots sounding at once; the oldest is cut for a new one
+	OneShotLevel   = 0.8      // Default level of the one-shot samples
+	oneShotMaxSize = 64 << 20 // Largest WAV file loaded into a slot, in bytes
+)
+
+// OneShotSample is a sample loaded into a slot, in stereo at its own rate
+type OneShotSample struct {
+	Name        string
+	left, right []float32
+	step        float64 // Sample frames advanced per output frame
+}
+
+// Duration returns the length of the sample in seconds
+func (s *OneShotSample) Duration() float64 {
+	return float64(len(s.left)) / s.step / SampleRate
+}
+
+// oneShot is a sounding sample; only touched by the audio callback
+type oneShot struct {
+	sample *OneShotSample
+	pos    float64
+	gain   float64
+}
+
+// OneShots plays samples such as risers and impacts from the sequencer, outside the
+// voice engine: they take no voices, ignore the envelope and play to their end
+type OneShots struct {
+	Level SmoothValue
+
+	slots   [OneShotSlots]atomic.Pointer[OneShotSample]
+	playing [MaxOneShots]oneShot
+}
+
+// NewOneShots creates empty sample slots
+func NewOneShots() *OneShots {
+	o := &OneShots{}
+	o.Level.Set(OneShotLevel)
+	return o
+}
+
+// SampleDir returns the directory one-shot WAV files are loaded from
+func SampleDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gosynth", "samples"), nil
+}
+
+// ListSamples returns the .wav files in the sample directory, sorted
+func ListSamples() ([]string, error) {
+	dir, err := SampleDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".wav") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Load reads a WAV file from the sample directory into a slot
+func (o *OneShots) Load(slot int, name string) error {
+	if slot < 0 || slot >= OneShotSlots {
+		return fmt.Errorf("no one-shot slot %d", slot+1)
+	}
+	dir, err := SampleDir()
+	if err != nil {
+		return err
+	}
+	sample, err := readWAV(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	sample.Name = name
+	o.slots[slot].Store(sample)
+	return nil
+}
+
+// Clear empties a slot
+func (o *OneShots) Clear(slot int) {
+	if slot >= 0 && slot < OneShotSlots {
+		o.slots[slot].Store(nil)
+	}
+}
+
+// Sample returns the sample in a slot, or nil
+func (o *OneShots) Sample(slot int) *OneShotSample {
+	if slot < 0 || slot >= OneShotSlots {
+		return nil
+	}
+	return o.slots[slot].Load()
+}
+
+// Names returns the file name loaded in each slot, empty for an empty slot
+func (o *OneShots) Names() []string {
+	names := make([]string, OneShotSlots)
+	for i := range names {
+		if s := o.slots[i].Load(); s != nil {
+			names[i] = s.Name
+		}
+	}
+	return names
+}
+
+// QueueOneShot asks the audio callback to play a slot's sample from the start
+func (e *Engine) QueueOneShot(slot int, velocity uint8) {
+	e.queueEvent(noteEvent{kind: oneShotEvent, note: uint8(slot), velocity: velocity})
+}
+
+// trigger starts a slot's sample on a free player, or the one that has sounded longest
+func (o *OneShots) trigger(slot int, velocity uint8) {
+	sample := o.Sample(slot)
+	if sample == nil {
+		return
+	}
+	p := &o.playing[0]
+	for i := range o.playing {
+		q := &o.playing[i]
+		if q.sample == nil {
+			p = q
+			break
+		}
+		if q.pos/q.sample.step > p.pos/p.sample.step {
+			p = q
+		}
+	}
+	*p = oneShot{sample: sample, gain: float64(velocity) / 127}
+}
+
+// nextFrame returns the next frame of every sounding sample, mixed at the one-shot level
+func (o *OneShots) nextFrame() (float64, float64) {
+	level := o.Level.Update()
+	var left, right float64
+	for i := range o.playing {
+		p := &o.playing[i]
+		if p.sample == nil {
+			continue
+		}
+		whole := int(p.pos)
+		if whole+1 >= len(p.sample.left) {
+			p.sample = nil
+			continue
+		}
+		frac := p.pos - float64(whole)
+		l := float64(p.sample.left[whole])*(1-frac) + float64(p.sample.left[whole+1])*frac
+		r := float64(p.sample.right[whole])*(1-frac) + float64(p.sample.right[whole+1])*frac
+		left += l * p.gain
+		right += r * p.gain
+		p.pos += p.sample.step
+	}
+	return left * level, right * level
+}
+
+// readWAV loads a PCM (8, 16, 24 or 32-bit) or 32-bit float WAV file. Mono files play
+// on both channels; files with more than two channels keep the first two.
+func readWAV(path string) (*OneShotSample, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > oneShotMaxSize {
+		return nil, fmt.Errorf("wav: %s is too large for a one-shot", filepath.Base(path))
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < 12 || string(raw[:4]) != "RIFF" || string(raw[8:12]) != "WAVE" {
+		return nil, errors.New("wav: not a WAV file")
+	}
+
+	var format, channels, bits, rate int
+	var data []byte
+	for body := raw[12:]; len(body) >= 8; {
+		id := string(body[:4])
+		size := int(binary.LittleEndian.Uint32(body[4:8]))
+		body = body[8:]
+		size = min(size, len(body)) // Tolerate a data chunk cut short
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, errors.New("wav: short format chunk")
+			}
+			format = int(binary.LittleEndian.Uint16(body[0:]))
+			channels = int(binary.LittleEndian.Uint16(body[2:]))
+			rate = int(binary.LittleEndian.Uint32(body[4:]))
+			bits = int(binary.LittleEndian.Uint16(body[14:]))
+			if format == 0xfffe && size >= 26 {
+				// WAVE_FORMAT_EXTENSIBLE keeps the real format in its sub-format GUID
+				format = int(binary.LittleEndian.Uint16(body[24:]))
+			}
+		case "data":
+			data = body[:size]
+		}
+		// Chunks are padded to an even size
+		if size%2 == 1 && size < len(body) {
+			size++
+		}
+		body = body[size:]
+	}
+	if data == nil || channels == 0 || rate == 0 {
+		return nil, errors.New("wav: missing format or data")
+	}
+
+	width := bits / 8
+	decode := func(b []byte) float32 {
+		switch {
+		case format == 3 && bits == 32:
+			return math.Float32frombits(binary.LittleEndian.Uint32(b))
+		case format == 1 && bits == 8:
+			return float32(int(b[0])-128) / 128
+		case format == 1 && bits == 16:
+			return float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+		case format == 1 && bits == 24:
+			return float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / 8388608
+		case format == 1 && bits == 32:
+			return float32(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+		}
+		return 0
+	}
+	if !(format == 3 && bits == 32) && !(format == 1 && (bits == 8 || bits == 16 || bits == 24 || bits == 32)) {
+		return nil, fmt.Errorf("wav: unsupported format %d with %d bits", format, bits)
+	}
+
+	frames := len(data) / (width * channels)
+	s := &OneShotSample{
+		left:  make([]float32, frames),
+		right: make([]float32, frames),
+		step:  float64(rate) / SampleRate,
+	}
+	for i := 0; i < frames; i++ {
+		frame := data[i*width*channels:]
+		s.left[i] = decode(frame)
+		s.right[i] = s.left[i]
+		if channels > 1 {
+			s.right[i] = decode(frame[width:])
+		}
+	}
+	return s, nil
+}
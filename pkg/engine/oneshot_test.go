@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shot.wav")
+	wav, err := CreateWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := make([]float32, 100*OutputChannels)
+	for i := range block {
+		block[i] = float32(math.Sin(float64(i) / 10))
+	}
+	if err := wav.Write(block); err != nil {
+		t.Fatal(err)
+	}
+	if err := wav.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sample, err := readWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sample.left) != 100 || sample.step != 1 {
+		t.Fatalf("read %d frames at step %v, want 100 at 1", len(sample.left), sample.step)
+	}
+	for i := range sample.left {
+		if math.Abs(float64(sample.left[i]-block[i*2])) > 1e-4 || math.Abs(float64(sample.right[i]-block[i*2+1])) > 1e-4 {
+			t.Fatalf("frame %d = %v, %v; want %v, %v", i, sample.left[i], sample.right[i], block[i*2], block[i*2+1])
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+// OutputUtils are master output fixes for checking compatibility and miswired setups
+type OutputUtils struct {
+	MonoSum     bool // Play the average of all channels on every channel
+	SwapLR      bool // Exchange the left and right channels
+	InvertLeft  bool // Flip the polarity of the left (or only) channel
+	InvertRight bool // Flip the polarity of the right channel
+}
+
+// Output returns the master output utilities
+func (e *Engine) Output() OutputUtils {
+	if o := e.output.Load(); o != nil {
+		return *o
+	}
+	return OutputUtils{}
+}
+
+// SetOutput sets the master output utilities, taking effect from the next block
+func (e *Engine) SetOutput(o OutputUtils) {
+	e.output.Store(&o)
+}
+
+// Process applies the utilities in place to a block of interleaved channels
+func (o *OutputUtils) Process(buf []float32, channels int) {
+	if channels < 2 {
+		// Summing and swapping need a pair; only the polarity flip applies
+		if o.InvertLeft {
+			for i := range buf {
+				buf[i] = -buf[i]
+			}
+		}
+		return
+	}
+
+	for i := 0; i+1 < len(buf); i += channels {
+		left, right := buf[i], buf[i+1]
+		if o.MonoSum {
+			left = (left + right) / 2
+			right = left
+		}
+		if o.SwapLR {
+			left, right = right, left
+		}
+		if o.InvertLeft {
+			left = -left
+		}
+		if o.InvertRight {
+			right = -right
+		}
+		buf[i], buf[i+1] = left, right
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// SmoothValue is a parameter set by the UI, MIDI or presets and followed smoothly by
+// the audio path, so that stepping a value glides instead of clicking. Every field is
+// atomic, so any goroutine can set a value while the audio callback reads it without
+// locks or races; only the audio path should call Update.
+type SmoothValue struct {
+	value   atomic.Uint64 // Target value as float64 bits
+	current atomic.Uint64 // Smoothed value followed by the audio path, as float64 bits
+	primed  atomic.Bool   // Whether current has started from the target
+	time    atomic.Uint64 // Smoothing time constant in seconds as float64 bits, 0 for ParamSmoothTime
+}
+
+// SetSmoothing sets the smoothing time constant in seconds; 0 restores the default
+// and a negative time makes the value jump
+func (sv *SmoothValue) SetSmoothing(seconds float64) {
+	sv.time.Store(math.Float64bits(seconds))
+}
+
+// Update moves the smoothed value one sample closer to the target and returns it
+func (sv *SmoothValue) Update() float64 {
+	target := sv.Get()
+	current := math.Float64frombits(sv.current.Load())
+	timeConstant := math.Float64frombits(sv.time.Load())
+	if !sv.primed.Load() || timeConstant < 0 || math.Abs(target-current) < smoothSnap {
+		sv.current.Store(math.Float64bits(target))
+		sv.primed.Store(true)
+		return target
+	}
+	if timeConstant == 0 {
+		timeConstant = ParamSmoothTime
+	}
+	current = target + (current-target)*math.Exp(-1/(timeConstant*SampleRate))
+	sv.current.Store(math.Float64bits(current))
+	return current
+}
+
+// Set changes the target value
+func (sv *SmoothValue) Set(value float64) {
+	sv.value.Store(math.Float64bits(value))
+}
+
+// CompareAndSet changes the target value only if it is still old, reporting whether it did
+func (sv *SmoothValue) CompareAndSet(old, value float64) bool {
+	return sv.value.CompareAndSwap(math.Float64bits(old), math.Float64bits(value))
+}
+
+// Get returns the target value
+func (sv *SmoothValue) Get() float64 {
+	return math.Float64frombits(sv.value.Load())
+}
+
+// Current returns the smoothed value the audio path last used
+func (sv *SmoothValue) Current() float64 {
+	if !sv.primed.Load() {
+		return sv.Get()
+	}
+	return math.Float64frombits(sv.current.Load())
+}
+
+// Param is a named, bounded synth parameter
+type Param struct {
+	Name  string
+	Value *SmoothValue
+	Min   float64
+	Max   float64
+}
+
+// Clamp limits a value to the parameter's range
+func (p Param) Clamp(value float64) float64 {
+	return clampFloat(value, p.Min, p.Max)
+}
+
+// Normalized returns the parameter's value as a position from 0 to 1 in its range
+func (p Param) Normalized() float64 {
+	return clampFloat((p.Value.Get()-p.Min)/(p.Max-p.Min), 0, 1)
+}
+
+// clampFloat limits a value to the given range
+func clampFloat(value, min, max float64) float64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
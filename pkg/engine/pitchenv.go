@@ -0,0 +1,22 @@
+package engine
+
+const (
+	PitchEnvMax   = 48  // Most semitones the pitch envelope bends either way
+	PitchEnvDecay = 0.1 // Default seconds for the pitch envelope to fall back
+)
+
+// PitchEnvelope bends each voice's pitch at note-on: it rises over Attack to Amount
+// semitones and falls back to the note over Decay, for the blip of a pluck or the sweep
+// of a kick drum. Its level is also a mod matrix source. It does nothing at amount 0.
+type PitchEnvelope struct {
+	Amount SmoothValue // Semitones at the peak, negative to bend down
+	Attack SmoothValue // Seconds to reach the peak
+	Decay  SmoothValue // Seconds to fall back to the note
+}
+
+// NewPitchEnvelope creates a pitch envelope that is off, with a short decay ready
+func NewPitchEnvelope() *PitchEnvelope {
+	p := &PitchEnvelope{}
+	p.Decay.Set(PitchEnvDecay)
+	return p
+}
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// VoicePart is a group of notes sharing the voices: the melodic preset or the drum kit
+type VoicePart int
+
+const (
+	PartKeys VoicePart = iota
+	PartDrums
+	PartCount
+)
+
+func (p VoicePart) String() string {
+	switch p {
+	case PartKeys:
+		return "keys"
+	case PartDrums:
+		return "drums"
+	}
+	return "unknown"
+}
+
+// partOf returns the part a note belongs to
+func partOf(drum bool) VoicePart {
+	if drum {
+		return PartDrums
+	}
+	return PartKeys
+}
+
+// StealPolicy chooses which sounding voice a new note takes when none is free
+type StealPolicy int
+
+const (
+	StealOldest   StealPolicy = iota // The voice started longest ago
+	StealQuietest                    // The voice with the lowest envelope level, usually one releasing
+	StealSameNote                    // A voice already playing the note, even releasing, else the oldest
+	stealPolicyCount
+)
+
+func (s StealPolicy) String() string {
+	switch s {
+	case StealOldest:
+		return "oldest"
+	case StealQuietest:
+		return "quietest"
+	case StealSameNote:
+		return "same note"
+	}
+	return "unknown"
+}
+
+// Next returns the following policy, wrapping around
+func (s StealPolicy) Next(dir int) StealPolicy {
+	return StealPolicy((int(s) + dir + int(stealPolicyCount)) % int(stealPolicyCount))
+}
+
+// PolyphonyState is the voice limit and stealing policy as saved with presets
+type PolyphonyState struct {
+	Voices int         `json:"voices"` // Most voices sounding at once, 1 to MaxVoices
+	Steal  StealPolicy `json:"steal"`
+}
+
+// PartVoices is a part's share of the voices
+type PartVoices struct {
+	Reserve int `json:"reserve"` // Voices kept free for the part, never stolen by other parts
+	Max     int `json:"max"`     // Most voices the part plays at once; past it, it steals its own oldest
+}
+
+// Polyphony limits how many voices sound at once, chooses which to steal past the limit,
+// and divides the voices between the parts, so a busy pad can't take the voices the
+// drums need. By default every voice may sound, the oldest is stolen, no voices are
+// reserved and every part may use them all.
+type Polyphony struct {
+	mu     sync.Mutex
+	voices int
+	steal  StealPolicy
+	parts  [PartCount]PartVoices
+	frame  atomic.Pointer[polyphonyFrame] // Copied on change, read without locking by the audio callback
+}
+
+// polyphonyFrame is the limits and policy the audio callback allocates voices by
+type polyphonyFrame struct {
+	voices int
+	steal  StealPolicy
+	parts  [PartCount]PartVoices
+}
+
+// NewPolyphony creates limits that leave voice allocation shared freely
+func NewPolyphony() *Polyphony {
+	p := &Polyphony{voices: MaxVoices}
+	p.SetState(nil)
+	return p
+}
+
+// Voices returns the most voices sounding at once
+func (p *Polyphony) Voices() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.voices
+}
+
+// SetVoices sets the most voices sounding at once, from 1 to MaxVoices
+func (p *Polyphony) SetVoices(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.voices = max(1, min(MaxVoices, n))
+	p.publish()
+}
+
+// Steal returns the policy choosing the voice a new note takes past the limit
+func (p *Polyphony) Steal() StealPolicy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.steal
+}
+
+// SetSteal sets the policy choosing the voice a new note takes past the limit
+func (p *Polyphony) SetSteal(s StealPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steal = s.Next(0)
+	p.publish()
+}
+
+// Limits returns the voice limit and stealing policy for saving with presets
+func (p *Polyphony) Limits() PolyphonyState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PolyphonyState{Voices: p.voices, Steal: p.steal}
+}
+
+// SetLimits restores a saved voice limit and stealing policy
+func (p *Polyphony) SetLimits(st PolyphonyState) {
+	p.SetVoices(st.Voices)
+	p.SetSteal(st.Steal)
+}
+
+// Part returns a part's reserve and maximum
+func (p *Polyphony) Part(part VoicePart) PartVoices {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.parts[part]
+}
+
+// SetReserve sets the voices kept for a part, limited to those the other parts haven't
+// reserved, raising the part's maximum to match if needed
+func (p *Polyphony) SetReserve(part VoicePart, n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	free := MaxVoices
+	for i := range p.parts {
+		if VoicePart(i) != part {
+			free -= p.parts[i].Reserve
+		}
+	}
+	v := &p.parts[part]
+	v.Reserve = max(0, min(free, n))
+	v.Max = max(v.Max, v.Reserve)
+	p.publish()
+}
+
+// SetMax sets the most voices a part plays at once, at least one and no fewer than it reserves
+func (p *Polyphony) SetMax(part VoicePart, n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	v := &p.parts[part]
+	v.Max = max(1, v.Reserve, min(MaxVoices, n))
+	p.publish()
+}
+
+// State returns every part's limits, in part order, for saving with presets
+func (p *Polyphony) State() []PartVoices {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PartVoices(nil), p.parts[:]...)
+}
+
+// SetState restores saved limits; parts missing from parts share the voices freely
+func (p *Polyphony) SetState(parts []PartVoices) {
+	p.mu.Lock()
+	for i := range p.parts {
+		p.parts[i] = PartVoices{Max: MaxVoices}
+	}
+	p.publish()
+	p.mu.Unlock()
+	for i := 0; i < min(len(parts), int(PartCount)); i++ {
+		p.SetReserve(VoicePart(i), parts[i].Reserve)
+		p.SetMax(VoicePart(i), parts[i].Max)
+	}
+}
+
+// publish copies the limits and policy for the audio callback; p.mu must be held
+func (p *Polyphony) publish() {
+	p.frame.Store(&polyphonyFrame{voices: p.voices, steal: p.steal, parts: p.parts})
+}
+
+// snapshot returns the limits and policy last published, without locking
+func (p *Polyphony) snapshot() polyphonyFrame {
+	return *p.frame.Load()
+}
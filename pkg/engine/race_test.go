@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestSettingsRaceRender switches the play mode and the effects' tempo sync from another
+// goroutine while blocks render, for go test -race to check
+func TestSettingsRaceRender(t *testing.T) {
+	e := NewEngine(BPM(120))
+	out := make([]float32, AudioBufferSize*OutputChannels)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			e.SetDrone(i%2 == 0)
+			e.Delay.SetSynced(i%3 == 0)
+			e.Delay.SetDiv(i % len(Divisions))
+			e.Chorus.SetSynced(i%2 == 1)
+			e.Chorus.SetDiv(i % len(Divisions))
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		e.Render(out)
+	}
+	close(stop)
+	wg.Wait()
+}
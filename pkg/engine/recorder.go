@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const RecorderBuffer = 64 // Audio blocks queued between the callback and the WAV writer
+
+// Recorder writes the output stream to a 16-bit PCM WAV file from its own goroutine
+type Recorder struct {
+	file   *os.File
+	blocks chan []float32
+	done   chan error
+}
+
+// RecordingDir returns the directory recordings are stored in
+func RecordingDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gosynth", "recordings"), nil
+}
+
+// StartRecording begins writing the output to a WAV file at path
+func (e *Engine) StartRecording(path string) error {
+	if e.recorder.Load() != nil {
+		return errors.New("already recording")
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	r := &Recorder{
+		file:   file,
+		blocks: make(chan []float32, RecorderBuffer),
+		done:   make(chan error, 1),
+	}
+	go r.run()
+	e.recorder.Store(r)
+	return nil
+}
+
+// Recording reports whether the output is being recorded
+func (e *Engine) Recording() bool {
+	return e.recorder.Load() != nil
+}
+
+// StopRecording finishes the WAV file
+func (e *Engine) StopRecording() error {
+	r := e.recorder.Swap(nil)
+	if r == nil {
+		return nil
+	}
+	close(r.blocks)
+	return <-r.done
+}
+
+// record hands a copy of an output block to the writer; called from the audio callback
+func (r *Recorder) record(block []float32) {
+	select {
+	case r.blocks <- append([]float32(nil), block...):
+	default:
+		// Drop the block rather than stall the audio callback
+	}
+}
+
+// run writes queued blocks until the channel is closed, then fills in the header sizes
+func (r *Recorder) run() {
+	wav, err := newWAVFile(r.file)
+	for block := range r.blocks {
+		if werr := wav.Write(block); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	if cerr := wav.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	r.done <- err
+}
+
+// WAVFile writes blocks of interleaved output to a 16-bit PCM WAV file as they are
+// given, for offline renders that must not drop a block
+type WAVFile struct {
+	file   *os.File
+	w      *bufio.Writer
+	frames uint32
+	sample [2]byte
+	err    error // First write error, reported by Close
+}
+
+// CreateWAV creates a WAV file at path, ready for blocks
+func CreateWAV(path string) (*WAVFile, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return newWAVFile(file)
+}
+
+// newWAVFile writes a placeholder header to an open file
+func newWAVFile(file *os.File) (*WAVFile, error) {
+	f := &WAVFile{file: file, w: bufio.NewWriter(file)}
+	f.err = writeWAVHeader(f.w, 0)
+	return f, f.err
+}
+
+// Write appends a block of interleaved samples, clipped to full scale
+func (f *WAVFile) Write(block []float32) error {
+	for _, x := range block {
+		binary.LittleEndian.PutUint16(f.sample[:], uint16(int16(clampFloat(float64(x), -1, 1)*32767)))
+		if _, err := f.w.Write(f.sample[:]); err != nil && f.err == nil {
+			f.err = err
+		}
+	}
+	f.frames += uint32(len(block) / OutputChannels)
+	return f.err
+}
+
+// Frames returns the number of frames written
+func (f *WAVFile) Frames() int {
+	return int(f.frames)
+}
+
+// Close fills in the header sizes and closes the file
+func (f *WAVFile) Close() error {
+	err := f.err
+	if ferr := f.w.Flush(); ferr != nil && err == nil {
+		err = ferr
+	}
+	if _, serr := f.file.Seek(0, 0); serr != nil && err == nil {
+		err = serr
+	}
+	if herr := writeWAVHeader(f.file, f.frames); herr != nil && err == nil {
+		err = herr
+	}
+	if cerr := f.file.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeWAVHeader writes a PCM WAV header for the given number of output frames
+func writeWAVHeader(w io.Writer, frames uint32) error {
+	const bitsPerSample = 16
+	blockAlign := uint16(OutputChannels * bitsPerSample / 8)
+	dataSize := frames * uint32(blockAlign)
+
+	header := []any{
+		[4]byte{'R', 'I', 'F', 'F'}, uint32(36 + dataSize), [4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '}, uint32(16), uint16(1), uint16(OutputChannels),
+		uint32(SampleRate), uint32(SampleRate) * uint32(blockAlign), blockAlign, uint16(bitsPerSample),
+		[4]byte{'d', 'a', 't', 'a'}, dataSize,
+	}
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package engine
+
+import "gosynth/pkg/fx"
+
+const (
+	ReverbSize    = 0.5  // Default room size
+	ReverbDamping = 0.5  // Default high-frequency damping
+	ReverbMix     = 0.25 // Default wet/dry balance
+)
+
+// Reverb adds room ambience to the output through fx.Reverb
+type Reverb struct {
+	Size    SmoothValue // Room size, 0 to 1
+	Damping SmoothValue // High-frequency damping, 0 to 1
+	Mix     SmoothValue // Wet/dry balance, 0 (dry) to 1 (wet)
+
+	channels []*fx.Reverb
+	kill     *killRamp     // Kill switch cutting the tail, if any
+	audition *auditionRamp // Fades for auditioning, if any
+}
+
+// NewReverb creates a reverb with a spread-tuned engine for each channel
+func NewReverb(channels int) *Reverb {
+	r := &Reverb{}
+	r.Size.Set(ReverbSize)
+	r.Damping.Set(ReverbDamping)
+	r.Mix.Set(ReverbMix)
+	for i := 0; i < channels; i++ {
+		r.channels = append(r.channels, fx.NewReverb(SampleRate, i*fx.StereoSpread))
+	}
+	return r
+}
+
+// Process runs a block of interleaved channels through the reverb
+func (r *Reverb) Process(in, out []float32) {
+	for _, engine := range r.channels {
+		engine.RoomSize = r.Size.Get()
+		engine.Damping = r.Damping.Get()
+	}
+	mix, kill, wetGain, dryGain := 0.0, 1.0, 1.0, 1.0
+	for i, x := range in {
+		// Once per frame, shared by its channels
+		if i%len(r.channels) == 0 {
+			mix = r.Mix.Update()
+			if r.kill != nil {
+				kill = r.kill.next()
+			}
+			if r.audition != nil {
+				wetGain, dryGain = r.audition.next()
+			}
+		}
+		dry := float64(x)
+		wet := r.channels[i%len(r.channels)].Process(dry)
+		out[i] = float32(dry*(1-mix*wetGain)*dryGain + wet*kill*mix*wetGain)
+	}
+}
@@ -0,0 +1,228 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gosynth/pkg/sf2"
+)
+
+const (
+	MaxStartOffset = 0.1 // Largest start offset modulation in seconds
+	DrumBank       = 128 // SoundFont bank holding percussion kits
+)
+
+// Sampler plays SoundFont presets through the voice engine
+type Sampler struct {
+	StartVelocity SmoothValue // Seconds skipped at the lowest velocity, less for harder hits
+	StartRandom   SmoothValue // Up to this many seconds skipped at random on each trigger
+
+	mu      sync.Mutex                  // Serializes changes to the loaded font and preset
+	current atomic.Pointer[samplerFont] // Copied on change, read without locking by the audio callback
+}
+
+// samplerFont is the loaded SoundFont and selected preset, never changed once published
+type samplerFont struct {
+	font   *sf2.SoundFont
+	name   string // File name of the loaded font
+	preset int    // Index into the font's presets
+}
+
+// SoundFontDir returns the directory SoundFont files are loaded from
+func SoundFontDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gosynth", "soundfonts"), nil
+}
+
+// ListSoundFonts returns the .sf2 files in the SoundFont directory, sorted
+func ListSoundFonts() ([]string, error) {
+	dir, err := SoundFontDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".sf2") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Load reads a SoundFont file from the SoundFont directory and selects its first preset
+func (sm *Sampler) Load(name string) error {
+	dir, err := SoundFontDir()
+	if err != nil {
+		return err
+	}
+	font, err := sf2.Load(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+
+	// Order presets by bank and program so browsing follows the GM layout
+	sort.SliceStable(font.Presets, func(i, j int) bool {
+		a, b := font.Presets[i], font.Presets[j]
+		if a.Bank != b.Bank {
+			return a.Bank < b.Bank
+		}
+		return a.Number < b.Number
+	})
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.current.Store(&samplerFont{font: font, name: name})
+	return nil
+}
+
+// Unload drops the SoundFont, returning the voices to the oscillator
+func (sm *Sampler) Unload() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.current.Store(nil)
+}
+
+// Loaded reports whether a SoundFont is loaded
+func (sm *Sampler) Loaded() bool {
+	return sm.state().font != nil
+}
+
+// Name returns the file name of the loaded SoundFont
+func (sm *Sampler) Name() string {
+	return sm.state().name
+}
+
+// PresetCount returns the number of presets in the loaded SoundFont
+func (sm *Sampler) PresetCount() int {
+	st := sm.state()
+	if st.font == nil {
+		return 0
+	}
+	return len(st.font.Presets)
+}
+
+// Preset returns the index of the selected preset
+func (sm *Sampler) Preset() int {
+	return sm.state().preset
+}
+
+// SelectPreset chooses the preset new notes are played with
+func (sm *Sampler) SelectPreset(index int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	st := sm.state()
+	if st.font != nil && index >= 0 && index < len(st.font.Presets) {
+		sm.current.Store(&samplerFont{font: st.font, name: st.name, preset: index})
+	}
+}
+
+// PresetName describes the selected preset as bank:program name
+func (sm *Sampler) PresetName() string {
+	st := sm.state()
+	if st.font == nil || len(st.font.Presets) == 0 {
+		return "none"
+	}
+	p := st.font.Presets[st.preset]
+	return fmt.Sprintf("%03d:%03d %s", p.Bank, p.Number, strings.TrimSpace(p.Name))
+}
+
+// zoneFor returns the first zone for a note with the sample data, taken from the
+// selected preset or, for drum notes, from the font's first percussion kit
+func (sm *Sampler) zoneFor(note, velocity uint8, drum bool) (*sf2.Zone, []float32) {
+	st := sm.state()
+	if st.font == nil || len(st.font.Presets) == 0 {
+		return nil, nil
+	}
+	preset := &st.font.Presets[st.preset]
+	if drum {
+		preset = drumKit(st.font)
+		if preset == nil {
+			return nil, nil
+		}
+	}
+	zones := preset.ZonesFor(note, velocity)
+	if len(zones) == 0 {
+		return nil, nil
+	}
+	return zones[0], st.font.Data
+}
+
+// noFont is the state of a sampler with no font loaded
+var noFont samplerFont
+
+// state returns the loaded font and selected preset, empty when none is loaded
+func (sm *Sampler) state() *samplerFont {
+	if st := sm.current.Load(); st != nil {
+		return st
+	}
+	return &noFont
+}
+
+// drumKit returns a font's first preset in the percussion bank
+func drumKit(font *sf2.SoundFont) *sf2.Preset {
+	for i := range font.Presets {
+		if font.Presets[i].Bank == DrumBank {
+			return &font.Presets[i]
+		}
+	}
+	return nil
+}
+
+// startOffset returns the seconds to skip into a sample for a trigger, softer
+// and random hits starting later to vary repeated notes; random is from 0 to 1
+func (sm *Sampler) startOffset(velocity uint8, random float64) float64 {
+	soft := 1 - float64(velocity)/127
+	return sm.StartVelocity.Get()*soft + sm.StartRandom.Get()*random
+}
+
+// startSample points a voice at a zone's sample, pitched for the note under the tuning
+// (drum kit samples keep their own pitch) and starting the given number of seconds in
+func (v *Voice) startSample(zone *sf2.Zone, data []float32, note uint8, offset float64) {
+	v.zone = zone
+	v.sampleData = data
+	skip := offset * float64(zone.Sample.SampleRate)
+	v.samplePos = float64(zone.Start) + math.Min(skip, math.Max(0, float64(zone.End-zone.Start-2)))
+	semitones := float64(int(note)-int(zone.RootKey)) + zone.Tune/100
+	if !v.drum {
+		semitones += 12 * math.Log2(v.freq/MIDINoteToFreq(note))
+	}
+	v.sampleStep = math.Pow(2, semitones/12) * float64(zone.Sample.SampleRate) / SampleRate
+	v.sampleGain = math.Pow(10, -zone.Attenuation/200)
+}
+
+// nextSample reads the voice's sample with linear interpolation, advancing at rate times
+// its pitch, and returns false at its end
+func (v *Voice) nextSample(rate float64) (float64, bool) {
+	z := v.zone
+	looping := z.LoopMode == sf2.LoopContinuous ||
+		(z.LoopMode == sf2.LoopUntilOff && v.env.Stage() != EnvRelease)
+	if looping && z.LoopEnd > z.LoopStart && v.samplePos >= float64(z.LoopEnd) {
+		v.samplePos -= float64(z.LoopEnd - z.LoopStart)
+	}
+
+	i := int(v.samplePos)
+	if i+1 >= z.End {
+		return 0, false
+	}
+	frac := v.samplePos - float64(i)
+	value := float64(v.sampleData[i])*(1-frac) + float64(v.sampleData[i+1])*frac
+	v.samplePos += v.sampleStep * rate
+	return value * v.sampleGain, true
+}
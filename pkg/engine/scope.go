@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+const ScopeSize = 8192 // Frames of output kept for the oscilloscope; a power of two
+
+// scopeTap is a lock-free ring of the mono output: the audio callback writes each
+// sample atomically and then publishes the new frame count, so readers never block it
+type scopeTap struct {
+	samples [ScopeSize]atomic.Uint32 // float32 bits
+	written atomic.Uint64            // Frames written since the engine started
+}
+
+// write appends the mono sum of an interleaved block; called from the audio callback
+func (s *scopeTap) write(buf []float32, channels int) {
+	pos := s.written.Load()
+	frames := len(buf) / channels
+	for i := 0; i < frames; i++ {
+		mono := float32(0)
+		for c := 0; c < channels; c++ {
+			mono += buf[i*channels+c]
+		}
+		s.samples[(pos+uint64(i))%ScopeSize].Store(math.Float32bits(mono / float32(channels)))
+	}
+	s.written.Store(pos + uint64(frames))
+}
+
+// latest returns the most recent frames written, oldest first
+func (s *scopeTap) latest(frames int) []float32 {
+	end := s.written.Load()
+	frames = int(min(uint64(frames), end, ScopeSize))
+	out := make([]float32, frames)
+	for i := range out {
+		out[i] = math.Float32frombits(s.samples[(end-uint64(frames)+uint64(i))%ScopeSize].Load())
+	}
+	return out
+}
+
+// ScopeTrace returns frames of the real output for an oscilloscope. With trigger set the
+// trace starts at the latest rising zero crossing that leaves a full trace after it, so
+// a periodic sound stands still; triggered reports whether one was found, and without
+// one the latest frames are returned free-running.
+func (e *Engine) ScopeTrace(frames int, trigger bool) (trace []float32, triggered bool) {
+	frames = min(frames, ScopeSize/2)
+	if !trigger {
+		return e.scope.latest(frames), false
+	}
+	window := e.scope.latest(frames * 2)
+	for i := len(window) - frames; i > 0; i-- {
+		if window[i-1] < 0 && window[i] >= 0 {
+			return window[i : i+frames], true
+		}
+	}
+	return window[max(0, len(window)-frames):], false
+}
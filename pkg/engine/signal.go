@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+const (
+	SignalMinFreq   = 20.0    // Lowest test tone and sweep frequency in Hz
+	SignalMaxFreq   = 20000.0 // Highest test tone and sweep frequency in Hz
+	SignalMinLevel  = -60.0   // Quietest test signal in dBFS
+	SignalFreq      = 1000.0  // Default test tone frequency in Hz
+	SignalLevel     = -20.0   // Default test signal level in dBFS, well clear of clipping
+	SignalSweepTime = 10.0    // Default seconds a sweep takes from its start to its end frequency
+	MaxSweepTime    = 60.0    // Longest sweep in seconds
+)
+
+// TestSignal is the signal the generator plays
+type TestSignal int
+
+const (
+	TestOff    TestSignal = iota // The synth plays as usual
+	TestSine                     // Sine at the tone frequency
+	TestSquare                   // Square at the tone frequency
+	TestWhite                    // White noise
+	TestPink                     // Pink noise
+	TestSweep                    // Sine sweeping logarithmically from the start to the end frequency, repeating
+	testSignalCount
+)
+
+func (s TestSignal) String() string {
+	switch s {
+	case TestOff:
+		return "off"
+	case TestSine:
+		return "sine"
+	case TestSquare:
+		return "square"
+	case TestWhite:
+		return "white noise"
+	case TestPink:
+		return "pink noise"
+	case TestSweep:
+		return "log sweep"
+	}
+	return "unknown"
+}
+
+// Next returns the following signal, wrapping around
+func (s TestSignal) Next(dir int) TestSignal {
+	return TestSignal((int(s) + dir + int(testSignalCount)) % int(testSignalCount))
+}
+
+// TestChannels are the output channels the test signal plays on
+type TestChannels int
+
+const (
+	TestBoth TestChannels = iota
+	TestLeft
+	TestRight
+	testChannelsCount
+)
+
+func (c TestChannels) String() string {
+	switch c {
+	case TestBoth:
+		return "both"
+	case TestLeft:
+		return "left"
+	case TestRight:
+		return "right"
+	}
+	return "unknown"
+}
+
+// Next returns the following channels, wrapping around
+func (c TestChannels) Next(dir int) TestChannels {
+	return TestChannels((int(c) + dir + int(testChannelsCount)) % int(testChannelsCount))
+}
+
+// SignalGenerator plays test and calibration signals for checking speakers and taking
+// measurements. While a signal is on it replaces the synth's output after the master
+// volume and clipping, so it leaves at exactly its level: the peak of the sine, square
+// and sweep, and near the peak of the noise. The output utilities still apply, so a
+// polarity or channel check goes through them.
+type SignalGenerator struct {
+	Freq      SmoothValue // Frequency of the sine and square in Hz
+	Level     SmoothValue // Peak level in dBFS
+	SweepFrom SmoothValue // Frequency the sweep starts from in Hz
+	SweepTo   SmoothValue // Frequency the sweep ends at in Hz
+	SweepTime SmoothValue // Seconds from the start to the end frequency
+	signal    atomic.Int32
+	channels  atomic.Int32
+	sweepFreq atomic.Uint64 // Frequency the sweep last played, for the UI
+
+	// Only touched by the audio callback
+	playing TestSignal // Signal of the last block, to restart a signal switched on
+	phase   float64    // Phase of the sine, square or sweep, 0 to 1
+	elapsed float64    // Seconds into the current sweep
+	noise   noiseState
+}
+
+// NewSignalGenerator creates a generator that is off, set for a 1 kHz tone at -20 dBFS
+// and a full-range sweep
+func NewSignalGenerator() *SignalGenerator {
+	g := &SignalGenerator{}
+	g.Freq.Set(SignalFreq)
+	g.Level.Set(SignalLevel)
+	g.SweepFrom.Set(SignalMinFreq)
+	g.SweepTo.Set(SignalMaxFreq)
+	g.SweepTime.Set(SignalSweepTime)
+	return g
+}
+
+// Signal returns the signal the generator plays
+func (g *SignalGenerator) Signal() TestSignal {
+	return TestSignal(g.signal.Load())
+}
+
+// SetSignal sets the signal the generator plays; TestOff gives the output back to the synth
+func (g *SignalGenerator) SetSignal(s TestSignal) {
+	g.signal.Store(int32(s.Next(0)))
+}
+
+// Channels returns the output channels the signal plays on
+func (g *SignalGenerator) Channels() TestChannels {
+	return TestChannels(g.channels.Load())
+}
+
+// SetChannels sets the output channels the signal plays on
+func (g *SignalGenerator) SetChannels(c TestChannels) {
+	g.channels.Store(int32(c.Next(0)))
+}
+
+// SweepFreq returns the frequency the sweep reached at the end of the last block
+func (g *SignalGenerator) SweepFreq() float64 {
+	return math.Float64frombits(g.sweepFreq.Load())
+}
+
+// render replaces a block of interleaved channels with the test signal, leaving it
+// untouched while the generator is off
+func (g *SignalGenerator) render(buf []float32, channels int) {
+	signal := g.Signal()
+	if signal != g.playing {
+		// Start a newly chosen signal from the top: phase 0 and the sweep's start
+		g.playing = signal
+		g.phase, g.elapsed = 0, 0
+		g.noise.reset(0.5)
+	}
+	if signal == TestOff {
+		return
+	}
+	left, right := 1.0, 1.0
+	switch g.Channels() {
+	case TestLeft:
+		right = 0
+	case TestRight:
+		left = 0
+	}
+	var freq float64
+	for i := 0; i+channels <= len(buf); i += channels {
+		gain := dbToGain(g.Level.Update())
+		var x float64
+		switch signal {
+		case TestSine:
+			freq = g.Freq.Update()
+			x = math.Sin(2 * math.Pi * g.phase)
+		case TestSquare:
+			freq = g.Freq.Update()
+			x = 1
+			if g.phase >= 0.5 {
+				x = -1
+			}
+		case TestWhite, TestPink:
+			color := NoiseWhite
+			if signal == TestPink {
+				color = NoisePink
+			}
+			x = g.noise.next(color, 0, 1)
+		case TestSweep:
+			// Exponential sweep: the frequency rises by the same interval every second
+			from, to, length := g.SweepFrom.Update(), g.SweepTo.Update(), g.SweepTime.Update()
+			if g.elapsed >= length {
+				g.elapsed, g.phase = 0, 0
+			}
+			freq = from * math.Pow(to/from, g.elapsed/length)
+			g.elapsed += 1.0 / SampleRate
+			x = math.Sin(2 * math.Pi * g.phase)
+		}
+		g.phase = math.Mod(g.phase+freq/SampleRate, 1)
+		x *= gain
+		buf[i] = float32(x * left)
+		if channels > 1 {
+			buf[i+1] = float32(x * right)
+		}
+		for c := 2; c < channels; c++ {
+			buf[i+c] = 0
+		}
+	}
+	if signal == TestSweep {
+		g.sweepFreq.Store(math.Float64bits(freq))
+	}
+}
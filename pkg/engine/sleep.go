@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// sleepFade is a running sleep timer fading the master volume to silence
+type sleepFade struct {
+	length  int64        // Frames from the start of the fade to silence
+	elapsed atomic.Int64 // Frames faded so far; advanced by the audio callback
+}
+
+// next advances the fade by one frame and returns its gain, squared so the level
+// keeps falling audibly instead of lingering near full volume
+func (f *sleepFade) next() float64 {
+	rest := 1 - float64(min(f.elapsed.Add(1), f.length))/float64(f.length)
+	return rest * rest
+}
+
+// StartSleep fades the master volume to silence over d, restarting any running
+// timer; a zero or negative d cancels the timer
+func (e *Engine) StartSleep(d time.Duration) {
+	if d <= 0 {
+		e.sleep.Store(nil)
+		return
+	}
+	e.sleep.Store(&sleepFade{length: max(1, int64(d.Seconds()*SampleRate))})
+}
+
+// SleepRemaining returns the time left until the sleep timer reaches silence, and
+// whether a timer is set
+func (e *Engine) SleepRemaining() (time.Duration, bool) {
+	f := e.sleep.Load()
+	if f == nil {
+		return 0, false
+	}
+	frames := max(0, f.length-f.elapsed.Load())
+	return time.Duration(float64(frames) / SampleRate * float64(time.Second)), true
+}
+
+// Asleep reports whether the sleep timer has faded the output to silence, after
+// which the driver should stop the engine
+func (e *Engine) Asleep() bool {
+	f := e.sleep.Load()
+	return f != nil && f.elapsed.Load() >= f.length
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	SpectrumSize  = 4096 // FFT length in frames; a power of two no larger than ScopeSize
+	SpectrumFloor = -120 // Lowest level Spectrum reports, in dB
+)
+
+// hannWindow tapers each analysis frame so partials between bins don't smear across the spectrum
+var hannWindow = func() []float64 {
+	w := make([]float64, SpectrumSize)
+	for i := range w {
+		w[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/SpectrumSize)
+	}
+	return w
+}()
+
+// Spectrum returns the level of the latest output in each FFT bin, from 0 Hz up to half
+// the sample rate in steps of SampleRate/SpectrumSize, in dB relative to a full-scale
+// sine. It reads the oscilloscope's tap, so it shows what is actually heard.
+func (e *Engine) Spectrum() []float64 {
+	return spectrum(e.scope.latest(SpectrumSize))
+}
+
+// InputSpectrum returns the level of the latest audio input in each FFT bin like
+// Spectrum, before the input level, or nil without an input
+func (e *Engine) InputSpectrum() []float64 {
+	if e.Input.Channels() == 0 {
+		return nil
+	}
+	return spectrum(e.Input.tap.latest(SpectrumSize))
+}
+
+// spectrum analyzes up to SpectrumSize of the newest samples into levels per FFT bin
+func spectrum(samples []float32) []float64 {
+	x := make([]complex128, SpectrumSize)
+	for i, s := range samples {
+		// A short tap right after start-up is aligned to the end, as the newest frames
+		x[SpectrumSize-len(samples)+i] = complex(float64(s)*hannWindow[SpectrumSize-len(samples)+i], 0)
+	}
+	fft(x)
+
+	// A full-scale sine peaks at a quarter of the length through the Hann window
+	levels := make([]float64, SpectrumSize/2+1)
+	for i := range levels {
+		magnitude := math.Hypot(real(x[i]), imag(x[i])) / (SpectrumSize / 4)
+		levels[i] = math.Max(SpectrumFloor, 20*math.Log10(magnitude+1e-12))
+	}
+	return levels
+}
+
+// fft transforms x in place with the iterative radix-2 Cooley-Tukey algorithm; len(x)
+// must be a power of two
+func fft(x []complex128) {
+	n := len(x)
+	shift := 64 - bits.Len(uint(n-1))
+	for i := range x {
+		if j := int(bits.Reverse64(uint64(i)) >> shift); j > i {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size *= 2 {
+		step := complex(math.Cos(-2*math.Pi/float64(size)), math.Sin(-2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], w*x[start+k+size/2]
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+const MaxSubOctaves = 2 // Furthest the sub-oscillator sits under the carrier
+
+// SubShape is the wave of the sub-oscillator
+type SubShape int
+
+const (
+	SubSine SubShape = iota
+	SubSquare
+	subShapeCount
+)
+
+func (w SubShape) String() string {
+	switch w {
+	case SubSine:
+		return "sine"
+	case SubSquare:
+		return "square"
+	}
+	return "unknown"
+}
+
+// Next returns the following shape, wrapping around
+func (w SubShape) Next(dir int) SubShape {
+	return SubShape((int(w) + dir + int(subShapeCount)) % int(subShapeCount))
+}
+
+// SubState is the sub-oscillator's shape and octave as saved with presets
+type SubState struct {
+	Shape   SubShape `json:"shape"`
+	Octaves int      `json:"octaves"` // Octaves under the carrier, 1 or 2
+}
+
+// SubOsc is a second oscillator following the carrier of each voice, and of the drone,
+// one or two octaves down and mixed under it for low-end weight. It's silent at level 0.
+type SubOsc struct {
+	Level   SmoothValue // Mix of the sub wave under the carrier, 0 to 1
+	shape   atomic.Int32
+	octaves atomic.Int32
+}
+
+// NewSubOsc creates a silent sine sub-oscillator an octave down
+func NewSubOsc() *SubOsc {
+	o := &SubOsc{}
+	o.octaves.Store(1)
+	return o
+}
+
+// Shape returns the wave of the sub-oscillator
+func (o *SubOsc) Shape() SubShape {
+	return SubShape(o.shape.Load())
+}
+
+// SetShape sets the wave of the sub-oscillator
+func (o *SubOsc) SetShape(w SubShape) {
+	o.shape.Store(int32(w.Next(0)))
+}
+
+// Octaves returns how many octaves the sub-oscillator sits under the carrier
+func (o *SubOsc) Octaves() int {
+	return int(o.octaves.Load())
+}
+
+// SetOctaves sets how far under the carrier the sub-oscillator sits, 1 or 2 octaves
+func (o *SubOsc) SetOctaves(n int) {
+	o.octaves.Store(int32(max(1, min(MaxSubOctaves, n))))
+}
+
+// State returns the shape and octave for saving with presets
+func (o *SubOsc) State() SubState {
+	return SubState{Shape: o.Shape(), Octaves: o.Octaves()}
+}
+
+// SetState restores a saved shape and octave
+func (o *SubOsc) SetState(st SubState) {
+	o.SetShape(st.Shape)
+	o.SetOctaves(st.Octaves)
+}
+
+// wave returns the sub-oscillator's table and its frequency as a fraction of the carrier's
+func (o *SubOsc) wave() (*wavetable, float64) {
+	table := sineTable
+	if o.Shape() == SubSquare {
+		table = softSquareTable
+	}
+	return table, math.Exp2(-float64(o.Octaves()))
+}
@@ -0,0 +1,232 @@
+package engine
+
+import (
+	"math"
+	"sync"
+)
+
+const SweepMaxPoints = 8 // Most breakpoints a sweep can have
+
+// SweepLoop is what the sweep does at the end of each cycle
+type SweepLoop int
+
+const (
+	SweepRepeat   SweepLoop = iota // Jump back to the first point
+	SweepPingPong                  // Run back through the points, then forward again
+	SweepOnce                      // Hold the last level until the next note starts the sweep again
+	sweepLoopCount
+)
+
+func (l SweepLoop) String() string {
+	switch l {
+	case SweepRepeat:
+		return "repeat"
+	case SweepPingPong:
+		return "ping-pong"
+	case SweepOnce:
+		return "once per note"
+	}
+	return "unknown"
+}
+
+// Next returns the following loop mode, wrapping around
+func (l SweepLoop) Next(dir int) SweepLoop {
+	return SweepLoop((int(l) + dir + int(sweepLoopCount)) % int(sweepLoopCount))
+}
+
+// SweepPoint is a breakpoint of the sweep
+type SweepPoint struct {
+	Pos   float64  `json:"pos"`   // Position in the cycle, 0 to 1; the first point is at 0
+	Level float64  `json:"level"` // 0 for the minimum modulator frequency to 1 for the maximum
+	Curve ModCurve `json:"curve"` // Shape of the segment arriving at this point
+}
+
+// SweepState is a sweep as saved in presets
+type SweepState struct {
+	Loop   SweepLoop    `json:"loop"`
+	Points []SweepPoint `json:"points"`
+}
+
+// sweepShape is a sweep's breakpoints in a fixed array, so the audio callback can copy
+// it once per block without allocating
+type sweepShape struct {
+	loop   SweepLoop
+	count  int
+	points [SweepMaxPoints]SweepPoint
+}
+
+// at returns the level at a position in the cycle. Past the last point the level holds.
+func (sh *sweepShape) at(pos float64) float64 {
+	for i := 1; i < sh.count; i++ {
+		p, q := sh.points[i-1], sh.points[i]
+		if pos < q.Pos {
+			x := 0.0
+			if q.Pos > p.Pos {
+				x = (pos - p.Pos) / (q.Pos - p.Pos)
+			}
+			return p.Level + (q.Level-p.Level)*q.Curve.shape(x, ModSteps)
+		}
+	}
+	return sh.points[sh.count-1].Level
+}
+
+// level returns the sweep level a number of cycles after it started
+func (sh *sweepShape) level(cycles float64) float64 {
+	switch sh.loop {
+	case SweepPingPong:
+		whole := math.Floor(cycles)
+		if int64(whole)%2 != 0 {
+			return sh.at(1 - (cycles - whole))
+		}
+		return sh.at(cycles - whole)
+	case SweepOnce:
+		return sh.at(math.Min(cycles, 1))
+	}
+	return sh.at(cycles - math.Floor(cycles))
+}
+
+// Sweep moves the modulator frequency between its minimum and maximum through a chain
+// of breakpoints, once per Sweep Time. It is edited from the UI and copied by the audio
+// callback once per block. Its level is also a mod matrix source.
+type Sweep struct {
+	mu    sync.Mutex
+	shape sweepShape
+}
+
+// NewSweep creates the classic sweep: a straight rise from minimum to maximum, repeated
+func NewSweep() *Sweep {
+	sw := &Sweep{}
+	sw.shape = defaultSweepShape()
+	return sw
+}
+
+// defaultSweepShape is the rising ramp the modulator has always followed
+func defaultSweepShape() sweepShape {
+	sh := sweepShape{loop: SweepRepeat, count: 2}
+	sh.points[1] = SweepPoint{Pos: 1, Level: 1, Curve: CurveLinear}
+	return sh
+}
+
+// snapshot returns a copy of the breakpoints for the audio callback
+func (sw *Sweep) snapshot() sweepShape {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.shape
+}
+
+// Loop returns the loop mode
+func (sw *Sweep) Loop() SweepLoop {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.shape.loop
+}
+
+// SetLoop changes the loop mode
+func (sw *Sweep) SetLoop(loop SweepLoop) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.shape.loop = loop
+}
+
+// Points returns a copy of the breakpoints
+func (sw *Sweep) Points() []SweepPoint {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return append([]SweepPoint(nil), sw.shape.points[:sw.shape.count]...)
+}
+
+// EditPoint applies a change to one breakpoint, keeping it between its neighbours
+// and its level in range; the first point stays at the start of the cycle
+func (sw *Sweep) EditPoint(i int, edit func(p *SweepPoint)) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if i < 0 || i >= sw.shape.count {
+		return
+	}
+	edit(&sw.shape.points[i])
+	sw.shape.normalize()
+}
+
+// AddPoint splits the longest segment with a point halfway along it
+func (sw *Sweep) AddPoint() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sh := &sw.shape
+	if sh.count >= SweepMaxPoints {
+		return
+	}
+	longest := 1
+	for i := 2; i < sh.count; i++ {
+		if sh.points[i].Pos-sh.points[i-1].Pos > sh.points[longest].Pos-sh.points[longest-1].Pos {
+			longest = i
+		}
+	}
+	mid := (sh.points[longest-1].Pos + sh.points[longest].Pos) / 2
+	point := SweepPoint{Pos: mid, Level: sh.at(mid), Curve: CurveLinear}
+	copy(sh.points[longest+1:], sh.points[longest:sh.count])
+	sh.points[longest] = point
+	sh.count++
+}
+
+// RemovePoint deletes the last breakpoint, keeping at least two
+func (sw *Sweep) RemovePoint() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.shape.count > 2 {
+		sw.shape.count--
+	}
+}
+
+// State returns the sweep for saving
+func (sw *Sweep) State() SweepState {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return SweepState{Loop: sw.shape.loop, Points: append([]SweepPoint(nil), sw.shape.points[:sw.shape.count]...)}
+}
+
+// SetState restores a saved sweep; one with fewer than two points restores the classic ramp
+func (sw *Sweep) SetState(state SweepState) {
+	sh := defaultSweepShape()
+	if len(state.Points) >= 2 {
+		sh.count = copy(sh.points[:], state.Points)
+	}
+	sh.loop = state.Loop
+	if sh.loop < 0 || sh.loop >= sweepLoopCount {
+		sh.loop = SweepRepeat
+	}
+	sh.normalize()
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.shape = sh
+}
+
+// normalize keeps the points in order within the cycle and their levels in range
+func (sh *sweepShape) normalize() {
+	sh.points[0].Pos = 0
+	for i := 0; i < sh.count; i++ {
+		p := &sh.points[i]
+		p.Level = clampFloat(p.Level, 0, 1)
+		if i > 0 {
+			p.Pos = clampFloat(p.Pos, sh.points[i-1].Pos, 1)
+		}
+		if p.Curve < 0 || p.Curve >= modCurveCount {
+			p.Curve = CurveLinear
+		}
+	}
+}
+
+// LevelAt returns the level at a position in the cycle, 0 to 1, for drawing the sweep
+func (sw *Sweep) LevelAt(pos float64) float64 {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.shape.at(clampFloat(pos, 0, 1))
+}
+
+// sweepLevelAt returns the sweep level at a time in the current block. A sweep played
+// once runs from the latest note-on; the others run from the start of the stream.
+func (e *Engine) sweepLevelAt(t float64) float64 {
+	if e.sweepShape.loop == SweepOnce {
+		t -= e.sweepOrigin
+	}
+	return e.sweepShape.level(t / e.SweepTime.Get())
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Tempo is the musical time the engine's synced effects follow
+type Tempo interface {
+	BeatDuration() time.Duration // Length of a quarter note
+}
+
+// BPM is a fixed tempo, for rendering without a running clock
+type BPM float64
+
+// BeatDuration returns the length of a quarter note at the tempo
+func (b BPM) BeatDuration() time.Duration {
+	return time.Duration(float64(time.Minute) / float64(b))
+}
+
+// Division is a musical note length used for tempo-synced rates
+type Division struct {
+	Name  string
+	Beats float64 // Length in quarter notes
+}
+
+// Divisions lists the selectable note lengths, longest first
+var Divisions = []Division{
+	{Name: "1/1", Beats: 4},
+	{Name: "1/2.", Beats: 3},
+	{Name: "1/2", Beats: 2},
+	{Name: "1/2T", Beats: 4.0 / 3},
+	{Name: "1/4.", Beats: 1.5},
+	{Name: "1/4", Beats: 1},
+	{Name: "1/4T", Beats: 2.0 / 3},
+	{Name: "1/8.", Beats: 0.75},
+	{Name: "1/8", Beats: 0.5},
+	{Name: "1/8T", Beats: 1.0 / 3},
+	{Name: "1/16.", Beats: 0.375},
+	{Name: "1/16", Beats: 0.25},
+	{Name: "1/16T", Beats: 1.0 / 6},
+	{Name: "1/32", Beats: 0.125},
+	{Name: "1/32T", Beats: 1.0 / 12},
+}
+
+// DivisionIndex returns the index of the named division, or -1
+func DivisionIndex(name string) int {
+	for i, d := range Divisions {
+		if d.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// TempoSync switches an effect's rate between its own setting and a note division at
+// the tempo. The UI changes it while the audio callback reads it, so both are atomic.
+type TempoSync struct {
+	sync atomic.Bool
+	div  atomic.Int32
+}
+
+// Synced reports whether the effect follows the tempo
+func (t *TempoSync) Synced() bool {
+	return t.sync.Load()
+}
+
+// SetSynced switches between the effect's own rate and a division at the tempo
+func (t *TempoSync) SetSynced(on bool) {
+	t.sync.Store(on)
+}
+
+// Div returns the index into Divisions used when synced
+func (t *TempoSync) Div() int {
+	return int(t.div.Load())
+}
+
+// SetDiv sets the division used when synced, limited to those there are
+func (t *TempoSync) SetDiv(div int) {
+	t.div.Store(int32(max(0, min(div, len(Divisions)-1))))
+}
@@ -0,0 +1,43 @@
+package engine
+
+// Timbre is another engine rendered alongside this one and mixed into its master bus,
+// one part of multitimbral playing. The timbre's engine runs its own voices and effects
+// with its own parameters; its output is placed with Level and Pan before the master
+// compressor, clipper and volume.
+type Timbre struct {
+	Engine *Engine
+	Level  SmoothValue // Gain of the part in the mix, 0 to 1
+	Pan    SmoothValue // Position of the part, -1 (left) to 1 (right)
+	buffer []float32
+}
+
+// NewTimbre creates a part around an engine, at full level in the centre
+func NewTimbre(e *Engine) *Timbre {
+	t := &Timbre{Engine: e, buffer: make([]float32, AudioBufferSize*OutputChannels)}
+	t.Level.Set(1)
+	return t
+}
+
+// SetTimbres replaces the parts mixed into the master bus
+func (e *Engine) SetTimbres(parts []*Timbre) {
+	list := append([]*Timbre(nil), parts...)
+	e.timbres.Store(&list)
+}
+
+// mixTimbres renders every part and adds it to an interleaved block
+func (e *Engine) mixTimbres(buf []float32) {
+	list := e.timbres.Load()
+	if list == nil {
+		return
+	}
+	for _, t := range *list {
+		part := t.buffer[:len(buf)]
+		t.Engine.Render(part)
+		for i := 0; i < len(buf); i += OutputChannels {
+			level := t.Level.Update()
+			gainL, gainR := panGains(t.Pan.Update())
+			buf[i] += part[i] * float32(level*gainL)
+			buf[i+1] += part[i+1] * float32(level*gainR)
+		}
+	}
+}
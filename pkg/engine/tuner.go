@@ -0,0 +1,88 @@
+package engine
+
+import "math"
+
+const (
+	TunerMinFreq = 40.0   // Lowest pitch the tuner detects in Hz, under a bass guitar's low E
+	TunerMaxFreq = 2000.0 // Highest pitch the tuner detects in Hz
+	tunerWindow  = 2048   // Frames compared at each lag
+	tunerGate    = 0.01   // RMS level under which the input counts as silent, -40 dB
+	yinThreshold = 0.15   // Normalized difference a lag must fall under to count as the period
+)
+
+// Pitch is a pitch detected on the audio input
+type Pitch struct {
+	Freq    float64 // Fundamental in Hz
+	Clarity float64 // How periodic the input is, 0 to 1; noisy input scores low
+}
+
+// InputPitch detects the pitch of the latest audio input, reporting false while there
+// is no input, it is too quiet or it has no clear pitch. It runs on the caller's
+// goroutine, not the audio callback's.
+func (e *Engine) InputPitch() (Pitch, bool) {
+	if e.Input.Channels() == 0 {
+		return Pitch{}, false
+	}
+	return detectPitch(e.Input.tap.latest(tunerWindow + lagOf(TunerMinFreq)))
+}
+
+// lagOf returns the period of a frequency in whole frames
+func lagOf(freq float64) int {
+	return int(SampleRate / freq)
+}
+
+// detectPitch finds the fundamental of a block with the YIN method: the period is the
+// first lag at which the block differs little from itself shifted by that lag, measured
+// against the average difference of the shorter lags so that octave errors are rare
+func detectPitch(x []float32) (Pitch, bool) {
+	minLag, maxLag := lagOf(TunerMaxFreq), lagOf(TunerMinFreq)
+	window := len(x) - maxLag
+	if window < tunerWindow {
+		return Pitch{}, false // Not enough input recorded yet
+	}
+	var energy float64
+	for _, s := range x[:window] {
+		energy += float64(s) * float64(s)
+	}
+	if math.Sqrt(energy/float64(window)) < tunerGate {
+		return Pitch{}, false
+	}
+
+	// Difference at each lag, normalized by the running mean of the shorter lags
+	diff := make([]float64, maxLag+1)
+	var running float64
+	for lag := 1; lag <= maxLag; lag++ {
+		var sum float64
+		for j := 0; j < window; j++ {
+			d := float64(x[j] - x[j+lag])
+			sum += d * d
+		}
+		running += sum
+		diff[lag] = 1
+		if running > 0 {
+			diff[lag] = sum * float64(lag) / running
+		}
+	}
+
+	// Take the first dip under the threshold, followed down to its lowest lag
+	lag := 0
+	for l := minLag; l < maxLag; l++ {
+		if diff[l] < yinThreshold {
+			for l+1 < maxLag && diff[l+1] < diff[l] {
+				l++
+			}
+			lag = l
+			break
+		}
+	}
+	if lag == 0 {
+		return Pitch{}, false
+	}
+
+	// Place the period between samples on a parabola through the dip
+	period := float64(lag)
+	if a, b, c := diff[lag-1], diff[lag], diff[lag+1]; a-2*b+c != 0 {
+		period += (a - c) / (2 * (a - 2*b + c))
+	}
+	return Pitch{Freq: SampleRate / period, Clarity: 1 - diff[lag]}, true
+}
@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gosynth/pkg/scala"
+)
+
+const (
+	ReferencePitch    = 440.0 // Default frequency of A4 in Hz
+	MinReferencePitch = 400.0 // Lowest reference pitch in Hz
+	MaxReferencePitch = 480.0 // Highest reference pitch in Hz
+)
+
+// TuningState is the scale and keyboard mapping as saved with presets, by file name
+type TuningState struct {
+	Scale   string `json:"scale"`             // .scl file, empty for equal temperament
+	Mapping string `json:"mapping,omitempty"` // .kbm file, empty for the default mapping
+}
+
+// Tuning maps MIDI notes to frequencies: twelve-tone equal temperament, or a Scala scale
+// placed on the keys by a keyboard mapping. The reference pitch moves every note by its
+// ratio to 440 Hz, a mapping's own reference frequency included.
+type Tuning struct {
+	mu        sync.Mutex
+	scale     string
+	mapping   string
+	table     atomic.Pointer[[scala.Notes]float64] // Frequency of each note, nil for equal temperament
+	reference atomic.Uint64                        // Frequency of A4 in Hz as float64 bits, 0 for ReferencePitch
+}
+
+// TuningDir returns the directory Scala scale and keyboard mapping files are loaded from
+func TuningDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gosynth", "tunings"), nil
+}
+
+// ListScales returns the .scl files in the tuning directory, sorted
+func ListScales() ([]string, error) {
+	return listTunings(".scl")
+}
+
+// ListMappings returns the .kbm files in the tuning directory, sorted
+func ListMappings() ([]string, error) {
+	return listTunings(".kbm")
+}
+
+// listTunings returns the files with an extension in the tuning directory, sorted
+func listTunings(ext string) ([]string, error) {
+	dir, err := TuningDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ext) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Load reads a scale and a keyboard mapping from the tuning directory and retunes every
+// note; an empty mapping places the scale with the default mapping
+func (t *Tuning) Load(scale, mapping string) error {
+	dir, err := TuningDir()
+	if err != nil {
+		return err
+	}
+	s, err := scala.LoadScale(filepath.Join(dir, scale))
+	if err != nil {
+		return err
+	}
+	m := scala.DefaultMapping()
+	if mapping != "" {
+		if m, err = scala.LoadMapping(filepath.Join(dir, mapping)); err != nil {
+			return err
+		}
+	}
+	table, err := scala.Frequencies(s, m)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scale = scale
+	t.mapping = mapping
+	t.table.Store(&table)
+	return nil
+}
+
+// Reset returns every note to equal temperament
+func (t *Tuning) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scale = ""
+	t.mapping = ""
+	t.table.Store(nil)
+}
+
+// Scale returns the file name of the loaded scale, empty for equal temperament
+func (t *Tuning) Scale() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.scale
+}
+
+// Mapping returns the file name of the loaded keyboard mapping, empty for the default
+func (t *Tuning) Mapping() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.mapping
+}
+
+// State returns the scale and mapping for saving with presets
+func (t *Tuning) State() TuningState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return TuningState{Scale: t.scale, Mapping: t.mapping}
+}
+
+// SetState loads a saved scale and mapping, or equal temperament without a scale
+func (t *Tuning) SetState(st TuningState) error {
+	if st.Scale == "" {
+		t.Reset()
+		return nil
+	}
+	return t.Load(st.Scale, st.Mapping)
+}
+
+// Reference returns the frequency of A4 in Hz
+func (t *Tuning) Reference() float64 {
+	if bits := t.reference.Load(); bits != 0 {
+		return math.Float64frombits(bits)
+	}
+	return ReferencePitch
+}
+
+// SetReference sets the frequency of A4, limited to MinReferencePitch to MaxReferencePitch
+func (t *Tuning) SetReference(hz float64) {
+	t.reference.Store(math.Float64bits(clampFloat(hz, MinReferencePitch, MaxReferencePitch)))
+}
+
+// NoteFreq returns the frequency of a MIDI note under the tuning, 0 for notes the
+// keyboard mapping leaves silent
+func (t *Tuning) NoteFreq(note uint8) float64 {
+	freq := MIDINoteToFreq(note)
+	if table := t.table.Load(); table != nil {
+		freq = table[note&0x7f]
+	}
+	return freq * t.Reference() / ReferencePitch
+}
+
+// Mapped reports whether a note sounds under the tuning
+func (t *Tuning) Mapped(note uint8) bool {
+	return t.NoteFreq(note) > 0
+}
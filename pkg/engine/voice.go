@@ -0,0 +1,429 @@
+package engine
+
+import (
+	"math"
+
+	"gosynth/pkg/sf2"
+)
+
+const (
+	MaxVoices       = 8   // Number of simultaneously sounding notes
+	NoteEventBuffer = 256 // Pending note events between MIDI/UI and the audio callback
+)
+
+// noteEventKind identifies a queued note event
+type noteEventKind int
+
+const (
+	noteOnEvent noteEventKind = iota
+	noteOffEvent
+	sustainEvent
+	oneShotEvent // Slot number in note
+	drumEvent    // Drum kind in note
+	panicEvent   // Release every voice
+)
+
+// noteEvent is a note or pedal change queued for the audio callback
+type noteEvent struct {
+	kind     noteEventKind
+	note     uint8
+	velocity uint8
+	down     bool
+	drum     bool // Played from the General MIDI drum map
+}
+
+// Voice is a single sounding note
+type Voice struct {
+	Note       uint8
+	velocity   float64
+	freq       float64
+	pitch      SmoothValue // Frequency freq follows, sliding between notes in mono mode
+	phase      float64
+	subPhase   float64 // Phase of the sub-oscillator, 0 to 1
+	env        Envelope
+	pitchEnv   Envelope // Attack-decay envelope bending the pitch from note-on
+	pitchLevel float64  // Level of pitchEnv this sample, for the mod matrix
+	sustained  bool     // Note-off arrived while the sustain pedal was down
+	started    uint64   // Allocation order, used to steal the oldest voice
+	drum       bool     // Playing a note of the drum kit rather than the melodic preset
+	pan        float64
+	random     float64 // Drawn at each note-on for the random modulation source
+	fx         voiceFXState
+	noise      noiseState
+	fm         fmVoice
+	filter     filterState
+
+	// Sample playback, used instead of the oscillator when a SoundFont zone is set
+	zone       *sf2.Zone
+	sampleData []float32
+	samplePos  float64
+	sampleStep float64 // Sample frames advanced per output sample
+	sampleGain float64
+}
+
+// Active reports whether the voice is still sounding
+func (v *Voice) Active() bool {
+	return v.env.Active()
+}
+
+// SustainDown reports whether the sustain pedal is currently held
+func (e *Engine) SustainDown() bool {
+	return e.sustainDown
+}
+
+// SoundingVoices returns the number of voices sounding at the end of the last block, for
+// the UI
+func (e *Engine) SoundingVoices() int {
+	return int(e.sounding.Load())
+}
+
+// ActiveVoices returns the number of voices currently sounding
+func (e *Engine) ActiveVoices() int {
+	count := 0
+	for i := range e.voices {
+		if e.voices[i].Active() {
+			count++
+		}
+	}
+	return count
+}
+
+// QueueNoteOn asks the audio callback to start a note, from the drum kit when drum is set
+func (e *Engine) QueueNoteOn(note, velocity uint8, drum bool) {
+	e.queueEvent(noteEvent{kind: noteOnEvent, note: note, velocity: velocity, drum: drum})
+}
+
+// QueueNoteOff asks the audio callback to release a note
+func (e *Engine) QueueNoteOff(note uint8, drum bool) {
+	e.queueEvent(noteEvent{kind: noteOffEvent, note: note, drum: drum})
+}
+
+// QueueSustain asks the audio callback to apply a sustain pedal change
+func (e *Engine) QueueSustain(down bool) {
+	e.queueEvent(noteEvent{kind: sustainEvent, down: down})
+}
+
+// QueuePanic asks the audio callback to release every voice, lifting the sustain pedal
+// and forgetting the keys held in mono mode
+func (e *Engine) QueuePanic() {
+	e.queueEvent(noteEvent{kind: panicEvent})
+}
+
+// queueEvent hands an event to the audio callback without blocking the caller
+func (e *Engine) queueEvent(ev noteEvent) {
+	select {
+	case e.events <- ev:
+	default:
+		// Drop the event rather than stall the MIDI or UI goroutine
+	}
+}
+
+// processEvents applies all queued note events; called from the audio callback
+func (e *Engine) processEvents() {
+	for {
+		select {
+		case ev := <-e.events:
+			switch ev.kind {
+			case noteOnEvent:
+				if !ev.drum && !e.Tuning.Mapped(ev.note) {
+					break // Keys the keyboard mapping leaves out are silent
+				}
+				if e.Mono.Enabled() && !ev.drum {
+					e.monoNoteOn(ev.note, ev.velocity)
+				} else {
+					e.startVoice(ev.note, ev.velocity, ev.drum)
+				}
+				if !ev.drum {
+					e.sweepOrigin = e.timeIndex
+				}
+				e.Bus.Publish(Event{Kind: EventNoteOn, Note: ev.note, Velocity: ev.velocity, Drum: ev.drum})
+			case noteOffEvent:
+				if e.Mono.Enabled() && !ev.drum {
+					e.monoNoteOff(ev.note)
+				} else {
+					e.unhold(ev.note)
+					e.releaseVoice(ev.note, ev.drum)
+				}
+				e.Bus.Publish(Event{Kind: EventNoteOff, Note: ev.note, Drum: ev.drum})
+			case sustainEvent:
+				e.applySustain(ev.down)
+			case oneShotEvent:
+				e.OneShots.trigger(int(ev.note), ev.velocity)
+			case drumEvent:
+				e.Drums.trigger(DrumKind(ev.note), ev.velocity)
+			case panicEvent:
+				e.releaseAll()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// startVoice assigns a note to a voice, retriggering it if the note is already sounding
+func (e *Engine) startVoice(note, velocity uint8, drum bool) {
+	zone, data := e.Sampler.zoneFor(note, velocity, drum)
+	if drum && zone == nil {
+		// Drum notes only sound when the SoundFont has a kit sample for them
+		return
+	}
+
+	v := e.findVoice(note, drum)
+	fresh := v == nil
+	if fresh {
+		if v = e.allocateVoice(note, drum); v == nil {
+			return // Every voice is held by other parts' reserves
+		}
+		v.phase = 0
+		v.subPhase = 0
+	}
+	e.voiceCounter++
+	v.Note = note
+	v.velocity = float64(velocity) / 127
+	v.freq = e.Tuning.NoteFreq(note)
+	v.pitch.SetSmoothing(-1)
+	v.pitch.Set(v.freq)
+	v.sustained = false
+	v.started = e.voiceCounter
+	v.drum = drum
+	v.pan = e.voicePan()
+	v.random = e.rand.Float64()
+	if fresh {
+		v.fx.reset(v.random)
+	}
+	v.noise.reset(v.random)
+	v.zone = nil
+	if zone != nil {
+		v.startSample(zone, data, note, e.Sampler.startOffset(velocity, e.rand.Float64()))
+		v.pan = clampFloat(v.pan+zone.Pan, -1, 1)
+	}
+	v.env.Trigger()
+	v.pitchEnv = Envelope{}
+	v.pitchEnv.Trigger()
+	v.fm.trigger(fresh)
+	v.filter.trigger(fresh)
+}
+
+// releaseVoice releases a note, or defers the release while the pedal is down
+func (e *Engine) releaseVoice(note uint8, drum bool) {
+	v := e.findVoice(note, drum)
+	if v == nil {
+		return
+	}
+	if e.sustainDown {
+		v.sustained = true
+		return
+	}
+	v.release()
+}
+
+// release releases the voice's envelope and its operators'
+func (v *Voice) release() {
+	v.env.Release()
+	v.fm.release()
+	v.filter.env.Release()
+}
+
+// applySustain updates the pedal state and releases deferred notes on pedal up
+func (e *Engine) applySustain(down bool) {
+	e.sustainDown = down
+	if down {
+		return
+	}
+	for i := range e.voices {
+		if e.voices[i].sustained {
+			e.voices[i].sustained = false
+			e.voices[i].release()
+		}
+	}
+}
+
+// releaseAll releases every sounding voice, whatever held it
+func (e *Engine) releaseAll() {
+	e.sustainDown = false
+	e.held = e.held[:0]
+	for i := range e.voices {
+		v := &e.voices[i]
+		v.sustained = false
+		if v.Active() {
+			v.release()
+			e.Bus.Publish(Event{Kind: EventNoteOff, Note: v.Note, Drum: v.drum})
+		}
+	}
+}
+
+// voicePan places successive notes at spread positions across the stereo field
+func (e *Engine) voicePan() float64 {
+	position := float64(e.voiceCounter%MaxVoices)/(MaxVoices-1)*2 - 1
+	return position * e.PanSpread.Get()
+}
+
+// findVoice returns the held or sustained voice playing a note
+func (e *Engine) findVoice(note uint8, drum bool) *Voice {
+	for i := range e.voices {
+		v := &e.voices[i]
+		if v.Active() && v.Note == note && v.drum == drum && v.env.Stage() != EnvRelease {
+			return v
+		}
+	}
+	return nil
+}
+
+// allocateVoice returns a voice for a note, or nil when none can be taken. With the
+// same-note policy a voice still releasing the note is taken first. A part at its maximum
+// steals one of its own voices. Otherwise it takes a free voice unless the voices under
+// the limit are owed to other parts' reserves, and then steals a voice of its own or of a
+// part playing more than it reserves: the quietest with that policy, else the oldest.
+func (e *Engine) allocateVoice(note uint8, drum bool) *Voice {
+	poly := &e.polyphony
+	part := partOf(drum)
+	if poly.steal == StealSameNote {
+		for i := range e.voices {
+			if v := &e.voices[i]; v.Active() && v.Note == note && v.drum == drum {
+				return v
+			}
+		}
+	}
+
+	var counts [PartCount]int
+	for i := range e.voices {
+		if v := &e.voices[i]; v.Active() {
+			counts[partOf(v.drum)]++
+		}
+	}
+	owed := 0
+	for p := range counts {
+		if VoicePart(p) != part {
+			owed += max(0, poly.parts[p].Reserve-counts[p])
+		}
+	}
+	free := poly.voices
+	for _, n := range counts {
+		free -= n
+	}
+	atMax := counts[part] >= poly.parts[part].Max
+
+	var victim *Voice
+	for i := range e.voices {
+		v := &e.voices[i]
+		if !v.Active() {
+			if !atMax && free > owed {
+				return v
+			}
+			continue
+		}
+		other := partOf(v.drum)
+		if other != part && (atMax || counts[other] <= poly.parts[other].Reserve) {
+			continue
+		}
+		if victim == nil || v.stealsBefore(victim, poly.steal) {
+			victim = v
+		}
+	}
+	return victim
+}
+
+// stealsBefore reports whether the voice should be stolen ahead of another under a policy
+func (v *Voice) stealsBefore(other *Voice, policy StealPolicy) bool {
+	if policy == StealQuietest && v.env.level != other.env.level {
+		return v.env.level < other.env.level
+	}
+	return v.started < other.started
+}
+
+// renderVoices advances every active voice by one sample and returns the panned left and right mix,
+// with every non-drum voice scaled by partsGain and modulated through the mod matrix
+func (e *Engine) renderVoices(partsGain, modulator float64) (float64, float64) {
+	shape := EnvelopeShape{
+		Delay:   e.EnvDelay.Get(),
+		Attack:  e.Attack.Get(),
+		Hold:    e.EnvHold.Get(),
+		Decay:   e.Decay.Get(),
+		Sustain: e.Sustain.Get(),
+		Release: e.Release.Get(),
+		Curves:  e.EnvCurves.State(),
+	}
+	drive, drift, chorus := e.VoiceFX.Drive.Update(), e.VoiceFX.Drift.Update(), e.VoiceFX.Chorus.Update()
+	voiceFX := drive > 0 || drift > 0 || chorus > 0
+	subLevel := e.Sub.Level.Update()
+	subTable, subRatio := e.Sub.wave()
+	pitchAmount, pitchAttack, pitchDecay := e.PitchEnv.Amount.Update(), e.PitchEnv.Attack.Get(), e.PitchEnv.Decay.Get()
+	fmOn := e.FM.Enabled()
+	var fm fmFrame
+	if fmOn {
+		fm = e.FM.frame()
+	}
+	filterOn := e.Filter.Active()
+	var filter filterFrame
+	if filterOn {
+		filter = e.Filter.frame()
+	}
+	noiseLevel, noiseEnvelope := e.Noise.Level.Update(), e.Noise.Envelope.Update()
+	noiseColor, burstFall := NoiseWhite, 1.0
+	if noiseLevel > 0 {
+		noiseColor, burstFall = e.Noise.Color(), noiseFall(e.Noise.Decay.Update())
+	}
+
+	var partsL, partsR, drumsL, drumsR float64
+	for i := range e.voices {
+		v := &e.voices[i]
+		if !v.Active() {
+			continue
+		}
+		level := v.env.NextShape(&shape)
+		v.freq = v.pitch.Update()
+		v.pitchLevel = v.pitchEnv.Next(pitchAttack, pitchDecay, 0, 0)
+		pitch, gain, pan := e.modulateVoice(v, level, modulator)
+		pitch += v.pitchLevel * pitchAmount
+		rate := 1.0
+		if pitch != 0 {
+			rate = math.Pow(2, pitch/12)
+		}
+
+		var value float64
+		if v.zone != nil {
+			var ok bool
+			if value, ok = v.nextSample(rate); !ok {
+				// A one-shot sample has ended, so the voice is done
+				v.env = Envelope{}
+				continue
+			}
+		} else {
+			if fmOn {
+				value = v.fm.next(&fm, v.freq*rate)
+			} else {
+				value = sineTable.at(v.phase)
+			}
+			v.phase += v.freq * rate / SampleRate
+			if v.phase >= 1 {
+				v.phase -= 1
+			}
+			if subLevel > 0 {
+				value += subTable.at(v.subPhase) * subLevel
+				v.subPhase = math.Mod(v.subPhase+v.freq*rate*subRatio/SampleRate, 1)
+			}
+			if noiseLevel > 0 {
+				value += v.noise.next(noiseColor, noiseEnvelope, burstFall) * noiseLevel
+			}
+		}
+
+		if filterOn && !v.drum {
+			value = v.filter.process(value, v.Note, &filter)
+		}
+		if voiceFX && !v.drum {
+			value = v.fx.process(value, v.freq*rate, v.random, drive, drift, chorus)
+		}
+		value *= level * v.velocity * gain
+		gainL, gainR := panGains(v.pan + pan)
+		if v.drum {
+			drumsL += value * gainL
+			drumsR += value * gainR
+		} else {
+			partsL += value * gainL
+			partsR += value * gainR
+			e.envLevel = math.Max(e.envLevel, level)
+		}
+	}
+
+	return partsL*partsGain + drumsL, partsR*partsGain + drumsR
+}
@@ -0,0 +1,80 @@
+package engine
+
+import "math"
+
+const (
+	VoiceDriveMax     = 10.0  // Input gain into the saturator at full drive
+	VoiceDriftOctaves = 1.0   // Furthest the filter wanders either way at full drift, in octaves
+	VoiceDriftRate    = 0.25  // Average rate of the filter drift in Hz
+	voiceFilterTrack  = 6.0   // Filter cutoff as a multiple of the note frequency
+	voiceChorusDelay  = 0.006 // Centre delay of the per-voice chorus in seconds
+	voiceChorusDepth  = 0.002 // Sweep of the per-voice chorus either side of the centre in seconds
+	voiceChorusRate   = 1.3   // Average LFO rate of the per-voice chorus in Hz
+	voiceChorusSize   = 356   // Frames of the chorus line: the longest delay plus room to interpolate
+	VoiceFXWarnLoad   = 0.8   // CPU load past which the UI warns that voice effects are too costly
+)
+
+// VoiceFX are lightweight effects run inside each voice before the voices are summed,
+// so every note is driven, filtered and chorused on its own. That thickens chords in a
+// way the bus effects can't, at a cost per sounding voice. Each is bypassed at zero.
+type VoiceFX struct {
+	Drive  SmoothValue // Saturation, 0 to 1
+	Drift  SmoothValue // How far each voice's tracking lowpass wanders, 0 to 1
+	Chorus SmoothValue // Blend of each voice's own short chorus, 0 to 1
+}
+
+// NewVoiceFX creates voice effects, all bypassed
+func NewVoiceFX() *VoiceFX {
+	return &VoiceFX{}
+}
+
+// Active reports whether any voice effect is running
+func (f *VoiceFX) Active() bool {
+	return f.Drive.Get() > 0 || f.Drift.Get() > 0 || f.Chorus.Get() > 0
+}
+
+// voiceFXState is a voice's own effect state; only touched by the audio callback
+type voiceFXState struct {
+	filter float64 // Lowpass output
+	drift  float64 // Drift LFO phase, 0 to 1
+	lfo    float64 // Chorus LFO phase, 0 to 1
+	pos    int     // Write position in the chorus line
+	line   [voiceChorusSize]float64
+}
+
+// reset clears the state for a new note, starting the LFOs at a phase of its own
+func (s *voiceFXState) reset(random float64) {
+	*s = voiceFXState{drift: random, lfo: math.Mod(random*7, 1)}
+}
+
+// process runs one sample of a voice at a frequency through drive, drift filter and
+// chorus. The LFO rates vary with the voice's random value, so voices don't move together.
+func (s *voiceFXState) process(x, freq, random, drive, drift, chorus float64) float64 {
+	if drive > 0 {
+		gain := 1 + drive*(VoiceDriveMax-1)
+		x = math.Tanh(x*gain) / math.Tanh(gain)
+	}
+
+	if drift > 0 {
+		cutoff := freq * voiceFilterTrack * math.Exp2(drift*VoiceDriftOctaves*sineTable.at(s.drift))
+		coeff := 1 - math.Exp(-2*math.Pi*math.Min(cutoff, SampleRate/2)/SampleRate)
+		s.filter += (x - s.filter) * coeff
+		x = s.filter
+		s.drift = math.Mod(s.drift+VoiceDriftRate*(0.5+random)/SampleRate, 1)
+	}
+
+	if chorus > 0 {
+		s.line[s.pos] = x
+		frames := (voiceChorusDelay + voiceChorusDepth*sineTable.at(s.lfo)) * SampleRate
+		whole := int(frames)
+		frac := frames - float64(whole)
+		a := s.line[(s.pos-whole+voiceChorusSize)%voiceChorusSize]
+		b := s.line[(s.pos-whole-1+voiceChorusSize)%voiceChorusSize]
+		s.pos = (s.pos + 1) % voiceChorusSize
+		s.lfo = math.Mod(s.lfo+voiceChorusRate*(0.5+random)/SampleRate, 1)
+
+		// At full blend the delayed copy is as loud as the note, for the beating of a detuned double
+		x = x*(1-chorus/2) + (a*(1-frac)+b*frac)*chorus/2
+	}
+	return x
+}
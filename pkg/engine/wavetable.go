@@ -0,0 +1,52 @@
+package engine
+
+import "math"
+
+// wavetableSize is the number of points per cycle; a power of two keeps the index wrap cheap
+const wavetableSize = 2048
+
+// wavetable is one cycle of a wave, read with linear interpolation in place of
+// computing the shape for every sample
+type wavetable [wavetableSize + 1]float64 // The extra point repeats the first for interpolation
+
+// Tables of the basic shapes, shared by every voice
+var (
+	sineTable = newWavetable(func(phase float64) float64 { return math.Sin(2 * math.Pi * phase) })
+
+	// A few harmonics keep the saw soft and free of aliasing at drone pitches
+	softSawTable = newWavetable(func(phase float64) float64 {
+		sum := 0.0
+		for h := 1.0; h <= 6; h++ {
+			sum += math.Sin(2*math.Pi*phase*h) / h
+		}
+		return sum * 0.55
+	})
+
+	// Odd harmonics up to the seventh round off a square an octave or two under the notes
+	softSquareTable = newWavetable(func(phase float64) float64 {
+		sum := 0.0
+		for h := 1.0; h <= 7; h += 2 {
+			sum += math.Sin(2*math.Pi*phase*h) / h
+		}
+		return sum * 0.9
+	})
+)
+
+// newWavetable samples one cycle of a wave given as a function of phase from 0 to 1
+func newWavetable(wave func(phase float64) float64) *wavetable {
+	w := &wavetable{}
+	for i := 0; i < wavetableSize; i++ {
+		w[i] = wave(float64(i) / wavetableSize)
+	}
+	w[wavetableSize] = w[0]
+	return w
+}
+
+// at returns the wave at a phase, wrapping it into one cycle
+func (w *wavetable) at(phase float64) float64 {
+	pos := (phase - math.Floor(phase)) * wavetableSize
+	i := int(pos)
+	frac := pos - float64(i)
+	i &= wavetableSize - 1 // Guards against rounding up to a full cycle
+	return w[i] + (w[i+1]-w[i])*frac
+}
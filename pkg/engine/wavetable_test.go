@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSineTableAccuracy(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		phase := float64(i) / 99991 * 3 // Spans several cycles to exercise the wrap
+		want := math.Sin(2 * math.Pi * phase)
+		if got := sineTable.at(phase); math.Abs(got-want) > 1e-5 {
+			t.Fatalf("sineTable.at(%v) = %v, want %v", phase, got, want)
+		}
+	}
+}
+
+var benchSink float64
+
+func BenchmarkMathSin(b *testing.B) {
+	phase := 0.0
+	for i := 0; i < b.N; i++ {
+		benchSink += math.Sin(2 * math.Pi * phase)
+		phase += 440.0 / SampleRate
+	}
+}
+
+func BenchmarkSineTable(b *testing.B) {
+	phase := 0.0
+	for i := 0; i < b.N; i++ {
+		benchSink += sineTable.at(phase)
+		phase += 440.0 / SampleRate
+	}
+}
+
+func BenchmarkSoftSawHarmonics(b *testing.B) {
+	phase := 0.0
+	for i := 0; i < b.N; i++ {
+		sum := 0.0
+		for h := 1.0; h <= 6; h++ {
+			sum += math.Sin(2*math.Pi*phase*h) / h
+		}
+		benchSink += sum * 0.55
+		phase += 440.0 / SampleRate
+	}
+}
+
+func BenchmarkSoftSawTable(b *testing.B) {
+	phase := 0.0
+	for i := 0; i < b.N; i++ {
+		benchSink += softSawTable.at(phase)
+		phase += 440.0 / SampleRate
+	}
+}
+
+// BenchmarkRender renders full blocks with every voice sounding
+func BenchmarkRender(b *testing.B) {
+	e := NewEngine(BPM(120))
+	e.SetDrone(false)
+	for i := 0; i < MaxVoices; i++ {
+		e.startVoice(uint8(48+i*3), 100, false)
+	}
+	out := make([]float32, AudioBufferSize*OutputChannels)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.Render(out)
+	}
+}
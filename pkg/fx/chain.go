@@ -0,0 +1,135 @@
+package fx
+
+import "sync"
+
+// Processor is an effect that transforms a block of samples. In and out may be
+// the same slice, so processors must read each sample before writing it.
+type Processor interface {
+	Process(in, out []float32)
+}
+
+// SlotState is the saved position and switch of one effect in a chain
+type SlotState struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// slot is a named effect in the chain
+type slot struct {
+	name    string
+	proc    Processor
+	enabled bool
+}
+
+// Chain runs an ordered list of effects, each of which can be switched off or moved
+type Chain struct {
+	mu    sync.Mutex
+	slots []slot
+}
+
+// Add appends a disabled effect to the end of the chain
+func (c *Chain) Add(name string, p Processor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slots = append(c.slots, slot{name: name, proc: p})
+}
+
+// Process runs a block through the enabled effects in order, in place
+func (c *Chain) Process(buf []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.slots {
+		if s.enabled {
+			s.proc.Process(buf, buf)
+		}
+	}
+}
+
+// Names returns the effect names in processing order
+func (c *Chain) Names() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, len(c.slots))
+	for i, s := range c.slots {
+		names[i] = s.name
+	}
+	return names
+}
+
+// Enabled reports whether the named effect is switched on
+func (c *Chain) Enabled(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i := c.index(name); i >= 0 {
+		return c.slots[i].enabled
+	}
+	return false
+}
+
+// SetEnabled switches the named effect on or off
+func (c *Chain) SetEnabled(name string, enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i := c.index(name); i >= 0 {
+		c.slots[i].enabled = enabled
+	}
+}
+
+// Move shifts the named effect dir places later in the chain, or earlier for a negative dir
+func (c *Chain) Move(name string, dir int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.index(name)
+	j := i + dir
+	if i < 0 || j < 0 || j >= len(c.slots) {
+		return
+	}
+	s := c.slots[i]
+	copy(c.slots[i:], c.slots[i+1:])
+	copy(c.slots[j+1:], c.slots[j:len(c.slots)-1])
+	c.slots[j] = s
+}
+
+// State returns the order and switches of the chain for saving
+func (c *Chain) State() []SlotState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := make([]SlotState, len(c.slots))
+	for i, s := range c.slots {
+		state[i] = SlotState{Name: s.name, Enabled: s.enabled}
+	}
+	return state
+}
+
+// SetState restores a saved order and switches. Unknown names are ignored and
+// effects missing from the state keep their relative order after the saved ones.
+func (c *Chain) SetState(state []SlotState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	slots := make([]slot, 0, len(c.slots))
+	used := make([]bool, len(c.slots))
+	for _, st := range state {
+		if i := c.index(st.Name); i >= 0 && !used[i] {
+			s := c.slots[i]
+			s.enabled = st.Enabled
+			slots = append(slots, s)
+			used[i] = true
+		}
+	}
+	for i, s := range c.slots {
+		if !used[i] {
+			slots = append(slots, s)
+		}
+	}
+	c.slots = slots
+}
+
+// index returns the position of the named effect, or -1; c.mu must be held
+func (c *Chain) index(name string) int {
+	for i, s := range c.slots {
+		if s.name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,57 @@
+package fx
+
+import "math"
+
+const (
+	chorusTaps      = 3     // Voices of the ensemble
+	chorusBaseDelay = 0.015 // Centre delay of each tap in seconds
+	chorusMaxDepth  = 0.008 // Largest sweep either side of the centre in seconds
+)
+
+// Chorus thickens a channel with several delay taps swept by phase-offset LFOs
+type Chorus struct {
+	Rate  float64 // LFO rate in Hz
+	Depth float64 // Sweep amount, 0 to 1
+
+	sampleRate float64
+	buffer     []float64
+	pos        int
+	phase      float64 // LFO phase, 0 to 1
+}
+
+// NewChorus creates a chorus for the given sample rate. Phase offsets the LFOs,
+// so a channel pair started a quarter cycle apart widens the image.
+func NewChorus(sampleRate, phase float64) *Chorus {
+	size := int((chorusBaseDelay+chorusMaxDepth)*sampleRate) + 2
+	return &Chorus{
+		Rate:       0.8,
+		Depth:      0.5,
+		sampleRate: sampleRate,
+		buffer:     make([]float64, size),
+		phase:      phase,
+	}
+}
+
+// Process runs one sample through the chorus and returns the averaged taps
+func (c *Chorus) Process(in float64) float64 {
+	c.buffer[c.pos] = in
+	size := len(c.buffer)
+
+	out := 0.0
+	for tap := 0; tap < chorusTaps; tap++ {
+		lfo := math.Sin(2 * math.Pi * (c.phase + float64(tap)/chorusTaps))
+		frames := (chorusBaseDelay + lfo*chorusMaxDepth*c.Depth) * c.sampleRate
+		whole := int(frames)
+		frac := frames - float64(whole)
+		a := c.buffer[(c.pos-whole+size)%size]
+		b := c.buffer[(c.pos-whole-1+size)%size]
+		out += a*(1-frac) + b*frac
+	}
+
+	c.pos = (c.pos + 1) % size
+	c.phase += c.Rate / c.sampleRate
+	if c.phase >= 1 {
+		c.phase -= 1
+	}
+	return out / chorusTaps
+}
@@ -0,0 +1,58 @@
+package fx
+
+import "math"
+
+// Compressor is a feed-forward peak compressor. A high ratio turns it into a limiter.
+type Compressor struct {
+	Threshold float64 // Level in dBFS above which gain is reduced
+	Ratio     float64 // Input dB over the threshold per output dB
+	Attack    float64 // Seconds to react to a rising level
+	Release   float64 // Seconds to recover after the level falls
+	Makeup    float64 // Gain in dB added after compression
+
+	sampleRate float64
+	envelope   float64 // Smoothed gain reduction in dB
+}
+
+// NewCompressor creates a compressor for the given sample rate
+func NewCompressor(sampleRate float64) *Compressor {
+	return &Compressor{
+		Threshold:  -12,
+		Ratio:      4,
+		Attack:     0.005,
+		Release:    0.1,
+		sampleRate: sampleRate,
+	}
+}
+
+// Process compresses one sample
+func (c *Compressor) Process(in float64) float64 {
+	// Gain computer: how far over the threshold the input is, scaled by the ratio
+	level := 20 * math.Log10(math.Abs(in)+1e-9)
+	target := 0.0
+	if over := level - c.Threshold; over > 0 && c.Ratio > 1 {
+		target = over - over/c.Ratio
+	}
+
+	// Smooth the reduction with separate attack and release times
+	coeff := c.coefficient(c.Release)
+	if target > c.envelope {
+		coeff = c.coefficient(c.Attack)
+	}
+	c.envelope = target + coeff*(c.envelope-target)
+
+	return in * math.Pow(10, (c.Makeup-c.envelope)/20)
+}
+
+// GainReduction returns the current gain reduction in dB
+func (c *Compressor) GainReduction() float64 {
+	return c.envelope
+}
+
+// coefficient returns the one-pole smoothing factor for a time constant
+func (c *Compressor) coefficient(seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return math.Exp(-1 / (seconds * c.sampleRate))
+}
@@ -0,0 +1,84 @@
+// Package fx provides audio effects that run on the synth's output
+package fx
+
+const (
+	reverbFixedGain = 0.015 // Input gain into the comb filters
+	reverbScaleRoom = 0.28  // Room size range of the comb feedback
+	reverbOffset    = 0.7   // Smallest comb feedback
+	reverbAllpassFb = 0.5   // Feedback of the diffusing allpass filters
+	StereoSpread    = 23    // Extra delay in samples for the right channel of a stereo pair
+)
+
+// Comb and allpass delay lengths in samples at 44.1kHz, from Freeverb
+var (
+	combTunings    = []int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	allpassTunings = []int{556, 441, 341, 225}
+)
+
+// comb is a feedback comb filter with a one-pole lowpass in the loop
+type comb struct {
+	buffer []float64
+	pos    int
+	store  float64 // Lowpass filter state
+}
+
+func (c *comb) process(in, feedback, damp float64) float64 {
+	out := c.buffer[c.pos]
+	c.store = out*(1-damp) + c.store*damp
+	c.buffer[c.pos] = in + c.store*feedback
+	c.pos = (c.pos + 1) % len(c.buffer)
+	return out
+}
+
+// allpass is a Schroeder allpass filter that diffuses the comb output
+type allpass struct {
+	buffer []float64
+	pos    int
+}
+
+func (a *allpass) process(in float64) float64 {
+	buffered := a.buffer[a.pos]
+	a.buffer[a.pos] = in + buffered*reverbAllpassFb
+	a.pos = (a.pos + 1) % len(a.buffer)
+	return buffered - in
+}
+
+// Reverb is a Freeverb-style reverberator for one channel: parallel lowpass
+// combs followed by series allpasses
+type Reverb struct {
+	RoomSize float64 // 0 (small) to 1 (large)
+	Damping  float64 // 0 (bright) to 1 (dark)
+
+	combs     []comb
+	allpasses []allpass
+}
+
+// NewReverb creates a reverb for the given sample rate. Spread lengthens every
+// delay by that many samples, so a channel pair with 0 and StereoSpread decorrelates.
+func NewReverb(sampleRate float64, spread int) *Reverb {
+	scale := sampleRate / 44100
+	r := &Reverb{RoomSize: 0.5, Damping: 0.5}
+	for _, n := range combTunings {
+		r.combs = append(r.combs, comb{buffer: make([]float64, int(float64(n+spread)*scale)+1)})
+	}
+	for _, n := range allpassTunings {
+		r.allpasses = append(r.allpasses, allpass{buffer: make([]float64, int(float64(n+spread)*scale)+1)})
+	}
+	return r
+}
+
+// Process runs one sample through the reverb and returns the wet signal
+func (r *Reverb) Process(in float64) float64 {
+	feedback := r.RoomSize*reverbScaleRoom + reverbOffset
+	damp := r.Damping * 0.4
+
+	input := in * reverbFixedGain
+	out := 0.0
+	for i := range r.combs {
+		out += r.combs[i].process(input, feedback, damp)
+	}
+	for i := range r.allpasses {
+		out = r.allpasses[i].process(out)
+	}
+	return out
+}
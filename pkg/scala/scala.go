@@ -0,0 +1,244 @@
+// Package scala reads Scala scale (.scl) and keyboard mapping (.kbm) files into the
+// frequencies of the MIDI notes.
+package scala
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Notes is the number of MIDI notes a tuning gives frequencies for
+const Notes = 128
+
+// Scale is a scale of pitches repeating at a period, usually the octave
+type Scale struct {
+	Description string
+	Ratios      []float64 // Frequency ratio of each degree to the first, the last being the period
+}
+
+// Mapping is a keyboard mapping placing a scale's degrees on the MIDI notes
+type Mapping struct {
+	Size      int     // Keys in the repeating pattern, 0 to map each key to the next degree
+	First     int     // Lowest note mapped
+	Last      int     // Highest note mapped
+	Middle    int     // Note playing the first degree of the scale
+	Reference int     // Note tuned to Frequency
+	Frequency float64 // Hz of the reference note
+	Octave    int     // Degrees each repetition of the pattern moves up, 0 for the whole scale
+	Keys      []int   // Degree of each key of the pattern, -1 for keys left silent
+}
+
+// DefaultMapping returns the mapping used without a .kbm file: every key plays the next
+// degree, with middle C on the first and A4 at 440 Hz
+func DefaultMapping() *Mapping {
+	return &Mapping{Last: Notes - 1, Middle: 60, Reference: 69, Frequency: 440}
+}
+
+// LoadScale reads a .scl file
+func LoadScale(path string) (*Scale, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseScale(f)
+}
+
+// ParseScale reads a scale in the .scl format: a description, the number of degrees, then
+// each degree after the first as cents (with a period) or a ratio, with ! starting comments
+func ParseScale(r io.Reader) (*Scale, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) < 2 {
+		return nil, errors.New("missing description or degree count")
+	}
+	count, err := strconv.Atoi(firstField(lines[1]))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid degree count %q", lines[1])
+	}
+	if len(lines)-2 < count {
+		return nil, fmt.Errorf("%d degrees listed, %d expected", len(lines)-2, count)
+	}
+	s := &Scale{Description: lines[0], Ratios: []float64{1}}
+	for _, line := range lines[2 : 2+count] {
+		ratio, err := parsePitch(firstField(line))
+		if err != nil {
+			return nil, err
+		}
+		s.Ratios = append(s.Ratios, ratio)
+	}
+	return s, nil
+}
+
+// Degrees returns the number of degrees in each period of the scale
+func (s *Scale) Degrees() int {
+	return len(s.Ratios) - 1
+}
+
+// ratio returns the frequency ratio of a degree to the first, counting past the period
+// and below the first degree
+func (s *Scale) ratio(degree int) float64 {
+	count := s.Degrees()
+	period := floorDiv(degree, count)
+	return math.Pow(s.Ratios[count], float64(period)) * s.Ratios[degree-period*count]
+}
+
+// LoadMapping reads a .kbm file
+func LoadMapping(path string) (*Mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseMapping(f)
+}
+
+// ParseMapping reads a keyboard mapping in the .kbm format: the pattern size, the first,
+// last, middle and reference notes, the reference frequency, the octave degree, then the
+// degree of each key of the pattern or x for a silent key, with ! starting comments.
+// Keys missing from the end of the pattern are silent.
+func ParseMapping(r io.Reader) (*Mapping, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) < 7 {
+		return nil, errors.New("missing header lines")
+	}
+	var header [7]float64
+	for i := range header {
+		if header[i], err = strconv.ParseFloat(firstField(lines[i]), 64); err != nil || math.IsInf(header[i], 0) || math.IsNaN(header[i]) {
+			return nil, fmt.Errorf("invalid header line %q", lines[i])
+		}
+	}
+	m := &Mapping{
+		Size:      int(header[0]),
+		First:     int(header[1]),
+		Last:      int(header[2]),
+		Middle:    int(header[3]),
+		Reference: int(header[4]),
+		Frequency: header[5],
+		Octave:    int(header[6]),
+	}
+	// A pattern is at most the keyboard long, which also bounds the silent keys padded on
+	if m.Size < 0 || m.Size > Notes || m.Frequency <= 0 || m.Reference < 0 || m.Reference >= Notes {
+		return nil, errors.New("invalid size, reference note or frequency")
+	}
+	for _, line := range lines[7:] {
+		if len(m.Keys) == m.Size {
+			break
+		}
+		field := firstField(line)
+		if strings.EqualFold(field, "x") {
+			m.Keys = append(m.Keys, -1)
+			continue
+		}
+		degree, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q", line)
+		}
+		m.Keys = append(m.Keys, degree)
+	}
+	for len(m.Keys) < m.Size {
+		m.Keys = append(m.Keys, -1)
+	}
+	return m, nil
+}
+
+// degree returns the scale degree a note plays, counted from the middle note, and whether
+// the note is mapped at all
+func (m *Mapping) degree(note, degrees int) (int, bool) {
+	if note < m.First || note > m.Last {
+		return 0, false
+	}
+	if m.Size == 0 {
+		return note - m.Middle, true
+	}
+	octave := m.Octave
+	if octave == 0 {
+		octave = degrees
+	}
+	repeat := floorDiv(note-m.Middle, m.Size)
+	key := m.Keys[note-m.Middle-repeat*m.Size]
+	if key < 0 {
+		return 0, false
+	}
+	return key + repeat*octave, true
+}
+
+// Frequencies returns the frequency of every MIDI note under a scale and mapping, 0 for
+// notes the mapping leaves silent
+func Frequencies(s *Scale, m *Mapping) ([Notes]float64, error) {
+	var freqs [Notes]float64
+	ref, ok := m.degree(m.Reference, s.Degrees())
+	if !ok {
+		return freqs, fmt.Errorf("reference note %d is not mapped", m.Reference)
+	}
+	base := m.Frequency / s.ratio(ref)
+	for note := range freqs {
+		if degree, ok := m.degree(note, s.Degrees()); ok {
+			freqs[note] = base * s.ratio(degree)
+		}
+	}
+	return freqs, nil
+}
+
+// parsePitch reads a degree as cents when it has a period, otherwise as a ratio or whole number
+func parsePitch(field string) (float64, error) {
+	if strings.Contains(field, ".") {
+		cents, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid cents %q", field)
+		}
+		return math.Exp2(cents / 1200), nil
+	}
+	num, den, found := strings.Cut(field, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	d := 1.0
+	if err == nil && found {
+		d, err = strconv.ParseFloat(den, 64)
+	}
+	if err != nil || n <= 0 || d <= 0 {
+		return 0, fmt.Errorf("invalid ratio %q", field)
+	}
+	return n / d, nil
+}
+
+// readLines returns the lines of a file that aren't comments, with surrounding space removed
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "!") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// firstField returns the text of a line up to the first space; the rest is a comment
+func firstField(line string) string {
+	if fields := strings.Fields(line); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// floorDiv divides rounding towards negative infinity, so notes below the middle fall in
+// the repetition below
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,168 @@
+package scala
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// near reports whether two frequencies agree to within a thousandth of a cent
+func near(a, b float64) bool {
+	return math.Abs(1200*math.Log2(a/b)) < 1e-3
+}
+
+// TestParseScale checks cents, ratios, comments and the malformed scales that must be refused
+func TestParseScale(t *testing.T) {
+	s, err := ParseScale(strings.NewReader(`! just.scl
+!
+Just major, with comments after the pitches
+ 7
+!
+9/8
+5/4 major third
+4/3
+3/2
+5/3
+15/8
+2/1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{1, 9.0 / 8, 5.0 / 4, 4.0 / 3, 3.0 / 2, 5.0 / 3, 15.0 / 8, 2}
+	if s.Degrees() != 7 || s.Description != "Just major, with comments after the pitches" {
+		t.Fatalf("scale %q with %d degrees, want 7", s.Description, s.Degrees())
+	}
+	for i, r := range want {
+		if !near(s.Ratios[i], r) {
+			t.Errorf("degree %d ratio %g, want %g", i, s.Ratios[i], r)
+		}
+	}
+
+	s, err = ParseScale(strings.NewReader("Quarter tones\n3\n50.0\n-25.\n1200.0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(s.Ratios[1], math.Exp2(50.0/1200)) || !near(s.Ratios[2], math.Exp2(-25.0/1200)) || !near(s.Ratios[3], 2) {
+		t.Errorf("cents read as ratios %v", s.Ratios)
+	}
+	if _, err := ParseScale(strings.NewReader("Whole\n1\n3\n")); err != nil {
+		t.Errorf("whole number ratio: %v", err)
+	}
+
+	for _, tc := range []struct{ name, text string }{
+		{"empty", ""},
+		{"no count", "Description only\n"},
+		{"bad count", "Bad\nseven\n"},
+		{"zero degrees", "None\n0\n"},
+		{"too few degrees", "Short\n3\n100.0\n200.0\n"},
+		{"bad cents", "Bad\n1\n1.2.3\n"},
+		{"zero ratio", "Bad\n1\n0/1\n"},
+		{"negative ratio", "Bad\n1\n-3/2\n"},
+		{"zero denominator", "Bad\n1\n3/0\n"},
+		{"word", "Bad\n1\noctave\n"},
+	} {
+		if _, err := ParseScale(strings.NewReader(tc.text)); err == nil {
+			t.Errorf("%s: no error", tc.name)
+		}
+	}
+}
+
+// TestParseMapping checks the header, silent and missing keys, and the malformed mappings
+// that must be refused
+func TestParseMapping(t *testing.T) {
+	m, err := ParseMapping(strings.NewReader(`! white.kbm
+7 ! size
+0
+127
+60
+69
+440.0
+7
+0
+x
+1
+! a comment between keys
+x
+2
+3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Size != 7 || m.Middle != 60 || m.Reference != 69 || m.Frequency != 440 || m.Octave != 7 {
+		t.Errorf("header read as %+v", m)
+	}
+	if want := []int{0, -1, 1, -1, 2, 3, -1}; !slices.Equal(m.Keys, want) {
+		t.Errorf("keys %v, want %v with the missing last key silent", m.Keys, want)
+	}
+
+	header := func(size string) string {
+		return size + "\n0\n127\n60\n69\n440\n0\n"
+	}
+	if m, err := ParseMapping(strings.NewReader(header("128"))); err != nil || len(m.Keys) != 128 {
+		t.Errorf("pattern of every key: %v", err)
+	}
+	for _, tc := range []struct{ name, text string }{
+		{"empty", ""},
+		{"short header", "12\n0\n127\n"},
+		{"bad number", header("twelve")},
+		{"negative size", header("-1")},
+		{"size past the keyboard", header("129")},
+		{"huge size", header("1e9")},
+		{"infinite size", header("inf")},
+		{"not a number", "12\n0\n127\n60\n69\nNaN\n0\n"},
+		{"zero frequency", "0\n0\n127\n60\n69\n0\n0\n"},
+		{"reference off the keyboard", "0\n0\n127\n60\n128\n440\n0\n"},
+		{"bad key", header("2") + "0\nseven\n"},
+	} {
+		if _, err := ParseMapping(strings.NewReader(tc.text)); err == nil {
+			t.Errorf("%s: no error", tc.name)
+		}
+	}
+}
+
+// TestFrequencies checks equal temperament, a mapping leaving keys silent, and notes
+// below the middle falling in the repetition below
+func TestFrequencies(t *testing.T) {
+	tet, err := ParseScale(strings.NewReader("12-TET\n12\n100.\n200.\n300.\n400.\n500.\n600.\n700.\n800.\n900.\n1000.\n1100.\n1200.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	freqs, err := Frequencies(tet, DefaultMapping())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for note, want := range map[int]float64{69: 440, 60: 261.6256, 0: 8.1758, 127: 12543.854} {
+		if math.Abs(freqs[note]-want) > want*1e-5 {
+			t.Errorf("note %d at %g Hz, want %g", note, freqs[note], want)
+		}
+	}
+
+	// The white keys play a just major scale from C, with A at 440 Hz
+	just, err := ParseScale(strings.NewReader("Just\n7\n9/8\n5/4\n4/3\n3/2\n5/3\n15/8\n2/1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	white := &Mapping{Size: 12, First: 0, Last: 127, Middle: 60, Reference: 69, Frequency: 440, Octave: 7,
+		Keys: []int{0, -1, 1, -1, 2, 3, -1, 4, -1, 5, -1, 6}}
+	freqs, err = Frequencies(just, white)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := 440 / (5.0 / 3)
+	for note, want := range map[int]float64{60: c, 64: c * 5 / 4, 67: c * 3 / 2, 72: c * 2, 59: c / 2 * 15 / 8, 48: c / 2} {
+		if !near(freqs[note], want) {
+			t.Errorf("note %d at %g Hz, want %g", note, freqs[note], want)
+		}
+	}
+	if freqs[61] != 0 || freqs[49] != 0 {
+		t.Errorf("black keys at %g and %g Hz, want silent", freqs[61], freqs[49])
+	}
+
+	// A range leaving out the reference note can't be tuned
+	if _, err := Frequencies(just, &Mapping{First: 0, Last: 60, Middle: 60, Reference: 69, Frequency: 440}); err == nil {
+		t.Error("unmapped reference note accepted")
+	}
+}
@@ -0,0 +1,454 @@
+// Package sf2 reads SoundFont 2 files into presets of playable sample zones.
+package sf2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Generator operators used when resolving zones
+const (
+	genStartAddrsOffset       = 0
+	genEndAddrsOffset         = 1
+	genStartloopAddrsOffset   = 2
+	genEndloopAddrsOffset     = 3
+	genStartAddrsCoarseOffset = 4
+	genEndAddrsCoarseOffset   = 12
+	genPan                    = 17
+	genInstrument             = 41
+	genKeyRange               = 43
+	genVelRange               = 44
+	genStartloopCoarseOffset  = 45
+	genInitialAttenuation     = 48
+	genEndloopCoarseOffset    = 50
+	genCoarseTune             = 51
+	genFineTune               = 52
+	genSampleID               = 53
+	genSampleModes            = 54
+	genOverridingRootKey      = 58
+)
+
+// Sample loop modes
+const (
+	LoopNone       = 0
+	LoopContinuous = 1
+	LoopUntilOff   = 3
+)
+
+// Sample is a region of the font's sample data
+type Sample struct {
+	Name       string
+	Start      int
+	End        int
+	LoopStart  int
+	LoopEnd    int
+	SampleRate int
+	RootKey    uint8
+	Correction int8 // Pitch correction in cents
+}
+
+// Zone is a playable sample mapped to a key and velocity range
+type Zone struct {
+	KeyLo, KeyHi uint8
+	VelLo, VelHi uint8
+	Sample       *Sample
+	Start        int // Offsets into the font's Data, after address generators
+	End          int
+	LoopStart    int
+	LoopEnd      int
+	LoopMode     int
+	RootKey      uint8
+	Tune         float64 // Total tuning offset in cents
+	Attenuation  float64 // Attenuation in centibels
+	Pan          float64 // -1 (left) to 1 (right)
+}
+
+// Matches reports whether the zone plays for a note and velocity
+func (z *Zone) Matches(note, velocity uint8) bool {
+	return note >= z.KeyLo && note <= z.KeyHi && velocity >= z.VelLo && velocity <= z.VelHi
+}
+
+// Preset is a playable instrument of the font
+type Preset struct {
+	Name   string
+	Bank   uint16
+	Number uint16
+	Zones  []Zone
+}
+
+// ZonesFor returns the zones of the preset that play for a note and velocity
+func (p *Preset) ZonesFor(note, velocity uint8) []*Zone {
+	var zones []*Zone
+	for i := range p.Zones {
+		if p.Zones[i].Matches(note, velocity) {
+			zones = append(zones, &p.Zones[i])
+		}
+	}
+	return zones
+}
+
+// SoundFont is a parsed SF2 file
+type SoundFont struct {
+	Name    string
+	Data    []float32 // All sample data, normalized to -1..1
+	Presets []Preset
+}
+
+// Load reads a SoundFont from a file
+func Load(path string) (*SoundFont, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// chunk is a RIFF chunk
+type chunk struct {
+	id   string
+	data []byte
+}
+
+// readChunks splits a RIFF body into its sub-chunks
+func readChunks(data []byte) ([]chunk, error) {
+	var chunks []chunk
+	for len(data) >= 8 {
+		id := string(data[:4])
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		data = data[8:]
+		if size > len(data) {
+			return nil, fmt.Errorf("sf2: chunk %q truncated", id)
+		}
+		chunks = append(chunks, chunk{id: id, data: data[:size]})
+		// Chunks are padded to an even size
+		if size%2 == 1 && size < len(data) {
+			size++
+		}
+		data = data[size:]
+	}
+	return chunks, nil
+}
+
+// Read parses a SoundFont from a reader
+func Read(r io.Reader) (*SoundFont, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < 12 || string(raw[:4]) != "RIFF" || string(raw[8:12]) != "sfbk" {
+		return nil, errors.New("sf2: not a SoundFont file")
+	}
+	top, err := readChunks(raw[12:])
+	if err != nil {
+		return nil, err
+	}
+
+	font := &SoundFont{}
+	var pdta []chunk
+	for _, c := range top {
+		if c.id != "LIST" || len(c.data) < 4 {
+			continue
+		}
+		sub, err := readChunks(c.data[4:])
+		if err != nil {
+			return nil, err
+		}
+		switch string(c.data[:4]) {
+		case "INFO":
+			for _, s := range sub {
+				if s.id == "INAM" {
+					font.Name = cString(s.data)
+				}
+			}
+		case "sdta":
+			for _, s := range sub {
+				if s.id == "smpl" {
+					font.Data = make([]float32, len(s.data)/2)
+					for i := range font.Data {
+						font.Data[i] = float32(int16(binary.LittleEndian.Uint16(s.data[i*2:]))) / 32768
+					}
+				}
+			}
+		case "pdta":
+			pdta = sub
+		}
+	}
+	if font.Data == nil {
+		return nil, errors.New("sf2: missing sample data")
+	}
+	if pdta == nil {
+		return nil, errors.New("sf2: missing preset data")
+	}
+	if err := font.parsePresets(pdta); err != nil {
+		return nil, err
+	}
+	return font, nil
+}
+
+// generator is a single generator record
+type generator struct {
+	oper   uint16
+	amount uint16
+}
+
+func (g generator) signed() int    { return int(int16(g.amount)) }
+func (g generator) unsigned() int  { return int(g.amount) }
+func (g generator) rangeLo() uint8 { return uint8(g.amount & 0xff) }
+func (g generator) rangeHi() uint8 { return uint8(g.amount >> 8) }
+
+// is reports whether the generator has the given operator
+func (g generator) is(oper int) bool {
+	return int(g.oper) == oper
+}
+
+// cString decodes a fixed-size, zero-terminated name
+func cString(b []byte) string {
+	return strings.TrimRight(string(bytes.SplitN(b, []byte{0}, 2)[0]), " ")
+}
+
+func u16(b []byte, off int) uint16 { return binary.LittleEndian.Uint16(b[off:]) }
+func u32(b []byte, off int) uint32 { return binary.LittleEndian.Uint32(b[off:]) }
+
+// records returns the number of fixed-size records in a chunk
+func records(b []byte, size int) int {
+	return len(b) / size
+}
+
+// zoneGens returns the generators of one bag
+func zoneGens(bags []byte, gens []generator, bag int) []generator {
+	if (bag+1)*4+2 > len(bags) {
+		return nil
+	}
+	start := int(u16(bags, bag*4))
+	end := int(u16(bags, (bag+1)*4))
+	if start > end || end > len(gens) {
+		return nil
+	}
+	return gens[start:end]
+}
+
+// parsePresets resolves the preset, instrument and sample hierarchy into flat zones
+func (font *SoundFont) parsePresets(pdta []chunk) error {
+	parts := make(map[string][]byte)
+	for _, c := range pdta {
+		parts[c.id] = c.data
+	}
+	for _, id := range []string{"phdr", "pbag", "pgen", "inst", "ibag", "igen", "shdr"} {
+		if _, ok := parts[id]; !ok {
+			return fmt.Errorf("sf2: missing %s chunk", id)
+		}
+	}
+
+	readGens := func(b []byte) []generator {
+		gens := make([]generator, records(b, 4))
+		for i := range gens {
+			gens[i] = generator{oper: u16(b, i*4), amount: u16(b, i*4+2)}
+		}
+		return gens
+	}
+	pgen := readGens(parts["pgen"])
+	igen := readGens(parts["igen"])
+	pbag, ibag := parts["pbag"], parts["ibag"]
+
+	// Samples
+	shdr := parts["shdr"]
+	samples := make([]Sample, records(shdr, 46))
+	for i := range samples {
+		r := shdr[i*46:]
+		samples[i] = Sample{
+			Name:       cString(r[:20]),
+			Start:      int(u32(r, 20)),
+			End:        int(u32(r, 24)),
+			LoopStart:  int(u32(r, 28)),
+			LoopEnd:    int(u32(r, 32)),
+			SampleRate: int(u32(r, 36)),
+			RootKey:    r[40],
+			Correction: int8(r[41]),
+		}
+	}
+
+	// Instruments, each a list of zones with their sample and generators
+	inst := parts["inst"]
+	instCount := max(0, records(inst, 22)-1) // The last record is a terminator
+	instZones := make([][]Zone, instCount)
+	for i := 0; i < instCount; i++ {
+		first := int(u16(inst, i*22+20))
+		last := int(u16(inst, (i+1)*22+20))
+		var global []generator
+		for bag := first; bag < last; bag++ {
+			gens := zoneGens(ibag, igen, bag)
+			sampleID := -1
+			for _, g := range gens {
+				if g.is(genSampleID) {
+					sampleID = g.unsigned()
+				}
+			}
+			if sampleID < 0 {
+				// A zone without a sample is the instrument's global zone
+				if bag == first {
+					global = gens
+				}
+				continue
+			}
+			if sampleID >= len(samples) {
+				continue
+			}
+			zone := font.newZone(&samples[sampleID])
+			applyInstrumentGens(&zone, withGlobal(global, gens))
+			zone.clampAddresses(len(font.Data))
+			instZones[i] = append(instZones[i], zone)
+		}
+	}
+
+	// Presets, whose zones select and restrict instruments
+	phdr := parts["phdr"]
+	presetCount := max(0, records(phdr, 38)-1)
+	for i := 0; i < presetCount; i++ {
+		r := phdr[i*38:]
+		preset := Preset{
+			Name:   cString(r[:20]),
+			Number: u16(r, 20),
+			Bank:   u16(r, 22),
+		}
+		first := int(u16(r, 24))
+		last := int(u16(phdr[(i+1)*38:], 24))
+		var global []generator
+		for bag := first; bag < last; bag++ {
+			gens := zoneGens(pbag, pgen, bag)
+			instID := -1
+			for _, g := range gens {
+				if g.is(genInstrument) {
+					instID = g.unsigned()
+				}
+			}
+			if instID < 0 {
+				if bag == first {
+					global = gens
+				}
+				continue
+			}
+			if instID >= len(instZones) {
+				continue
+			}
+			for _, zone := range instZones[instID] {
+				applyPresetGens(&zone, withGlobal(global, gens))
+				if zone.KeyLo <= zone.KeyHi && zone.VelLo <= zone.VelHi {
+					preset.Zones = append(preset.Zones, zone)
+				}
+			}
+		}
+		font.Presets = append(font.Presets, preset)
+	}
+	return nil
+}
+
+// withGlobal returns a zone's generators with those of its global zone it doesn't set
+// itself: a zone's own generator replaces the global one of the same operator
+func withGlobal(global, local []generator) []generator {
+	gens := append([]generator(nil), local...)
+	for _, g := range global {
+		if !slices.ContainsFunc(local, func(l generator) bool { return l.oper == g.oper }) {
+			gens = append(gens, g)
+		}
+	}
+	return gens
+}
+
+// newZone creates a zone covering the whole keyboard for a sample
+func (font *SoundFont) newZone(s *Sample) Zone {
+	return Zone{
+		KeyLo: 0, KeyHi: 127,
+		VelLo: 0, VelHi: 127,
+		Sample:    s,
+		Start:     s.Start,
+		End:       s.End,
+		LoopStart: s.LoopStart,
+		LoopEnd:   s.LoopEnd,
+		RootKey:   s.RootKey,
+		Tune:      float64(s.Correction),
+	}
+}
+
+// applyInstrumentGens applies instrument-level generators, which set absolute values
+func applyInstrumentGens(z *Zone, gens []generator) {
+	for _, g := range gens {
+		switch int(g.oper) {
+		case genKeyRange:
+			z.KeyLo, z.KeyHi = g.rangeLo(), g.rangeHi()
+		case genVelRange:
+			z.VelLo, z.VelHi = g.rangeLo(), g.rangeHi()
+		case genStartAddrsOffset:
+			z.Start += g.signed()
+		case genEndAddrsOffset:
+			z.End += g.signed()
+		case genStartloopAddrsOffset:
+			z.LoopStart += g.signed()
+		case genEndloopAddrsOffset:
+			z.LoopEnd += g.signed()
+		case genStartAddrsCoarseOffset:
+			z.Start += g.signed() * 32768
+		case genEndAddrsCoarseOffset:
+			z.End += g.signed() * 32768
+		case genStartloopCoarseOffset:
+			z.LoopStart += g.signed() * 32768
+		case genEndloopCoarseOffset:
+			z.LoopEnd += g.signed() * 32768
+		case genCoarseTune:
+			z.Tune += float64(g.signed()) * 100
+		case genFineTune:
+			z.Tune += float64(g.signed())
+		case genSampleModes:
+			z.LoopMode = g.unsigned() & 3
+		case genOverridingRootKey:
+			if g.signed() >= 0 && g.signed() <= 127 {
+				z.RootKey = uint8(g.signed())
+			}
+		case genInitialAttenuation:
+			z.Attenuation = float64(g.signed())
+		case genPan:
+			z.Pan = float64(g.signed()) / 500
+		}
+	}
+}
+
+// applyPresetGens applies preset-level generators, which narrow ranges and add to the
+// instrument's values
+func applyPresetGens(z *Zone, gens []generator) {
+	for _, g := range gens {
+		switch int(g.oper) {
+		case genKeyRange:
+			z.KeyLo = max(z.KeyLo, g.rangeLo())
+			z.KeyHi = min(z.KeyHi, g.rangeHi())
+		case genVelRange:
+			z.VelLo = max(z.VelLo, g.rangeLo())
+			z.VelHi = min(z.VelHi, g.rangeHi())
+		case genCoarseTune:
+			z.Tune += float64(g.signed()) * 100
+		case genFineTune:
+			z.Tune += float64(g.signed())
+		case genInitialAttenuation:
+			z.Attenuation += float64(g.signed())
+		case genPan:
+			z.Pan += float64(g.signed()) / 500
+		}
+	}
+}
+
+// clampAddresses keeps sample addresses inside the font's data
+func (z *Zone) clampAddresses(n int) {
+	clampInt := func(v int) int { return max(0, min(n, v)) }
+	z.Start = clampInt(z.Start)
+	z.End = clampInt(z.End)
+	z.LoopStart = clampInt(z.LoopStart)
+	z.LoopEnd = clampInt(z.LoopEnd)
+	if z.LoopEnd <= z.LoopStart {
+		z.LoopMode = LoopNone
+	}
+}
@@ -0,0 +1,160 @@
+package sf2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// riff builds a chunk, padded to an even size
+func riff(id string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := binary.LittleEndian.AppendUint32([]byte(id), uint32(len(body)))
+	b = append(b, body...)
+	if len(body)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// name20 encodes a fixed-size record name
+func name20(name string) []byte {
+	b := make([]byte, 20)
+	copy(b, name)
+	return b
+}
+
+// gen encodes a generator record
+func gen(oper int, amount int) generator {
+	return generator{oper: uint16(oper), amount: uint16(int16(amount))}
+}
+
+// rangeGen encodes a key or velocity range generator
+func rangeGen(oper int, lo, hi uint8) generator {
+	return generator{oper: uint16(oper), amount: uint16(hi)<<8 | uint16(lo)}
+}
+
+// testFont builds a font with one 1000-frame sample, one instrument and one preset, from
+// the generators of each zone; a zone's last generator picks its sample or instrument,
+// and a zone without one is global
+func testFont(instZones, presetZones [][]generator) []byte {
+	bagsAndGens := func(zones [][]generator) ([]byte, []byte) {
+		var bags, gens []byte
+		n := 0
+		for _, zone := range zones {
+			bags = binary.LittleEndian.AppendUint16(bags, uint16(n))
+			bags = binary.LittleEndian.AppendUint16(bags, 0)
+			for _, g := range zone {
+				gens = binary.LittleEndian.AppendUint16(gens, g.oper)
+				gens = binary.LittleEndian.AppendUint16(gens, g.amount)
+				n++
+			}
+		}
+		bags = binary.LittleEndian.AppendUint16(bags, uint16(n))
+		bags = binary.LittleEndian.AppendUint16(bags, 0)
+		return bags, append(gens, make([]byte, 4)...)
+	}
+	ibag, igen := bagsAndGens(instZones)
+	pbag, pgen := bagsAndGens(presetZones)
+
+	var phdr []byte
+	for i, name := range []string{"Piano", "EOP"} {
+		phdr = append(phdr, name20(name)...)
+		phdr = binary.LittleEndian.AppendUint16(phdr, 0)                          // Preset number
+		phdr = binary.LittleEndian.AppendUint16(phdr, 0)                          // Bank
+		phdr = binary.LittleEndian.AppendUint16(phdr, uint16(i*len(presetZones))) // First bag
+		phdr = append(phdr, make([]byte, 12)...)
+	}
+	var inst []byte
+	for i, name := range []string{"Keys", "EOI"} {
+		inst = append(inst, name20(name)...)
+		inst = binary.LittleEndian.AppendUint16(inst, uint16(i*len(instZones)))
+	}
+	var shdr []byte
+	for _, name := range []string{"C4", "EOS"} {
+		shdr = append(shdr, name20(name)...)
+		for _, v := range []uint32{0, 1000, 100, 900, 44100} { // Start, end, loop start, loop end, rate
+			shdr = binary.LittleEndian.AppendUint32(shdr, v)
+		}
+		shdr = append(shdr, 60, 0, 0, 0, 0, 0) // Root key, correction, link, type
+	}
+
+	pdta := riff("LIST", []byte("pdta"),
+		riff("phdr", phdr), riff("pbag", pbag), riff("pmod", make([]byte, 10)), riff("pgen", pgen),
+		riff("inst", inst), riff("ibag", ibag), riff("imod", make([]byte, 10)), riff("igen", igen),
+		riff("shdr", shdr))
+	sdta := riff("LIST", []byte("sdta"), riff("smpl", make([]byte, 2000)))
+	info := riff("LIST", []byte("INFO"), riff("INAM", []byte("Test\x00")))
+	return riff("RIFF", []byte("sfbk"), info, sdta, pdta)
+}
+
+// TestZoneGenerators checks that a zone's own generators replace those of its global
+// zone, at both levels, while preset generators add to the instrument's
+func TestZoneGenerators(t *testing.T) {
+	instZones := [][]generator{
+		{gen(genCoarseTune, 2), gen(genStartAddrsOffset, 10), gen(genInitialAttenuation, 60), rangeGen(genKeyRange, 0, 100)}, // Global
+		{gen(genCoarseTune, 5), gen(genFineTune, 10), gen(genStartAddrsOffset, 4), gen(genSampleID, 0)},
+		{gen(genSampleID, 0)},
+	}
+	presetZones := [][]generator{
+		{gen(genCoarseTune, 1), gen(genInitialAttenuation, 20)}, // Global
+		{gen(genInitialAttenuation, 30), rangeGen(genKeyRange, 48, 72), gen(genInstrument, 0)},
+	}
+	font, err := Read(bytes.NewReader(testFont(instZones, presetZones)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if font.Name != "Test" || len(font.Presets) != 1 || len(font.Presets[0].Zones) != 2 {
+		t.Fatalf("font %q with presets %+v, want Test with one preset of two zones", font.Name, font.Presets)
+	}
+	for i, want := range []struct {
+		tune, attenuation float64
+		start             int
+	}{
+		// Own coarse tune and start offset replace the global ones; the preset adds a
+		// semitone, and its zone's attenuation replaces its global zone's
+		{600 + 10, 60 + 30, 4},
+		// Global instrument generators apply to a zone without its own
+		{300, 60 + 30, 10},
+	} {
+		z := font.Presets[0].Zones[i]
+		if z.Tune != want.tune || z.Attenuation != want.attenuation || z.Start != want.start {
+			t.Errorf("zone %d: tune %g, attenuation %g, start %d, want %g, %g, %d",
+				i, z.Tune, z.Attenuation, z.Start, want.tune, want.attenuation, want.start)
+		}
+		if z.KeyLo != 48 || z.KeyHi != 72 {
+			t.Errorf("zone %d: keys %d-%d, want the preset's 48-72 within the instrument's 0-100", i, z.KeyLo, z.KeyHi)
+		}
+	}
+}
+
+// TestReadErrors checks that files which aren't SoundFonts, or are missing parts, are refused
+func TestReadErrors(t *testing.T) {
+	good := testFont([][]generator{{gen(genSampleID, 0)}}, [][]generator{{gen(genInstrument, 0)}})
+	if _, err := Read(bytes.NewReader(good)); err != nil {
+		t.Fatalf("good font: %v", err)
+	}
+	truncated := append([]byte(nil), good...)
+	binary.LittleEndian.PutUint32(truncated[12+4:], 1<<30) // The INFO list claims more than there is
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		msg  string
+	}{
+		{"empty", nil, "not a SoundFont"},
+		{"wave file", riff("RIFF", []byte("WAVE")), "not a SoundFont"},
+		{"truncated chunk", truncated, "truncated"},
+		{"no samples", riff("RIFF", []byte("sfbk")), "missing sample data"},
+		{"no presets", riff("RIFF", []byte("sfbk"), riff("LIST", []byte("sdta"), riff("smpl", make([]byte, 4)))), "missing preset data"},
+		{"no sample headers", riff("RIFF", []byte("sfbk"),
+			riff("LIST", []byte("sdta"), riff("smpl", make([]byte, 4))),
+			riff("LIST", []byte("pdta"), riff("phdr", nil))), "missing"},
+	} {
+		_, err := Read(bytes.NewReader(tc.data))
+		if err == nil || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%s: error %v, want one mentioning %q", tc.name, err, tc.msg)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+// Package smf reads Standard MIDI Files (.mid) into their tracks of timed events, merges
+// them into a timeline following the file's tempo map, and writes them.
+package smf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultTempo = 500000   // Microseconds per quarter note before the first tempo change, 120 BPM
+	maxFileSize  = 16 << 20 // Largest file read, in bytes
+
+	metaTrackName  = 0x03
+	metaEndOfTrack = 0x2f
+	metaTempo      = 0x51
+)
+
+// File is a Standard MIDI File
+type File struct {
+	Format   int // 0 for a single track, 1 for simultaneous tracks, 2 for independent ones
+	Division int // Ticks per quarter note
+	Tracks   []Track
+}
+
+// Track is one track of a file
+type Track struct {
+	Name   string
+	Events []Event
+}
+
+// Event is a channel message or meta event at a tick of its track
+type Event struct {
+	Tick    uint64 // Ticks from the start of the track
+	Message []byte // Channel message with its status byte, nil for a meta event
+	Meta    byte   // Meta event type, for a meta event
+	Data    []byte // Meta event data
+}
+
+// Tempo returns the microseconds per quarter note a tempo meta event sets
+func (e Event) Tempo() (uint32, bool) {
+	if e.Message != nil || e.Meta != metaTempo || len(e.Data) != 3 {
+		return 0, false
+	}
+	return uint32(e.Data[0])<<16 | uint32(e.Data[1])<<8 | uint32(e.Data[2]), true
+}
+
+// Load reads a .mid file
+func Load(path string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxFileSize {
+		return nil, fmt.Errorf("smf: %s is too large", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(data))
+}
+
+// Parse reads a Standard MIDI File. Files timed in SMPTE frames are not supported, and
+// chunks other than the header and tracks are skipped.
+func Parse(r io.Reader) (*File, error) {
+	id, chunk, err := readChunk(r)
+	if err != nil {
+		return nil, err
+	}
+	if id != "MThd" || len(chunk) < 6 {
+		return nil, errors.New("smf: not a MIDI file")
+	}
+	f := &File{
+		Format:   int(binary.BigEndian.Uint16(chunk[0:])),
+		Division: int(binary.BigEndian.Uint16(chunk[4:])),
+	}
+	tracks := int(binary.BigEndian.Uint16(chunk[2:]))
+	if f.Division&0x8000 != 0 {
+		return nil, errors.New("smf: SMPTE time division not supported")
+	}
+	if f.Division == 0 {
+		return nil, errors.New("smf: zero ticks per quarter note")
+	}
+	for len(f.Tracks) < tracks {
+		id, chunk, err := readChunk(r)
+		if err == io.EOF {
+			break // Tolerate files listing more tracks than they hold
+		}
+		if err != nil {
+			return nil, err
+		}
+		if id != "MTrk" {
+			continue
+		}
+		track, err := parseTrack(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("smf: track %d: %w", len(f.Tracks)+1, err)
+		}
+		f.Tracks = append(f.Tracks, track)
+	}
+	return f, nil
+}
+
+// readChunk reads a chunk's type and body. The body grows as it's read rather than
+// being allocated at the size the header claims, so a corrupt size can't take more
+// memory than the input holds.
+func readChunk(r io.Reader) (string, []byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return "", nil, errors.New("smf: truncated chunk header")
+		}
+		return "", nil, err
+	}
+	size := int64(binary.BigEndian.Uint32(header[4:]))
+	if size > maxFileSize {
+		return "", nil, errors.New("smf: chunk larger than a file can be")
+	}
+	var body bytes.Buffer
+	if _, err := io.CopyN(&body, r, size); err != nil {
+		return "", nil, errors.New("smf: truncated chunk")
+	}
+	return string(header[:4]), body.Bytes(), nil
+}
+
+// parseTrack reads the events of a track chunk, up to its end-of-track event
+func parseTrack(data []byte) (Track, error) {
+	var t Track
+	var tick uint64
+	var running byte // Status of the last channel message, for running status
+	for pos := 0; pos < len(data); {
+		delta, n := readVarLen(data[pos:])
+		if n == 0 {
+			return t, errors.New("bad delta time")
+		}
+		pos += n
+		tick += uint64(delta)
+		if pos >= len(data) {
+			return t, errors.New("event missing after delta time")
+		}
+
+		status := data[pos]
+		switch {
+		case status == 0xff:
+			if pos+2 > len(data) {
+				return t, errors.New("truncated meta event")
+			}
+			kind := data[pos+1]
+			length, n := readVarLen(data[pos+2:])
+			start := pos + 2 + n
+			if n == 0 || start+int(length) > len(data) {
+				return t, errors.New("truncated meta event")
+			}
+			body := data[start : start+int(length)]
+			pos = start + int(length)
+			running = 0
+			if kind == metaEndOfTrack {
+				return t, nil
+			}
+			if kind == metaTrackName && t.Name == "" {
+				t.Name = string(body)
+				continue
+			}
+			t.Events = append(t.Events, Event{Tick: tick, Meta: kind, Data: append([]byte(nil), body...)})
+		case status == 0xf0 || status == 0xf7:
+			// System exclusive messages are skipped
+			length, n := readVarLen(data[pos+1:])
+			if n == 0 || pos+1+n+int(length) > len(data) {
+				return t, errors.New("truncated system exclusive message")
+			}
+			pos += 1 + n + int(length)
+			running = 0
+		default:
+			if status&0x80 != 0 {
+				running = status
+				pos++
+			} else if running == 0 {
+				return t, errors.New("data byte without a status")
+			}
+			size := 2
+			if kind := running & 0xf0; kind == 0xc0 || kind == 0xd0 {
+				size = 1
+			}
+			if pos+size > len(data) {
+				return t, errors.New("truncated channel message")
+			}
+			msg := append([]byte{running}, data[pos:pos+size]...)
+			pos += size
+			t.Events = append(t.Events, Event{Tick: tick, Message: msg})
+		}
+	}
+	return t, nil // Tolerate a missing end-of-track event
+}
+
+// readVarLen reads a variable-length quantity, returning the bytes used, 0 when malformed
+func readVarLen(data []byte) (uint32, int) {
+	var v uint32
+	for i := 0; i < len(data) && i < 4; i++ {
+		v = v<<7 | uint32(data[i]&0x7f)
+		if data[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// TimedEvent is a channel message of a track at its time from the start of the file
+type TimedEvent struct {
+	Time    time.Duration
+	Track   int
+	Message []byte
+}
+
+// Timeline merges the channel messages of every track in time order, placing them by
+// the tempo changes of any track. Independent tracks of format 2 files are merged too.
+func (f *File) Timeline() []TimedEvent {
+	type merged struct {
+		Event
+		track int
+	}
+	var events []merged
+	for i, t := range f.Tracks {
+		for _, e := range t.Events {
+			events = append(events, merged{e, i})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Tick < events[j].Tick })
+
+	var timeline []TimedEvent
+	var lastTick uint64
+	var elapsed float64 // Microseconds up to lastTick
+	tempo := float64(DefaultTempo)
+	for _, e := range events {
+		elapsed += float64(e.Tick-lastTick) * tempo / float64(f.Division)
+		lastTick = e.Tick
+		if us, ok := e.Tempo(); ok && us > 0 {
+			tempo = float64(us)
+			continue
+		}
+		if e.Message != nil {
+			timeline = append(timeline, TimedEvent{
+				Time:    time.Duration(elapsed * float64(time.Microsecond)),
+				Track:   e.track,
+				Message: e.Message,
+			})
+		}
+	}
+	return timeline
+}
+
+// Save writes a file as a .mid file at path
+func Save(path string, f *File) error {
+	var buf bytes.Buffer
+	if err := Write(&buf, f); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// Write writes a Standard MIDI File. Each track's events must be in tick order; a track
+// name is written first when set, and every track is ended with an end-of-track event.
+func Write(w io.Writer, f *File) error {
+	if f.Division <= 0 || f.Division >= 0x8000 {
+		return fmt.Errorf("smf: invalid division %d", f.Division)
+	}
+	var header [14]byte
+	copy(header[:], "MThd")
+	binary.BigEndian.PutUint32(header[4:], 6)
+	binary.BigEndian.PutUint16(header[8:], uint16(f.Format))
+	binary.BigEndian.PutUint16(header[10:], uint16(len(f.Tracks)))
+	binary.BigEndian.PutUint16(header[12:], uint16(f.Division))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	for _, t := range f.Tracks {
+		var body []byte
+		if t.Name != "" {
+			body = appendMeta(body, 0, metaTrackName, []byte(t.Name))
+		}
+		var tick uint64
+		for _, e := range t.Events {
+			if e.Tick < tick {
+				return errors.New("smf: events out of order")
+			}
+			if e.Message != nil {
+				body = append(appendVarLen(body, uint32(e.Tick-tick)), e.Message...)
+			} else {
+				body = appendMeta(body, uint32(e.Tick-tick), e.Meta, e.Data)
+			}
+			tick = e.Tick
+		}
+		body = appendMeta(body, 0, metaEndOfTrack, nil)
+
+		var chunk [8]byte
+		copy(chunk[:], "MTrk")
+		binary.BigEndian.PutUint32(chunk[4:], uint32(len(body)))
+		if _, err := w.Write(append(chunk[:], body...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TempoEvent returns a tempo meta event setting a tempo in beats per minute
+func TempoEvent(tick uint64, bpm float64) Event {
+	us := uint32(60e6 / bpm)
+	return Event{Tick: tick, Meta: metaTempo, Data: []byte{byte(us >> 16), byte(us >> 8), byte(us)}}
+}
+
+// appendMeta appends a meta event after a delta time
+func appendMeta(b []byte, delta uint32, kind byte, data []byte) []byte {
+	b = append(appendVarLen(b, delta), 0xff, kind)
+	return append(appendVarLen(b, uint32(len(data))), data...)
+}
+
+// appendVarLen appends a variable-length quantity
+func appendVarLen(b []byte, v uint32) []byte {
+	var tmp [4]byte
+	n := 0
+	for {
+		tmp[n] = byte(v & 0x7f)
+		n++
+		v >>= 7
+		if v == 0 {
+			break
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		if i > 0 {
+			tmp[i] |= 0x80
+		}
+		b = append(b, tmp[i])
+	}
+	return b
+}
@@ -0,0 +1,173 @@
+package smf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// midiFile builds a format 1 file at 96 ticks per quarter note from track bodies
+func midiFile(tracks ...[]byte) []byte {
+	b := []byte("MThd\x00\x00\x00\x06")
+	b = binary.BigEndian.AppendUint16(b, 1)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(tracks)))
+	b = binary.BigEndian.AppendUint16(b, 96)
+	for _, t := range tracks {
+		b = append(b, "MTrk"...)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(t)))
+		b = append(b, t...)
+	}
+	return b
+}
+
+// TestParseTrack checks running status, skipped system exclusive messages, meta events
+// and the malformed tracks that must be refused
+func TestParseTrack(t *testing.T) {
+	endOfTrack := []byte{0, 0xff, metaEndOfTrack, 0}
+	for _, tc := range []struct {
+		name   string
+		data   []byte
+		want   []Event
+		hasErr bool
+	}{
+		{
+			name: "running status",
+			data: []byte{0, 0x90, 60, 100, 10, 64, 90, 10, 60, 0},
+			want: []Event{
+				{Tick: 0, Message: []byte{0x90, 60, 100}},
+				{Tick: 10, Message: []byte{0x90, 64, 90}},
+				{Tick: 20, Message: []byte{0x90, 60, 0}},
+			},
+		},
+		{
+			name: "running status of a one-byte message",
+			data: []byte{0, 0xc1, 5, 4, 6},
+			want: []Event{
+				{Tick: 0, Message: []byte{0xc1, 5}},
+				{Tick: 4, Message: []byte{0xc1, 6}},
+			},
+		},
+		{
+			name: "system exclusive skipped",
+			data: append([]byte{0, 0xf0, 3, 0x7e, 0x7f, 0xf7, 5, 0xb0, 7, 100}, endOfTrack...),
+			want: []Event{{Tick: 5, Message: []byte{0xb0, 7, 100}}},
+		},
+		{
+			name: "track name and tempo",
+			data: append([]byte{0, 0xff, metaTrackName, 4, 'B', 'a', 's', 's', 0, 0xff, metaTempo, 3, 0x07, 0xa1, 0x20}, endOfTrack...),
+			want: []Event{{Tick: 0, Meta: metaTempo, Data: []byte{0x07, 0xa1, 0x20}}},
+		},
+		{
+			name: "events after the end of the track ignored",
+			data: append(append([]byte{0, 0x90, 60, 100}, endOfTrack...), 0, 0x80, 60, 0),
+			want: []Event{{Tick: 0, Message: []byte{0x90, 60, 100}}},
+		},
+		{
+			name: "missing end of track",
+			data: []byte{0, 0x90, 60, 100},
+			want: []Event{{Tick: 0, Message: []byte{0x90, 60, 100}}},
+		},
+		{name: "running status after system exclusive", data: []byte{0, 0x90, 60, 100, 0, 0xf0, 1, 0xf7, 0, 60, 0}, hasErr: true},
+		{name: "running status after meta event", data: []byte{0, 0x90, 60, 100, 0, 0xff, 0x01, 0, 0, 60, 0}, hasErr: true},
+		{name: "data byte first", data: []byte{0, 60, 100}, hasErr: true},
+		{name: "meta event without a type", data: []byte{0, 0xff}, hasErr: true},
+		{name: "meta event without a length", data: []byte{0, 0xff, metaTempo}, hasErr: true},
+		{name: "meta event longer than the track", data: []byte{0, 0xff, metaTempo, 3, 0x07}, hasErr: true},
+		{name: "meta length overrunning", data: []byte{0, 0xff, 0x01, 0xff, 0xff, 0xff, 0x7f}, hasErr: true},
+		{name: "truncated system exclusive", data: []byte{0, 0xf0, 5, 1, 2}, hasErr: true},
+		{name: "truncated channel message", data: []byte{0, 0x90, 60}, hasErr: true},
+		{name: "delta time without an event", data: []byte{0x81}, hasErr: true},
+		{name: "overlong delta time", data: []byte{0x81, 0x81, 0x81, 0x81, 0x01, 0x90, 60, 100}, hasErr: true},
+	} {
+		track, err := parseTrack(tc.data)
+		if (err != nil) != tc.hasErr {
+			t.Errorf("%s: error %v, want error %v", tc.name, err, tc.hasErr)
+			continue
+		}
+		if !tc.hasErr && !reflect.DeepEqual(track.Events, tc.want) {
+			t.Errorf("%s: events %v, want %v", tc.name, track.Events, tc.want)
+		}
+	}
+}
+
+// TestParseChunks checks the header, skipped chunks, and chunk sizes larger than the
+// input, which must fail without allocating what they claim
+func TestParseChunks(t *testing.T) {
+	track := []byte{0, 0x90, 60, 100, 0, 0xff, metaEndOfTrack, 0}
+	f, err := Parse(bytes.NewReader(midiFile(track, track)))
+	if err != nil || len(f.Tracks) != 2 || f.Division != 96 || f.Format != 1 {
+		t.Fatalf("Parse = %+v, %v, want two tracks at 96 ticks", f, err)
+	}
+
+	// An unknown chunk between the tracks is skipped
+	data := midiFile(track)
+	data = append(data, "XFIH\x00\x00\x00\x02ab"...)
+	data = append(data, midiFile(track)[14:]...)
+	binary.BigEndian.PutUint16(data[10:], 2)
+	if f, err := Parse(bytes.NewReader(data)); err != nil || len(f.Tracks) != 2 {
+		t.Errorf("unknown chunk: %d tracks, %v, want 2", len(f.Tracks), err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		msg  string
+	}{
+		{"huge chunk size", append([]byte("MThd\xff\xff\xff\xff"), make([]byte, 64)...), "larger than"},
+		{"chunk size past the input", append([]byte("MThd\x00\x10\x00\x00"), make([]byte, 64)...), "truncated chunk"},
+		{"truncated header", []byte("MTh"), "truncated chunk header"},
+		{"not a MIDI file", []byte("RIFF\x00\x00\x00\x06\x00\x00\x00\x01\x00\x60"), "not a MIDI file"},
+		{"SMPTE division", []byte("MThd\x00\x00\x00\x06\x00\x00\x00\x01\xe7\x28"), "SMPTE"},
+		{"zero division", []byte("MThd\x00\x00\x00\x06\x00\x00\x00\x01\x00\x00"), "zero ticks"},
+		{"bad track", midiFile([]byte{0, 60}), "track 1"},
+	} {
+		_, err := Parse(bytes.NewReader(tc.data))
+		if err == nil || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%s: error %v, want one mentioning %q", tc.name, err, tc.msg)
+		}
+	}
+
+	// A file listing more tracks than it holds keeps those it has
+	data = midiFile(track)
+	binary.BigEndian.PutUint16(data[10:], 3)
+	if f, err := Parse(bytes.NewReader(data)); err != nil || len(f.Tracks) != 1 {
+		t.Errorf("missing tracks: %d tracks, %v, want 1", len(f.Tracks), err)
+	}
+}
+
+// TestTimeline checks that tempo changes on one track time the notes of every track
+func TestTimeline(t *testing.T) {
+	f := &File{
+		Format:   1,
+		Division: 96,
+		Tracks: []Track{
+			{Events: []Event{TempoEvent(192, 60)}}, // Half speed after two beats
+			{Events: []Event{
+				{Tick: 0, Message: []byte{0x90, 60, 100}},
+				{Tick: 96, Message: []byte{0x80, 60, 0}},
+				{Tick: 288, Message: []byte{0x90, 62, 100}},
+			}},
+			{Events: []Event{{Tick: 192, Message: []byte{0x90, 36, 100}}}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, f); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TimedEvent{
+		{Time: 0, Track: 1, Message: []byte{0x90, 60, 100}},
+		{Time: 500 * time.Millisecond, Track: 1, Message: []byte{0x80, 60, 0}},
+		{Time: time.Second, Track: 2, Message: []byte{0x90, 36, 100}},
+		{Time: 2 * time.Second, Track: 1, Message: []byte{0x90, 62, 100}},
+	}
+	if got := parsed.Timeline(); !reflect.DeepEqual(got, want) {
+		t.Errorf("timeline %v, want %v", got, want)
+	}
+}
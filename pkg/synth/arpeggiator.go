@@ -0,0 +1,309 @@
+package synth
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"gosynth/pkg/engine"
+)
+
+const (
+	ArpRate    = 8.0 // Default arpeggiator steps per second
+	ArpOctaves = 1   // Default octave range
+	ArpGate    = 0.5 // Default fraction of each step the note sounds
+	ArpSyncDiv = "1/16"
+)
+
+// ArpMode selects the order in which held notes are played
+type ArpMode int
+
+const (
+	ArpUp ArpMode = iota
+	ArpDown
+	ArpUpDown
+	ArpRandom
+	arpModeCount
+)
+
+func (m ArpMode) String() string {
+	switch m {
+	case ArpUp:
+		return "up"
+	case ArpDown:
+		return "down"
+	case ArpUpDown:
+		return "up-down"
+	case ArpRandom:
+		return "random"
+	}
+	return "unknown"
+}
+
+// Next returns the following mode, wrapping around
+func (m ArpMode) Next(dir int) ArpMode {
+	return ArpMode((int(m) + dir + int(arpModeCount)) % int(arpModeCount))
+}
+
+// heldNote is a key currently held down while the arpeggiator is running
+type heldNote struct {
+	note     uint8
+	velocity uint8
+	released bool // Key was released while the pedal held the pattern
+}
+
+// Arpeggiator plays the currently held notes one at a time in a repeating pattern
+type Arpeggiator struct {
+	Rate    engine.SmoothValue // Steps per second
+	Octaves engine.SmoothValue // Number of octaves the pattern spans
+	Gate    engine.SmoothValue // Fraction of each step the note sounds
+
+	engine.TempoSync // Step on the clock at a division instead of at Rate
+
+	clock   *Clock
+	noteOn  func(note, velocity uint8)
+	noteOff func(note uint8)
+
+	mu      sync.Mutex
+	mode    ArpMode
+	held    []heldNote
+	hold    bool
+	step    int
+	running bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewArpeggiator creates an arpeggiator that plays through the given note functions
+func NewArpeggiator(clock *Clock, noteOn func(note, velocity uint8), noteOff func(note uint8)) *Arpeggiator {
+	a := &Arpeggiator{
+		mode:    ArpUp,
+		clock:   clock,
+		noteOn:  noteOn,
+		noteOff: noteOff,
+	}
+	a.SetDiv(engine.DivisionIndex(ArpSyncDiv))
+	a.Rate.Set(ArpRate)
+	a.Octaves.Set(ArpOctaves)
+	a.Gate.Set(ArpGate)
+	return a
+}
+
+// Enabled reports whether the arpeggiator goroutine is running
+func (a *Arpeggiator) Enabled() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.running
+}
+
+// SetEnabled starts or stops the arpeggiator goroutine
+func (a *Arpeggiator) SetEnabled(enabled bool) {
+	a.mu.Lock()
+	if enabled == a.running {
+		a.mu.Unlock()
+		return
+	}
+	a.running = enabled
+	if enabled {
+		a.held = a.held[:0]
+		a.hold = false
+		a.step = 0
+		a.stop = make(chan struct{})
+		a.done = make(chan struct{})
+		go a.run(a.stop, a.done)
+		a.mu.Unlock()
+		return
+	}
+	stop, done := a.stop, a.done
+	a.mu.Unlock()
+
+	// Wait for the goroutine so its last note is released before returning
+	close(stop)
+	<-done
+}
+
+// Mode returns the order the held notes are played in
+func (a *Arpeggiator) Mode() ArpMode {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.mode
+}
+
+// SetMode sets the order the held notes are played in
+func (a *Arpeggiator) SetMode(mode ArpMode) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.mode = mode.Next(0)
+}
+
+// NoteOn adds a key to the set of held notes
+func (a *Arpeggiator) NoteOn(note, velocity uint8) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.held {
+		if a.held[i].note == note {
+			a.held[i].velocity = velocity
+			a.held[i].released = false
+			return
+		}
+	}
+	a.held = append(a.held, heldNote{note: note, velocity: velocity})
+	sort.Slice(a.held, func(i, j int) bool { return a.held[i].note < a.held[j].note })
+}
+
+// NoteOff removes a key from the set of held notes
+func (a *Arpeggiator) NoteOff(note uint8) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.held {
+		if a.held[i].note == note {
+			if a.hold {
+				a.held[i].released = true
+			} else {
+				a.held = append(a.held[:i], a.held[i+1:]...)
+			}
+			return
+		}
+	}
+}
+
+// Hold reports whether the pedal is holding the pattern
+func (a *Arpeggiator) Hold() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.hold
+}
+
+// SetHold keeps released notes in the pattern while down, dropping them on release
+func (a *Arpeggiator) SetHold(down bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.hold = down
+	if down {
+		return
+	}
+	kept := a.held[:0]
+	for _, h := range a.held {
+		if !h.released {
+			kept = append(kept, h)
+		}
+	}
+	a.held = kept
+}
+
+// clear drops every held note and lifts the pedal hold, so the pattern falls silent
+func (a *Arpeggiator) clear() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.held = a.held[:0]
+	a.hold = false
+}
+
+// nextNote picks the next note of the pattern, returning false when no keys are held
+func (a *Arpeggiator) nextNote() (heldNote, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.held) == 0 {
+		a.step = 0
+		return heldNote{}, false
+	}
+
+	// Expand the held notes across the octave range
+	octaves := int(a.Octaves.Get())
+	if octaves < 1 {
+		octaves = 1
+	}
+	pattern := make([]heldNote, 0, len(a.held)*octaves)
+	for oct := 0; oct < octaves; oct++ {
+		for _, h := range a.held {
+			note := int(h.note) + oct*12
+			if note > 127 {
+				continue
+			}
+			pattern = append(pattern, heldNote{note: uint8(note), velocity: h.velocity})
+		}
+	}
+
+	var idx int
+	n := len(pattern)
+	switch a.mode {
+	case ArpUp:
+		idx = a.step % n
+	case ArpDown:
+		idx = n - 1 - a.step%n
+	case ArpUpDown:
+		if n == 1 {
+			idx = 0
+		} else {
+			// Bounce without repeating the top and bottom notes
+			cycle := 2*n - 2
+			idx = a.step % cycle
+			if idx >= n {
+				idx = cycle - idx
+			}
+		}
+	case ArpRandom:
+		idx = rand.Intn(n)
+	}
+	a.step++
+	return pattern[idx], true
+}
+
+// stepLength returns the current step period, from the clock when synced
+func (a *Arpeggiator) stepLength() time.Duration {
+	if a.Synced() {
+		return time.Duration(divisionTicks(engine.Divisions[a.Div()])) * a.clock.TickDuration()
+	}
+	rate := a.Rate.Get()
+	if rate <= 0 {
+		rate = ArpRate
+	}
+	return time.Duration(float64(time.Second) / rate)
+}
+
+// run plays pattern steps until stop is closed
+func (a *Arpeggiator) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	next := time.Now()
+	var tick uint64
+	synced := false
+	for {
+		// When synced, wait for the next division boundary on the clock
+		if a.Synced() {
+			every := divisionTicks(engine.Divisions[a.Div()])
+			if !synced {
+				tick = a.clock.NextBoundary(every)
+				synced = true
+			}
+			if !waitUntil(a.clock.TimeAt(tick), stop) {
+				return
+			}
+			tick += every
+		} else {
+			synced = false
+			if !waitUntil(next, stop) {
+				return
+			}
+		}
+
+		period := a.stepLength()
+		next = time.Now().Add(period)
+		gate := time.Duration(float64(period) * a.Gate.Get())
+
+		h, ok := a.nextNote()
+		if ok {
+			a.noteOn(h.note, h.velocity)
+		}
+
+		// Hold the note for the gate length; the rest of the step is spent waiting above
+		stopped := !waitUntil(time.Now().Add(gate), stop)
+		if ok {
+			a.noteOff(h.note)
+		}
+		if stopped {
+			return
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package synth
+
+import "testing"
+
+// TestSetArpTakesSoundingNotes checks that notes sounding when the arpeggiator starts
+// become its held keys, so releasing them stops the pattern instead of sticking
+func TestSetArpTakesSoundingNotes(t *testing.T) {
+	s := NewSynth()
+	s.SetDrone(false)
+	s.NoteOn(60, 100)
+	s.NoteOn(64, 90)
+	s.SetArp(true)
+	defer s.SetArp(false)
+
+	s.Arp.mu.Lock()
+	held := len(s.Arp.held)
+	s.Arp.mu.Unlock()
+	if held != 2 {
+		t.Fatalf("arpeggiator holds %d notes after starting, want 2", held)
+	}
+
+	s.NoteOff(60)
+	s.NoteOff(64)
+	s.Arp.mu.Lock()
+	held = len(s.Arp.held)
+	s.Arp.mu.Unlock()
+	if held != 0 {
+		t.Errorf("arpeggiator holds %d notes after their release, want 0", held)
+	}
+	if notes, _ := s.routed.notes(); len(notes) != 0 {
+		t.Errorf("notes %v still routed after their release", notes)
+	}
+}
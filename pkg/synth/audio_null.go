@@ -0,0 +1,48 @@
+//go:build noportaudio
+
+package synth
+
+import (
+	"time"
+
+	"gosynth/pkg/engine"
+)
+
+// nullStream stands in for an audio device on builds without PortAudio, rendering
+// blocks at the real-time rate and discarding them so meters, the scope and timers
+// behave as they would with a device
+type nullStream struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// openAudio starts rendering blocks of bufferSize frames from render at the sample rate;
+// there is no device to choose and no input to record
+func openAudio(render func(in, out []float32), device, input string, bufferSize int) (audioStream, error) {
+	n := &nullStream{stop: make(chan struct{}), done: make(chan struct{})}
+	go n.run(render, bufferSize)
+	return n, nil
+}
+
+// run renders a block per block period until stopped
+func (n *nullStream) run(render func(in, out []float32), bufferSize int) {
+	defer close(n.done)
+	out := make([]float32, bufferSize*engine.OutputChannels)
+	ticker := time.NewTicker(time.Second * time.Duration(bufferSize) / engine.SampleRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			render(nil, out)
+		}
+	}
+}
+
+// Close stops rendering
+func (n *nullStream) Close() error {
+	close(n.stop)
+	<-n.done
+	return nil
+}
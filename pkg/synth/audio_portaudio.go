@@ -0,0 +1,117 @@
+//go:build !noportaudio
+
+package synth
+
+import (
+	"fmt"
+	"strings"
+
+	"gosynth/pkg/engine"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// portaudioStream plays the engine through a PortAudio output device, recording from an
+// input device as well when one is open
+type portaudioStream struct {
+	stream *portaudio.Stream
+}
+
+// openAudio initializes PortAudio and starts a stream on the first output device whose
+// name contains device, or the default one, that pulls blocks of bufferSize frames from render.
+// When input names an input device, the first whose name contains it or "default" for the
+// system default, the stream records from it too and hands render each recorded block.
+func openAudio(render func(in, out []float32), device, input string, bufferSize int) (audioStream, error) {
+	if err := portaudio.Initialize(); err != nil {
+		return nil, err
+	}
+
+	output, err := findOutputDevice(device)
+	if err != nil {
+		portaudio.Terminate()
+		return nil, err
+	}
+
+	// Set up high-priority audio stream with optimal buffer size
+	streamParams := portaudio.StreamParameters{
+		Output: portaudio.StreamDeviceParameters{
+			Device:   output,
+			Channels: engine.OutputChannels,
+			Latency:  output.DefaultHighOutputLatency,
+		},
+		SampleRate:      engine.SampleRate,
+		FramesPerBuffer: bufferSize,
+	}
+	var callback any = func(out []float32) { render(nil, out) }
+	if input != "" {
+		in, err := findInputDevice(input)
+		if err != nil {
+			portaudio.Terminate()
+			return nil, err
+		}
+		// A mono microphone records one channel, a line input two
+		streamParams.Input = portaudio.StreamDeviceParameters{
+			Device:   in,
+			Channels: min(engine.OutputChannels, in.MaxInputChannels),
+			Latency:  in.DefaultLowInputLatency,
+		}
+		callback = render
+	}
+
+	// Open audio stream with optimized parameters
+	stream, err := portaudio.OpenStream(streamParams, callback)
+	if err != nil {
+		portaudio.Terminate()
+		return nil, err
+	}
+	if err := stream.Start(); err != nil {
+		stream.Close()
+		portaudio.Terminate()
+		return nil, err
+	}
+	return &portaudioStream{stream: stream}, nil
+}
+
+// findOutputDevice returns the first output device whose name contains name, or the
+// default output device when name is empty
+func findOutputDevice(name string) (*portaudio.DeviceInfo, error) {
+	if name == "" {
+		return portaudio.DefaultOutputDevice()
+	}
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range devices {
+		if d.MaxOutputChannels >= engine.OutputChannels && strings.Contains(d.Name, name) {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("no audio output device matching %q", name)
+}
+
+// findInputDevice returns the first input device whose name contains name, or the
+// default input device for "default"
+func findInputDevice(name string) (*portaudio.DeviceInfo, error) {
+	if name == DefaultAudioInput {
+		return portaudio.DefaultInputDevice()
+	}
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range devices {
+		if d.MaxInputChannels > 0 && strings.Contains(d.Name, name) {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("no audio input device matching %q", name)
+}
+
+// Close stops the stream and shuts PortAudio down
+func (p *portaudioStream) Close() error {
+	if err := p.stream.Close(); err != nil {
+		return err
+	}
+	return portaudio.Terminate()
+}
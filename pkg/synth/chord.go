@@ -0,0 +1,348 @@
+package synth
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MaxChordNotes = 8                      // Most notes in a chord shape
+	MaxStrum      = 200 * time.Millisecond // Longest strum between one note of a chord and the next
+)
+
+// StrumDirection is the order a strummed chord's notes start in
+type StrumDirection int
+
+const (
+	StrumUp        StrumDirection = iota // Lowest note first
+	StrumDown                            // Highest note first
+	StrumAlternate                       // Up and down on alternate keys, like a guitarist's hand
+	strumDirectionCount
+)
+
+func (d StrumDirection) String() string {
+	switch d {
+	case StrumUp:
+		return "up"
+	case StrumDown:
+		return "down"
+	case StrumAlternate:
+		return "alternate"
+	}
+	return "unknown"
+}
+
+// Next returns the following direction, wrapping around
+func (d StrumDirection) Next(dir int) StrumDirection {
+	return StrumDirection((int(d) + dir + int(strumDirectionCount)) % int(strumDirectionCount))
+}
+
+// ChordShape is a named chord of semitones above the played note
+type ChordShape struct {
+	Name      string
+	Intervals []int
+}
+
+// ChordShapes lists the built-in shapes, in the order the UI steps through them
+var ChordShapes = []ChordShape{
+	{Name: "major", Intervals: []int{0, 4, 7}},
+	{Name: "minor", Intervals: []int{0, 3, 7}},
+	{Name: "sus4", Intervals: []int{0, 5, 7}},
+	{Name: "maj7", Intervals: []int{0, 4, 7, 11}},
+	{Name: "min7", Intervals: []int{0, 3, 7, 10}},
+	{Name: "dom7", Intervals: []int{0, 4, 7, 10}},
+	{Name: "power", Intervals: []int{0, 7, 12}},
+	{Name: "octaves", Intervals: []int{-12, 0, 12}},
+}
+
+// ChordMemory plays a chord shape from each key: every note played becomes the shape's
+// notes above it, which go through the latch and arpeggiator as if played together.
+// Each note of the shape sounds with its own chance, and a strum staggers their starts
+// up or down the chord. It starts off, on a major triad played whole and together.
+type ChordMemory struct {
+	mu        sync.Mutex
+	enabled   bool
+	intervals []int
+	chance    [MaxChordNotes]int // Percent chance each note of the shape sounds
+	strum     time.Duration      // Time from one note of the chord starting to the next
+	direction StrumDirection
+	upNext    bool              // Strum up on the next key, alternating
+	playing   map[uint8][]uint8 // Notes each held key has started, for its note-off
+	held      map[uint8]int     // Keys held on each played note
+	presses   map[uint8]int     // Last press or release of each key, so a late strum note knows its key has moved on
+	count     int               // Presses and releases so far, numbering them
+
+	// Held while a chord's notes are started or released and routed, so a strummed note
+	// can't start after its key's release has gone by
+	order sync.Mutex
+}
+
+// NewChordMemory creates chord memory that is off
+func NewChordMemory() *ChordMemory {
+	c := &ChordMemory{
+		intervals: ChordShapes[0].Intervals,
+		upNext:    true,
+		playing:   make(map[uint8][]uint8),
+		held:      make(map[uint8]int),
+		presses:   make(map[uint8]int),
+	}
+	for i := range c.chance {
+		c.chance[i] = 100
+	}
+	return c
+}
+
+// Chances returns the percent chance each note of the shape sounds, lowest note first
+func (c *ChordMemory) Chances() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.chance[:len(c.intervals)])
+}
+
+// SetChances sets the percent chance of each note of the shape sounding, lowest note
+// first, from 0 to 100; notes past the list always sound
+func (c *ChordMemory) SetChances(chances []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.chance {
+		c.chance[i] = 100
+		if i < len(chances) {
+			c.chance[i] = max(0, min(chances[i], 100))
+		}
+	}
+}
+
+// Strum returns the time from one note of a chord starting to the next, and the order
+// they start in
+func (c *ChordMemory) Strum() (time.Duration, StrumDirection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.strum, c.direction
+}
+
+// SetStrum sets the time from one note of a chord starting to the next, up to
+// MaxStrum, and the order they start in; 0 starts them together
+func (c *ChordMemory) SetStrum(strum time.Duration, direction StrumDirection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.strum = max(0, min(strum, MaxStrum))
+	c.direction = direction.Next(0)
+}
+
+// Enabled reports whether keys play the chord shape
+func (c *ChordMemory) Enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enabled
+}
+
+// SetEnabled switches between playing the chord shape and single notes; held keys
+// release what they started either way
+func (c *ChordMemory) SetEnabled(on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enabled = on
+}
+
+// Intervals returns the semitones of the chord shape above the played note
+func (c *ChordMemory) Intervals() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.intervals)
+}
+
+// SetIntervals sets the chord shape, sorted with repeats removed and limited to
+// MaxChordNotes notes within four octaves of the played note; an empty shape plays the
+// note alone
+func (c *ChordMemory) SetIntervals(intervals []int) {
+	shape := make([]int, 0, len(intervals)+1)
+	for _, i := range intervals {
+		shape = append(shape, max(-48, min(48, i)))
+	}
+	slices.Sort(shape)
+	shape = slices.Compact(shape)
+	if len(shape) == 0 {
+		shape = append(shape, 0)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.intervals = shape[:min(len(shape), MaxChordNotes)]
+}
+
+// Name returns the name of the built-in shape the chord matches, or "user"
+func (c *ChordMemory) Name() string {
+	if i := c.shapeIndex(); i >= 0 {
+		return ChordShapes[i].Name
+	}
+	return "user"
+}
+
+// shapeIndex returns the index into ChordShapes of the current shape, or -1
+func (c *ChordMemory) shapeIndex() int {
+	intervals := c.Intervals()
+	return slices.IndexFunc(ChordShapes, func(s ChordShape) bool { return slices.Equal(s.Intervals, intervals) })
+}
+
+// StepShape moves to the next or previous built-in shape; a user shape moves to the first
+// or last
+func (c *ChordMemory) StepShape(dir int) {
+	i := c.shapeIndex()
+	switch {
+	case i >= 0:
+		i = (i + dir + len(ChordShapes)) % len(ChordShapes)
+	case dir > 0:
+		i = 0
+	default:
+		i = len(ChordShapes) - 1
+	}
+	c.SetIntervals(ChordShapes[i].Intervals)
+}
+
+// chordNote is a note a pressed key plays, after a delay when strummed
+type chordNote struct {
+	note  uint8
+	delay time.Duration
+}
+
+// noteOn returns the notes a pressed key plays, in the order they start, and the press
+// they belong to: the notes of the chord shape above it that win their chance when
+// enabled, or the lowest of the shape if none does, else the note alone. None is
+// started until start is called for it.
+func (c *ChordMemory) noteOn(note uint8) ([]chordNote, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count++
+	c.presses[note] = c.count
+	c.playing[note] = nil
+	if !c.enabled {
+		return []chordNote{{note: note}}, c.presses[note]
+	}
+	var notes []chordNote
+	first := -1
+	for i, interval := range c.intervals {
+		n := int(note) + interval
+		if n < 0 || n > 127 {
+			continue
+		}
+		if first < 0 {
+			first = n
+		}
+		if c.chance[i] >= 100 || rand.Intn(100) < c.chance[i] {
+			notes = append(notes, chordNote{note: uint8(n)})
+		}
+	}
+	if len(notes) == 0 && first >= 0 {
+		notes = append(notes, chordNote{note: uint8(first)})
+	}
+	up := c.direction == StrumUp || c.direction == StrumAlternate && c.upNext
+	c.upNext = !c.upNext
+	if !up {
+		slices.Reverse(notes)
+	}
+	for i := range notes {
+		notes[i].delay = time.Duration(i) * c.strum
+	}
+	return notes, c.presses[note]
+}
+
+// start records a note of a key's chord as sounding, unless the key has been released or
+// pressed again since press, reporting whether it should play
+func (c *ChordMemory) start(key, note uint8, press int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.presses[key] != press {
+		return false
+	}
+	c.held[note]++
+	c.playing[key] = append(c.playing[key], note)
+	return true
+}
+
+// noteOff returns the notes a released key stops, leaving those another key still holds;
+// strummed notes of the key yet to start never will
+func (c *ChordMemory) noteOff(note uint8) []uint8 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count++
+	c.presses[note] = c.count
+	notes, ok := c.playing[note]
+	if !ok {
+		notes = []uint8{note}
+	}
+	delete(c.playing, note)
+	var released []uint8
+	for _, n := range notes {
+		if c.held[n]--; c.held[n] > 0 {
+			continue
+		}
+		delete(c.held, n)
+		released = append(released, n)
+	}
+	return released
+}
+
+// clear forgets the keys held and the notes they play
+func (c *ChordMemory) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.playing)
+	clear(c.held)
+	clear(c.presses)
+}
+
+// FormatIntervals writes a chord shape as semitones separated by spaces, such as "0 4 7"
+func FormatIntervals(intervals []int) string {
+	parts := make([]string, len(intervals))
+	for i, n := range intervals {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseChances reads the percent chance of each note of a chord sounding, lowest first,
+// separated by spaces or commas with an optional %, such as "100 100 50 25"
+func ParseChances(text string) ([]int, error) {
+	var chances []int
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' }) {
+		n, err := strconv.Atoi(strings.TrimSuffix(field, "%"))
+		if err != nil || n < 0 || n > 100 {
+			return nil, fmt.Errorf("bad chance %q, want 0 to 100", field)
+		}
+		chances = append(chances, n)
+	}
+	if len(chances) == 0 {
+		return nil, fmt.Errorf("no chances in %q", text)
+	}
+	if len(chances) > MaxChordNotes {
+		return nil, fmt.Errorf("at most %d notes in a chord", MaxChordNotes)
+	}
+	return chances, nil
+}
+
+// ParseIntervals reads a chord shape as semitones above the played note separated by
+// spaces or commas, such as "0 4 7 11"
+func ParseIntervals(text string) ([]int, error) {
+	var intervals []int
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("bad interval %q", field)
+		}
+		if n < -48 || n > 48 {
+			return nil, fmt.Errorf("interval %d is more than four octaves", n)
+		}
+		intervals = append(intervals, n)
+	}
+	if len(intervals) == 0 {
+		return nil, fmt.Errorf("no intervals in %q", text)
+	}
+	if len(intervals) > MaxChordNotes {
+		return nil, fmt.Errorf("at most %d notes in a chord", MaxChordNotes)
+	}
+	return intervals, nil
+}
@@ -0,0 +1,60 @@
+package synth
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestChordChanceAndStrum checks that notes with no chance are left out, that a strum
+// staggers the rest in its direction, and that alternating strums change direction
+func TestChordChanceAndStrum(t *testing.T) {
+	c := NewChordMemory()
+	c.SetEnabled(true)
+	c.SetIntervals([]int{0, 4, 7, 12})
+	c.SetChances([]int{100, 0, 100})
+	c.SetStrum(10*time.Millisecond, StrumAlternate)
+
+	for i, want := range [][]chordNote{
+		{{60, 0}, {67, 10 * time.Millisecond}, {72, 20 * time.Millisecond}},
+		{{72, 0}, {67, 10 * time.Millisecond}, {60, 20 * time.Millisecond}},
+	} {
+		notes, _ := c.noteOn(60)
+		if !slices.Equal(notes, want) {
+			t.Errorf("press %d plays %v, want %v", i+1, notes, want)
+		}
+		c.noteOff(60)
+	}
+
+	c.SetChances([]int{0, 0, 0, 0})
+	if notes, _ := c.noteOn(48); !slices.Equal(notes, []chordNote{{48, 0}}) {
+		t.Errorf("chord with no chances plays %v, want its lowest note", notes)
+	}
+}
+
+// TestStrumReleasedEarly checks that a key released before its strum has finished
+// leaves none of its chord sounding
+func TestStrumReleasedEarly(t *testing.T) {
+	s := NewSynth()
+	s.SetDrone(false)
+	s.Chord.SetEnabled(true)
+	s.Chord.SetStrum(20*time.Millisecond, StrumUp)
+
+	s.NoteOn(60, 100)
+	if notes, _ := s.routed.notes(); !slices.Equal(notes, []uint8{60}) {
+		t.Errorf("notes %v sounding as the strum starts, want the lowest alone", notes)
+	}
+	time.Sleep(30 * time.Millisecond)
+	s.NoteOff(60)
+	time.Sleep(50 * time.Millisecond)
+	if notes, _ := s.routed.notes(); len(notes) != 0 {
+		t.Errorf("notes %v still sounding after the key's release", notes)
+	}
+
+	s.NoteOn(60, 100)
+	time.Sleep(60 * time.Millisecond)
+	if notes, _ := s.routed.notes(); !slices.Equal(notes, []uint8{60, 64, 67}) {
+		t.Errorf("notes %v sounding after the strum, want the whole chord", notes)
+	}
+	s.NoteOff(60)
+}
@@ -0,0 +1,240 @@
+package synth
+
+import (
+	"sync"
+	"time"
+
+	"gosynth/pkg/engine"
+)
+
+const (
+	ClockPPQN  = 96            // Clock ticks per quarter note
+	DefaultBPM = 120.0         // Default clock tempo
+	NoSwing    = 50.0          // Swing of a straight rhythm, each sixteenth half of its eighth
+	MaxSwing   = 75.0          // Most swing, the off-beat sixteenth a quarter of its eighth
+	swingTicks = ClockPPQN / 2 // Ticks of the eighth note swing shares between its sixteenths
+)
+
+// Clock is the shared musical tempo that sequencer, arpeggiator and effects sync to
+type Clock struct {
+	BPM   engine.SmoothValue
+	Swing engine.SmoothValue // Percentage of each eighth note its first sixteenth takes, from NoSwing to MaxSwing
+
+	mu        sync.Mutex
+	tick      uint64    // Ticks elapsed since the clock started
+	tickTime  time.Time // Wall time of the last tick
+	listeners []func(tick uint64)
+	running   bool
+	external  bool      // Ticks come from incoming MIDI clock instead of the generator
+	lastPulse time.Time // Wall time of the last external clock pulse
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewClock creates a stopped clock at the default tempo
+func NewClock() *Clock {
+	c := &Clock{
+		tickTime: time.Now(),
+	}
+	c.BPM.Set(DefaultBPM)
+	c.Swing.Set(NoSwing)
+	return c
+}
+
+// OnTick registers a callback run on every clock tick, from the clock goroutine
+func (c *Clock) OnTick(fn func(tick uint64)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+// Start runs the internal tick generator
+func (c *Clock) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		return
+	}
+	c.running = true
+	c.tickTime = time.Now()
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go c.run(c.stop, c.done)
+}
+
+// Stop halts the internal tick generator
+func (c *Clock) Stop() {
+	c.mu.Lock()
+	if !c.running {
+		c.mu.Unlock()
+		return
+	}
+	c.running = false
+	stop, done := c.stop, c.done
+	c.mu.Unlock()
+
+	close(stop)
+	<-done
+}
+
+// Tick advances the clock by one tick and notifies listeners
+func (c *Clock) Tick() {
+	c.mu.Lock()
+	c.tick++
+	c.tickTime = time.Now()
+	tick := c.tick
+	listeners := c.listeners
+	c.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(tick)
+	}
+}
+
+// Reset moves the clock back to the first tick
+func (c *Clock) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tick = 0
+	c.tickTime = time.Now()
+}
+
+// External reports whether the clock follows incoming pulses
+func (c *Clock) External() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.external
+}
+
+// SetExternal switches between the internal generator and external pulses
+func (c *Clock) SetExternal(external bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.external = external
+	c.lastPulse = time.Time{}
+}
+
+// Pulse advances the clock by one external pulse of ClockPPQN/ppqn ticks,
+// following the tempo implied by the pulse spacing
+func (c *Clock) Pulse(ppqn int) {
+	now := time.Now()
+	c.mu.Lock()
+	if !c.external {
+		c.mu.Unlock()
+		return
+	}
+	last := c.lastPulse
+	c.lastPulse = now
+	c.mu.Unlock()
+
+	// Ease towards the measured tempo so pulse jitter does not wobble the tempo
+	if interval := now.Sub(last); !last.IsZero() && interval > 0 && interval < time.Second {
+		measured := float64(time.Minute) / float64(interval) / float64(ppqn)
+		bpm := c.BPM.Get()
+		c.BPM.Set(bpm + (measured-bpm)*0.1)
+	}
+
+	for i := 0; i < ClockPPQN/ppqn; i++ {
+		c.Tick()
+	}
+}
+
+// Ticks returns the number of ticks elapsed
+func (c *Clock) Ticks() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tick
+}
+
+// TickDuration returns the length of one tick at the current tempo
+func (c *Clock) TickDuration() time.Duration {
+	bpm := c.BPM.Get()
+	if bpm <= 0 {
+		bpm = DefaultBPM
+	}
+	return time.Duration(float64(time.Minute) / bpm / ClockPPQN)
+}
+
+// BeatDuration returns the length of one beat at the current tempo, so the clock can
+// drive the engine's tempo-synced effects
+func (c *Clock) BeatDuration() time.Duration {
+	return c.TickDuration() * ClockPPQN
+}
+
+// divisionTicks returns the length of a note division in clock ticks
+func divisionTicks(d engine.Division) uint64 {
+	return uint64(d.Beats * ClockPPQN)
+}
+
+// TimeAt projects the wall time at which a tick will occur at the current tempo, moved
+// by the swing
+func (c *Clock) TimeAt(tick uint64) time.Time {
+	c.mu.Lock()
+	last, lastTime := c.tick, c.tickTime
+	c.mu.Unlock()
+	return lastTime.Add(time.Duration((c.Swung(tick) - float64(last)) * float64(c.TickDuration())))
+}
+
+// Swung returns where a tick falls with the swing applied, in ticks. Each eighth note is
+// stretched so its second sixteenth starts later, and everything within it in proportion,
+// so the sequencer and the arpeggiator at any division swing together; eighth notes
+// themselves stay on the beat.
+func (c *Clock) Swung(tick uint64) float64 {
+	swing := c.Swing.Get() / 100
+	pos := float64(tick % swingTicks)
+	start := float64(tick - tick%swingTicks)
+	half := swingTicks / 2.0
+	if pos < half {
+		return start + pos*2*swing
+	}
+	return start + swingTicks*swing + (pos-half)*2*(1-swing)
+}
+
+// NextBoundary returns the next tick that is a whole multiple of every
+func (c *Clock) NextBoundary(every uint64) uint64 {
+	if every == 0 {
+		every = 1
+	}
+	tick := c.Ticks()
+	return (tick/every + 1) * every
+}
+
+// BeatPhase returns the position in beats, including the fraction since the last tick
+func (c *Clock) BeatPhase() float64 {
+	c.mu.Lock()
+	tick, tickTime := c.tick, c.tickTime
+	c.mu.Unlock()
+	frac := float64(time.Since(tickTime)) / float64(c.TickDuration())
+	if frac > 1 {
+		frac = 1
+	}
+	return (float64(tick) + frac) / ClockPPQN
+}
+
+// run generates ticks at the current tempo until stop is closed
+func (c *Clock) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	next := time.Now()
+	for {
+		next = next.Add(c.TickDuration())
+		select {
+		case <-stop:
+			return
+		case <-time.After(time.Until(next)):
+		}
+		if !c.External() {
+			c.Tick()
+		}
+	}
+}
+
+// waitUntil sleeps until the given time, returning false if stop was closed first
+func waitUntil(t time.Time, stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(time.Until(t)):
+		return true
+	}
+}
@@ -0,0 +1,291 @@
+package synth
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gosynth/pkg/engine"
+)
+
+const (
+	DefaultVirtualIn  = "gosynth" // Virtual MIDI input created unless the config names another
+	DefaultAudioInput = "default" // Audio input setting that records from the system default input
+)
+
+// Config holds the startup defaults read from the config file. Command-line flags
+// override each of them.
+type Config struct {
+	AudioDevice  string            // Output device, the first whose name contains it; the system default when empty
+	AudioInput   string            // Input device run through the effects, the first whose name contains it or DefaultAudioInput; none when empty
+	SampleRate   int               // Output sample rate; the engine only runs at engine.SampleRate
+	BufferSize   int               // Frames per audio buffer, up to engine.AudioBufferSize
+	MIDIIn       string            // Input port played from, the first whose name contains it; the first port when empty
+	MIDIOut      string            // Output port for split notes and clock, matched the same way
+	VirtualIn    string            // Name of the virtual MIDI input created for other programs; none when empty
+	Preset       string            // Preset loaded at startup
+	Theme        string            // Colour theme of the UI
+	Keys         map[string]string // Key bound to each UI action, by action name
+	OSC          string            // UDP address the OSC server listens on, such as ":9000"; off when empty
+	HTTP         string            // TCP address the JSON API listens on, such as ":8080" (loopback only); off when empty
+	Reference    float64           // Frequency of A4 in Hz that every note is tuned from
+	FirstProgram int               // MIDI program number that selects the first preset
+	BankSize     int               // Presets per bank selected with bank select
+}
+
+// DefaultConfig returns the defaults used without a config file
+func DefaultConfig() Config {
+	return Config{
+		SampleRate: engine.SampleRate,
+		BufferSize: engine.AudioBufferSize,
+		VirtualIn:  DefaultVirtualIn,
+		Keys:       make(map[string]string),
+		Reference:  engine.ReferencePitch,
+		BankSize:   DefaultBankSize,
+	}
+}
+
+// ConfigPath returns the config file
+func ConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gosynth", "config.toml"), nil
+}
+
+// LoadConfig reads the config file over the defaults; a missing file gives the defaults
+func LoadConfig() (Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return DefaultConfig(), err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DefaultConfig(), nil
+	}
+	if err != nil {
+		return DefaultConfig(), err
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return DefaultConfig(), fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ParseConfig reads config TOML: top-level keys with string or number values, and a
+// [keys] table binding UI actions to keys, such as undo = "u"
+func ParseConfig(data []byte) (Config, error) {
+	cfg := DefaultConfig()
+	table := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(stripComment(scanner.Text()))
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			table = strings.TrimSpace(text[1 : len(text)-1])
+			if table != "keys" {
+				return cfg, fmt.Errorf("line %d: unknown table [%s]", line, table)
+			}
+			continue
+		}
+		key, raw, ok := strings.Cut(text, "=")
+		if !ok {
+			return cfg, fmt.Errorf("line %d: expected key = value", line)
+		}
+		key, raw = strings.TrimSpace(key), strings.TrimSpace(raw)
+		if err := cfg.set(table, key, raw); err != nil {
+			return cfg, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// set applies one key of a table from its raw TOML value
+func (c *Config) set(table, key, raw string) error {
+	if table == "keys" {
+		value, err := tomlString(raw)
+		if err != nil {
+			return err
+		}
+		c.Keys[key] = value
+		return nil
+	}
+
+	texts := map[string]*string{
+		"audio_device": &c.AudioDevice,
+		"audio_input":  &c.AudioInput,
+		"midi_in":      &c.MIDIIn,
+		"midi_out":     &c.MIDIOut,
+		"virtual_in":   &c.VirtualIn,
+		"preset":       &c.Preset,
+		"theme":        &c.Theme,
+		"osc":          &c.OSC,
+		"http":         &c.HTTP,
+	}
+	numbers := map[string]*int{
+		"sample_rate":   &c.SampleRate,
+		"buffer_size":   &c.BufferSize,
+		"first_program": &c.FirstProgram,
+		"bank_size":     &c.BankSize,
+	}
+	if target, ok := texts[key]; ok {
+		value, err := tomlString(raw)
+		*target = value
+		return err
+	}
+	if target, ok := numbers[key]; ok {
+		value, err := strconv.Atoi(strings.ReplaceAll(raw, "_", ""))
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number", key)
+		}
+		*target = value
+		return nil
+	}
+	if key == "reference_pitch" {
+		value, err := strconv.ParseFloat(strings.ReplaceAll(raw, "_", ""), 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", key)
+		}
+		c.Reference = value
+		return nil
+	}
+	return fmt.Errorf("unknown setting %q", key)
+}
+
+// Validate checks the values the synth can't adapt to
+func (c Config) Validate() error {
+	if c.SampleRate != engine.SampleRate {
+		return fmt.Errorf("sample_rate %d isn't supported; the engine runs at %d Hz", c.SampleRate, engine.SampleRate)
+	}
+	if c.BufferSize < 64 || c.BufferSize > engine.AudioBufferSize {
+		return fmt.Errorf("buffer_size must be from 64 to %d frames", engine.AudioBufferSize)
+	}
+	if !(c.Reference >= engine.MinReferencePitch && c.Reference <= engine.MaxReferencePitch) { // Also refuses NaN
+		return fmt.Errorf("reference_pitch must be from %.0f to %.0f Hz", engine.MinReferencePitch, engine.MaxReferencePitch)
+	}
+	if c.FirstProgram < 0 || c.FirstProgram > 127 {
+		return fmt.Errorf("first_program must be from 0 to 127")
+	}
+	if c.BankSize < 1 || c.BankSize > 128 {
+		return fmt.Errorf("bank_size must be from 1 to 128")
+	}
+	if c.OSC != "" {
+		if _, err := net.ResolveUDPAddr("udp", c.OSC); err != nil {
+			return fmt.Errorf("osc must be a UDP address such as \":9000\": %w", err)
+		}
+	}
+	if c.HTTP != "" {
+		if _, err := net.ResolveTCPAddr("tcp", c.HTTP); err != nil {
+			return fmt.Errorf("http must be a TCP address such as \":8080\": %w", err)
+		}
+	}
+	return nil
+}
+
+// SaveReference retunes every note to a frequency of A4 and saves it as the
+// reference_pitch of the config file, which is created if needed
+func (s *Synth) SaveReference(hz float64) error {
+	s.SetReference(hz)
+	s.Config.Reference = s.Tuning.Reference()
+	path, err := ConfigPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	value := strconv.FormatFloat(s.Config.Reference, 'f', -1, 64)
+	if err := os.WriteFile(path, setConfigValue(data, "reference_pitch", value), 0o644); err != nil {
+		return err
+	}
+
+	// The watch already has this edit, so it isn't reported as one made by hand
+	if s.configWatch != nil {
+		s.configWatch.file.Reference = s.Config.Reference
+		if info, err := os.Stat(path); err == nil {
+			s.configWatch.modTime = info.ModTime()
+		}
+	}
+	return nil
+}
+
+// setConfigValue sets a top-level key of config TOML, replacing its line and keeping any
+// comment, or adding it ahead of the first table
+func setConfigValue(data []byte, key, raw string) []byte {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	entry := key + " = " + raw
+	insert := len(lines)
+	for i, line := range lines {
+		text := strings.TrimSpace(stripComment(line))
+		if strings.HasPrefix(text, "[") {
+			insert = i
+			break
+		}
+		if name, _, ok := strings.Cut(text, "="); ok && strings.TrimSpace(name) == key {
+			comment := strings.TrimRight(line[len(stripComment(line)):], "\n")
+			if comment != "" {
+				entry += "  " + comment
+			}
+			lines[i] = entry + "\n"
+			return []byte(strings.Join(lines, ""))
+		}
+	}
+	if insert == len(lines) && insert > 0 && !strings.HasSuffix(lines[insert-1], "\n") {
+		lines[insert-1] += "\n"
+	}
+	lines = append(lines[:insert], append([]string{entry + "\n"}, lines[insert:]...)...)
+	return []byte(strings.Join(lines, ""))
+}
+
+// stripComment removes a # comment outside a quoted string. Backslashes escape the
+// next character of a basic string only, as literal strings have no escapes.
+func stripComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case r == quote:
+			quote = 0
+		case r == '#' && quote == 0:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlString reads a basic or literal TOML string
+func tomlString(raw string) (string, error) {
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	value, err := strconv.Unquote(raw)
+	if err != nil || !strings.HasPrefix(raw, `"`) {
+		return "", fmt.Errorf("expected a quoted string, got %s", raw)
+	}
+	return value, nil
+}
@@ -0,0 +1,93 @@
+package synth
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseConfig checks quoting, comments, numbers and the [keys] table
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`# gosynth settings
+audio_device = "USB Audio"   # trailing comment
+midi_in = 'Keystation # 49'   # a # inside a literal string
+midi_out = "Port \"A\" # 2"
+preset = "C:\\presets\\"  # escaped backslash before the closing quote
+theme = "mono"
+buffer_size = 1_024
+reference_pitch = 432.5
+   virtual_in = ""
+
+[keys]
+undo = "u"
+redo = 'U'
+[ keys ]
+panic = "esc"   # a table may open twice
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ name, got, want string }{
+		{"audio_device", cfg.AudioDevice, "USB Audio"},
+		{"midi_in", cfg.MIDIIn, "Keystation # 49"},
+		{"midi_out", cfg.MIDIOut, `Port "A" # 2`},
+		{"preset", cfg.Preset, `C:\presets\`},
+		{"theme", cfg.Theme, "mono"},
+		{"virtual_in", cfg.VirtualIn, ""},
+		{"undo", cfg.Keys["undo"], "u"},
+		{"redo", cfg.Keys["redo"], "U"},
+		{"panic", cfg.Keys["panic"], "esc"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.name, tc.got, tc.want)
+		}
+	}
+	if cfg.BufferSize != 1024 || cfg.Reference != 432.5 {
+		t.Errorf("buffer_size %d and reference_pitch %g, want 1024 and 432.5", cfg.BufferSize, cfg.Reference)
+	}
+	if def := DefaultConfig(); cfg.SampleRate != def.SampleRate || cfg.BankSize != def.BankSize {
+		t.Errorf("unset settings changed from the defaults: %+v", cfg)
+	}
+}
+
+// TestParseConfigErrors checks that malformed lines and values are refused with their line
+func TestParseConfigErrors(t *testing.T) {
+	for _, tc := range []struct{ name, text, msg string }{
+		{"no equals sign", "theme \"mono\"", "line 1: expected key = value"},
+		{"unknown setting", "\n\ncolour = \"red\"", "line 3: unknown setting"},
+		{"unknown table", "[audio]\n", "line 1: unknown table"},
+		{"unclosed table", "[keys\n", "line 1: expected key = value"},
+		{"unquoted string", "midi_in = Keystation", "line 1: expected a quoted string"},
+		{"unclosed string", `midi_in = "Keystation`, "expected a quoted string"},
+		{"unclosed literal string", "midi_in = 'Keystation", "expected a quoted string"},
+		{"text after a string", `midi_in = "a" "b"`, "expected a quoted string"},
+		{"bad escape", `midi_in = "\q"`, "expected a quoted string"},
+		{"comment in an unclosed string", `midi_in = "a # b`, "expected a quoted string"},
+		{"quoted number", `buffer_size = "512"`, "buffer_size must be a whole number"},
+		{"fractional number", "bank_size = 1.5", "bank_size must be a whole number"},
+		{"bad pitch", "reference_pitch = A440", "reference_pitch must be a number"},
+		{"pitch that isn't a number", "reference_pitch = nan", "reference_pitch must be from"},
+		{"unquoted key binding", "[keys]\nundo = u", "line 2: expected a quoted string"},
+		{"out of range", "buffer_size = 8", "buffer_size must be from"},
+		{"bad address", `osc = "nowhere"`, "osc must be a UDP address"},
+	} {
+		_, err := ParseConfig([]byte(tc.text))
+		if err == nil || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%s: error %v, want one containing %q", tc.name, err, tc.msg)
+		}
+	}
+}
+
+// TestSetConfigValue checks that a setting is replaced in place with its comment kept,
+// or added ahead of the first table
+func TestSetConfigValue(t *testing.T) {
+	for _, tc := range []struct{ name, data, want string }{
+		{"empty file", "", "reference_pitch = 432\n"},
+		{"replaced", "theme = \"mono\"\nreference_pitch = 440  # concert\n", "theme = \"mono\"\nreference_pitch = 432  # concert\n"},
+		{"before a table", "theme = \"mono\"\n[keys]\nreference_pitch = \"x\"\n", "theme = \"mono\"\nreference_pitch = 432\n[keys]\nreference_pitch = \"x\"\n"},
+		{"no final newline", "theme = \"mono\"", "theme = \"mono\"\nreference_pitch = 432\n"},
+	} {
+		if got := string(setConfigValue([]byte(tc.data), "reference_pitch", "432")); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package synth
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"time"
+)
+
+const ConfigCheckInterval = time.Second // How often the config file is checked for edits
+
+// ConfigReload is what rereading an edited config file changed
+type ConfigReload struct {
+	Applied []string // Settings in effect at once, by their name in the file
+	Restart []string // Changed settings that only take effect when the synth is restarted
+	Failed  []string // Changed settings that couldn't be applied, with why
+	Err     error    // Why the file couldn't be used; the settings are left as they were
+}
+
+// configWatch is the config file as last read, for spotting edits
+type configWatch struct {
+	modTime time.Time
+	file    Config // Settings read from the file, before command-line flags
+}
+
+// WatchConfig starts watching the config file for edits, given the settings it was
+// read with at startup. Only settings later changed in the file are applied, so those
+// given as flags keep their values until the file changes them.
+func (s *Synth) WatchConfig(file Config) {
+	s.configWatch = &configWatch{file: file}
+	if path, err := ConfigPath(); err == nil {
+		if info, err := os.Stat(path); err == nil {
+			s.configWatch.modTime = info.ModTime()
+		}
+	}
+}
+
+// ReloadConfig rereads the config file when it was edited since it was last read,
+// applying the changed settings that can change while running and listing those that
+// need a restart. It reports whether the file was edited.
+func (s *Synth) ReloadConfig() (ConfigReload, bool) {
+	if s.configWatch == nil {
+		return ConfigReload{}, false
+	}
+	path, err := ConfigPath()
+	if err != nil {
+		return ConfigReload{}, false
+	}
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+	if modTime.Equal(s.configWatch.modTime) {
+		return ConfigReload{}, false
+	}
+	s.configWatch.modTime = modTime
+
+	// A removed file goes back to the defaults, as at startup
+	file := DefaultConfig()
+	if !modTime.IsZero() {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			file, err = ParseConfig(data)
+		}
+		if err != nil {
+			return ConfigReload{Err: fmt.Errorf("%s: %w", path, err)}, true
+		}
+	}
+	old := s.configWatch.file
+	s.configWatch.file = file
+
+	var reload ConfigReload
+	restart := func(name string, changed bool) {
+		if changed {
+			reload.Restart = append(reload.Restart, name)
+		}
+	}
+	restart("audio_device", file.AudioDevice != old.AudioDevice)
+	restart("audio_input", file.AudioInput != old.AudioInput)
+	restart("sample_rate", file.SampleRate != old.SampleRate)
+	restart("buffer_size", file.BufferSize != old.BufferSize)
+
+	// The startup preset is only read at startup, so there's nothing to report
+	if file.Preset != old.Preset {
+		s.Config.Preset = file.Preset
+	}
+	if file.Theme != old.Theme {
+		s.Config.Theme = file.Theme
+		reload.Applied = append(reload.Applied, "theme")
+	}
+	if !maps.Equal(file.Keys, old.Keys) {
+		s.Config.Keys = file.Keys
+		reload.Applied = append(reload.Applied, "keys")
+	}
+	if file.MIDIIn != old.MIDIIn {
+		s.Config.MIDIIn = file.MIDIIn
+		reload.Applied = append(reload.Applied, "midi_in")
+	}
+	if file.MIDIOut != old.MIDIOut {
+		s.Config.MIDIOut = file.MIDIOut
+		reload.Applied = append(reload.Applied, "midi_out")
+	}
+	if file.VirtualIn != old.VirtualIn {
+		s.Config.VirtualIn = file.VirtualIn
+		reload.Applied = append(reload.Applied, "virtual_in")
+	}
+	if file.MIDIIn != old.MIDIIn || file.MIDIOut != old.MIDIOut || file.VirtualIn != old.VirtualIn {
+		s.reopenMIDI()
+	}
+	if file.Reference != old.Reference {
+		s.Config.Reference = file.Reference
+		s.SetReference(file.Reference)
+		reload.Applied = append(reload.Applied, "reference_pitch")
+	}
+	if file.FirstProgram != old.FirstProgram {
+		s.Config.FirstProgram = file.FirstProgram
+		s.Programs.SetNumbering(s.Config.FirstProgram, s.Config.BankSize)
+		reload.Applied = append(reload.Applied, "first_program")
+	}
+	if file.BankSize != old.BankSize {
+		s.Config.BankSize = file.BankSize
+		s.Programs.SetNumbering(s.Config.FirstProgram, s.Config.BankSize)
+		reload.Applied = append(reload.Applied, "bank_size")
+	}
+	if file.OSC != old.OSC {
+		s.Config.OSC = file.OSC
+		if err := s.StartOSC(file.OSC); err != nil {
+			reload.Failed = append(reload.Failed, fmt.Sprintf("osc (%v)", err))
+		} else {
+			reload.Applied = append(reload.Applied, "osc")
+		}
+	}
+	if file.HTTP != old.HTTP {
+		s.Config.HTTP = file.HTTP
+		if err := s.StartHTTP(file.HTTP); err != nil {
+			reload.Failed = append(reload.Failed, fmt.Sprintf("http (%v)", err))
+		} else {
+			reload.Applied = append(reload.Applied, "http")
+		}
+	}
+	return reload, true
+}
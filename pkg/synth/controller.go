@@ -0,0 +1,187 @@
+package synth
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+
+	"gosynth/pkg/engine"
+)
+
+const ControllerFeedbackRate = 50 * time.Millisecond // Interval between LED feedback updates
+
+// ControlMapping assigns a controller CC to a preset parameter
+type ControlMapping struct {
+	CC    uint8
+	Param string // Name from Synth.Params
+}
+
+// ControllerProfile maps a known control surface's factory layout to synth parameters
+type ControllerProfile struct {
+	Name     string
+	Match    string // Case-insensitive part of the MIDI port name that identifies the device
+	Feedback bool   // The device shows sent CC values on LED rings
+	Channel  uint8  // Channel feedback is sent on
+	Mappings []ControlMapping
+}
+
+// Parameters assigned to a bank of eight controls, in order
+var (
+	faderParams = []string{"volume", "modIndex", "attack", "decay", "sustain", "release", "delayMix", "reverbMix"}
+	knobParams  = []string{"carrierFreq", "minModFreq", "maxModFreq", "sweepTime", "arpRate", "arpGate", "chorusMix", "bpm"}
+	knob2Params = []string{"delayTime", "delayFeedback", "reverbSize", "reverbDamping", "chorusRate", "chorusDepth", "arpOctaves", "sampleStartRandom"}
+)
+
+// bank maps consecutive CCs starting at first to the given parameters
+func bank(first uint8, params []string) []ControlMapping {
+	mappings := make([]ControlMapping, len(params))
+	for i, param := range params {
+		mappings[i] = ControlMapping{CC: first + uint8(i), Param: param}
+	}
+	return mappings
+}
+
+// concat joins mapping banks
+func concat(banks ...[]ControlMapping) []ControlMapping {
+	var mappings []ControlMapping
+	for _, b := range banks {
+		mappings = append(mappings, b...)
+	}
+	return mappings
+}
+
+// ControllerProfiles are the built-in profiles, checked in order against port names.
+// Each follows the device's factory template or default setup.
+var ControllerProfiles = []ControllerProfile{
+	{
+		Name:     "Novation Launch Control XL",
+		Match:    "launch control xl",
+		Mappings: concat(bank(13, knobParams), bank(29, knob2Params), bank(77, faderParams)),
+	},
+	{
+		Name:     "Novation Launch Control",
+		Match:    "launch control",
+		Mappings: concat(bank(21, knobParams), bank(41, knob2Params)),
+	},
+	{
+		Name:     "Korg nanoKONTROL2",
+		Match:    "nanokontrol2",
+		Mappings: concat(bank(0, faderParams), bank(16, knobParams)),
+	},
+	{
+		Name:     "Korg nanoKONTROL",
+		Match:    "nanokontrol",
+		Mappings: concat(bank(2, faderParams[:5]), bank(8, faderParams[5:]), bank(14, knobParams)),
+	},
+	{
+		Name:     "Faderfox EC4",
+		Match:    "faderfox",
+		Feedback: true,
+		Mappings: concat(bank(1, knobParams), bank(9, faderParams)),
+	},
+}
+
+// FindControllerProfile returns the profile for a MIDI port name, or nil
+func FindControllerProfile(portName string) *ControllerProfile {
+	name := strings.ToLower(portName)
+	for i := range ControllerProfiles {
+		if strings.Contains(name, ControllerProfiles[i].Match) {
+			return &ControllerProfiles[i]
+		}
+	}
+	return nil
+}
+
+// Controller is a detected control surface driving parameters through its profile
+type Controller struct {
+	Profile *ControllerProfile
+	Port    string // Input port name
+
+	params map[string]engine.Param
+	out    MIDIOut
+	mu     sync.Mutex
+	sent   map[uint8]uint8 // Last value sent or received per CC, to skip unchanged feedback
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// newController binds a profile to the synth's parameters, opening the device's
+// output for LED feedback when the profile supports it
+func (s *Synth) newController(profile *ControllerProfile, port string) *Controller {
+	c := &Controller{
+		Profile: profile,
+		Port:    port,
+		params:  make(map[string]engine.Param),
+		sent:    make(map[uint8]uint8),
+	}
+	for _, param := range s.Params() {
+		c.params[param.Name] = param
+	}
+	if profile.Feedback && c.out.OpenNamed(port) == nil {
+		c.stop = make(chan struct{})
+		c.done = make(chan struct{})
+		go c.runFeedback(c.stop, c.done)
+	}
+	return c
+}
+
+// handleCC sets the parameter mapped to a CC, returning false for unmapped CCs
+func (c *Controller) handleCC(cc, value uint8) bool {
+	for _, m := range c.Profile.Mappings {
+		if m.CC != cc {
+			continue
+		}
+		param, ok := c.params[m.Param]
+		if !ok {
+			return false
+		}
+		param.Value.Set(param.Min + (param.Max-param.Min)*float64(value)/127)
+		c.mu.Lock()
+		c.sent[cc] = value
+		c.mu.Unlock()
+		return true
+	}
+	return false
+}
+
+// Close stops LED feedback and closes the device's output
+func (c *Controller) Close() {
+	if c.stop != nil {
+		close(c.stop)
+		<-c.done
+	}
+	c.out.Close()
+}
+
+// runFeedback sends changed parameter values back to the device until stop is closed,
+// so its LED rings follow edits made from the UI, presets and undo
+func (c *Controller) runFeedback(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(ControllerFeedbackRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		for _, m := range c.Profile.Mappings {
+			param, ok := c.params[m.Param]
+			if !ok {
+				continue
+			}
+			value := uint8(math.Round(param.Normalized() * 127))
+			c.mu.Lock()
+			last, seen := c.sent[m.CC]
+			c.sent[m.CC] = value
+			c.mu.Unlock()
+			if !seen || last != value {
+				c.out.Send(midi.ControlChange(c.Profile.Channel, m.CC, value))
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package synth
+
+import (
+	"time"
+
+	"gosynth/pkg/engine"
+)
+
+const DrumChannel = 9 // MIDI channel 10, the General MIDI percussion channel
+
+// gmDrumNames maps General MIDI percussion notes to their instruments
+var gmDrumNames = map[uint8]string{
+	35: "Acoustic Bass Drum",
+	36: "Bass Drum 1",
+	37: "Side Stick",
+	38: "Acoustic Snare",
+	39: "Hand Clap",
+	40: "Electric Snare",
+	41: "Low Floor Tom",
+	42: "Closed Hi-Hat",
+	43: "High Floor Tom",
+	44: "Pedal Hi-Hat",
+	45: "Low Tom",
+	46: "Open Hi-Hat",
+	47: "Low-Mid Tom",
+	48: "Hi-Mid Tom",
+	49: "Crash Cymbal 1",
+	50: "High Tom",
+	51: "Ride Cymbal 1",
+	52: "Chinese Cymbal",
+	53: "Ride Bell",
+	54: "Tambourine",
+	55: "Splash Cymbal",
+	56: "Cowbell",
+	57: "Crash Cymbal 2",
+	58: "Vibraslap",
+	59: "Ride Cymbal 2",
+	60: "Hi Bongo",
+	61: "Low Bongo",
+	62: "Mute Hi Conga",
+	63: "Open Hi Conga",
+	64: "Low Conga",
+	65: "High Timbale",
+	66: "Low Timbale",
+	67: "High Agogo",
+	68: "Low Agogo",
+	69: "Cabasa",
+	70: "Maracas",
+	71: "Short Whistle",
+	72: "Long Whistle",
+	73: "Short Guiro",
+	74: "Long Guiro",
+	75: "Claves",
+	76: "Hi Wood Block",
+	77: "Low Wood Block",
+	78: "Mute Cuica",
+	79: "Open Cuica",
+	80: "Mute Triangle",
+	81: "Open Triangle",
+}
+
+// drumVoiceNotes maps General MIDI percussion notes to the drum voices playing them
+var drumVoiceNotes = map[uint8]engine.DrumKind{
+	35: engine.DrumKick,
+	36: engine.DrumKick,
+	37: engine.DrumSnare,
+	38: engine.DrumSnare,
+	40: engine.DrumSnare,
+	42: engine.DrumHat,
+	44: engine.DrumHat,
+	46: engine.DrumHat,
+}
+
+// DrumVoiceFor returns the drum voice playing a General MIDI percussion note, or -1
+func DrumVoiceFor(note uint8) engine.DrumKind {
+	if kind, ok := drumVoiceNotes[note]; ok {
+		return kind
+	}
+	return -1
+}
+
+// GMDrums reports whether MIDI channel 10 plays from the SoundFont drum kit
+func (s *Synth) GMDrums() bool {
+	return s.gmDrums.Load()
+}
+
+// SetGMDrums switches playing MIDI channel 10 from the SoundFont drum kit
+func (s *Synth) SetGMDrums(on bool) {
+	s.gmDrums.Store(on)
+}
+
+// DrumVoices reports whether the kick, snare and hi-hat notes of MIDI channel 10 play
+// from the drum synthesis
+func (s *Synth) DrumVoices() bool {
+	return s.drumVoices.Load()
+}
+
+// SetDrumVoices switches playing the kick, snare and hi-hat notes of MIDI channel 10
+// from the drum synthesis, ahead of the SoundFont kit
+func (s *Synth) SetDrumVoices(on bool) {
+	s.drumVoices.Store(on)
+}
+
+// PlayDrum plays a drum voice, bypassing the split, latch and arpeggiator
+func (s *Synth) PlayDrum(kind engine.DrumKind, velocity uint8) {
+	s.Stats.noteOn(s.PresetName(), time.Now())
+	s.QueueDrum(kind, velocity)
+}
+
+// GMDrumName returns the General MIDI percussion instrument for a note, or ""
+func GMDrumName(note uint8) string {
+	return gmDrumNames[note]
+}
+
+// DrumNoteOn plays a percussion note from the SoundFont's drum kit, bypassing
+// the split, latch and arpeggiator
+func (s *Synth) DrumNoteOn(note, velocity uint8) {
+	s.Stats.noteOn(s.PresetName(), time.Now())
+	s.QueueNoteOn(note, velocity, true)
+}
+
+// DrumNoteOff releases a percussion note
+func (s *Synth) DrumNoteOff(note uint8) {
+	s.QueueNoteOff(note, true)
+}
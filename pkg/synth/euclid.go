@@ -0,0 +1,62 @@
+package synth
+
+import "gosynth/pkg/engine"
+
+// EuclidTracks is the number of tracks the Euclidean generator fills: the notes, each
+// drum voice, then each one-shot slot
+const EuclidTracks = 1 + int(engine.DrumKinds) + engine.OneShotSlots
+
+// EuclidDrum returns the drum voice of a generator track, or -1 for another track
+func EuclidDrum(track int) engine.DrumKind {
+	if track < 1 || track > int(engine.DrumKinds) {
+		return -1
+	}
+	return engine.DrumKind(track - 1)
+}
+
+// EuclidShot returns the one-shot slot of a generator track from 1, or 0 for another track
+func EuclidShot(track int) int {
+	return max(0, track-int(engine.DrumKinds))
+}
+
+// Euclid returns a Euclidean rhythm: hits spread as evenly as they go over steps, the
+// first on the first step, then moved later by rotate steps
+func Euclid(hits, steps, rotate int) []bool {
+	rhythm := make([]bool, max(steps, 0))
+	if steps <= 0 {
+		return rhythm
+	}
+	hits = max(0, min(hits, steps))
+	rotate = (rotate%steps + steps) % steps
+	for i := range rhythm {
+		rhythm[(i+rotate)%steps] = i*hits%steps < hits
+	}
+	return rhythm
+}
+
+// FillEuclid fills one track over the pattern's length with a Euclidean rhythm. Track 0
+// is the notes, switching steps on and off; the drum tracks switch their drum on and off,
+// and the one-shot tracks place their slot on the hits and take it off the other steps.
+func (sq *Sequencer) FillEuclid(track, hits, rotate int) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if track < 0 || track >= EuclidTracks {
+		return
+	}
+	for i, hit := range Euclid(hits, sq.pattern.Length, rotate) {
+		step := &sq.pattern.Steps[i]
+		drum, shot := EuclidDrum(track), EuclidShot(track)
+		switch {
+		case track == 0:
+			step.Active = hit
+		case drum >= 0 && hit:
+			step.Drums |= 1 << drum
+		case drum >= 0:
+			step.Drums &^= 1 << drum
+		case hit:
+			step.Shot = shot
+		case step.Shot == shot:
+			step.Shot = 0
+		}
+	}
+}
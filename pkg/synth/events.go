@@ -0,0 +1,41 @@
+package synth
+
+import (
+	"time"
+
+	"gosynth/pkg/engine"
+)
+
+const ChangeWatchRate = 20 * time.Millisecond // Interval between checks for parameter and transport changes
+
+// watchChanges publishes parameter and transport changes on the engine's bus until stop
+// is closed. Watching the values, as the controller feedback does, catches every source
+// of change: the UI, MIDI CCs, control surfaces, preset loads, crossfades and undo.
+func (s *Synth) watchChanges(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(ChangeWatchRate)
+	defer ticker.Stop()
+	values := make(map[string]float64)
+	for _, param := range s.Params() {
+		values[param.Name] = param.Value.Get()
+	}
+	playing := s.Seq.Playing()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		for _, param := range s.Params() {
+			if v := param.Value.Get(); v != values[param.Name] {
+				values[param.Name] = v
+				s.Bus.Publish(engine.Event{Kind: engine.EventParam, Param: param.Name, Value: v})
+			}
+		}
+		if p := s.Seq.Playing(); p != playing {
+			playing = p
+			s.Bus.Publish(engine.Event{Kind: engine.EventTransport, Playing: p})
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package synth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MaxHistory       = 100             // Undo steps kept per session
+	MaxHistoryFiles  = 20              // Session history files kept, the newest
+	HistorySaveDelay = 2 * time.Second // Quiet time after a change before the history is written
+)
+
+// History is the undo and redo stack of engine snapshots, shared by parameter
+// edits and preset loads and saved to a file per session. Changes are written in the
+// background once they stop for HistorySaveDelay, and Save writes any still pending.
+type History struct {
+	mu      sync.Mutex
+	undo    []Preset
+	redo    []Preset
+	session string      // Session file name, from the start time
+	timer   *time.Timer // Pending background save
+	err     error       // Failure of the last background save, until reported
+	pruned  bool        // Older sessions' files have been pruned
+
+	saveMu sync.Mutex // Serializes writes of the file
+}
+
+// NewHistory creates an empty history for a session starting now
+func NewHistory() *History {
+	return &History{session: time.Now().Format("20060102-150405") + ".json"}
+}
+
+// HistoryDir returns the directory session history files are stored in
+func HistoryDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gosynth", "history"), nil
+}
+
+// Snapshot records the current engine state as an undo step before a change
+func (s *Synth) Snapshot() error {
+	h := s.History
+	h.mu.Lock()
+	h.undo = append(h.undo, s.CapturePreset(s.PresetName()))
+	if len(h.undo) > MaxHistory {
+		h.undo = h.undo[len(h.undo)-MaxHistory:]
+	}
+	h.redo = nil
+	h.mu.Unlock()
+	return h.changed()
+}
+
+// Undo restores the state before the last change, returning false if there is none
+func (s *Synth) Undo() (bool, error) {
+	return s.History.step(s, &s.History.undo, &s.History.redo)
+}
+
+// Redo reapplies the last undone change, returning false if there is none
+func (s *Synth) Redo() (bool, error) {
+	return s.History.step(s, &s.History.redo, &s.History.undo)
+}
+
+// step moves one snapshot from one stack to the other, applying it and
+// recording the current state on the opposite stack
+func (h *History) step(s *Synth, from, to *[]Preset) (bool, error) {
+	h.mu.Lock()
+	if len(*from) == 0 {
+		h.mu.Unlock()
+		return false, nil
+	}
+	p := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, s.CapturePreset(s.PresetName()))
+	h.mu.Unlock()
+
+	s.ApplyPreset(p)
+	return true, h.changed()
+}
+
+// changed schedules a background save, or puts off the one pending, and returns the
+// failure of the last background save so the UI can report it once
+func (h *History) changed() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.timer == nil {
+		h.timer = time.AfterFunc(HistorySaveDelay, h.saveLater)
+	} else {
+		h.timer.Reset(HistorySaveDelay)
+	}
+	err := h.err
+	h.err = nil
+	return err
+}
+
+// saveLater is the background save, keeping its failure for changed to report
+func (h *History) saveLater() {
+	if err := h.write(); err != nil {
+		h.mu.Lock()
+		h.err = err
+		h.mu.Unlock()
+	}
+}
+
+// Save writes the session's undo and redo stacks to its history file now, as on quit,
+// in place of any background save still pending
+func (h *History) Save() error {
+	h.mu.Lock()
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.mu.Unlock()
+	return h.write()
+}
+
+// write saves the undo and redo stacks, and on the session's first save prunes the
+// files of older sessions beyond MaxHistoryFiles
+func (h *History) write() error {
+	h.saveMu.Lock()
+	defer h.saveMu.Unlock()
+	dir, err := HistoryDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	data, err := json.MarshalIndent(struct {
+		Undo []Preset `json:"undo"`
+		Redo []Preset `json:"redo"`
+	}{h.undo, h.redo}, "", "  ")
+	prune := !h.pruned
+	h.pruned = true
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, h.session), data, 0o644); err != nil {
+		return err
+	}
+	if prune {
+		return pruneHistory(dir, MaxHistoryFiles)
+	}
+	return nil
+}
+
+// pruneHistory removes the oldest session files of a directory beyond keep. The files
+// are named after their session's start time, so they sort oldest first.
+func pruneHistory(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			files = append(files, e.Name())
+		}
+	}
+	sort.Strings(files)
+	for _, name := range files[:max(0, len(files)-keep)] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package synth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestHistorySave checks that snapshots are written in the background rather than at
+// each edit, that Save writes them at once, and that old sessions' files are pruned
+func TestHistorySave(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir, err := HistoryDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < MaxHistoryFiles+5; i++ {
+		name := fmt.Sprintf("20200101-%06d.json", i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewSynth()
+	for i := 0; i < 3; i++ {
+		if err := s.Snapshot(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	session := filepath.Join(dir, s.History.session)
+	if _, err := os.Stat(session); !os.IsNotExist(err) {
+		t.Errorf("history written at each snapshot: %v", err)
+	}
+
+	if err := s.History.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct{ Undo, Redo []Preset }
+	if err := json.Unmarshal(data, &saved); err != nil || len(saved.Undo) != 3 {
+		t.Errorf("saved %d undo steps, %v, want 3", len(saved.Undo), err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != MaxHistoryFiles || !slices.Contains(names, s.History.session) || slices.Contains(names, "20200101-000005.json") {
+		t.Errorf("history files after pruning: %v, want the newest %d with this session's", names, MaxHistoryFiles)
+	}
+}
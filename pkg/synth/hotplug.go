@@ -0,0 +1,49 @@
+package synth
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/drivers"
+)
+
+const MIDIScanInterval = 2 * time.Second // How often the MIDI ports are checked for devices plugged in or out
+
+// RescanMIDI looks for MIDI devices plugged in or out since the ports were opened and,
+// when there are any, opens the ports again: a controller plugged in after launch is
+// played, and one unplugged and plugged back in is reconnected. The output port chosen
+// is kept while it's still there. It reports whether the ports changed.
+func (s *Synth) RescanMIDI() bool {
+	if s.NoMIDI || drivers.Get() == nil {
+		return false
+	}
+	if slices.Equal(midiPortNames(), s.midiSeen) {
+		return false
+	}
+	out := s.MIDIOut.Name()
+	s.reopenMIDI()
+	if out != "" && s.MIDIOut.Name() != out {
+		s.MIDIOut.OpenNamed(out)
+	}
+	return true
+}
+
+// MIDIInputs returns the input ports being listened to
+func (s *Synth) MIDIInputs() []string {
+	return s.midiInputs
+}
+
+// midiPortNames lists every input and output port, sorted
+func midiPortNames() []string {
+	var names []string
+	for _, port := range midi.GetInPorts() {
+		names = append(names, "in "+port.String())
+	}
+	for _, port := range midi.GetOutPorts() {
+		names = append(names, "out "+port.String())
+	}
+	sort.Strings(names)
+	return names
+}
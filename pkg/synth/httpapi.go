@@ -0,0 +1,281 @@
+package synth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	HTTPMaxBody     = 1 << 16     // Largest request body read, in bytes
+	HTTPDefaultHost = "127.0.0.1" // Host listened on when an address gives only a port
+)
+
+// HTTPServer serves the JSON API:
+//
+//	GET   /state    the preset state, session settings, selected preset and transport
+//	PATCH /params   sets parameters from {"name": value, ...}, all or none
+//	POST  /notes    plays {"note": 60, "velocity": 100}, with "duration" in milliseconds to
+//	                release it, or a velocity of 0 to release it at once
+//	GET   /presets  lists the saved presets
+//	POST  /preset   loads {"name": "pad"}, listing the problems found in it
+//	GET   /live     WebSocket of LiveMessages: parameter changes, meter and waveform
+//
+// The API has no authentication: anyone who can reach the address can play and change
+// the synth, so it listens on the loopback interface unless a host is given. Browsers
+// send cross-site form posts without asking, so requests with a body must be JSON, which
+// a page on another site can only send after a CORS preflight the server never allows.
+// A page whose name is pointed at 127.0.0.1 after loading passes those checks as its own
+// site, so on the loopback interface requests must also name this machine as their Host.
+type HTTPServer struct {
+	server   *http.Server
+	listener net.Listener
+	live     liveConns // WebSocket clients of /live
+	done     chan struct{}
+}
+
+// APIState is the state GET /state serves
+type APIState struct {
+	Preset   string          `json:"preset"`   // Name of the last loaded or saved preset
+	State    Preset          `json:"state"`    // Every preset parameter, pattern and routing
+	Settings SessionSettings `json:"settings"` // Switches presets don't keep
+	Playing  bool            `json:"playing"`  // Whether the sequencer is running
+	Locked   []string        `json:"locked"`   // Parameters preset loads leave alone
+}
+
+// apiNote is the body of POST /notes
+type apiNote struct {
+	Note     *int `json:"note"`
+	Velocity *int `json:"velocity"` // RemoteVelocity when left out
+	Duration int  `json:"duration"` // Milliseconds until the note is released; 0 holds it
+}
+
+// apiPresetLoad is the answer to POST /preset
+type apiPresetLoad struct {
+	Preset string        `json:"preset"`
+	Issues []PresetIssue `json:"issues,omitempty"` // Problems skipped or clamped while loading
+}
+
+// apiError is the body of a failed request
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// StartHTTP serves the JSON API on a TCP address such as ":8080", on HTTPDefaultHost
+// when only a port is given; an empty address leaves the server stopped. A running
+// server is stopped first.
+func (s *Synth) StartHTTP(addr string) error {
+	s.StopHTTP()
+	if addr == "" {
+		return nil
+	}
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		addr = net.JoinHostPort(HTTPDefaultHost, port)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &HTTPServer{
+		listener: listener,
+		live:     liveConns{conns: make(map[net.Conn]bool)},
+		done:     make(chan struct{}),
+	}
+	loopback := false
+	if tcp, ok := listener.Addr().(*net.TCPAddr); ok {
+		loopback = tcp.IP.IsLoopback()
+	}
+	server.server = &http.Server{Handler: s.apiHandler(&server.live, loopback), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		defer close(server.done)
+		server.server.Serve(listener)
+	}()
+	s.http = server
+	return nil
+}
+
+// StopHTTP stops the HTTP server, if running
+func (s *Synth) StopHTTP() {
+	if s.http == nil {
+		return
+	}
+	s.http.server.Close()
+	s.http.live.closeAll()
+	<-s.http.done
+	s.http = nil
+}
+
+// HTTPAddr returns the address the HTTP server listens on, empty when stopped
+func (s *Synth) HTTPAddr() string {
+	if s.http == nil {
+		return ""
+	}
+	return s.http.listener.Addr().String()
+}
+
+// apiHandler routes the API's endpoints; on the loopback interface it refuses requests
+// whose Host header doesn't name this machine
+func (s *Synth) apiHandler(live *liveConns, loopback bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", s.apiMethod(http.MethodGet, s.apiState))
+	mux.HandleFunc("/params", s.apiMethod(http.MethodPatch, s.apiParams))
+	mux.HandleFunc("/notes", s.apiMethod(http.MethodPost, s.apiNotes))
+	mux.HandleFunc("/panic", s.apiMethod(http.MethodPost, s.apiPanic))
+	mux.HandleFunc("/presets", s.apiMethod(http.MethodGet, s.apiPresets))
+	mux.HandleFunc("/preset", s.apiMethod(http.MethodPost, s.apiLoadPreset))
+	mux.HandleFunc("/live", s.apiMethod(http.MethodGet, s.apiLive(live)))
+	if !loopback {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !localHost((&url.URL{Host: r.Host}).Hostname()) {
+			writeJSON(w, http.StatusForbidden, apiError{Error: "Host must be localhost or a loopback address"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// localHost reports whether a host name, without its port, names this machine
+func localHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// apiMethod only lets requests with the endpoint's method through, and for methods
+// changing the synth only JSON ones
+func (s *Synth) apiMethod(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: method + " only"})
+			return
+		}
+		if method != http.MethodGet {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeJSON(w, http.StatusUnsupportedMediaType, apiError{Error: "Content-Type must be application/json"})
+				return
+			}
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, HTTPMaxBody)
+		handler(w, r)
+	}
+}
+
+func (s *Synth) apiState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, APIState{
+		Preset:   s.PresetName(),
+		State:    s.CapturePreset(s.PresetName()),
+		Settings: s.captureSettings(),
+		Playing:  s.Seq.Playing(),
+		Locked:   s.Locks.Names(),
+	})
+}
+
+// apiParams checks every name before setting any, so a request with a mistake changes nothing
+func (s *Synth) apiParams(w http.ResponseWriter, r *http.Request) {
+	var values map[string]float64
+	if err := json.NewDecoder(r.Body).Decode(&values); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("expected {\"name\": value}: %v", err)})
+		return
+	}
+	known := make(map[string]bool)
+	for _, param := range s.Params() {
+		known[param.Name] = true
+	}
+	for name := range values {
+		if !known[name] {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("unknown parameter %q", name)})
+			return
+		}
+	}
+	for name, v := range values {
+		s.SetParam(name, v)
+	}
+	current := make(map[string]float64)
+	for _, param := range s.Params() {
+		if _, ok := values[param.Name]; ok {
+			current[param.Name] = param.Value.Get()
+		}
+	}
+	writeJSON(w, http.StatusOK, current)
+}
+
+func (s *Synth) apiNotes(w http.ResponseWriter, r *http.Request) {
+	var n apiNote
+	if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("expected {\"note\": 60, \"velocity\": 100}: %v", err)})
+		return
+	}
+	velocity := RemoteVelocity
+	if n.Velocity != nil {
+		velocity = *n.Velocity
+	}
+	if n.Note == nil || *n.Note < 0 || *n.Note > 127 || velocity < 0 || velocity > 127 || n.Duration < 0 {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "note and velocity must be from 0 to 127, and duration not negative"})
+		return
+	}
+	note := uint8(*n.Note)
+	if velocity == 0 {
+		s.NoteOff(note)
+	} else {
+		s.NoteOn(note, uint8(velocity))
+		if n.Duration > 0 {
+			time.AfterFunc(time.Duration(n.Duration)*time.Millisecond, func() { s.NoteOff(note) })
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Synth) apiPanic(w http.ResponseWriter, r *http.Request) {
+	s.Panic()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Synth) apiPresets(w http.ResponseWriter, r *http.Request) {
+	names, err := ListPresets()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, append([]string{}, names...))
+}
+
+// apiLoadPreset answers 200 with the problems of a preset that loaded with some
+func (s *Synth) apiLoadPreset(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" || filepath.Base(body.Name) != body.Name {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "expected {\"name\": \"preset\"}"})
+		return
+	}
+	err := s.LoadPreset(body.Name)
+	var issues PresetIssues
+	switch {
+	case err == nil, errors.As(err, &issues):
+		writeJSON(w, http.StatusOK, apiPresetLoad{Preset: body.Name, Issues: issues})
+	case errors.Is(err, os.ErrNotExist):
+		writeJSON(w, http.StatusNotFound, apiError{Error: fmt.Sprintf("no preset %q", body.Name)})
+	default:
+		writeJSON(w, http.StatusUnprocessableEntity, apiError{Error: err.Error()})
+	}
+}
+
+// writeJSON sends a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
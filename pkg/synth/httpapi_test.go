@@ -0,0 +1,118 @@
+package synth
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestAPIContentType checks that requests changing the synth must be JSON, so a form
+// posted by a page on another site is refused
+func TestAPIContentType(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	s := NewSynth()
+	handler := s.apiHandler(&liveConns{conns: make(map[net.Conn]bool)}, false)
+	for _, tc := range []struct {
+		method, path, contentType, body string
+		status                          int
+	}{
+		{http.MethodPatch, "/params", "application/json", `{"volume": 0.25}`, http.StatusOK},
+		{http.MethodPatch, "/params", "application/json; charset=utf-8", `{"volume": 0.25}`, http.StatusOK},
+		{http.MethodPatch, "/params", "text/plain", `{"volume": 0.9}`, http.StatusUnsupportedMediaType},
+		{http.MethodPost, "/panic", "", "", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "/panic", "application/x-www-form-urlencoded", "a=b", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "/panic", "application/json", "", http.StatusNoContent},
+		{http.MethodGet, "/presets", "", "", http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Errorf("%s %s as %q: status %d, want %d", tc.method, tc.path, tc.contentType, w.Code, tc.status)
+		}
+	}
+	if v := s.Volume.Get(); v != 0.25 {
+		t.Errorf("volume %g after the requests, want 0.25 from the JSON ones only", v)
+	}
+}
+
+// TestHTTPLoopback checks that an address giving only a port listens on the loopback
+// interface
+func TestHTTPLoopback(t *testing.T) {
+	s := NewSynth()
+	if err := s.StartHTTP(":0"); err != nil {
+		t.Fatal(err)
+	}
+	defer s.StopHTTP()
+	host, _, err := net.SplitHostPort(s.HTTPAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !net.ParseIP(host).IsLoopback() {
+		t.Errorf("listening on %s, want the loopback interface", s.HTTPAddr())
+	}
+}
+
+// TestAPIHost checks that on the loopback interface only requests naming this machine as
+// their Host are served, so a page on another site can't reach the API by pointing its
+// own name at 127.0.0.1
+func TestAPIHost(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	s := NewSynth()
+	handler := s.apiHandler(&liveConns{conns: make(map[net.Conn]bool)}, true)
+	for _, tc := range []struct {
+		host   string
+		status int
+	}{
+		{"127.0.0.1:8080", http.StatusOK},
+		{"localhost:8080", http.StatusOK},
+		{"LocalHost", http.StatusOK},
+		{"[::1]:8080", http.StatusOK},
+		{"attacker.example:8080", http.StatusForbidden},
+		{"localhost.attacker.example", http.StatusForbidden},
+		{"192.168.1.20:8080", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/presets", nil)
+		req.Host = tc.host
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Errorf("host %q: status %d, want %d", tc.host, w.Code, tc.status)
+		}
+	}
+
+	// A server listening on the loopback interface checks the Host its clients send
+	if err := s.StartHTTP(":0"); err != nil {
+		t.Fatal(err)
+	}
+	defer s.StopHTTP()
+	for _, tc := range []struct {
+		host   string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"rebound.example", http.StatusForbidden},
+	} {
+		req, err := http.NewRequest(http.MethodGet, "http://"+s.HTTPAddr()+"/presets", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.host != "" {
+			req.Host = tc.host
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("server with host %q: status %d, want %d", tc.host, resp.StatusCode, tc.status)
+		}
+	}
+}
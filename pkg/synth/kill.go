@@ -0,0 +1,18 @@
+package synth
+
+import "gosynth/pkg/engine"
+
+// KillNotes are the MIDI notes that hold each kill switch while pressed, indexed by
+// engine.Kill: the lowest keys of the MIDI range, so they stay clear of played parts
+var KillNotes = [...]uint8{engine.KillDelay: 0, engine.KillReverb: 1, engine.KillParts: 2}
+
+// handleKillNote holds a kill switch while its MIDI note is down, returning false for other notes
+func (s *Synth) handleKillNote(note uint8, down bool) bool {
+	for k, n := range KillNotes {
+		if n == note {
+			s.SetKill(engine.Kill(k), down)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package synth
+
+import "sync"
+
+// LatchMode is how the latch holds notes after their keys are released
+type LatchMode int
+
+const (
+	LatchChord LatchMode = iota // The last chord, replaced by the next one played
+	LatchHold                   // Every note, until its key is pressed again, to build up drones
+	latchModeCount
+)
+
+func (m LatchMode) String() string {
+	switch m {
+	case LatchChord:
+		return "chord"
+	case LatchHold:
+		return "hold"
+	}
+	return "unknown"
+}
+
+// Next returns the following mode, wrapping around
+func (m LatchMode) Next(dir int) LatchMode {
+	return LatchMode((int(m) + dir + int(latchModeCount)) % int(latchModeCount))
+}
+
+// Latch keeps notes sounding after their keys are released. In chord mode the chord is
+// replaced when a new note is played with no keys down; in hold mode notes pile up, each
+// sounding until its key is pressed again or the latch is turned off.
+type Latch struct {
+	mu      sync.Mutex
+	enabled bool
+	mode    LatchMode
+	pressed map[uint8]bool // Keys physically held down
+	latched map[uint8]bool // Notes kept sounding by the latch
+}
+
+// NewLatch creates a disabled latch
+func NewLatch() *Latch {
+	return &Latch{
+		pressed: make(map[uint8]bool),
+		latched: make(map[uint8]bool),
+	}
+}
+
+// Enabled reports whether the latch is engaged
+func (l *Latch) Enabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enabled
+}
+
+// SetEnabled engages or disengages the latch, returning the notes that must now be released
+func (l *Latch) SetEnabled(enabled bool) []uint8 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.enabled = enabled
+	if enabled {
+		return nil
+	}
+	var released []uint8
+	for note := range l.latched {
+		if !l.pressed[note] {
+			released = append(released, note)
+		}
+		delete(l.latched, note)
+	}
+	return released
+}
+
+// Mode returns how the latch holds notes
+func (l *Latch) Mode() LatchMode {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.mode
+}
+
+// SetMode changes how the latch holds notes; notes already held stay until the new mode
+// lets them go
+func (l *Latch) SetMode(mode LatchMode) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.mode = mode.Next(0)
+}
+
+// NoteOn records a key press, returning the latched notes to release and whether the
+// note should play. In hold mode pressing a held note's key releases it instead.
+func (l *Latch) NoteOn(note uint8) ([]uint8, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.enabled && l.mode == LatchHold {
+		l.pressed[note] = true
+		if l.latched[note] {
+			delete(l.latched, note)
+			return []uint8{note}, false
+		}
+		l.latched[note] = true
+		return nil, true
+	}
+	var released []uint8
+	if l.enabled && len(l.pressed) == 0 {
+		// A fresh chord replaces whatever was latched
+		for held := range l.latched {
+			if held != note {
+				released = append(released, held)
+			}
+			delete(l.latched, held)
+		}
+	}
+	l.pressed[note] = true
+	if l.enabled {
+		l.latched[note] = true
+	}
+	return released, true
+}
+
+// NoteOff records a key release, reporting whether the note should actually stop
+func (l *Latch) NoteOff(note uint8) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.pressed, note)
+	return !l.latched[note]
+}
+
+// clear forgets the keys down and the notes latched, keeping the latch engaged or not
+func (l *Latch) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	clear(l.pressed)
+	clear(l.latched)
+}
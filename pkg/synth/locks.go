@@ -0,0 +1,113 @@
+package synth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Locks are the preset parameters held through preset loads, such as the master volume
+// or the tempo during a performance. They are kept in a config file across runs.
+type Locks struct {
+	mu     sync.Mutex
+	locked map[string]bool
+}
+
+// NewLocks creates an empty set of locks
+func NewLocks() *Locks {
+	return &Locks{locked: make(map[string]bool)}
+}
+
+// LocksPath returns the file the locked parameter names are stored in
+func LocksPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gosynth", "locks.json"), nil
+}
+
+// Locked reports whether a parameter is locked
+func (l *Locks) Locked(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.locked[name]
+}
+
+// SetLocked locks or unlocks a parameter and saves the locks
+func (l *Locks) SetLocked(name string, locked bool) error {
+	l.mu.Lock()
+	if locked {
+		l.locked[name] = true
+	} else {
+		delete(l.locked, name)
+	}
+	l.mu.Unlock()
+	return l.save()
+}
+
+// Names returns the locked parameters, sorted
+func (l *Locks) Names() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	names := make([]string, 0, len(l.locked))
+	for name := range l.locked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load reads the locks from the config file; a missing file leaves nothing locked
+func (l *Locks) Load() error {
+	path, err := LocksPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.locked = make(map[string]bool)
+	for _, name := range names {
+		l.locked[name] = true
+	}
+	return nil
+}
+
+// save writes the locked parameter names to the config file
+func (l *Locks) save() error {
+	path, err := LocksPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(l.Names(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// drop removes the locked parameters from a preset's values, so applying the preset
+// leaves them as they are
+func (l *Locks) drop(values map[string]float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for name := range l.locked {
+		delete(values, name)
+	}
+}
@@ -0,0 +1,192 @@
+package synth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gosynth/pkg/engine"
+)
+
+const (
+	Macros          = 4  // Macro knobs
+	MacroCC         = 16 // Controller number of the first macro knob, the first general purpose controller
+	MaxMacroTargets = 8  // Most parameters one macro moves
+)
+
+// MacroTarget is a parameter a macro moves, from one value with the knob at the bottom
+// to another at the top. From above To turns the parameter down as the knob goes up.
+type MacroTarget struct {
+	Param string  `json:"param"` // Name from Synth.Params
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+}
+
+// MacroState is the targets of every macro knob, as saved with presets
+type MacroState [Macros][]MacroTarget
+
+// MacroSet is the macro knobs. Each sweeps several preset parameters at once, so one
+// control or CC moves a whole timbre. The knob positions are preset parameters too.
+type MacroSet struct {
+	mu      sync.Mutex
+	Value   [Macros]engine.SmoothValue // Knob positions from 0 to 1
+	targets MacroState
+}
+
+// NewMacroSet creates macro knobs at the bottom with nothing to move
+func NewMacroSet() *MacroSet {
+	return &MacroSet{}
+}
+
+// State returns the targets of every knob for saving
+func (ms *MacroSet) State() MacroState {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var st MacroState
+	for i, targets := range ms.targets {
+		st[i] = append([]MacroTarget(nil), targets...)
+	}
+	return st
+}
+
+// SetState replaces the targets of every knob, which should already be checked
+func (ms *MacroSet) SetState(st MacroState) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for i, targets := range st {
+		ms.targets[i] = append([]MacroTarget(nil), targets...)
+	}
+}
+
+// Targets returns the parameters one knob moves
+func (ms *MacroSet) Targets(macro int) []MacroTarget {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return append([]MacroTarget(nil), ms.targets[macro]...)
+}
+
+// Invert swaps the ends of every target of a knob, reversing its direction
+func (ms *MacroSet) Invert(macro int) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for i := range ms.targets[macro] {
+		t := &ms.targets[macro][i]
+		t.From, t.To = t.To, t.From
+	}
+}
+
+// macroParamName returns the preset parameter name of a knob position, macro1 and so on
+func macroParamName(macro int) string {
+	return fmt.Sprintf("macro%d", macro+1)
+}
+
+// macroParams returns the knob positions as preset parameters
+func (s *Synth) macroParams() []engine.Param {
+	params := make([]engine.Param, Macros)
+	for i := range params {
+		params[i] = engine.Param{Name: macroParamName(i), Value: &s.Macros.Value[i], Min: 0, Max: 1}
+	}
+	return params
+}
+
+// macroIndex returns the knob a preset parameter name is the position of
+func macroIndex(name string) (int, bool) {
+	for i := 0; i < Macros; i++ {
+		if name == macroParamName(i) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// macroTargetParam returns the parameter a macro target names; knob positions can't be
+// targets, so macros never move each other
+func (s *Synth) macroTargetParam(name string) (engine.Param, bool) {
+	if _, ok := macroIndex(name); ok {
+		return engine.Param{}, false
+	}
+	for _, param := range s.Params() {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return engine.Param{}, false
+}
+
+// SetMacro turns a knob to a position from 0 to 1, moving each of its targets to the
+// same point between its ends
+func (s *Synth) SetMacro(macro int, position float64) {
+	if macro < 0 || macro >= Macros {
+		return
+	}
+	position = max(0, min(position, 1))
+	s.Macros.Value[macro].Set(position)
+	for _, t := range s.Macros.Targets(macro) {
+		if param, ok := s.macroTargetParam(t.Param); ok {
+			param.Value.Set(param.Clamp(t.From + (t.To-t.From)*position))
+		}
+	}
+}
+
+// SetMacroTargets replaces the parameters a knob moves, clamping the ends to each
+// parameter's range. Unknown parameters and too many targets are errors.
+func (s *Synth) SetMacroTargets(macro int, targets []MacroTarget) error {
+	if macro < 0 || macro >= Macros {
+		return fmt.Errorf("no macro %d", macro+1)
+	}
+	if len(targets) > MaxMacroTargets {
+		return fmt.Errorf("at most %d parameters per macro", MaxMacroTargets)
+	}
+	checked := make([]MacroTarget, len(targets))
+	for i, t := range targets {
+		param, ok := s.macroTargetParam(t.Param)
+		if !ok {
+			return fmt.Errorf("unknown parameter %q", t.Param)
+		}
+		checked[i] = MacroTarget{Param: t.Param, From: param.Clamp(t.From), To: param.Clamp(t.To)}
+	}
+	s.Macros.mu.Lock()
+	defer s.Macros.mu.Unlock()
+	s.Macros.targets[macro] = checked
+	return nil
+}
+
+// FormatMacro writes a knob's targets as each parameter name and its ends, such as
+// "filterCutoff 200..8000, reverbMix 0.5..0"
+func FormatMacro(targets []MacroTarget) string {
+	if len(targets) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(targets))
+	for i, t := range targets {
+		parts[i] = fmt.Sprintf("%s %g..%g", t.Param, t.From, t.To)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseMacro reads targets written as FormatMacro does; "none" or nothing clears them
+func ParseMacro(text string) ([]MacroTarget, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.EqualFold(text, "none") {
+		return nil, nil
+	}
+	var targets []MacroTarget
+	for _, entry := range strings.Split(text, ",") {
+		name, span, ok := strings.Cut(strings.TrimSpace(entry), " ")
+		from, to, found := strings.Cut(strings.TrimSpace(span), "..")
+		if !ok || !found {
+			return nil, fmt.Errorf("bad target %q, expected a parameter and from..to", strings.TrimSpace(entry))
+		}
+		t := MacroTarget{Param: name}
+		var err error
+		if t.From, err = strconv.ParseFloat(strings.TrimSpace(from), 64); err != nil {
+			return nil, fmt.Errorf("bad value %q for %s", from, name)
+		}
+		if t.To, err = strconv.ParseFloat(strings.TrimSpace(to), 64); err != nil {
+			return nil, fmt.Errorf("bad value %q for %s", to, name)
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
@@ -0,0 +1,68 @@
+package synth
+
+import (
+	"gitlab.com/gomidi/midi/v2"
+)
+
+const MIDIClockPPQN = 24 // MIDI timing clock pulses per quarter note
+
+// ClockSync reports whether the clock follows incoming MIDI clock
+func (s *Synth) ClockSync() bool {
+	return s.Clock.External()
+}
+
+// SetClockSync makes the clock follow incoming MIDI clock, start and stop messages
+func (s *Synth) SetClockSync(enabled bool) {
+	s.Clock.SetExternal(enabled)
+}
+
+// ClockOut reports whether MIDI clock is sent to the output port
+func (s *Synth) ClockOut() bool {
+	return s.clockOut
+}
+
+// SetClockOut starts or stops sending MIDI clock, with a start or stop message for the receiver
+func (s *Synth) SetClockOut(enabled bool) {
+	if enabled == s.clockOut {
+		return
+	}
+	s.clockOut = enabled
+	if enabled {
+		s.MIDIOut.Send(midi.Start())
+	} else {
+		s.MIDIOut.Send(midi.Stop())
+	}
+}
+
+// sendClock emits a MIDI clock pulse on every ClockPPQN/MIDIClockPPQN clock ticks
+func (s *Synth) sendClock(tick uint64) {
+	if s.clockOut && tick%(ClockPPQN/MIDIClockPPQN) == 0 {
+		s.MIDIOut.Send(midi.TimingClock())
+	}
+}
+
+// handleClockMessage applies incoming MIDI clock and transport messages while synced,
+// returning false for messages it does not handle
+func (s *Synth) handleClockMessage(msg midi.Message) bool {
+	switch {
+	case msg.Is(midi.TimingClockMsg):
+		s.Clock.Pulse(MIDIClockPPQN)
+	case msg.Is(midi.StartMsg):
+		if s.ClockSync() {
+			s.Seq.Stop()
+			s.Clock.Reset()
+			s.Seq.Play()
+		}
+	case msg.Is(midi.ContinueMsg):
+		if s.ClockSync() {
+			s.Seq.Play()
+		}
+	case msg.Is(midi.StopMsg):
+		if s.ClockSync() {
+			s.Seq.Stop()
+		}
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,370 @@
+package synth
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gosynth/pkg/smf"
+)
+
+// MIDIFileDir returns the directory .mid files are browsed from
+func MIDIFileDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gosynth", "midi"), nil
+}
+
+// ListMIDIFiles returns the .mid files in the MIDI file directory, sorted
+func ListMIDIFiles() ([]string, error) {
+	dir, err := MIDIFileDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if ext := strings.ToLower(filepath.Ext(e.Name())); !e.IsDir() && (ext == ".mid" || ext == ".midi") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// patchFamilies are the words naming the sounds of the General MIDI program families,
+// eight programs to a family, looked for in preset names and in a file's track names
+var patchFamilies = [16][]string{
+	{"piano", "keys", "rhodes"},
+	{"bell", "mallet", "vibe", "marimba"},
+	{"organ"},
+	{"guitar", "pluck"},
+	{"bass"},
+	{"string", "violin", "cello"},
+	{"choir", "ensemble", "voice", "vox"},
+	{"brass", "trumpet", "horn"},
+	{"sax", "reed", "clarinet", "oboe"},
+	{"flute", "pipe", "whistle"},
+	{"lead"},
+	{"pad"},
+	{"fx", "atmos", "sweep"},
+	{"sitar", "koto", "ethnic"},
+	{"perc", "drum"},
+	{"sfx", "noise"},
+}
+
+// patchFamily returns the program family whose words a name contains, or false for none
+func patchFamily(name string) (int, bool) {
+	name = strings.ToLower(name)
+	for family, words := range patchFamilies {
+		for _, word := range words {
+			if strings.Contains(name, word) {
+				return family, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// familyPreset returns the first of the presets whose name places it in a program family
+func familyPreset(family int, presets []string) (string, bool) {
+	for _, name := range presets {
+		if f, ok := patchFamily(name); ok && f == family {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// fileTrack is a track of the loaded file that has notes
+type fileTrack struct {
+	name    string
+	channel uint8 // Channel of its first note
+}
+
+// playerNote is a note the MIDI file player started, for releasing it on stop
+type playerNote struct {
+	target *Synth // Synth playing the note, nil for a drum
+	note   uint8
+}
+
+// MIDIPlayer plays a Standard MIDI File through the synth as if its notes came from the
+// MIDI input. A file with one track of notes plays by channel, like the input: channels
+// of parts play the parts, drum channel notes the drums and the rest the main synth. A
+// file with several tracks of notes plays its first such track on the main synth and
+// each following one on the next part, whatever their channels, the drum channel aside.
+type MIDIPlayer struct {
+	s          *Synth
+	mapPatches atomic.Bool // Load presets for the file's program changes and track names
+
+	mu       sync.Mutex
+	name     string
+	timeline []smf.TimedEvent
+	length   time.Duration
+	tracks   map[int]int       // Order of each track among those with notes, when playing by track
+	names    map[int]fileTrack // Tracks with notes
+	running  bool
+	started  time.Time
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewMIDIPlayer creates a player with no file, playing through s and choosing its patches
+func NewMIDIPlayer(s *Synth) *MIDIPlayer {
+	p := &MIDIPlayer{s: s}
+	p.mapPatches.Store(true)
+	return p
+}
+
+// MapPatches reports whether playback loads presets for the file's program changes and
+// track names
+func (p *MIDIPlayer) MapPatches() bool {
+	return p.mapPatches.Load()
+}
+
+// SetMapPatches sets whether playback loads presets for the file's program changes and
+// track names. Each is taken to its General MIDI program family and the first preset
+// whose name has a word of that family, such as "bass" or "pad", is loaded into the
+// synth or part playing it; a family without such a preset leaves the patch as it is.
+func (p *MIDIPlayer) SetMapPatches(on bool) {
+	p.mapPatches.Store(on)
+}
+
+// Load stops playback and reads a .mid file; a name without a directory is looked up in
+// the MIDI file directory
+func (p *MIDIPlayer) Load(path string) error {
+	if filepath.Base(path) == path {
+		if dir, err := MIDIFileDir(); err == nil {
+			path = filepath.Join(dir, path)
+		}
+	}
+	f, err := smf.Load(path)
+	if err != nil {
+		return err
+	}
+	timeline := f.Timeline()
+	tracks := make(map[int]int)
+	names := make(map[int]fileTrack)
+	for _, ev := range timeline {
+		if _, ok := tracks[ev.Track]; !ok && ev.Message[0]&0xf0 == 0x90 {
+			tracks[ev.Track] = len(tracks)
+			names[ev.Track] = fileTrack{name: f.Tracks[ev.Track].Name, channel: ev.Message[0] & 0x0f}
+		}
+	}
+	if len(tracks) < 2 {
+		tracks = nil
+	}
+
+	p.Stop()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.name = filepath.Base(path)
+	p.timeline = timeline
+	p.tracks = tracks
+	p.names = names
+	p.length = 0
+	if len(timeline) > 0 {
+		p.length = timeline[len(timeline)-1].Time
+	}
+	return nil
+}
+
+// Name returns the file name of the loaded file, empty when none is loaded
+func (p *MIDIPlayer) Name() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.name
+}
+
+// Length returns the time from the start of the loaded file to its last event
+func (p *MIDIPlayer) Length() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.length
+}
+
+// Playing reports whether the file is playing
+func (p *MIDIPlayer) Playing() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running
+}
+
+// Position returns how far playback has got, 0 when stopped
+func (p *MIDIPlayer) Position() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.running {
+		return 0
+	}
+	return min(time.Since(p.started), p.length)
+}
+
+// Play starts the loaded file from the beginning
+func (p *MIDIPlayer) Play() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running || p.timeline == nil {
+		return
+	}
+	p.running = true
+	p.started = time.Now()
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run(p.timeline, p.tracks, p.names, p.started, p.stop, p.done)
+}
+
+// Stop halts playback and releases the notes it left sounding
+func (p *MIDIPlayer) Stop() {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return
+	}
+	stop, done := p.stop, p.done
+	p.mu.Unlock()
+
+	close(stop)
+	<-done
+}
+
+// run plays the timeline until its end or until stop is closed, first choosing the
+// patches the track names call for
+func (p *MIDIPlayer) run(timeline []smf.TimedEvent, tracks map[int]int, names map[int]fileTrack, start time.Time, stop <-chan struct{}, done chan<- struct{}) {
+	held := make(map[playerNote]bool)
+	sustained := make(map[*Synth]bool)
+	patched := make(map[*Synth]int)
+	defer func() {
+		for n := range held {
+			if n.target == nil {
+				p.s.DrumNoteOff(n.note)
+			} else {
+				n.target.NoteOff(n.note)
+			}
+		}
+		for target := range sustained {
+			target.SetSustain(false)
+		}
+		p.mu.Lock()
+		p.running = false
+		p.mu.Unlock()
+		close(done)
+	}()
+
+	if p.MapPatches() {
+		order := make([]int, 0, len(names))
+		for track := range names {
+			order = append(order, track)
+		}
+		sort.Ints(order) // The first track sharing a target chooses its patch
+		for _, track := range order {
+			t := names[track]
+			if family, ok := patchFamily(t.name); ok && t.channel != DrumChannel {
+				target, source := p.target(track, t.channel, tracks)
+				p.patch(target, source, family, patched)
+			}
+		}
+	}
+	for _, ev := range timeline {
+		if !waitUntil(start.Add(ev.Time), stop) {
+			return
+		}
+		p.send(ev, tracks, held, sustained, patched)
+	}
+}
+
+// target returns the synth or part playing a track's messages on a channel, and the
+// track or channel it takes them from
+func (p *MIDIPlayer) target(track int, channel uint8, tracks map[int]int) (*Synth, int) {
+	if tracks != nil {
+		if order := tracks[track]; order > 0 {
+			if parts := p.s.Parts(); order <= len(parts) {
+				return parts[order-1].Synth, track
+			}
+		}
+		return p.s, track
+	}
+	if part := p.s.partFor(channel); part != nil {
+		return part.Synth, int(channel)
+	}
+	return p.s, int(channel)
+}
+
+// patch loads the preset for a program family into target, unless another track or
+// channel of the file sharing target chose its patch first; patched records who did
+func (p *MIDIPlayer) patch(target *Synth, source, family int, patched map[*Synth]int) {
+	if owner, ok := patched[target]; ok && owner != source {
+		return
+	}
+	patched[target] = source
+	presets, err := ListPresets()
+	if err != nil {
+		return
+	}
+	if name, ok := familyPreset(family, presets); ok && name != target.PresetName() {
+		target.loadPreset(name) // Problems in the preset only skip its faulty fields
+	}
+}
+
+// send plays one message of the file, noting the notes and pedals it leaves down and
+// the patches its program changes choose
+func (p *MIDIPlayer) send(ev smf.TimedEvent, tracks map[int]int, held map[playerNote]bool, sustained map[*Synth]bool, patched map[*Synth]int) {
+	msg := ev.Message
+	if len(msg) == 2 && msg[0]&0xf0 == 0xc0 && msg[0]&0x0f != DrumChannel && p.MapPatches() {
+		target, source := p.target(ev.Track, msg[0]&0x0f, tracks)
+		p.patch(target, source, int(msg[1]/8), patched)
+		return
+	}
+	if len(msg) < 3 {
+		return
+	}
+	kind, channel, key, value := msg[0]&0xf0, msg[0]&0x0f, msg[1], msg[2]
+	on := kind == 0x90 && value > 0
+	off := kind == 0x80 || kind == 0x90 && value == 0
+
+	// Drum channel notes play the drums when they're on, as from the input
+	if channel == DrumChannel && (on || off) {
+		switch {
+		case p.s.DrumVoices() && DrumVoiceFor(key) >= 0:
+			if on {
+				p.s.PlayDrum(DrumVoiceFor(key), value)
+			}
+			return
+		case p.s.GMDrums():
+			if on {
+				p.s.DrumNoteOn(key, value)
+				held[playerNote{note: key}] = true
+			} else if held[playerNote{note: key}] {
+				p.s.DrumNoteOff(key)
+				delete(held, playerNote{note: key})
+			}
+			return
+		}
+	}
+
+	target, _ := p.target(ev.Track, channel, tracks)
+	note := playerNote{target: target, note: key}
+	switch {
+	case on:
+		target.NoteOn(key, value)
+		held[note] = true
+	case off && held[note]:
+		target.NoteOff(key)
+		delete(held, note)
+	case kind == 0xb0 && key == SustainCC:
+		target.SetSustain(value >= 64)
+		sustained[target] = value >= 64
+	}
+}
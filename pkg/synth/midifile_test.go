@@ -0,0 +1,65 @@
+package synth
+
+import (
+	"testing"
+
+	"gosynth/pkg/smf"
+)
+
+// TestPatchFamily checks that preset and track names are placed in the General MIDI
+// program family their words name
+func TestPatchFamily(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		family int
+		ok     bool
+	}{
+		{"Fretless Bass", 4, true},
+		{"warm pad", 11, true},
+		{"Lead 1", 10, true},
+		{"Strings", 5, true},
+		{"Grand Piano", 0, true},
+		{"Track 3", 0, false},
+	} {
+		family, ok := patchFamily(tc.name)
+		if ok != tc.ok || ok && family != tc.family {
+			t.Errorf("patchFamily(%q) = %d, %v, want %d, %v", tc.name, family, ok, tc.family, tc.ok)
+		}
+	}
+	if name, ok := familyPreset(4, []string{"init", "soft pad", "sub bass", "warm bass"}); !ok || name != "sub bass" {
+		t.Errorf("familyPreset(bass) = %q, %v, want the first bass preset", name, ok)
+	}
+}
+
+// TestPlayerProgramChange checks that a program change in a file loads a preset of its
+// program's family, and that a second channel sharing the synth doesn't take it over
+func TestPlayerProgramChange(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	s := NewSynth()
+	for _, name := range []string{"init", "soft pad", "warm bass"} {
+		if err := s.SavePreset(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.setPresetName("init")
+
+	patched := make(map[*Synth]int)
+	send := func(channel, program uint8) {
+		s.Player.send(smf.TimedEvent{Message: []byte{0xc0 | channel, program}}, nil, nil, nil, patched)
+	}
+	send(0, 33) // Electric bass
+	if got := s.PresetName(); got != "warm bass" {
+		t.Errorf("program 33 loaded %q, want warm bass", got)
+	}
+	send(1, 89) // Warm pad, on another channel playing the same synth
+	if got := s.PresetName(); got != "warm bass" {
+		t.Errorf("second channel's program loaded %q over the first's", got)
+	}
+
+	s.Player.SetMapPatches(false)
+	patched = make(map[*Synth]int)
+	send(0, 89)
+	if got := s.PresetName(); got != "warm bass" {
+		t.Errorf("program change loaded %q with patch mapping off", got)
+	}
+}
@@ -0,0 +1,183 @@
+package synth
+
+import (
+	"sync"
+
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/drivers"
+)
+
+const (
+	SplitLowNote  = 0  // Default lowest note sent to external gear
+	SplitHighNote = 47 // Default highest note sent to external gear (B2)
+)
+
+// MIDIOut sends note messages to an external MIDI output port
+type MIDIOut struct {
+	mu   sync.Mutex
+	port drivers.Out
+	send func(msg midi.Message) error
+}
+
+// Open connects to the output port with the given number
+func (o *MIDIOut) Open(portNumber int) error {
+	port, err := midi.OutPort(portNumber)
+	if err != nil {
+		return err
+	}
+	return o.open(port)
+}
+
+// OpenNamed connects to the output port whose name contains the given name
+func (o *MIDIOut) OpenNamed(name string) error {
+	port, err := midi.FindOutPort(name)
+	if err != nil {
+		return err
+	}
+	return o.open(port)
+}
+
+// open starts sending to a port, closing the one sent to before
+func (o *MIDIOut) open(port drivers.Out) error {
+	send, err := midi.SendTo(port)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.port != nil && o.port != port {
+		o.port.Close()
+	}
+	o.port = port
+	o.send = send
+	return nil
+}
+
+// Connected reports whether an output port is open
+func (o *MIDIOut) Connected() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.send != nil
+}
+
+// Name returns the name of the open output port
+func (o *MIDIOut) Name() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.port == nil {
+		return ""
+	}
+	return o.port.String()
+}
+
+// Send writes a message to the output port, ignoring it when no port is open
+func (o *MIDIOut) Send(msg midi.Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.send != nil {
+		o.send(msg)
+	}
+}
+
+// Close disconnects from the output port
+func (o *MIDIOut) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.port != nil {
+		o.port.Close()
+	}
+	o.port = nil
+	o.send = nil
+}
+
+// Split routes a key range to the MIDI output instead of the internal engine
+type Split struct {
+	mu       sync.Mutex
+	enabled  bool
+	low      uint8           // Lowest note routed to the output
+	high     uint8           // Highest note routed to the output
+	channel  uint8           // Output MIDI channel, 0-15
+	external map[uint8]uint8 // Channels of notes started on the output, so their note-offs follow
+}
+
+// NewSplit creates a disabled split covering the default bass range
+func NewSplit() *Split {
+	return &Split{
+		low:      SplitLowNote,
+		high:     SplitHighNote,
+		external: make(map[uint8]uint8),
+	}
+}
+
+// Enabled reports whether the key range is routed to the output
+func (sp *Split) Enabled() bool {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.enabled
+}
+
+// SetEnabled switches routing the key range to the output on or off
+func (sp *Split) SetEnabled(on bool) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.enabled = on
+}
+
+// Range returns the lowest and highest notes routed to the output
+func (sp *Split) Range() (low, high uint8) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.low, sp.high
+}
+
+// SetRange sets the notes routed to the output, keeping the highest at or above the lowest
+func (sp *Split) SetRange(low, high uint8) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.low = min(low, 127)
+	sp.high = max(sp.low, min(high, 127))
+}
+
+// Channel returns the output MIDI channel, 0-15
+func (sp *Split) Channel() uint8 {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.channel
+}
+
+// SetChannel sets the output MIDI channel, 0-15
+func (sp *Split) SetChannel(channel uint8) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.channel = min(channel, 15)
+}
+
+// noteOn reports whether a note belongs to the split, and on which channel, recording it
+// as external
+func (sp *Split) noteOn(note uint8) (uint8, bool) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if !sp.enabled || note < sp.low || note > sp.high {
+		return 0, false
+	}
+	sp.external[note] = sp.channel
+	return sp.channel, true
+}
+
+// noteOff reports whether a released note was started on the output, and on which channel
+func (sp *Split) noteOff(note uint8) (uint8, bool) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	channel, ok := sp.external[note]
+	if ok {
+		delete(sp.external, note)
+	}
+	return channel, ok
+}
+
+// clear forgets the notes started on the output
+func (sp *Split) clear() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	clear(sp.external)
+}
@@ -0,0 +1,127 @@
+package synth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"gosynth/pkg/smf"
+)
+
+const RecordDivision = 480 // Ticks per quarter note of recorded MIDI files
+
+// Tracks of a recorded MIDI file after its tempo track
+const (
+	recordInput     = iota // Messages from the MIDI input ports
+	recordSequencer        // Notes the sequencer played
+	recordTracks
+)
+
+// recordTrackNames are the names of the recorded tracks
+var recordTrackNames = [recordTracks]string{"Input", "Sequencer"}
+
+// MIDIRecorder captures the notes and controllers played into the MIDI input, and the
+// sequencer's notes, with their times, and saves them as a Standard MIDI File so a
+// performance can be edited in a DAW. Times are placed at the tempo recording started at.
+type MIDIRecorder struct {
+	mu     sync.Mutex
+	path   string // File being recorded, empty when not recording
+	start  time.Time
+	bpm    float64
+	tracks [recordTracks][]smf.Event
+	held   [recordTracks]map[[2]byte]bool // Channel and key of notes sounding, released at the end
+}
+
+// StartMIDIRecording begins capturing a performance to be saved at path
+func (s *Synth) StartMIDIRecording(path string) error {
+	r := s.MIDIRec
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.path != "" {
+		return errors.New("already recording MIDI")
+	}
+	r.path = path
+	r.start = time.Now()
+	r.bpm = s.Clock.BPM.Get()
+	for i := range r.tracks {
+		r.tracks[i] = nil
+		r.held[i] = make(map[[2]byte]bool)
+	}
+	return nil
+}
+
+// Recording reports whether a performance is being captured
+func (r *MIDIRecorder) Recording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.path != ""
+}
+
+// record adds a channel message to a track, at the time since recording started
+func (r *MIDIRecorder) record(track int, msg []byte) {
+	if len(msg) == 0 || msg[0] < 0x80 || msg[0] >= 0xf0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.path == "" {
+		return
+	}
+	r.tracks[track] = append(r.tracks[track], smf.Event{Tick: r.tick(time.Now()), Message: append([]byte(nil), msg...)})
+	if kind := msg[0] & 0xf0; len(msg) == 3 && (kind == 0x80 || kind == 0x90) {
+		note := [2]byte{msg[0] & 0x0f, msg[1]}
+		if kind == 0x90 && msg[2] > 0 {
+			r.held[track][note] = true
+		} else {
+			delete(r.held[track], note)
+		}
+	}
+}
+
+// tick returns the file position of a time, with the lock held
+func (r *MIDIRecorder) tick(t time.Time) uint64 {
+	return uint64(t.Sub(r.start).Seconds() * r.bpm / 60 * RecordDivision)
+}
+
+// recordNoteOn returns the sequencer's note start through a route, recording the note on
+// the route's channel
+func (s *Synth) recordNoteOn(r *NoteRoute) func(note, velocity uint8) {
+	play := s.partNoteOn(r)
+	return func(note, velocity uint8) {
+		s.MIDIRec.record(recordSequencer, []byte{0x90 | r.Channel(), note, velocity})
+		play(note, velocity)
+	}
+}
+
+// recordNoteOff returns the sequencer's note release through a route, recording it
+func (s *Synth) recordNoteOff(r *NoteRoute) func(note uint8) {
+	release := s.partNoteOff(r)
+	return func(note uint8) {
+		s.MIDIRec.record(recordSequencer, []byte{0x80 | r.Channel(), note, 0})
+		release(note)
+	}
+}
+
+// StopMIDIRecording ends the capture and saves the file, with the notes still held
+// released at the end. It returns the path saved, empty when nothing was recording.
+func (s *Synth) StopMIDIRecording() (string, error) {
+	r := s.MIDIRec
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.path == "" {
+		return "", nil
+	}
+	path := r.path
+	r.path = ""
+
+	end := r.tick(time.Now())
+	f := &smf.File{Format: 1, Division: RecordDivision}
+	f.Tracks = append(f.Tracks, smf.Track{Name: "gosynth", Events: []smf.Event{smf.TempoEvent(0, r.bpm)}})
+	for i, events := range r.tracks {
+		for note := range r.held[i] {
+			events = append(events, smf.Event{Tick: end, Message: []byte{0x80 | note[0], note[1], 0}})
+		}
+		f.Tracks = append(f.Tracks, smf.Track{Name: recordTrackNames[i], Events: events})
+	}
+	return path, smf.Save(path, f)
+}
@@ -0,0 +1,152 @@
+package synth
+
+import (
+	"errors"
+	"slices"
+
+	"gosynth/pkg/engine"
+)
+
+const MaxParts = 4 // Parts that can play besides the main synth
+
+// Part is a multitimbral part: a synth of its own, loaded from a preset and played from
+// one MIDI channel, mixed into the main synth's master bus with its level and pan.
+// Notes on channels no part plays go to the main synth.
+type Part struct {
+	*engine.Timbre
+	Synth   *Synth
+	Channel uint8 // MIDI channel the part plays from, 0-15
+}
+
+// PartState is a part as saved with the session
+type PartState struct {
+	Preset  string  `json:"preset"`
+	Channel uint8   `json:"channel"`
+	Level   float64 `json:"level"`
+	Pan     float64 `json:"pan"`
+}
+
+// AddPart adds a part with the default sound on the first channel no other part plays
+func (s *Synth) AddPart() (*Part, error) {
+	s.partsMu.Lock()
+	defer s.partsMu.Unlock()
+	if len(s.parts) >= MaxParts {
+		return nil, errors.New("no more parts")
+	}
+	part := newPart()
+	part.Synth.Tuning.SetReference(s.Tuning.Reference())
+	for channel := uint8(1); channel < 16; channel++ {
+		if !slices.ContainsFunc(s.parts, func(p *Part) bool { return p.Channel == channel }) {
+			part.Channel = channel
+			break
+		}
+	}
+	s.parts = append(s.parts, part)
+	s.updateTimbres()
+	return part, nil
+}
+
+// newPart creates a part that is silent until played
+func newPart() *Part {
+	ps := NewSynth()
+	ps.SetDrone(false)
+	return &Part{Timbre: engine.NewTimbre(ps.Engine), Synth: ps}
+}
+
+// SetReference tunes the main synth and every part to a frequency of A4
+func (s *Synth) SetReference(hz float64) {
+	s.partsMu.Lock()
+	defer s.partsMu.Unlock()
+	s.Tuning.SetReference(hz)
+	for _, p := range s.parts {
+		p.Synth.Tuning.SetReference(hz)
+	}
+}
+
+// RemovePart removes the last part added
+func (s *Synth) RemovePart() {
+	s.partsMu.Lock()
+	defer s.partsMu.Unlock()
+	if len(s.parts) > 0 {
+		s.parts = s.parts[:len(s.parts)-1]
+		s.updateTimbres()
+	}
+}
+
+// Parts returns the parts besides the main synth
+func (s *Synth) Parts() []*Part {
+	s.partsMu.Lock()
+	defer s.partsMu.Unlock()
+	return append([]*Part(nil), s.parts...)
+}
+
+// SetPartChannel moves a part to another MIDI channel, 0-15
+func (s *Synth) SetPartChannel(p *Part, channel uint8) {
+	s.partsMu.Lock()
+	defer s.partsMu.Unlock()
+	p.Channel = min(channel, 15)
+}
+
+// PartChannel returns the MIDI channel a part plays from
+func (s *Synth) PartChannel(p *Part) uint8 {
+	s.partsMu.Lock()
+	defer s.partsMu.Unlock()
+	return p.Channel
+}
+
+// updateTimbres hands the parts to the audio callback; s.partsMu must be held
+func (s *Synth) updateTimbres() {
+	timbres := make([]*engine.Timbre, len(s.parts))
+	for i, p := range s.parts {
+		timbres[i] = p.Timbre
+	}
+	s.SetTimbres(timbres)
+}
+
+// partFor returns the part playing a MIDI channel, or nil for the main synth
+func (s *Synth) partFor(channel uint8) *Part {
+	s.partsMu.Lock()
+	defer s.partsMu.Unlock()
+	for _, p := range s.parts {
+		if p.Channel == channel {
+			return p
+		}
+	}
+	return nil
+}
+
+// PartStates returns the parts for saving with the session
+func (s *Synth) PartStates() []PartState {
+	var states []PartState
+	for _, p := range s.Parts() {
+		states = append(states, PartState{
+			Preset:  p.Synth.PresetName(),
+			Channel: s.PartChannel(p),
+			Level:   p.Level.Get(),
+			Pan:     p.Pan.Get(),
+		})
+	}
+	return states
+}
+
+// SetPartStates replaces the parts with saved ones, loading their presets at once. A
+// preset that can't be loaded leaves its part with the default sound.
+func (s *Synth) SetPartStates(states []PartState) {
+	var parts []*Part
+	for _, st := range states[:min(len(states), MaxParts)] {
+		p := newPart()
+		p.Channel = min(st.Channel, 15)
+		p.Level.Set(max(0, min(st.Level, 1)))
+		p.Pan.Set(max(-1, min(st.Pan, 1)))
+		if st.Preset != "" && st.Preset != DefaultPresetName {
+			p.Synth.PresetFade.Set(0)
+			p.Synth.LoadPreset(st.Preset)
+			p.Synth.PresetFade.Set(engine.PresetFadeTime)
+		}
+		parts = append(parts, p)
+	}
+	s.partsMu.Lock()
+	defer s.partsMu.Unlock()
+	s.parts = parts
+	s.updateTimbres()
+}
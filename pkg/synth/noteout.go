@@ -0,0 +1,138 @@
+package synth
+
+import (
+	"errors"
+	"sync"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// NoteDest is where the sequencer or arpeggiator plays its notes
+type NoteDest int
+
+const (
+	DestInternal NoteDest = iota // The synth's own voices
+	DestExternal                 // The MIDI output port
+	DestBoth                     // The voices and the output port together
+	noteDestCount
+)
+
+func (d NoteDest) String() string {
+	switch d {
+	case DestInternal:
+		return "internal"
+	case DestExternal:
+		return "MIDI out"
+	case DestBoth:
+		return "internal + MIDI out"
+	}
+	return "unknown"
+}
+
+// Next returns the following destination, wrapping around
+func (d NoteDest) Next(dir int) NoteDest {
+	return NoteDest((int(d) + dir + int(noteDestCount)) % int(noteDestCount))
+}
+
+// NoteRoute sends a part's notes to the voices, the MIDI output or both. It remembers
+// the notes sent out, so they're released there even after the destination changes.
+type NoteRoute struct {
+	mu      sync.Mutex
+	dest    NoteDest
+	channel uint8           // Output MIDI channel, 0-15
+	sent    map[uint8]uint8 // Channel each note still held on the output was sent on
+}
+
+// NewNoteRoute creates a route to the internal voices on the first channel
+func NewNoteRoute() *NoteRoute {
+	return &NoteRoute{sent: make(map[uint8]uint8)}
+}
+
+// Dest returns where the notes are played
+func (r *NoteRoute) Dest() NoteDest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dest
+}
+
+// SetDest sets where the notes are played; notes already sent out are still released there
+func (r *NoteRoute) SetDest(dest NoteDest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dest = dest.Next(0)
+}
+
+// Channel returns the output MIDI channel, 0-15
+func (r *NoteRoute) Channel() uint8 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.channel
+}
+
+// SetChannel sets the output MIDI channel, 0-15
+func (r *NoteRoute) SetChannel(channel uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.channel = min(channel, 15)
+}
+
+// partNoteOn returns the note start of a part, played through its route
+func (s *Synth) partNoteOn(r *NoteRoute) func(note, velocity uint8) {
+	return func(note, velocity uint8) {
+		r.mu.Lock()
+		dest, channel := r.dest, r.channel
+		if dest != DestInternal {
+			r.sent[note] = channel
+		}
+		r.mu.Unlock()
+		if dest != DestExternal {
+			s.playNoteOn(note, velocity)
+		}
+		if dest != DestInternal {
+			s.MIDIOut.Send(midi.NoteOn(channel, note, velocity))
+		}
+	}
+}
+
+// partNoteOff returns the note release of a part. The voices are always released, which is
+// harmless for a note they weren't playing; the output only gets releases of notes sent to it.
+func (s *Synth) partNoteOff(r *NoteRoute) func(note uint8) {
+	return func(note uint8) {
+		s.playNoteOff(note)
+		r.mu.Lock()
+		channel, ok := r.sent[note]
+		delete(r.sent, note)
+		r.mu.Unlock()
+		if ok {
+			s.MIDIOut.Send(midi.NoteOff(channel, note))
+		}
+	}
+}
+
+// OpenNext switches to the output port before or after the open one, wrapping around
+func (o *MIDIOut) OpenNext(dir int) error {
+	ports := midi.GetOutPorts()
+	if len(ports) == 0 {
+		return errors.New("no MIDI output ports")
+	}
+	current := -1
+	name := o.Name()
+	for i, port := range ports {
+		if port.String() == name {
+			current = i
+		}
+	}
+	next := (current + dir + len(ports)) % len(ports)
+	if current < 0 && dir < 0 {
+		next = len(ports) - 1
+	}
+	o.Close()
+	return o.open(ports[next])
+}
+
+// clear forgets the notes sent to the output
+func (r *NoteRoute) clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.sent)
+}
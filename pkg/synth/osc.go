@@ -0,0 +1,318 @@
+package synth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync"
+
+	"gosynth/pkg/engine"
+)
+
+const (
+	OSCPrefix       = "/synth/" // Address prefix of every message the server handles
+	OSCNoteAddress  = "/synth/note"
+	OSCPanicAddress = "/synth/panic"
+	OSCPlayAddress  = "/synth/playing" // Transport state mirrored to clients, 1 while playing
+	RemoteVelocity  = 100              // Velocity of notes sent over OSC or HTTP without one
+	oscPacketSize   = 4096             // Largest UDP packet read
+	oscMaxClients   = 8                // Senders the mirror replies to, the most recent kept
+)
+
+// oscMessage is a decoded OSC message with its numeric arguments
+type oscMessage struct {
+	Address string
+	Args    []float64
+}
+
+// OSCServer listens for OSC messages over UDP: /synth/<param> with a value sets a preset
+// parameter, /synth/note with a note and velocity plays it, a velocity of 0 releasing it,
+// and /synth/panic releases every note. Parameter and transport changes on the engine's
+// bus are mirrored back to the addresses messages last came from, so a control surface
+// follows changes made elsewhere.
+type OSCServer struct {
+	conn     net.PacketConn
+	done     chan struct{}
+	stop     chan struct{} // Closed to end the mirror
+	mirrored chan struct{}
+
+	mu      sync.Mutex
+	clients []net.Addr // Recent senders, oldest first
+}
+
+// StartOSC listens for OSC messages on a UDP address such as ":9000"; an empty address
+// leaves the server stopped. A running server is stopped first.
+func (s *Synth) StartOSC(addr string) error {
+	s.StopOSC()
+	if addr == "" {
+		return nil
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	s.osc = &OSCServer{conn: conn, done: make(chan struct{}), stop: make(chan struct{}), mirrored: make(chan struct{})}
+	events, unsubscribe := s.Bus.Subscribe()
+	go s.serveOSC(s.osc)
+	go s.osc.mirror(events, unsubscribe)
+	return nil
+}
+
+// StopOSC stops the OSC server, if running
+func (s *Synth) StopOSC() {
+	if s.osc == nil {
+		return
+	}
+	close(s.osc.stop)
+	s.osc.conn.Close()
+	<-s.osc.done
+	<-s.osc.mirrored
+	s.osc = nil
+}
+
+// OSCAddr returns the address the OSC server listens on, empty when stopped
+func (s *Synth) OSCAddr() string {
+	if s.osc == nil {
+		return ""
+	}
+	return s.osc.conn.LocalAddr().String()
+}
+
+// serveOSC handles packets until the connection is closed. Packets that can't be decoded
+// and messages for unknown addresses are ignored.
+func (s *Synth) serveOSC(server *OSCServer) {
+	defer close(server.done)
+	buf := make([]byte, oscPacketSize)
+	for {
+		n, from, err := server.conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		messages, err := decodeOSC(buf[:n])
+		if err != nil {
+			continue
+		}
+		server.remember(from)
+		for _, msg := range messages {
+			s.handleOSC(msg)
+		}
+	}
+}
+
+// handleOSC applies one message
+func (s *Synth) handleOSC(msg oscMessage) {
+	if msg.Address == OSCPanicAddress {
+		s.Panic()
+		return
+	}
+	if msg.Address == OSCNoteAddress {
+		if len(msg.Args) == 0 || !(msg.Args[0] >= 0 && msg.Args[0] <= 127) {
+			return
+		}
+		note, velocity := uint8(msg.Args[0]), float64(RemoteVelocity)
+		if len(msg.Args) > 1 {
+			if math.IsNaN(msg.Args[1]) {
+				return
+			}
+			velocity = math.Max(0, math.Min(msg.Args[1], 127))
+		}
+		if velocity < 1 {
+			s.NoteOff(note)
+		} else {
+			s.NoteOn(note, uint8(velocity))
+		}
+		return
+	}
+	if name, ok := strings.CutPrefix(msg.Address, OSCPrefix); ok && len(msg.Args) > 0 {
+		s.SetParam(name, msg.Args[0])
+	}
+}
+
+// remember adds a sender to the clients the mirror replies to, dropping the oldest
+// beyond oscMaxClients
+func (server *OSCServer) remember(addr net.Addr) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	for i, client := range server.clients {
+		if client.String() == addr.String() {
+			server.clients = append(server.clients[:i], server.clients[i+1:]...)
+			break
+		}
+	}
+	server.clients = append(server.clients, addr)
+	if len(server.clients) > oscMaxClients {
+		server.clients = server.clients[1:]
+	}
+}
+
+// mirror sends parameter and transport changes from the bus to the clients until the
+// server stops
+func (server *OSCServer) mirror(events <-chan engine.Event, unsubscribe func()) {
+	defer close(server.mirrored)
+	defer unsubscribe()
+	for {
+		var packet []byte
+		select {
+		case <-server.stop:
+			return
+		case ev := <-events:
+			switch ev.Kind {
+			case engine.EventParam:
+				packet = encodeOSC(OSCPrefix+ev.Param, float32(ev.Value))
+			case engine.EventTransport:
+				playing := float32(0)
+				if ev.Playing {
+					playing = 1
+				}
+				packet = encodeOSC(OSCPlayAddress, playing)
+			default:
+				continue
+			}
+		}
+		server.mu.Lock()
+		clients := append([]net.Addr(nil), server.clients...)
+		server.mu.Unlock()
+		for _, client := range clients {
+			server.conn.WriteTo(packet, client)
+		}
+	}
+}
+
+// encodeOSC builds a message with one float argument
+func encodeOSC(address string, value float32) []byte {
+	packet := appendOSCString(nil, address)
+	packet = appendOSCString(packet, ",f")
+	return binary.BigEndian.AppendUint32(packet, math.Float32bits(value))
+}
+
+// appendOSCString appends a null-terminated string padded to a multiple of four bytes
+func appendOSCString(b []byte, s string) []byte {
+	b = append(b, s...)
+	return append(b, make([]byte, 4-len(s)%4)...)
+}
+
+// decodeOSC reads a packet holding a message or a bundle of them, nested bundles included
+func decodeOSC(packet []byte) ([]oscMessage, error) {
+	r := bytes.NewReader(packet)
+	head, err := oscString(r)
+	if err != nil {
+		return nil, err
+	}
+	if head != "#bundle" {
+		msg, err := oscArgs(r, head)
+		return []oscMessage{msg}, err
+	}
+
+	// Bundle elements are applied at once, whatever their time tag
+	if r.Len() < 8 {
+		return nil, errors.New("osc: bundle without a time tag")
+	}
+	r.Seek(8, io.SeekCurrent)
+	var messages []oscMessage
+	for r.Len() > 0 {
+		var size int32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size < 0 || int(size) > r.Len() {
+			return nil, errors.New("osc: bundle element overruns the packet")
+		}
+		element := make([]byte, size)
+		r.Read(element)
+		inner, err := decodeOSC(element)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, inner...)
+	}
+	return messages, nil
+}
+
+// oscArgs reads the type tags and arguments of a message. Numbers and booleans are
+// kept; strings and blobs are skipped.
+func oscArgs(r *bytes.Reader, address string) (oscMessage, error) {
+	msg := oscMessage{Address: address}
+	if r.Len() == 0 {
+		return msg, nil // Old senders may leave out the type tags of a message without arguments
+	}
+	tags, err := oscString(r)
+	if err != nil {
+		return msg, err
+	}
+	if !strings.HasPrefix(tags, ",") {
+		return msg, fmt.Errorf("osc: bad type tags %q", tags)
+	}
+	for _, tag := range tags[1:] {
+		switch tag {
+		case 'f':
+			var v float32
+			err = binary.Read(r, binary.BigEndian, &v)
+			msg.Args = append(msg.Args, float64(v))
+		case 'i':
+			var v int32
+			err = binary.Read(r, binary.BigEndian, &v)
+			msg.Args = append(msg.Args, float64(v))
+		case 'd':
+			var v float64
+			err = binary.Read(r, binary.BigEndian, &v)
+			msg.Args = append(msg.Args, v)
+		case 'h':
+			var v int64
+			err = binary.Read(r, binary.BigEndian, &v)
+			msg.Args = append(msg.Args, float64(v))
+		case 'T':
+			msg.Args = append(msg.Args, 1)
+		case 'F':
+			msg.Args = append(msg.Args, 0)
+		case 's', 'S':
+			_, err = oscString(r)
+		case 'b':
+			var size int32
+			if err = binary.Read(r, binary.BigEndian, &size); err != nil {
+				break
+			}
+			padded := (int64(size) + 3) &^ 3
+			if size < 0 || padded > int64(r.Len()) {
+				return msg, errors.New("osc: blob overruns the message")
+			}
+			r.Seek(padded, io.SeekCurrent)
+		case 'N', 'I':
+		default:
+			return msg, fmt.Errorf("osc: unsupported argument type %q", tag)
+		}
+		if err != nil {
+			return msg, err
+		}
+	}
+	return msg, nil
+}
+
+// oscString reads a null-terminated string padded to a multiple of four bytes
+func oscString(r *bytes.Reader) (string, error) {
+	var b strings.Builder
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", errors.New("osc: unterminated string")
+		}
+		if c == 0 {
+			break
+		}
+		b.WriteByte(c)
+	}
+	// The terminator and padding take the string to the next multiple of four
+	for pad := 3 - b.Len()%4; pad > 0; pad-- {
+		if _, err := r.ReadByte(); err != nil {
+			return "", errors.New("osc: unpadded string")
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,158 @@
+package synth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// oscPacket joins the parts of a packet built by a test
+func oscPacket(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// oscStr encodes a padded OSC string
+func oscStr(s string) []byte {
+	return appendOSCString(nil, s)
+}
+
+// oscInt encodes a big-endian 32-bit integer, as used for int arguments and sizes
+func oscInt(v int32) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(v))
+}
+
+// oscBundle wraps elements in a bundle with an immediate time tag
+func oscBundle(elements ...[]byte) []byte {
+	packet := oscPacket(oscStr("#bundle"), make([]byte, 8))
+	for _, e := range elements {
+		packet = append(packet, oscInt(int32(len(e)))...)
+		packet = append(packet, e...)
+	}
+	return packet
+}
+
+// TestOSCString checks the padding of strings to four bytes, the terminator included
+func TestOSCString(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		data   []byte
+		want   string
+		rest   int // Bytes left after the string
+		hasErr bool
+	}{
+		{"empty", []byte{0, 0, 0, 0}, "", 0, false},
+		{"three letters", []byte("abc\x00"), "abc", 0, false},
+		{"four letters", []byte("abcd\x00\x00\x00\x00"), "abcd", 0, false},
+		{"one letter", []byte("a\x00\x00\x00next"), "a", 4, false},
+		{"unterminated", []byte("abcd"), "", 0, true},
+		{"unpadded", []byte("ab\x00"), "", 0, true},
+		{"no data", nil, "", 0, true},
+	} {
+		r := bytes.NewReader(tc.data)
+		got, err := oscString(r)
+		if (err != nil) != tc.hasErr {
+			t.Errorf("%s: error %v, want error %v", tc.name, err, tc.hasErr)
+			continue
+		}
+		if !tc.hasErr && (got != tc.want || r.Len() != tc.rest) {
+			t.Errorf("%s: got %q with %d bytes left, want %q with %d", tc.name, got, r.Len(), tc.want, tc.rest)
+		}
+	}
+}
+
+// TestOSCArgs checks the argument types a message can carry, and the malformed ones
+func TestOSCArgs(t *testing.T) {
+	float := binary.BigEndian.AppendUint32(nil, math.Float32bits(0.5))
+	double := binary.BigEndian.AppendUint64(nil, math.Float64bits(-2))
+	for _, tc := range []struct {
+		name   string
+		data   []byte
+		want   []float64
+		hasErr bool
+	}{
+		{"missing type tags", nil, nil, false},
+		{"no arguments", oscStr(","), nil, false},
+		{"float", oscPacket(oscStr(",f"), float), []float64{0.5}, false},
+		{"int and double", oscPacket(oscStr(",id"), oscInt(-7), double), []float64{-7, -2}, false},
+		{"booleans", oscStr(",TFNI"), []float64{1, 0}, false},
+		{"string skipped", oscPacket(oscStr(",sf"), oscStr("hello"), float), []float64{0.5}, false},
+		{"blob skipped", oscPacket(oscStr(",bf"), oscInt(3), []byte{1, 2, 3, 0}, float), []float64{0.5}, false},
+		{"tags without comma", oscStr("f"), nil, true},
+		{"unsupported type", oscStr(",c"), nil, true},
+		{"truncated float", oscPacket(oscStr(",f"), []byte{0, 0}), nil, true},
+		{"missing argument", oscStr(",ii"), nil, true},
+		{"negative blob size", oscPacket(oscStr(",bf"), oscInt(-8), float), nil, true},
+		{"blob overruns the packet", oscPacket(oscStr(",b"), oscInt(64), []byte{1, 2, 3, 4}), nil, true},
+	} {
+		msg, err := oscArgs(bytes.NewReader(tc.data), "/synth/volume")
+		if (err != nil) != tc.hasErr {
+			t.Errorf("%s: error %v, want error %v", tc.name, err, tc.hasErr)
+			continue
+		}
+		if !tc.hasErr && !reflect.DeepEqual(msg.Args, tc.want) {
+			t.Errorf("%s: args %v, want %v", tc.name, msg.Args, tc.want)
+		}
+	}
+}
+
+// TestDecodeOSC checks messages and bundles, nested ones included, and bundles whose
+// sizes don't match their contents
+func TestDecodeOSC(t *testing.T) {
+	volume := encodeOSC("/synth/volume", 0.5)
+	note := oscPacket(oscStr("/synth/note"), oscStr(",ii"), oscInt(60), oscInt(100))
+	for _, tc := range []struct {
+		name   string
+		packet []byte
+		want   []oscMessage
+		hasErr bool
+	}{
+		{"message", volume, []oscMessage{{"/synth/volume", []float64{0.5}}}, false},
+		{"panic without type tags", oscStr("/synth/panic"), []oscMessage{{Address: "/synth/panic"}}, false},
+		{"bundle", oscBundle(volume, note), []oscMessage{{"/synth/volume", []float64{0.5}}, {"/synth/note", []float64{60, 100}}}, false},
+		{"nested bundle", oscBundle(note, oscBundle(volume)), []oscMessage{{"/synth/note", []float64{60, 100}}, {"/synth/volume", []float64{0.5}}}, false},
+		{"empty bundle", oscBundle(), nil, false},
+		{"empty packet", nil, nil, true},
+		{"bundle without time tag", oscStr("#bundle"), nil, true},
+		{"element overruns the bundle", oscPacket(oscStr("#bundle"), make([]byte, 8), oscInt(64), volume), nil, true},
+		{"negative element size", oscPacket(oscStr("#bundle"), make([]byte, 8), oscInt(-4), volume), nil, true},
+		{"truncated element size", oscPacket(oscStr("#bundle"), make([]byte, 8), []byte{0, 0}), nil, true},
+		{"bad element", oscBundle(volume, []byte("/bad")), nil, true},
+	} {
+		got, err := decodeOSC(tc.packet)
+		if (err != nil) != tc.hasErr {
+			t.Errorf("%s: error %v, want error %v", tc.name, err, tc.hasErr)
+			continue
+		}
+		if !tc.hasErr && !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: decoded %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestHandleOSCNaN checks that arguments which aren't numbers are dropped, so they can't
+// play a note or leave a parameter stuck
+func TestHandleOSCNaN(t *testing.T) {
+	s := NewSynth()
+	s.Volume.Set(0.5)
+	for _, msg := range []oscMessage{
+		{OSCPrefix + "volume", []float64{math.NaN()}},
+		{OSCPrefix + "volume", []float64{math.Inf(1)}},
+		{OSCNoteAddress, []float64{math.NaN(), 100}},
+		{OSCNoteAddress, []float64{60, math.NaN()}},
+	} {
+		s.handleOSC(msg)
+	}
+	if v := s.Volume.Get(); v != 0.5 {
+		t.Errorf("volume %g after values that aren't numbers, want 0.5", v)
+	}
+	if n := s.Stats.Session().Notes; n != 0 {
+		t.Errorf("%d notes played by values that aren't numbers, want none", n)
+	}
+
+	s.handleOSC(oscMessage{OSCPrefix + "volume", []float64{0.25}})
+	if v := s.Volume.Update(); v != 0.25 {
+		t.Errorf("volume %g after a good value, want 0.25", v)
+	}
+}
@@ -0,0 +1,48 @@
+package synth
+
+import "gitlab.com/gomidi/midi/v2"
+
+const (
+	AllSoundOffCC      = 120 // Channel mode message that silences a channel
+	ResetControllersCC = 121 // Channel mode message that resets a channel's controllers
+	AllNotesOffCC      = 123 // Channel mode message that releases a channel's notes
+)
+
+// Panic releases every voice of the synth and its parts and forgets every note held by
+// the latch, chord memory, scale filter, arpeggiator and split, so a stuck note stops
+// whatever kept it. The controllers are reset and every channel of the MIDI output is
+// sent all notes off. The sequencer and MIDI file player carry on playing.
+func (s *Synth) Panic() {
+	for _, p := range s.Parts() {
+		p.Synth.releaseAll()
+	}
+	s.releaseAll()
+	s.Split.clear()
+	s.SeqOut.clear()
+	s.ArpOut.clear()
+	if s.MIDIOut.Connected() {
+		for channel := uint8(0); channel < 16; channel++ {
+			s.MIDIOut.Send(midi.ControlChange(channel, SustainCC, 0))
+			s.MIDIOut.Send(midi.ControlChange(channel, AllNotesOffCC, 0))
+		}
+	}
+}
+
+// releaseAll releases the voices and held notes of one synth and resets its controllers,
+// as the all notes off and all sound off messages of its channel do
+func (s *Synth) releaseAll() {
+	s.Latch.clear()
+	s.Chord.clear()
+	s.Quantize.clear()
+	s.Arp.clear()
+	s.routed.clear()
+	s.resetControllers()
+	s.QueuePanic()
+}
+
+// resetControllers lifts the sustain pedal and returns bank select to the first bank
+func (s *Synth) resetControllers() {
+	s.SetSustain(false)
+	s.Programs.setBank(false, 0)
+	s.Programs.setBank(true, 0)
+}
@@ -0,0 +1,412 @@
+package synth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gosynth/pkg/engine"
+	"gosynth/pkg/fx"
+)
+
+// DefaultPresetName is the preset the synth starts with
+const DefaultPresetName = "init"
+
+// Params returns the parameters stored in presets, in display order
+func (s *Synth) Params() []engine.Param {
+	return append([]engine.Param{
+		{Name: "carrierFreq", Value: &s.CarrierFreq, Min: 20, Max: 2000},
+		{Name: "minModFreq", Value: &s.MinModFreq, Min: 20, Max: 2000},
+		{Name: "maxModFreq", Value: &s.MaxModFreq, Min: 20, Max: 2000},
+		{Name: "sweepTime", Value: &s.SweepTime, Min: 0.01, Max: 1},
+		{Name: "modIndex", Value: &s.ModIndex, Min: 0, Max: 1},
+		{Name: "modFeedback", Value: &s.ModFeedback, Min: 0, Max: 1},
+		{Name: "binauralBeat", Value: &s.Binaural, Min: 0, Max: engine.MaxBinauralBeat},
+		{Name: "subLevel", Value: &s.Sub.Level, Min: 0, Max: 1},
+		{Name: "noiseLevel", Value: &s.Noise.Level, Min: 0, Max: 1},
+		{Name: "noiseDecay", Value: &s.Noise.Decay, Min: 0.001, Max: engine.MaxNoiseDecay},
+		{Name: "noiseEnvelope", Value: &s.Noise.Envelope, Min: 0, Max: 1},
+		{Name: "volume", Value: &s.Volume, Min: 0, Max: 1},
+		{Name: "pan", Value: &s.Pan, Min: -1, Max: 1},
+		{Name: "panSpread", Value: &s.PanSpread, Min: 0, Max: 1},
+		{Name: "envDelay", Value: &s.EnvDelay, Min: 0, Max: 2},
+		{Name: "attack", Value: &s.Attack, Min: 0, Max: 2},
+		{Name: "envHold", Value: &s.EnvHold, Min: 0, Max: 2},
+		{Name: "decay", Value: &s.Decay, Min: 0, Max: 2},
+		{Name: "sustain", Value: &s.Sustain, Min: 0, Max: 1},
+		{Name: "release", Value: &s.Release, Min: 0, Max: 5},
+		{Name: "filterCutoff", Value: &s.Filter.Cutoff, Min: engine.FilterMinCutoff, Max: engine.FilterMaxCutoff},
+		{Name: "filterResonance", Value: &s.Filter.Resonance, Min: 0, Max: engine.FilterMaxRes},
+		{Name: "filterKeyTrack", Value: &s.Filter.KeyTrack, Min: 0, Max: 1},
+		{Name: "filterEnvAmount", Value: &s.Filter.EnvAmount, Min: -engine.FilterEnvOctaves, Max: engine.FilterEnvOctaves},
+		{Name: "filterAttack", Value: &s.Filter.Attack, Min: 0, Max: 2},
+		{Name: "filterDecay", Value: &s.Filter.Decay, Min: 0, Max: 2},
+		{Name: "filterSustain", Value: &s.Filter.Sustain, Min: 0, Max: 1},
+		{Name: "filterRelease", Value: &s.Filter.Release, Min: 0, Max: 5},
+		{Name: "pitchEnvAmount", Value: &s.PitchEnv.Amount, Min: -engine.PitchEnvMax, Max: engine.PitchEnvMax},
+		{Name: "pitchEnvAttack", Value: &s.PitchEnv.Attack, Min: 0, Max: 2},
+		{Name: "pitchEnvDecay", Value: &s.PitchEnv.Decay, Min: 0, Max: 2},
+		{Name: "portamento", Value: &s.Mono.Portamento, Min: 0, Max: engine.MaxPortamento},
+		{Name: "lfoRate", Value: &s.LFO.Rate, Min: engine.LFOMinRate, Max: engine.LFOMaxRate},
+		{Name: "arpRate", Value: &s.Arp.Rate, Min: 0.5, Max: 32},
+		{Name: "arpOctaves", Value: &s.Arp.Octaves, Min: 1, Max: 4},
+		{Name: "arpGate", Value: &s.Arp.Gate, Min: 0.05, Max: 1},
+		{Name: "droneLevel", Value: &s.DroneLayer.Level, Min: 0, Max: 1},
+		{Name: "droneDetune", Value: &s.DroneLayer.Detune, Min: 0, Max: 50},
+		{Name: "droneFade", Value: &s.DroneLayer.Fade, Min: 0, Max: 10},
+		{Name: "oneShotLevel", Value: &s.OneShots.Level, Min: 0, Max: 1},
+		{Name: "drumLevel", Value: &s.Drums.Level, Min: 0, Max: 1},
+		{Name: "kickPitch", Value: &s.Drums.KickPitch, Min: engine.MinKickPitch, Max: engine.MaxKickPitch},
+		{Name: "kickDecay", Value: &s.Drums.Decay[engine.DrumKick], Min: engine.MinDrumDecay, Max: engine.MaxDrumDecay},
+		{Name: "snareDecay", Value: &s.Drums.Decay[engine.DrumSnare], Min: engine.MinDrumDecay, Max: engine.MaxDrumDecay},
+		{Name: "hatDecay", Value: &s.Drums.Decay[engine.DrumHat], Min: engine.MinDrumDecay, Max: engine.MaxDrumDecay},
+		{Name: "voiceDrive", Value: &s.VoiceFX.Drive, Min: 0, Max: 1},
+		{Name: "voiceDrift", Value: &s.VoiceFX.Drift, Min: 0, Max: 1},
+		{Name: "voiceChorus", Value: &s.VoiceFX.Chorus, Min: 0, Max: 1},
+		{Name: "bpm", Value: &s.Clock.BPM, Min: 20, Max: 300},
+		{Name: "swing", Value: &s.Clock.Swing, Min: NoSwing, Max: MaxSwing},
+		{Name: "chorusRate", Value: &s.Chorus.Rate, Min: 0.1, Max: 5},
+		{Name: "chorusDepth", Value: &s.Chorus.Depth, Min: 0, Max: 1},
+		{Name: "chorusMix", Value: &s.Chorus.Mix, Min: 0, Max: 1},
+		{Name: "delayTime", Value: &s.Delay.Time, Min: 0.01, Max: engine.MaxDelayTime},
+		{Name: "delayFeedback", Value: &s.Delay.Feedback, Min: 0, Max: 0.95},
+		{Name: "delayMix", Value: &s.Delay.Mix, Min: 0, Max: 1},
+		{Name: "reverbSize", Value: &s.Reverb.Size, Min: 0, Max: 1},
+		{Name: "reverbDamping", Value: &s.Reverb.Damping, Min: 0, Max: 1},
+		{Name: "reverbMix", Value: &s.Reverb.Mix, Min: 0, Max: 1},
+		{Name: "compThreshold", Value: &s.Comp.Threshold, Min: -60, Max: 0},
+		{Name: "compRatio", Value: &s.Comp.Ratio, Min: 1, Max: engine.MaxCompRatio},
+		{Name: "compAttack", Value: &s.Comp.Attack, Min: 0.0001, Max: 0.2},
+		{Name: "compRelease", Value: &s.Comp.Release, Min: 0.01, Max: 2},
+		{Name: "compMakeup", Value: &s.Comp.Makeup, Min: 0, Max: 24},
+		{Name: "mixTrim", Value: &s.MixTrim, Min: -engine.MaxTrim, Max: engine.MaxTrim},
+		{Name: "fxTrim", Value: &s.FXTrim, Min: -engine.MaxTrim, Max: engine.MaxTrim},
+		{Name: "sampleStartVelocity", Value: &s.Sampler.StartVelocity, Min: 0, Max: engine.MaxStartOffset},
+		{Name: "sampleStartRandom", Value: &s.Sampler.StartRandom, Min: 0, Max: engine.MaxStartOffset},
+	}, append(s.fmParams(), s.macroParams()...)...)
+}
+
+// fmParams returns the FM feedback and every operator's settings, named op1Ratio and so on
+func (s *Synth) fmParams() []engine.Param {
+	params := []engine.Param{{Name: "fmFeedback", Value: &s.FM.Feedback, Min: 0, Max: 1}}
+	for i := range s.FM.Operators {
+		op := &s.FM.Operators[i]
+		name := fmt.Sprintf("op%d", i+1)
+		params = append(params,
+			engine.Param{Name: name + "Ratio", Value: &op.Ratio, Min: engine.FMMinRatio, Max: engine.FMMaxRatio},
+			engine.Param{Name: name + "Level", Value: &op.Level, Min: 0, Max: 1},
+			engine.Param{Name: name + "Attack", Value: &op.Attack, Min: 0, Max: 2},
+			engine.Param{Name: name + "Decay", Value: &op.Decay, Min: 0, Max: 2},
+			engine.Param{Name: name + "Sustain", Value: &op.Sustain, Min: 0, Max: 1},
+			engine.Param{Name: name + "Release", Value: &op.Release, Min: 0, Max: 5},
+		)
+	}
+	return params
+}
+
+// SetParam sets a preset parameter by name, clamped to its range. Setting a macro knob
+// moves its targets too. A value that isn't a finite number is refused.
+func (s *Synth) SetParam(name string, value float64) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("%s must be a finite number, not %g", name, value)
+	}
+	if macro, ok := macroIndex(name); ok {
+		s.SetMacro(macro, value)
+		return nil
+	}
+	for _, param := range s.Params() {
+		if param.Name == name {
+			param.Value.Set(param.Clamp(value))
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown parameter %q", name)
+}
+
+// Preset is a saved synth patch together with its sequencer patterns and song, effects chain,
+// mod matrix, modulator sweep, one-shot samples, voice limits, mono mode, sub-oscillator, noise color,
+// FM algorithm, envelope curves, LFO shape and macro knobs
+type Preset struct {
+	Name    string                 `json:"name"`
+	Drone   bool                   `json:"drone"`
+	Params  map[string]float64     `json:"params"`
+	Pattern *Pattern               `json:"pattern,omitempty"` // Selected pattern, also kept in the song
+	Song    *SongState             `json:"song,omitempty"`    // Stored patterns and their song order
+	Effects []fx.SlotState         `json:"effects,omitempty"`
+	Mod     []engine.ModRouting    `json:"mod,omitempty"`
+	Sweep   *engine.SweepState     `json:"sweep,omitempty"`
+	Shots   []string               `json:"shots,omitempty"`     // WAV file in each one-shot slot, empty for none
+	Voices  []engine.PartVoices    `json:"voices,omitempty"`    // Voice reserve and maximum of each part
+	Poly    *engine.PolyphonyState `json:"polyphony,omitempty"` // Voice limit and stealing policy
+	Mono    *engine.MonoState      `json:"mono,omitempty"`      // Mono mode and note priority
+	Sub     *engine.SubState       `json:"sub,omitempty"`       // Sub-oscillator shape and octave
+	Noise   *engine.NoiseState     `json:"noise,omitempty"`     // Noise color
+	FM      *engine.FMState        `json:"fm,omitempty"`        // FM mode and operator algorithm
+	Curves  *engine.EnvelopeCurves `json:"curves,omitempty"`    // Curves of the voice envelope's stages
+	LFO     *engine.LFOState       `json:"lfo,omitempty"`       // LFO shape and tempo sync
+	Tuning  *engine.TuningState    `json:"tuning,omitempty"`    // Scala scale and keyboard mapping files
+	Macros  *MacroState            `json:"macros,omitempty"`    // Parameters each macro knob moves
+}
+
+// CapturePreset snapshots the current synth state as a preset
+func (s *Synth) CapturePreset(name string) Preset {
+	p := Preset{
+		Name:   name,
+		Drone:  s.Drone(),
+		Params: make(map[string]float64),
+	}
+	for _, param := range s.Params() {
+		p.Params[param.Name] = param.Value.Get()
+	}
+	pattern := s.Seq.Pattern()
+	p.Pattern = &pattern
+	song := s.Seq.SongState()
+	p.Song = &song
+	p.Effects = s.FX.State()
+	routings := s.Mod.Routings()
+	p.Mod = routings[:]
+	sweep := s.Sweep.State()
+	p.Sweep = &sweep
+	p.Shots = s.OneShots.Names()
+	p.Voices = s.Polyphony.State()
+	poly := s.Polyphony.Limits()
+	p.Poly = &poly
+	mono := s.Mono.State()
+	p.Mono = &mono
+	sub := s.Sub.State()
+	p.Sub = &sub
+	noise := s.Noise.State()
+	p.Noise = &noise
+	fm := s.FM.State()
+	p.FM = &fm
+	curves := s.EnvCurves.State()
+	p.Curves = &curves
+	lfo := s.LFO.State()
+	p.LFO = &lfo
+	tuning := s.Tuning.State()
+	p.Tuning = &tuning
+	macros := s.Macros.State()
+	p.Macros = &macros
+	return p
+}
+
+// ApplyPreset sets the synth state from a preset, clamping values to their ranges
+func (s *Synth) ApplyPreset(p Preset) {
+	s.SetDrone(p.Drone)
+
+	// Glide to the new values so sounding notes change without a click
+	var params []engine.Param
+	var values []float64
+	for _, param := range s.Params() {
+		if v, ok := p.Params[param.Name]; ok {
+			params = append(params, param)
+			values = append(values, param.Clamp(v))
+		}
+	}
+	if !s.StartMorph(params, values) {
+		for i, param := range params {
+			param.Value.Set(values[i])
+		}
+	}
+	// Presets from before song mode store their one pattern
+	if p.Song != nil {
+		s.Seq.SetSongState(*p.Song)
+	} else {
+		if p.Pattern != nil {
+			s.Seq.SetPattern(*p.Pattern)
+		}
+		s.Seq.SetSongState(SongState{Patterns: []Pattern{s.Seq.Pattern()}})
+	}
+	if p.Effects != nil {
+		s.FX.SetState(p.Effects)
+	}
+	if p.Mod != nil {
+		s.Mod.SetRoutings(p.Mod)
+	}
+	// Presets from before the sweep designer had the classic rising ramp
+	if p.Sweep != nil {
+		s.Sweep.SetState(*p.Sweep)
+	} else {
+		s.Sweep.SetState(engine.SweepState{})
+	}
+	if p.Shots != nil {
+		s.loadOneShots(p.Shots)
+	}
+	// Presets without voice limits share the voices freely
+	s.Polyphony.SetState(p.Voices)
+	// Presets from before the voice limit may sound every voice, stealing the oldest
+	if p.Poly != nil {
+		s.Polyphony.SetLimits(*p.Poly)
+	} else {
+		s.Polyphony.SetLimits(engine.PolyphonyState{Voices: engine.MaxVoices})
+	}
+	// Presets from before mono mode play polyphonically
+	if p.Mono != nil {
+		s.Mono.SetState(*p.Mono)
+	} else {
+		s.Mono.SetState(engine.MonoState{})
+	}
+	// Presets from before the sub-oscillator keep it a sine an octave down, silent at level 0
+	if p.Sub != nil {
+		s.Sub.SetState(*p.Sub)
+	} else {
+		s.Sub.SetState(engine.SubState{Shape: engine.SubSine, Octaves: 1})
+	}
+	if p.Noise != nil {
+		s.Noise.SetState(*p.Noise)
+	} else {
+		s.Noise.SetState(engine.NoiseState{})
+	}
+	// Presets from before FM play the plain sine
+	if p.FM != nil {
+		s.FM.SetState(*p.FM)
+	} else {
+		s.FM.SetState(engine.FMState{})
+	}
+	// Presets from before envelope curves have straight segments
+	if p.Curves != nil {
+		s.EnvCurves.SetState(*p.Curves)
+	} else {
+		s.EnvCurves.SetState(engine.EnvelopeCurves{})
+	}
+	if p.LFO != nil {
+		s.LFO.SetState(*p.LFO)
+	} else {
+		s.LFO.SetState(engine.LFOState{Div: engine.DivisionIndex(engine.LFOSyncDiv)})
+	}
+	// Presets from before microtuning, and those whose scale can't be read, are equal-tempered
+	if p.Tuning == nil || s.Tuning.SetState(*p.Tuning) != nil {
+		s.Tuning.Reset()
+	}
+	// Presets from before macros have knobs that move nothing
+	if p.Macros != nil {
+		s.Macros.SetState(*p.Macros)
+	} else {
+		s.Macros.SetState(MacroState{})
+	}
+	s.setPresetName(p.Name)
+}
+
+// loadOneShots fills the one-shot slots from sample file names, leaving a slot empty when
+// its file is missing or can't be read. Slots already holding their file aren't reread.
+func (s *Synth) loadOneShots(names []string) {
+	for slot := 0; slot < engine.OneShotSlots; slot++ {
+		if current := s.OneShots.Sample(slot); current != nil && slot < len(names) && current.Name == names[slot] {
+			continue
+		}
+		if slot >= len(names) || names[slot] == "" || s.OneShots.Load(slot, names[slot]) != nil {
+			s.OneShots.Clear(slot)
+		}
+	}
+}
+
+// PresetName returns the name of the last loaded or saved preset
+func (s *Synth) PresetName() string {
+	if name := s.presetName.Load(); name != nil {
+		return *name
+	}
+	return ""
+}
+
+// setPresetName records the name of a loaded or saved preset
+func (s *Synth) setPresetName(name string) {
+	s.presetName.Store(&name)
+}
+
+// PresetDir returns the directory presets are stored in
+func PresetDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gosynth", "presets"), nil
+}
+
+// ListPresets returns the names of all saved presets, sorted
+func ListPresets() ([]string, error) {
+	dir, err := PresetDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SavePreset writes the current state to the named preset file
+func (s *Synth) SavePreset(name string) error {
+	dir, err := PresetDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.CapturePreset(name), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), data, 0o644); err != nil {
+		return err
+	}
+	s.setPresetName(name)
+	return nil
+}
+
+// LoadPreset reads the named preset file and applies it, except for locked parameters.
+// Fields with problems are skipped or clamped and the rest applied, returning the
+// problems as PresetIssues; a file that can't be read or parsed changes nothing.
+func (s *Synth) LoadPreset(name string) error {
+	err := s.loadPreset(name)
+	var issues PresetIssues
+	if err == nil || errors.As(err, &issues) {
+		s.PlayPreview()
+	}
+	return err
+}
+
+// loadPreset loads a preset like LoadPreset without playing the preview, for patch
+// changes in the middle of a piece
+func (s *Synth) loadPreset(name string) error {
+	dir, err := PresetDir()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return err
+	}
+	p, issues, err := s.decodePreset(data)
+	if err != nil {
+		return err
+	}
+	p.Name = name
+	s.Locks.drop(p.Params)
+	s.ApplyPreset(p)
+	for slot, shot := range p.Shots {
+		if shot != "" && slot < engine.OneShotSlots && s.OneShots.Sample(slot) == nil {
+			issues.add(fmt.Sprintf("shots[%d]", slot), "sample %s couldn't be loaded, slot left empty", shot)
+		}
+	}
+	if len(issues) > 0 {
+		return issues
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package synth
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"gosynth/pkg/engine"
+	"gosynth/pkg/fx"
+)
+
+// PresetIssue is a problem found in a preset file. The faulty part was skipped or
+// clamped and the rest of the preset loaded.
+type PresetIssue struct {
+	Field   string `json:"field"` // Where in the file, such as params.volume or mod[2]
+	Problem string `json:"problem"`
+}
+
+func (i PresetIssue) String() string {
+	return i.Field + ": " + i.Problem
+}
+
+// PresetIssues is the error LoadPreset returns when a preset loaded with problems
+type PresetIssues []PresetIssue
+
+func (is PresetIssues) Error() string {
+	texts := make([]string, len(is))
+	for i, issue := range is {
+		texts[i] = issue.String()
+	}
+	return "preset problems: " + strings.Join(texts, "; ")
+}
+
+// add records a problem with a field
+func (is *PresetIssues) add(field, format string, args ...any) {
+	*is = append(*is, PresetIssue{Field: field, Problem: fmt.Sprintf(format, args...)})
+}
+
+// decodePreset reads a preset field by field, keeping the fields that decode and
+// clamping parameters to their ranges. Only a file that isn't a JSON object fails.
+func (s *Synth) decodePreset(data []byte) (Preset, PresetIssues, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Preset{}, nil, fmt.Errorf("corrupt preset file: %w", err)
+	}
+
+	var p Preset
+	var issues PresetIssues
+	decode := func(field string, raw json.RawMessage, target any) bool {
+		if err := json.Unmarshal(raw, target); err != nil {
+			issues.add(field, "unreadable, skipped (%v)", err)
+			return false
+		}
+		return true
+	}
+	for field, raw := range fields {
+		switch field {
+		case "name":
+			decode(field, raw, &p.Name)
+		case "drone":
+			decode(field, raw, &p.Drone)
+		case "params":
+			var values map[string]json.RawMessage
+			if decode(field, raw, &values) {
+				p.Params = s.checkParams(values, &issues)
+			}
+		case "pattern":
+			var pattern Pattern
+			if decode(field, raw, &pattern) {
+				if pattern.Length < 1 || pattern.Length > MaxSteps {
+					issues.add(field, "length %d is outside 1 to %d, clamped", pattern.Length, MaxSteps)
+				}
+				p.Pattern = &pattern
+			}
+		case "song":
+			var song SongState
+			if decode(field, raw, &song) {
+				if len(song.Patterns) > MaxPatterns {
+					issues.add(field, "%d patterns, only the first %d kept", len(song.Patterns), MaxPatterns)
+				}
+				for i, pattern := range song.Patterns {
+					if pattern.Length < 1 || pattern.Length > MaxSteps {
+						issues.add(fmt.Sprintf("%s.patterns[%d]", field, i), "length %d is outside 1 to %d, clamped", pattern.Length, MaxSteps)
+					}
+				}
+				if song.Selected < 0 || song.Selected >= MaxPatterns {
+					issues.add(field, "selected pattern %d out of range, clamped", song.Selected+1)
+				}
+				if n := len(checkSong(song.Order)); n != len(song.Order) {
+					issues.add(field, "%d song entries out of range skipped", len(song.Order)-n)
+				}
+				p.Song = &song
+			}
+		case "effects":
+			if decode(field, raw, &p.Effects) {
+				p.Effects = s.checkEffects(p.Effects, &issues)
+			}
+		case "mod":
+			if decode(field, raw, &p.Mod) {
+				checkRoutings(p.Mod, &issues)
+			}
+		case "sweep":
+			var sweep engine.SweepState
+			if decode(field, raw, &sweep) {
+				p.Sweep = &sweep
+			}
+		case "shots":
+			decode(field, raw, &p.Shots)
+		case "voices":
+			decode(field, raw, &p.Voices)
+		case "polyphony":
+			var poly engine.PolyphonyState
+			if decode(field, raw, &poly) {
+				if poly.Voices < 1 || poly.Voices > engine.MaxVoices {
+					issues.add(field, "voice limit %d out of range, clamped", poly.Voices)
+				}
+				if poly.Steal.Next(0) != poly.Steal {
+					issues.add(field, "unknown stealing policy %d, oldest used", poly.Steal)
+					poly.Steal = engine.StealOldest
+				}
+				p.Poly = &poly
+			}
+		case "mono":
+			var mono engine.MonoState
+			if decode(field, raw, &mono) {
+				if mono.Priority.Next(0) != mono.Priority {
+					issues.add(field, "unknown note priority %d, last note used", mono.Priority)
+					mono.Priority = engine.PriorityLast
+				}
+				p.Mono = &mono
+			}
+		case "sub":
+			var sub engine.SubState
+			if decode(field, raw, &sub) {
+				if sub.Shape.Next(0) != sub.Shape {
+					issues.add(field, "unknown shape %d, sine used", sub.Shape)
+					sub.Shape = engine.SubSine
+				}
+				if sub.Octaves < 1 || sub.Octaves > engine.MaxSubOctaves {
+					issues.add(field, "%d octaves is outside 1 to %d, clamped", sub.Octaves, engine.MaxSubOctaves)
+				}
+				p.Sub = &sub
+			}
+		case "noise":
+			var noise engine.NoiseState
+			if decode(field, raw, &noise) {
+				if noise.Color.Next(0) != noise.Color {
+					issues.add(field, "unknown color %d, white used", noise.Color)
+					noise.Color = engine.NoiseWhite
+				}
+				p.Noise = &noise
+			}
+		case "fm":
+			var fm engine.FMState
+			if decode(field, raw, &fm) {
+				if fm.Algorithm.Next(0) != fm.Algorithm {
+					issues.add(field, "unknown algorithm %d, %s used", fm.Algorithm, engine.FMStack)
+					fm.Algorithm = engine.FMStack
+				}
+				p.FM = &fm
+			}
+		case "curves":
+			var curves engine.EnvelopeCurves
+			if decode(field, raw, &curves) {
+				for _, c := range []engine.EnvCurve{curves.Attack, curves.Decay, curves.Release} {
+					if c.Next(0) != c {
+						issues.add(field, "unknown curve %d, linear used", c)
+					}
+				}
+				p.Curves = &curves
+			}
+		case "lfo":
+			var lfo engine.LFOState
+			if decode(field, raw, &lfo) {
+				if lfo.Shape.Next(0) != lfo.Shape {
+					issues.add(field, "unknown shape %d, sine used", lfo.Shape)
+					lfo.Shape = engine.LFOSine
+				}
+				if lfo.Div < 0 || lfo.Div >= len(engine.Divisions) {
+					issues.add(field, "unknown division %d, clamped", lfo.Div)
+				}
+				p.LFO = &lfo
+			}
+		case "tuning":
+			var tuning engine.TuningState
+			if decode(field, raw, &tuning) {
+				if tuning.Scale == "" && tuning.Mapping != "" {
+					issues.add(field, "keyboard mapping without a scale, ignored")
+				}
+				p.Tuning = &tuning
+			}
+		case "macros":
+			var macros MacroState
+			if decode(field, raw, &macros) {
+				s.checkMacros(&macros, &issues)
+				p.Macros = &macros
+			}
+		default:
+			issues.add(field, "unknown field, ignored")
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Field < issues[j].Field })
+	return p, issues, nil
+}
+
+// checkParams reads the parameter values, dropping unknown names and values that
+// aren't numbers and clamping the rest
+func (s *Synth) checkParams(raw map[string]json.RawMessage, issues *PresetIssues) map[string]float64 {
+	values := make(map[string]float64)
+	for name, rawValue := range raw {
+		field := "params." + name
+		i := slices.IndexFunc(s.Params(), func(param engine.Param) bool { return param.Name == name })
+		if i < 0 {
+			issues.add(field, "unknown parameter, ignored")
+			continue
+		}
+		var v float64
+		if err := json.Unmarshal(rawValue, &v); err != nil {
+			issues.add(field, "%s isn't a number, ignored", rawValue)
+			continue
+		}
+		param := s.Params()[i]
+		if clamped := param.Clamp(v); clamped != v {
+			issues.add(field, "%g is outside %g to %g, clamped to %g", v, param.Min, param.Max, clamped)
+			v = clamped
+		}
+		values[name] = v
+	}
+	return values
+}
+
+// checkEffects drops effects the chain doesn't have
+func (s *Synth) checkEffects(state []fx.SlotState, issues *PresetIssues) []fx.SlotState {
+	names := s.FX.Names()
+	var known []fx.SlotState
+	for i, st := range state {
+		if !slices.Contains(names, st.Name) {
+			issues.add(fmt.Sprintf("effects[%d]", i), "unknown effect %q, ignored", st.Name)
+			continue
+		}
+		known = append(known, st)
+	}
+	return known
+}
+
+// checkRoutings turns off routings with a source, destination or curve this version
+// doesn't have
+func checkRoutings(routings []engine.ModRouting, issues *PresetIssues) {
+	for i := range routings {
+		r := &routings[i]
+		if r.Source.Next(0) != r.Source || r.Dest.Next(0) != r.Dest || r.Curve.Next(0) != r.Curve {
+			issues.add(fmt.Sprintf("mod[%d]", i), "unknown source, destination or curve, turned off")
+			*r = engine.ModRouting{Source: engine.ModOff, Dest: engine.ModPitch, Curve: engine.CurveLinear, Steps: engine.ModSteps}
+		}
+	}
+	if len(routings) > engine.ModSlots {
+		issues.add("mod", "%d routings but only %d slots, extra ones ignored", len(routings), engine.ModSlots)
+	}
+}
+
+// checkMacros drops macro targets naming unknown parameters, and those past the most a
+// macro moves, and clamps the ends to each parameter's range
+func (s *Synth) checkMacros(macros *MacroState, issues *PresetIssues) {
+	for i, targets := range macros {
+		var known []MacroTarget
+		for j, t := range targets {
+			field := fmt.Sprintf("macros[%d][%d]", i, j)
+			param, ok := s.macroTargetParam(t.Param)
+			switch {
+			case !ok:
+				issues.add(field, "unknown parameter %q, ignored", t.Param)
+				continue
+			case len(known) == MaxMacroTargets:
+				issues.add(field, "more than %d targets, ignored", MaxMacroTargets)
+				continue
+			}
+			if from, to := param.Clamp(t.From), param.Clamp(t.To); from != t.From || to != t.To {
+				issues.add(field, "%g..%g is outside %g to %g, clamped", t.From, t.To, param.Min, param.Max)
+				t.From, t.To = from, to
+			}
+			known = append(known, t)
+		}
+		macros[i] = known
+	}
+}
@@ -0,0 +1,122 @@
+package synth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PreviewLength   = 1500 * time.Millisecond // Default time the preview chord is held
+	PreviewVelocity = 100                     // Default velocity of the preview chord
+)
+
+// Preview is a note or chord played on startup and on preset load, so a patch is heard
+// without a keyboard. It has no notes until configured.
+type Preview struct {
+	mu       sync.Mutex
+	notes    []uint8
+	velocity uint8
+	length   time.Duration
+	playing  []uint8     // Notes of the sounding preview
+	release  *time.Timer // Ends the sounding preview
+}
+
+// NewPreview creates an empty preview with the default length and velocity
+func NewPreview() *Preview {
+	return &Preview{velocity: PreviewVelocity, length: PreviewLength}
+}
+
+// Set configures the notes, velocity and hold time; no notes turns the preview off
+func (p *Preview) Set(notes []uint8, velocity uint8, length time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.notes = append([]uint8(nil), notes...)
+	p.velocity = velocity
+	p.length = length
+}
+
+// Notes returns the configured notes
+func (p *Preview) Notes() []uint8 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]uint8(nil), p.notes...)
+}
+
+// PlayPreview sounds the preview chord on the voices, bypassing the arpeggiator and
+// latch, and releases it after its length; a preview still sounding is cut short first
+func (s *Synth) PlayPreview() {
+	p := s.Preview
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.notes) == 0 {
+		return
+	}
+	if p.release != nil && p.release.Stop() {
+		for _, note := range p.playing {
+			s.playNoteOff(note)
+		}
+	}
+	p.playing = append([]uint8(nil), p.notes...)
+	for _, note := range p.playing {
+		s.playNoteOn(note, p.velocity)
+	}
+	notes := p.playing
+	p.release = time.AfterFunc(p.length, func() {
+		for _, note := range notes {
+			s.playNoteOff(note)
+		}
+	})
+}
+
+// ParseNote reads a note given as a MIDI number (60) or a name with an octave (C4, F#3,
+// Bb2), where C4 is middle C
+func ParseNote(text string) (uint8, error) {
+	text = strings.TrimSpace(text)
+	if n, err := strconv.Atoi(text); err == nil {
+		if n < 0 || n > 127 {
+			return 0, fmt.Errorf("note %d out of range", n)
+		}
+		return uint8(n), nil
+	}
+	if text == "" {
+		return 0, fmt.Errorf("empty note")
+	}
+	pitch := strings.IndexByte("C D EF G A B", strings.ToUpper(text)[0])
+	if pitch < 0 {
+		return 0, fmt.Errorf("bad note name %q", text)
+	}
+	rest := text[1:]
+	switch {
+	case strings.HasPrefix(rest, "#"):
+		pitch++
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "b"):
+		pitch--
+		rest = rest[1:]
+	}
+	octave, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0, fmt.Errorf("bad octave in note %q", text)
+	}
+	n := (octave+1)*12 + pitch
+	if n < 0 || n > 127 {
+		return 0, fmt.Errorf("note %q out of range", text)
+	}
+	return uint8(n), nil
+}
+
+// ParseChord reads comma-separated notes, as accepted by ParseNote
+func ParseChord(text string) ([]uint8, error) {
+	var notes []uint8
+	for _, name := range strings.Split(text, ",") {
+		note, err := ParseNote(name)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, nil
+}
@@ -0,0 +1,84 @@
+package synth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	BankSelectCC    = 0   // Controller number of the bank select MSB
+	BankSelectLSBCC = 32  // Controller number of the bank select LSB
+	DefaultBankSize = 128 // Presets per bank unless the config sets another
+)
+
+// ProgramMap numbers the saved presets for MIDI program changes. The presets are taken
+// in the order they are listed, the first selected by the configured first program, and
+// bank select moves on by a bank of presets at a time, so a foot controller or DAW can
+// reach more than 128 of them.
+type ProgramMap struct {
+	mu       sync.Mutex
+	first    int      // Program number of the first preset of a bank
+	bankSize int      // Presets per bank
+	bank     [2]uint8 // Bank select MSB and LSB last received
+}
+
+// NewProgramMap creates a map where program 0 of bank 0 selects the first preset
+func NewProgramMap() *ProgramMap {
+	return &ProgramMap{bankSize: DefaultBankSize}
+}
+
+// SetNumbering sets the program number of the first preset and the presets per bank
+func (pm *ProgramMap) SetNumbering(first, bankSize int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.first = first
+	pm.bankSize = max(1, bankSize)
+}
+
+// setBank records a bank select MSB or LSB
+func (pm *ProgramMap) setBank(lsb bool, value uint8) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if lsb {
+		pm.bank[1] = value
+	} else {
+		pm.bank[0] = value
+	}
+}
+
+// slot returns the index in the preset list a program selects in the current bank, or
+// false for a program below the first or past the bank
+func (pm *ProgramMap) slot(program uint8) (int, bool) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	n := int(program) - pm.first
+	if n < 0 || n >= pm.bankSize {
+		return 0, false
+	}
+	bank := int(pm.bank[0])<<7 | int(pm.bank[1])
+	return bank*pm.bankSize + n, true
+}
+
+// ProgramChange loads the preset a program change selects into target, the main synth
+// or a part, returning its name. Programs without a preset are errors.
+func (s *Synth) ProgramChange(target *Synth, program uint8) (string, error) {
+	slot, ok := s.Programs.slot(program)
+	if !ok {
+		return "", fmt.Errorf("program %d selects no preset", program)
+	}
+	names, err := ListPresets()
+	if err != nil {
+		return "", err
+	}
+	if slot >= len(names) {
+		return "", fmt.Errorf("program %d selects preset %d of %d", program, slot+1, len(names))
+	}
+	name := names[slot]
+	err = target.LoadPreset(name)
+	var issues PresetIssues
+	if errors.As(err, &issues) {
+		err = nil // Loaded what it could, as from the UI
+	}
+	return name, err
+}
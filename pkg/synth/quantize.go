@@ -0,0 +1,276 @@
+package synth
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const blockedNote = 0xff // Stands for the note of a key the filter blocked
+
+// pitchNames names the pitch classes from C, sharps for the black keys
+var pitchNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// QuantizeMode is what the scale filter does with a note outside the scale
+type QuantizeMode int
+
+const (
+	QuantizeOff   QuantizeMode = iota // Every note plays as it is
+	QuantizeSnap                      // Notes outside the scale move to the nearest note in it
+	QuantizeBlock                     // Notes outside the scale are dropped
+	quantizeModeCount
+)
+
+func (q QuantizeMode) String() string {
+	switch q {
+	case QuantizeOff:
+		return "off"
+	case QuantizeSnap:
+		return "snap"
+	case QuantizeBlock:
+		return "block"
+	}
+	return "unknown"
+}
+
+// Next returns the following mode, wrapping around
+func (q QuantizeMode) Next(dir int) QuantizeMode {
+	return QuantizeMode((int(q) + dir + int(quantizeModeCount)) % int(quantizeModeCount))
+}
+
+// NoteScale is a scale the scale filter keeps notes in
+type NoteScale int
+
+const (
+	ScaleMajor NoteScale = iota
+	ScaleMinor
+	ScalePentatonic      // Major pentatonic
+	ScaleMinorPentatonic // Minor pentatonic
+	ScaleUser            // The notes set by SetUserScale, whatever the key
+	noteScaleCount
+)
+
+// scaleSteps are the semitones above the key of each built-in scale, one bit each
+var scaleSteps = [noteScaleCount]uint16{
+	ScaleMajor:           0b101010110101,
+	ScaleMinor:           0b010110101101,
+	ScalePentatonic:      0b001010010101,
+	ScaleMinorPentatonic: 0b010010101001,
+}
+
+func (s NoteScale) String() string {
+	switch s {
+	case ScaleMajor:
+		return "major"
+	case ScaleMinor:
+		return "minor"
+	case ScalePentatonic:
+		return "pentatonic"
+	case ScaleMinorPentatonic:
+		return "minor pentatonic"
+	case ScaleUser:
+		return "user"
+	}
+	return "unknown"
+}
+
+// Next returns the following scale, wrapping around
+func (s NoteScale) Next(dir int) NoteScale {
+	return NoteScale((int(s) + dir + int(noteScaleCount)) % int(noteScaleCount))
+}
+
+// Quantizer is the scale filter: it snaps notes played outside a scale to the nearest
+// note in it, or blocks them, before they reach the latch and arpeggiator, so a jam stays
+// in key whatever keys are hit. It starts off, on C major.
+type Quantizer struct {
+	mu      sync.Mutex
+	mode    QuantizeMode
+	key     int             // Pitch class of the scale's first note, 0 for C
+	scale   NoteScale       // Scale kept to
+	user    uint16          // Pitch classes of the user scale, one bit each from C
+	playing map[uint8]uint8 // Note each held key plays, or blockedNote, for its note-off
+	held    map[uint8]int   // Keys held on each played note
+}
+
+// NewQuantizer creates a scale filter that lets every note through
+func NewQuantizer() *Quantizer {
+	return &Quantizer{
+		user:    scaleSteps[ScaleMajor],
+		playing: make(map[uint8]uint8),
+		held:    make(map[uint8]int),
+	}
+}
+
+// Mode returns what the filter does with notes outside the scale
+func (q *Quantizer) Mode() QuantizeMode {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.mode
+}
+
+// SetMode sets what the filter does with notes outside the scale
+func (q *Quantizer) SetMode(mode QuantizeMode) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.mode = mode.Next(0)
+}
+
+// Key returns the pitch class the scale starts on, 0 for C
+func (q *Quantizer) Key() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.key
+}
+
+// SetKey sets the pitch class the scale starts on, wrapping into C to B
+func (q *Quantizer) SetKey(key int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.key = (key%12 + 12) % 12
+}
+
+// Scale returns the scale notes are kept to
+func (q *Quantizer) Scale() NoteScale {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.scale
+}
+
+// SetScale sets the scale notes are kept to
+func (q *Quantizer) SetScale(scale NoteScale) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.scale = scale.Next(0)
+}
+
+// UserScale returns the pitch classes of the user scale, one bit each from C
+func (q *Quantizer) UserScale() uint16 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.user
+}
+
+// SetUserScale sets the pitch classes of the user scale, one bit each from C
+func (q *Quantizer) SetUserScale(pitches uint16) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.user = pitches & 0xfff
+}
+
+// Notes names the pitch classes of the current scale, such as "C D E F G A B"
+func (q *Quantizer) Notes() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return PitchClassNames(q.pitches())
+}
+
+// pitches returns the pitch classes in the scale, one bit each from C
+func (q *Quantizer) pitches() uint16 {
+	if q.scale == ScaleUser {
+		return q.user
+	}
+	steps := scaleSteps[q.scale]
+	return (steps<<q.key | steps>>(12-q.key)) & 0xfff
+}
+
+// noteOn returns the note a pressed key plays, or false when the filter blocks it
+func (q *Quantizer) noteOn(note uint8) (uint8, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	played, ok := note, true
+	if pitches := q.pitches(); q.mode != QuantizeOff && pitches != 0 && pitches&(1<<(note%12)) == 0 {
+		if q.mode == QuantizeSnap {
+			played, ok = nearestInScale(note, pitches)
+		} else {
+			ok = false
+		}
+	}
+	if !ok {
+		q.playing[note] = blockedNote
+		return 0, false
+	}
+	q.playing[note] = played
+	q.held[played]++
+	return played, true
+}
+
+// noteOff returns the note a released key was playing, or false when nothing is to be
+// released: the key was blocked or another key still holds the note
+func (q *Quantizer) noteOff(note uint8) (uint8, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	played, ok := q.playing[note]
+	if !ok {
+		return note, q.held[note] == 0
+	}
+	delete(q.playing, note)
+	if played == blockedNote {
+		return 0, false
+	}
+	if q.held[played]--; q.held[played] > 0 {
+		return 0, false
+	}
+	delete(q.held, played)
+	return played, true
+}
+
+// clear forgets the keys held and the notes they play
+func (q *Quantizer) clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	clear(q.playing)
+	clear(q.held)
+}
+
+// nearestInScale returns the closest note in a set of pitch classes, the lower of two
+// equally close, and false when none is in the MIDI range
+func nearestInScale(note uint8, pitches uint16) (uint8, bool) {
+	for distance := 1; distance < 12; distance++ {
+		for _, n := range []int{int(note) - distance, int(note) + distance} {
+			if n >= 0 && n <= 127 && pitches&(1<<(n%12)) != 0 {
+				return uint8(n), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// PitchClassNames names a set of pitch classes, one bit each from C, in order from C
+func PitchClassNames(pitches uint16) string {
+	var names []string
+	for pc, name := range pitchNames {
+		if pitches&(1<<pc) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, " ")
+}
+
+// ParsePitchClasses reads note names without octaves separated by spaces or commas, such
+// as "C D Eb G A", into a set of pitch classes, one bit each from C
+func ParsePitchClasses(text string) (uint16, error) {
+	var pitches uint16
+	for _, name := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' }) {
+		pitch := strings.IndexByte("C D EF G A B", strings.ToUpper(name)[0])
+		if pitch < 0 {
+			return 0, fmt.Errorf("bad note name %q", name)
+		}
+		switch name[1:] {
+		case "":
+		case "#":
+			pitch++
+		case "b":
+			pitch--
+		default:
+			return 0, fmt.Errorf("bad note name %q", name)
+		}
+		pitches |= 1 << ((pitch + 12) % 12)
+	}
+	if pitches == 0 {
+		return 0, fmt.Errorf("no notes in %q", text)
+	}
+	return pitches, nil
+}
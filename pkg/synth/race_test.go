@@ -0,0 +1,61 @@
+package synth
+
+import (
+	"sync"
+	"testing"
+
+	"gosynth/pkg/engine"
+)
+
+// TestPresetRaceRender applies presets from another goroutine, as the HTTP, OSC and
+// program change handlers do, while blocks render and notes read the preset name, for
+// go test -race to check
+func TestPresetRaceRender(t *testing.T) {
+	s := NewSynth()
+	drone, voices := s.CapturePreset("drone"), s.CapturePreset("voices")
+	voices.Drone = false
+	out := make([]float32, engine.AudioBufferSize*engine.OutputChannels)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if i%2 == 0 {
+				s.ApplyPreset(drone)
+			} else {
+				s.ApplyPreset(voices)
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		s.Render(out)
+		s.NoteOn(60, 100)
+		_ = s.PresetName()
+		s.NoteOff(60)
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// TestStopTwice checks that stopping a started synth again, as a deferred Stop after a
+// shutdown does, is harmless
+func TestStopTwice(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	s := NewSynth()
+	s.NoMIDI = true
+	if err := s.Start(); err != nil {
+		t.Skipf("no audio output: %v", err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package synth
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"gosynth/pkg/engine"
+)
+
+// RandomPresetName is the name a randomized patch goes by until it is saved
+const RandomPresetName = "random"
+
+// RandomRange is how the patch generator draws one preset parameter
+type RandomRange struct {
+	Min, Max float64 // Range values are drawn from, inside the parameter's own range
+	Base     float64 // Value used when the parameter isn't drawn
+	Weight   float64 // Chance from 0 to 1 that a patch draws the parameter
+	Log      bool    // Draw evenly in pitch or time rather than in value; Min must be above 0
+}
+
+// randomRanges keep random patches playable: pitches stay in the middle of the keyboard,
+// envelopes short enough to play, resonance short of ringing and the layers and effects
+// off more often than not. Parameters not listed, such as the volume and tempo, keep
+// their values.
+var randomRanges = map[string]RandomRange{
+	"carrierFreq":     {Min: 55, Max: 880, Weight: 1, Log: true},
+	"minModFreq":      {Min: 30, Max: 600, Base: 100, Weight: 1, Log: true},
+	"maxModFreq":      {Min: 200, Max: 2000, Base: 800, Weight: 1, Log: true},
+	"sweepTime":       {Min: 0.05, Max: 1, Base: 0.2, Weight: 0.7, Log: true},
+	"modIndex":        {Min: 0, Max: 0.8, Weight: 0.9},
+	"modFeedback":     {Min: 0, Max: 0.5, Weight: 0.4},
+	"subLevel":        {Min: 0.1, Max: 0.6, Weight: 0.4},
+	"noiseLevel":      {Min: 0.02, Max: 0.3, Weight: 0.25},
+	"attack":          {Min: 0.001, Max: 1, Base: 0.01, Weight: 0.8, Log: true},
+	"decay":           {Min: 0.05, Max: 1.5, Base: 0.3, Weight: 0.8, Log: true},
+	"sustain":         {Min: 0.2, Max: 1, Base: 0.7, Weight: 0.8},
+	"release":         {Min: 0.05, Max: 2, Base: 0.3, Weight: 0.8, Log: true},
+	"filterCutoff":    {Min: 200, Max: 12000, Base: engine.FilterMaxCutoff, Weight: 0.8, Log: true},
+	"filterResonance": {Min: 0, Max: 0.7, Weight: 0.6},
+	"filterEnvAmount": {Min: -2, Max: 4, Weight: 0.6},
+	"filterAttack":    {Min: 0.001, Max: 0.5, Base: 0.01, Weight: 0.6, Log: true},
+	"filterDecay":     {Min: 0.05, Max: 1.5, Base: 0.3, Weight: 0.6, Log: true},
+	"filterSustain":   {Min: 0, Max: 1, Base: 1, Weight: 0.6},
+	"filterRelease":   {Min: 0.05, Max: 2, Base: 0.3, Weight: 0.6, Log: true},
+	"pitchEnvAmount":  {Min: -12, Max: 12, Weight: 0.15},
+	"pitchEnvDecay":   {Min: 0.01, Max: 0.5, Base: 0.1, Weight: 0.15, Log: true},
+	"lfoRate":         {Min: 0.1, Max: 8, Base: 1, Weight: 0.5, Log: true},
+	"voiceDrive":      {Min: 0.05, Max: 0.5, Weight: 0.3},
+	"voiceDrift":      {Min: 0.05, Max: 0.3, Weight: 0.4},
+	"voiceChorus":     {Min: 0.1, Max: 0.6, Weight: 0.3},
+	"chorusMix":       {Min: 0.1, Max: 0.5, Weight: 0.3},
+	"delayMix":        {Min: 0.1, Max: 0.4, Weight: 0.4},
+	"delayFeedback":   {Min: 0.1, Max: 0.6, Base: 0.3, Weight: 0.4},
+	"reverbMix":       {Min: 0.1, Max: 0.5, Weight: 0.6},
+	"reverbSize":      {Min: 0.2, Max: 0.9, Base: 0.5, Weight: 0.6},
+}
+
+// minModSpread is the least the modulator sweep covers, as the UI keeps it
+const minModSpread = 10
+
+// draw returns a value for one parameter: a draw from its range as often as its weight
+// says, otherwise its base value
+func (r RandomRange) draw(rng *rand.Rand) float64 {
+	if rng.Float64() >= r.Weight {
+		return r.Base
+	}
+	if r.Log {
+		return r.Min * math.Pow(r.Max/r.Min, rng.Float64())
+	}
+	return r.Min + (r.Max-r.Min)*rng.Float64()
+}
+
+// RandomPreset returns the current state with its ranged parameters drawn at random,
+// except locked ones. The modulator sweep is kept rising from its low to its high
+// frequency, moving whichever end isn't locked.
+func (s *Synth) RandomPreset(rng *rand.Rand) Preset {
+	p := s.CapturePreset(RandomPresetName)
+	for _, param := range s.Params() {
+		// Drawn in display order, so a seed always makes the same patch
+		if r, ok := randomRanges[param.Name]; ok && !s.Locks.Locked(param.Name) {
+			p.Params[param.Name] = r.draw(rng)
+		}
+	}
+	if low, high := p.Params["minModFreq"], p.Params["maxModFreq"]; high < low+minModSpread {
+		if s.Locks.Locked("maxModFreq") {
+			p.Params["minModFreq"] = high - minModSpread
+		} else {
+			p.Params["maxModFreq"] = low + minModSpread
+		}
+	}
+	return p
+}
+
+// Randomize applies a random patch and plays the preview so it is heard
+func (s *Synth) Randomize() {
+	s.ApplyPreset(s.RandomPreset(rand.New(rand.NewSource(time.Now().UnixNano()))))
+	s.PlayPreview()
+}
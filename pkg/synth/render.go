@@ -0,0 +1,169 @@
+package synth
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"gosynth/pkg/engine"
+)
+
+const (
+	RenderTail        = 10 * time.Second       // Default longest tail captured after the pattern ends
+	RenderSilence     = -72.0                  // Default level in dB below which the tail counts as silent
+	renderSilenceHold = 500 * time.Millisecond // How long the tail must stay silent to end the render
+)
+
+// RenderOptions configures an offline render of the sequencer pattern
+type RenderOptions struct {
+	Loops   int           // Times the pattern is played
+	Tail    time.Duration // Longest tail after the pattern ends, for reverb and delay to decay
+	Silence float64       // Level in dB that ends the tail early once the output stays below it; 0 keeps the whole tail
+}
+
+// RenderResult describes a finished render
+type RenderResult struct {
+	Length time.Duration // Duration of the file
+	Tail   time.Duration // Part of it after the pattern ended
+}
+
+// renderEvent is a note start or release, or a one-shot or drum hits, at a frame of an
+// offline render
+type renderEvent struct {
+	frame int
+	on    bool
+	note  uint8
+	vel   uint8
+	shot  int   // One-shot slot from 1, for a one-shot event
+	drums uint8 // Drum voices, one bit per engine.DrumKind, for a drum event
+}
+
+// release reports whether the event ends a note
+func (ev renderEvent) release() bool {
+	return !ev.on && ev.shot == 0 && ev.drums == 0
+}
+
+// RenderPattern plays the sequencer pattern into a WAV file at path, faster than real
+// time. Steps land on exact sample positions at the clock's tempo rather than on the wall
+// clock, and after the last step the effect tails are captured until they fall silent or
+// the tail length runs out, so exports don't cut off. It drives the engine directly, so
+// the synth must not be started.
+func (s *Synth) RenderPattern(path string, opts RenderOptions) (RenderResult, error) {
+	if s.audio != nil {
+		return RenderResult{}, errors.New("can't render offline while the audio output is running")
+	}
+	pattern := s.Seq.Pattern()
+	tickFrames := s.Clock.TickDuration().Seconds() * engine.SampleRate
+	stepFrames := TicksPerStep * tickFrames
+
+	// Lay out every note, one-shot and drum hit of the song, releases first where they meet a start
+	steps := max(1, opts.Loops) * pattern.Length
+	stepStart := func(i int) float64 {
+		return max(0, s.Clock.Swung(uint64(i*TicksPerStep))+float64(pattern.played(i%pattern.Length).Nudge)) * tickFrames
+	}
+	var events []renderEvent
+	for i := 0; i < steps; i++ {
+		step := pattern.played(i % pattern.Length)
+		start := stepStart(i)
+		if step.Shot > 0 {
+			events = append(events, renderEvent{frame: int(math.Round(start)), shot: step.Shot, vel: step.Velocity})
+		}
+		if step.Drums != 0 {
+			events = append(events, renderEvent{frame: int(math.Round(start)), drums: step.Drums, vel: step.Velocity})
+		}
+		if !step.Active {
+			continue
+		}
+		// Like the live sequencer, a note nudged late is cut off by the next one rather than overlapping it
+		end := start + stepFrames*step.Gate
+		if i+1 < steps && pattern.Steps[(i+1)%pattern.Length].Active {
+			end = min(end, stepStart(i+1))
+		}
+		events = append(events,
+			renderEvent{frame: int(math.Round(start)), on: true, note: step.Note, vel: step.Velocity},
+			renderEvent{frame: int(math.Round(end)), note: step.Note},
+		)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].frame != events[j].frame {
+			return events[i].frame < events[j].frame
+		}
+		return events[i].release() && !events[j].release()
+	})
+	songFrames := int(math.Round(float64(max(1, opts.Loops)*pattern.Length) * stepFrames))
+
+	wav, err := engine.CreateWAV(path)
+	if err != nil {
+		return RenderResult{}, err
+	}
+	block := make([]float32, engine.AudioBufferSize*engine.OutputChannels)
+
+	// Render up to each note event, so notes start and stop on their exact frame
+	frame := 0
+	for frame < songFrames {
+		for len(events) > 0 && events[0].frame <= frame {
+			switch {
+			case events[0].shot > 0:
+				s.QueueOneShot(events[0].shot-1, events[0].vel)
+			case events[0].drums != 0:
+				for kind := engine.DrumKind(0); kind < engine.DrumKinds; kind++ {
+					if events[0].drums&(1<<kind) != 0 {
+						s.QueueDrum(kind, events[0].vel)
+					}
+				}
+			case events[0].on:
+				s.playNoteOn(events[0].note, events[0].vel)
+			default:
+				s.playNoteOff(events[0].note)
+			}
+			events = events[1:]
+		}
+		frames := min(engine.AudioBufferSize, songFrames-frame)
+		if len(events) > 0 {
+			frames = min(frames, events[0].frame-frame)
+		}
+		s.Render(block[:frames*engine.OutputChannels])
+		if err := wav.Write(block[:frames*engine.OutputChannels]); err != nil {
+			wav.Close()
+			return RenderResult{}, err
+		}
+		frame += frames
+	}
+	for _, ev := range events {
+		if ev.release() {
+			s.playNoteOff(ev.note) // Gates running past the end of the song
+		}
+	}
+
+	// Capture the tail until it has been quiet for a while or runs out
+	tailFrames := int(opts.Tail.Seconds() * engine.SampleRate)
+	holdFrames := int(renderSilenceHold.Seconds() * engine.SampleRate)
+	threshold := math.Pow(10, opts.Silence/20)
+	tail, quiet := 0, 0
+	for tail < tailFrames && (opts.Silence == 0 || quiet < holdFrames) {
+		frames := min(engine.AudioBufferSize, tailFrames-tail)
+		out := block[:frames*engine.OutputChannels]
+		s.Render(out)
+		if err := wav.Write(out); err != nil {
+			wav.Close()
+			return RenderResult{}, err
+		}
+		tail += frames
+		peak := 0.0
+		for _, x := range out {
+			peak = math.Max(peak, math.Abs(float64(x)))
+		}
+		if peak < threshold {
+			quiet += frames
+		} else {
+			quiet = 0
+		}
+	}
+
+	result := RenderResult{
+		Length: time.Duration(float64(frame+tail) / engine.SampleRate * float64(time.Second)),
+		Tail:   time.Duration(float64(tail) / engine.SampleRate * float64(time.Second)),
+	}
+	return result, wav.Close()
+}
@@ -0,0 +1,301 @@
+package synth
+
+import (
+	"sync"
+	"time"
+
+	"gosynth/pkg/engine"
+)
+
+const (
+	MaxSteps       = 64                       // Longest pattern the sequencer can hold
+	DefaultSteps   = 16                       // Default pattern length
+	StepsPerBeat   = 4                        // Steps are sixteenth notes
+	TicksPerStep   = ClockPPQN / StepsPerBeat // Clock ticks per step
+	StepNote       = 60                       // Default note for new steps (C4)
+	StepVelocity   = 100                      // Default velocity for new steps
+	StepGateLength = 0.5                      // Default fraction of a step a note sounds
+	MaxNudge       = TicksPerStep / 2         // Most ticks a step plays off the grid, half a step
+)
+
+// Step is a single sequencer step
+type Step struct {
+	Active   bool    `json:"active"`
+	Note     uint8   `json:"note"`
+	Velocity uint8   `json:"velocity"`
+	Gate     float64 `json:"gate"`            // Fraction of the step the note sounds
+	Shot     int     `json:"shot,omitempty"`  // One-shot sample slot triggered by the step, from 1; 0 for none
+	Drums    uint8   `json:"drums,omitempty"` // Drum voices triggered by the step, one bit per engine.DrumKind
+	Nudge    int     `json:"nudge,omitempty"` // Ticks the step plays after its place on the grid, or before when negative
+}
+
+// HasDrum reports whether the step triggers a drum voice
+func (st Step) HasDrum(kind engine.DrumKind) bool {
+	return st.Drums&(1<<kind) != 0
+}
+
+// Pattern is a sequence of steps played in a loop
+type Pattern struct {
+	Length int     `json:"length"`
+	Steps  []Step  `json:"steps"`
+	Groove *Groove `json:"groove,omitempty"` // Template laid over the steps as they play, nil for none
+}
+
+// NewPattern creates an empty pattern with the default length
+func NewPattern() *Pattern {
+	p := &Pattern{Length: DefaultSteps, Steps: make([]Step, MaxSteps)}
+	for i := range p.Steps {
+		p.Steps[i] = Step{Note: StepNote, Velocity: StepVelocity, Gate: StepGateLength}
+	}
+	return p
+}
+
+// normalize pads or trims a loaded pattern to MaxSteps and clamps its length
+func (p *Pattern) normalize() {
+	for len(p.Steps) < MaxSteps {
+		p.Steps = append(p.Steps, Step{Note: StepNote, Velocity: StepVelocity, Gate: StepGateLength})
+	}
+	p.Steps = p.Steps[:MaxSteps]
+	if p.Length < 1 {
+		p.Length = 1
+	}
+	if p.Length > MaxSteps {
+		p.Length = MaxSteps
+	}
+	for i := range p.Steps {
+		if p.Steps[i].Shot < 0 || p.Steps[i].Shot > engine.OneShotSlots {
+			p.Steps[i].Shot = 0
+		}
+		p.Steps[i].Drums &= 1<<engine.DrumKinds - 1
+		p.Steps[i].Nudge = max(-MaxNudge, min(p.Steps[i].Nudge, MaxNudge))
+	}
+	if p.Groove != nil {
+		p.Groove.normalize()
+		if len(p.Groove.Timing) == 0 && len(p.Groove.Velocity) == 0 {
+			p.Groove = nil
+		}
+	}
+}
+
+// played returns a step as it plays, with the pattern's groove applied
+func (p *Pattern) played(index int) Step {
+	return p.Groove.apply(p.Steps[index], index)
+}
+
+// Sequencer plays a pattern of steps into the voice engine from its own goroutine,
+// placing each step on the shared clock's sixteenth-note grid
+type Sequencer struct {
+	clock   *Clock
+	noteOn  func(note, velocity uint8)
+	noteOff func(note uint8)
+	shot    func(slot int, velocity uint8)
+	drum    func(kind engine.DrumKind, velocity uint8)
+
+	mu       sync.Mutex
+	pattern  *Pattern              // Pattern edited and played, one of bank
+	bank     [MaxPatterns]*Pattern // Stored patterns
+	selected int                   // Index of pattern in bank
+	song     []SongEntry           // Order the patterns play in song mode
+	songMode bool                  // Play the song rather than loop the pattern
+	songPos  int                   // Entry of the song playing
+	repeat   int                   // Times the entry's pattern has finished
+	current  int                   // Step last taken from the pattern, -1 when stopped
+	sounding int                   // Step last played, shown by the UI; behind current while a step waits out its nudge
+	running  bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewSequencer creates a stopped sequencer with empty patterns, playing notes through
+// noteOn and noteOff, one-shot samples through shot and drum voices through drum
+func NewSequencer(clock *Clock, noteOn func(note, velocity uint8), noteOff func(note uint8), shot func(slot int, velocity uint8), drum func(kind engine.DrumKind, velocity uint8)) *Sequencer {
+	sq := &Sequencer{
+		clock:    clock,
+		noteOn:   noteOn,
+		noteOff:  noteOff,
+		shot:     shot,
+		drum:     drum,
+		current:  -1,
+		sounding: -1,
+	}
+	sq.setSong(SongState{})
+	return sq
+}
+
+// Playing reports whether the transport is running
+func (sq *Sequencer) Playing() bool {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return sq.running
+}
+
+// CurrentStep returns the step being played, or -1 when stopped
+func (sq *Sequencer) CurrentStep() int {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return sq.sounding
+}
+
+// Play starts the transport from the first step
+func (sq *Sequencer) Play() {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if sq.running {
+		return
+	}
+	sq.running = true
+	if sq.songMode && len(sq.song) > 0 {
+		sq.songPos, sq.repeat = 0, 0
+		sq.selectPattern(sq.song[0].Pattern)
+	}
+	sq.stop = make(chan struct{})
+	sq.done = make(chan struct{})
+	go sq.run(sq.stop, sq.done)
+}
+
+// Stop halts the transport and releases the sounding step
+func (sq *Sequencer) Stop() {
+	sq.mu.Lock()
+	if !sq.running {
+		sq.mu.Unlock()
+		return
+	}
+	sq.running = false
+	stop, done := sq.stop, sq.done
+	sq.mu.Unlock()
+
+	close(stop)
+	<-done
+
+	sq.mu.Lock()
+	sq.current, sq.sounding = -1, -1
+	sq.mu.Unlock()
+}
+
+// Pattern returns a copy of the current pattern
+func (sq *Sequencer) Pattern() Pattern {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	p := *sq.pattern
+	p.Steps = append([]Step(nil), sq.pattern.Steps...)
+	return p
+}
+
+// SetPattern replaces the current pattern
+func (sq *Sequencer) SetPattern(p Pattern) {
+	p.Steps = append([]Step(nil), p.Steps...)
+	p.normalize()
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.pattern = &p
+	sq.bank[sq.selected] = &p
+}
+
+// EditStep applies a change to one step of the pattern
+func (sq *Sequencer) EditStep(index int, edit func(step *Step)) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if index >= 0 && index < len(sq.pattern.Steps) {
+		edit(&sq.pattern.Steps[index])
+	}
+}
+
+// SetGroove lays a groove template over the current pattern, or takes it off with nil
+func (sq *Sequencer) SetGroove(g *Groove) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.pattern.Groove = g
+}
+
+// SetLength changes the number of steps in the loop
+func (sq *Sequencer) SetLength(length int) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.pattern.Length = length
+	sq.pattern.normalize()
+}
+
+// advance moves to the next step and returns it. In song mode the end of a pattern
+// counts a repeat of its entry and, after the last, moves to the next entry's pattern.
+func (sq *Sequencer) advance() Step {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if sq.current+1 >= sq.pattern.Length && sq.current >= 0 && sq.songMode && len(sq.song) > 0 {
+		sq.songPos = min(sq.songPos, len(sq.song)-1)
+		if sq.repeat++; sq.repeat >= sq.song[sq.songPos].Repeats {
+			sq.songPos = (sq.songPos + 1) % len(sq.song)
+			sq.repeat = 0
+		}
+		sq.selectPattern(sq.song[sq.songPos].Pattern)
+		sq.current = -1
+	}
+	sq.current = (sq.current + 1) % sq.pattern.Length
+	return sq.pattern.played(sq.current)
+}
+
+// played marks the step last taken from the pattern as sounding
+func (sq *Sequencer) played() {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.sounding = sq.current
+}
+
+// run plays steps until stop is closed. Each step is taken from the pattern half a step
+// ahead of its place on the grid, so one nudged early can still play on time; the note of
+// the step before is released when its gate ends while the next one waits.
+func (sq *Sequencer) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	var offAt time.Time // When the sounding note's gate ends
+	var off *uint8      // Note sounding, if any
+	release := func() {
+		if off != nil {
+			sq.noteOff(*off)
+			off = nil
+		}
+	}
+	defer release()
+
+	// wait sleeps until t, releasing the sounding note on the way when its gate ends first
+	wait := func(t time.Time) bool {
+		if off != nil && offAt.Before(t) {
+			if !waitUntil(offAt, stop) {
+				return false
+			}
+			release()
+		}
+		return waitUntil(t, stop)
+	}
+
+	tick := sq.clock.NextBoundary(TicksPerStep)
+	for {
+		// Wait for the step's position on the clock, which follows tempo changes
+		tickLen := sq.clock.TickDuration()
+		if !wait(sq.clock.TimeAt(tick).Add(-MaxNudge * tickLen)) {
+			return
+		}
+		step := sq.advance()
+		start := sq.clock.TimeAt(tick).Add(time.Duration(step.Nudge) * tickLen)
+		if !wait(start) {
+			return
+		}
+		sq.played()
+		if step.Shot > 0 {
+			sq.shot(step.Shot-1, step.Velocity)
+		}
+		for kind := engine.DrumKind(0); kind < engine.DrumKinds; kind++ {
+			if step.HasDrum(kind) {
+				sq.drum(kind, step.Velocity)
+			}
+		}
+		if step.Active {
+			// A late step with a long gate can still be sounding; end it before the next note
+			release()
+			sq.noteOn(step.Note, step.Velocity)
+			note := step.Note
+			off = &note
+			offAt = start.Add(time.Duration(float64(TicksPerStep*tickLen) * step.Gate))
+		}
+		tick += TicksPerStep
+	}
+}
@@ -0,0 +1,85 @@
+package synth
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSequencerNudge checks that a step nudged late plays that many ticks after its
+// place on the grid, and that nudges are kept within half a step
+func TestSequencerNudge(t *testing.T) {
+	clock := NewClock()
+	clock.BPM.Set(300)
+	clock.Start()
+	defer clock.Stop()
+
+	var mu sync.Mutex
+	var ons []time.Time
+	var offs []uint8
+	sq := NewSequencer(clock, func(note, velocity uint8) {
+		mu.Lock()
+		defer mu.Unlock()
+		ons = append(ons, time.Now())
+	}, func(note uint8) {
+		mu.Lock()
+		defer mu.Unlock()
+		offs = append(offs, note)
+	}, nil, nil)
+
+	pattern := *NewPattern()
+	pattern.Length = 3
+	for i := range pattern.Steps[:3] {
+		pattern.Steps[i].Active = true
+		pattern.Steps[i].Note = uint8(60 + i)
+	}
+	pattern.Steps[1].Nudge = 2 * MaxNudge
+	sq.SetPattern(pattern)
+	if got := sq.Pattern().Steps[1].Nudge; got != MaxNudge {
+		t.Fatalf("nudge of %d ticks kept as %d, want %d", 2*MaxNudge, got, MaxNudge)
+	}
+
+	sq.Play()
+	time.Sleep(4 * TicksPerStep * clock.TickDuration())
+	sq.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ons) < 3 {
+		t.Fatalf("%d notes played, want at least 3", len(ons))
+	}
+	tick := clock.TickDuration()
+	for i, want := range []time.Duration{(TicksPerStep + MaxNudge) * tick, (TicksPerStep - MaxNudge) * tick} {
+		// Leave room for the scheduler, but less than the nudge itself
+		if gap := ons[i+1].Sub(ons[i]); gap < want-MaxNudge*tick/2 || gap > want+MaxNudge*tick/2 {
+			t.Errorf("step %d played %v after step %d, want %v", i+2, gap, i+1, want)
+		}
+	}
+	if len(offs) != len(ons) {
+		t.Errorf("%d notes released after stopping, want all %d", len(offs), len(ons))
+	}
+}
+
+// TestGrooveLeavesStepsAlone checks that a groove moves and accents the steps as they
+// play, repeating over its cycle, without changing the pattern's steps
+func TestGrooveLeavesStepsAlone(t *testing.T) {
+	pattern := *NewPattern()
+	pattern.Steps[1].Nudge = MaxNudge - 1
+	pattern.Steps[2].Velocity = 3
+	pattern.Groove = &Groove{Name: "test", Timing: []int{0, 4, 2 * MaxNudge}, Velocity: []int{0, -8, -10}}
+	pattern.normalize()
+
+	for i, want := range []Step{
+		{Note: StepNote, Velocity: StepVelocity, Gate: StepGateLength},
+		{Note: StepNote, Velocity: StepVelocity - 8, Gate: StepGateLength, Nudge: MaxNudge},
+		{Note: StepNote, Velocity: 1, Gate: StepGateLength, Nudge: MaxNudge},
+		{Note: StepNote, Velocity: StepVelocity, Gate: StepGateLength},
+	} {
+		if got := pattern.played(i); got != want {
+			t.Errorf("step %d plays as %+v, want %+v", i+1, got, want)
+		}
+	}
+	if pattern.Steps[1].Nudge != MaxNudge-1 || pattern.Steps[2].Velocity != 3 {
+		t.Errorf("groove changed the steps: %+v, %+v", pattern.Steps[1], pattern.Steps[2])
+	}
+}
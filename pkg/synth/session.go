@@ -0,0 +1,195 @@
+package synth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gosynth/pkg/engine"
+)
+
+// Session is the state the synth was left in, saved on quit and restored on the next start
+type Session struct {
+	State    Preset          `json:"state"`           // Every parameter, named after the selected preset
+	Settings SessionSettings `json:"settings"`        // State presets don't keep
+	Page     int             `json:"page"`            // Page the UI was showing
+	Parts    []PartState     `json:"parts,omitempty"` // Multitimbral parts besides the main synth
+}
+
+// SessionSettings are the switches and choices outside presets: the arpeggiator, split,
+// drum map, drone layer chord, output utilities, where the sequencer and arpeggiator play,
+// the scale filter, chord memory and latch mode
+type SessionSettings struct {
+	Arp        bool               `json:"arp"`
+	ArpMode    ArpMode            `json:"arpMode"`
+	ArpSync    bool               `json:"arpSync"`
+	ArpDiv     int                `json:"arpDiv"`
+	Split      bool               `json:"split"`
+	SplitLow   uint8              `json:"splitLow"`
+	SplitHigh  uint8              `json:"splitHigh"`
+	SplitChan  uint8              `json:"splitChannel"`
+	GMDrums    bool               `json:"gmDrums"`
+	DrumVoices bool               `json:"drumVoices"`
+	DroneLayer bool               `json:"droneLayer"`
+	DroneRoot  uint8              `json:"droneRoot"`
+	DroneChord int                `json:"droneChord"`
+	DroneWave  engine.DroneWave   `json:"droneWave"`
+	Output     engine.OutputUtils `json:"output"`
+	SeqDest    NoteDest           `json:"seqDest"`
+	SeqChannel uint8              `json:"seqChannel"`
+	ArpDest    NoteDest           `json:"arpDest"`
+	ArpChannel uint8              `json:"arpChannel"`
+	Quantize   QuantizeMode       `json:"quantize"`
+	ScaleKey   int                `json:"scaleKey"`
+	Scale      NoteScale          `json:"scale"`
+	UserScale  uint16             `json:"userScale,omitempty"` // Pitch classes of the user scale, one bit each from C
+	Chord      bool               `json:"chord"`
+	ChordShape []int              `json:"chordShape,omitempty"`  // Semitones above the played note
+	ChordOdds  []int              `json:"chordChance,omitempty"` // Percent chance of each note of the chord sounding
+	Strum      float64            `json:"strum,omitempty"`       // Milliseconds from one note of a chord to the next
+	StrumDir   StrumDirection     `json:"strumDirection,omitempty"`
+	LatchMode  LatchMode          `json:"latchMode"`
+	InputLevel *float64           `json:"inputLevel,omitempty"`  // Gain of the audio input; full when missing
+	FilePatch  *bool              `json:"filePatches,omitempty"` // MIDI files choose their patches; on when missing
+}
+
+// captureSettings snapshots the state presets don't keep
+func (s *Synth) captureSettings() SessionSettings {
+	inputLevel := s.Input.Level.Get()
+	filePatches := s.Player.MapPatches()
+	strum, strumDir := s.Chord.Strum()
+	splitLow, splitHigh := s.Split.Range()
+	return SessionSettings{
+		Arp:        s.Arp.Enabled(),
+		ArpMode:    s.Arp.Mode(),
+		ArpSync:    s.Arp.Synced(),
+		ArpDiv:     s.Arp.Div(),
+		Split:      s.Split.Enabled(),
+		SplitLow:   splitLow,
+		SplitHigh:  splitHigh,
+		SplitChan:  s.Split.Channel(),
+		GMDrums:    s.GMDrums(),
+		DrumVoices: s.DrumVoices(),
+		DroneLayer: s.DroneLayer.Enabled(),
+		DroneRoot:  s.DroneLayer.Root(),
+		DroneChord: s.DroneLayer.Chord(),
+		DroneWave:  s.DroneLayer.Wave(),
+		Output:     s.Output(),
+		SeqDest:    s.SeqOut.Dest(),
+		SeqChannel: s.SeqOut.Channel(),
+		ArpDest:    s.ArpOut.Dest(),
+		ArpChannel: s.ArpOut.Channel(),
+		Quantize:   s.Quantize.Mode(),
+		ScaleKey:   s.Quantize.Key(),
+		Scale:      s.Quantize.Scale(),
+		UserScale:  s.Quantize.UserScale(),
+		Chord:      s.Chord.Enabled(),
+		ChordShape: s.Chord.Intervals(),
+		ChordOdds:  s.Chord.Chances(),
+		Strum:      float64(strum) / float64(time.Millisecond),
+		StrumDir:   strumDir,
+		LatchMode:  s.Latch.Mode(),
+		InputLevel: &inputLevel,
+		FilePatch:  &filePatches,
+	}
+}
+
+// applySettings restores the state presets don't keep, within the ranges the UI allows
+func (s *Synth) applySettings(settings SessionSettings) {
+	s.Arp.SetMode(ArpMode(max(0, min(int(settings.ArpMode), int(arpModeCount)-1))))
+	s.Arp.SetSynced(settings.ArpSync)
+	s.Arp.SetDiv(settings.ArpDiv)
+	s.SetArp(settings.Arp)
+	s.Split.SetEnabled(settings.Split)
+	s.Split.SetRange(settings.SplitLow, settings.SplitHigh)
+	s.Split.SetChannel(settings.SplitChan)
+	s.SetGMDrums(settings.GMDrums)
+	s.SetDrumVoices(settings.DrumVoices)
+	s.DroneLayer.SetRoot(int(settings.DroneRoot))
+	s.DroneLayer.SetChord(settings.DroneChord)
+	s.DroneLayer.SetWave(settings.DroneWave.Next(0))
+	s.DroneLayer.SetEnabled(settings.DroneLayer)
+	s.SetOutput(settings.Output)
+	s.SeqOut.SetDest(settings.SeqDest)
+	s.SeqOut.SetChannel(settings.SeqChannel)
+	s.ArpOut.SetDest(settings.ArpDest)
+	s.ArpOut.SetChannel(settings.ArpChannel)
+	s.Quantize.SetMode(settings.Quantize)
+	s.Quantize.SetKey(settings.ScaleKey)
+	s.Quantize.SetScale(settings.Scale)
+	if settings.UserScale != 0 {
+		s.Quantize.SetUserScale(settings.UserScale)
+	}
+	s.Chord.SetEnabled(settings.Chord)
+	if settings.ChordShape != nil {
+		s.Chord.SetIntervals(settings.ChordShape)
+	}
+	s.Chord.SetChances(settings.ChordOdds)
+	s.Chord.SetStrum(time.Duration(settings.Strum*float64(time.Millisecond)), settings.StrumDir)
+	s.Latch.SetMode(settings.LatchMode)
+	if settings.InputLevel != nil {
+		s.Input.Level.Set(max(0, min(*settings.InputLevel, 1)))
+	}
+	if settings.FilePatch != nil {
+		s.Player.SetMapPatches(*settings.FilePatch)
+	}
+}
+
+// SessionPath returns the file the last session is saved in
+func SessionPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gosynth", "session.json"), nil
+}
+
+// SaveSession writes the current state and the UI page to the session file
+func (s *Synth) SaveSession(page int) error {
+	path, err := SessionPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(Session{
+		State:    s.CapturePreset(s.PresetName()),
+		Settings: s.captureSettings(),
+		Page:     page,
+		Parts:    s.PartStates(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadSession restores the state saved by SaveSession at once, without a crossfade, and
+// returns the session. With no saved session the synth is left as it is.
+func (s *Synth) LoadSession() (Session, error) {
+	path, err := SessionPath()
+	if err != nil {
+		return Session{}, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Session{}, nil
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return Session{}, err
+	}
+
+	fade := s.PresetFade.Get()
+	s.PresetFade.Set(0)
+	s.ApplyPreset(session.State)
+	s.PresetFade.Set(fade)
+	s.applySettings(session.Settings)
+	s.SetPartStates(session.Parts)
+	return session, nil
+}
@@ -0,0 +1,215 @@
+package synth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxPatterns    = 16 // Patterns the sequencer stores
+	MaxSongEntries = 64 // Longest song order
+	MaxRepeats     = 64 // Most times one song entry repeats its pattern
+)
+
+// SongEntry is a pattern played some number of times in a row in the song
+type SongEntry struct {
+	Pattern int `json:"pattern"` // Index into the stored patterns
+	Repeats int `json:"repeats"`
+}
+
+// SongState is the stored patterns and the song arranging them, as saved with presets
+type SongState struct {
+	Patterns []Pattern   `json:"patterns"`        // Stored patterns, without blank ones at the end
+	Selected int         `json:"selected"`        // Pattern edited and looped
+	Order    []SongEntry `json:"order,omitempty"` // Patterns in the order the song plays them
+	Enabled  bool        `json:"enabled"`         // Play the song rather than loop the selected pattern
+}
+
+// blank reports whether a pattern is as NewPattern creates it
+func (p *Pattern) blank() bool {
+	if p.Length != DefaultSteps || p.Groove != nil {
+		return false
+	}
+	for _, st := range p.Steps {
+		if st != (Step{Note: StepNote, Velocity: StepVelocity, Gate: StepGateLength}) {
+			return false
+		}
+	}
+	return true
+}
+
+// SongState returns the stored patterns and song for saving
+func (sq *Sequencer) SongState() SongState {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	last := sq.selected
+	for i, p := range sq.bank {
+		if !p.blank() {
+			last = max(last, i)
+		}
+	}
+	for _, e := range sq.song {
+		last = max(last, e.Pattern)
+	}
+	st := SongState{Selected: sq.selected, Order: append([]SongEntry(nil), sq.song...), Enabled: sq.songMode}
+	for _, p := range sq.bank[:last+1] {
+		c := *p
+		c.Steps = append([]Step(nil), p.Steps...)
+		st.Patterns = append(st.Patterns, c)
+	}
+	return st
+}
+
+// SetSongState replaces the stored patterns and song; missing patterns are blank
+func (sq *Sequencer) SetSongState(st SongState) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.setSong(st)
+}
+
+// setSong replaces the stored patterns and song, with the lock held
+func (sq *Sequencer) setSong(st SongState) {
+	for i := range sq.bank {
+		p := NewPattern()
+		if i < len(st.Patterns) {
+			*p = st.Patterns[i]
+			p.Steps = append([]Step(nil), p.Steps...)
+			p.normalize()
+		}
+		sq.bank[i] = p
+	}
+	sq.song = checkSong(st.Order)
+	sq.songMode = st.Enabled
+	sq.songPos, sq.repeat = 0, 0
+	sq.selectPattern(st.Selected)
+}
+
+// checkSong keeps the song entries naming a stored pattern, repeating at least once
+func checkSong(order []SongEntry) []SongEntry {
+	var song []SongEntry
+	for _, e := range order {
+		if e.Pattern >= 0 && e.Pattern < MaxPatterns && len(song) < MaxSongEntries {
+			song = append(song, SongEntry{Pattern: e.Pattern, Repeats: max(1, min(e.Repeats, MaxRepeats))})
+		}
+	}
+	return song
+}
+
+// Selected returns the index of the pattern being edited and played
+func (sq *Sequencer) Selected() int {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return sq.selected
+}
+
+// SelectPattern switches to editing and playing a stored pattern. A running sequencer
+// carries on from the same step of the new pattern.
+func (sq *Sequencer) SelectPattern(index int) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.selectPattern(index)
+}
+
+// selectPattern switches patterns with the lock held
+func (sq *Sequencer) selectPattern(index int) {
+	sq.selected = max(0, min(index, MaxPatterns-1))
+	sq.pattern = sq.bank[sq.selected]
+}
+
+// CopyPattern copies the selected pattern over another stored pattern
+func (sq *Sequencer) CopyPattern(to int) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if to < 0 || to >= MaxPatterns || to == sq.selected {
+		return
+	}
+	p := *sq.pattern
+	p.Steps = append([]Step(nil), sq.pattern.Steps...)
+	sq.bank[to] = &p
+}
+
+// SongMode reports whether the sequencer plays the song rather than loop the pattern
+func (sq *Sequencer) SongMode() bool {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return sq.songMode
+}
+
+// SetSongMode switches between playing the song and looping the selected pattern. A
+// running sequencer picks up the song at its first entry when the pattern ends.
+func (sq *Sequencer) SetSongMode(on bool) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if on && !sq.songMode && len(sq.song) > 0 {
+		sq.songPos, sq.repeat = len(sq.song)-1, MaxRepeats
+	}
+	sq.songMode = on
+}
+
+// Song returns the song order
+func (sq *Sequencer) Song() []SongEntry {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return append([]SongEntry(nil), sq.song...)
+}
+
+// SetSong replaces the song order, restarting it from the first entry
+func (sq *Sequencer) SetSong(order []SongEntry) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.song = checkSong(order)
+	sq.songPos, sq.repeat = 0, 0
+}
+
+// SongPosition returns the song entry playing and how many times its pattern has
+// finished, or -1 when the song isn't playing
+func (sq *Sequencer) SongPosition() (entry, repeat int) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if !sq.running || !sq.songMode || len(sq.song) == 0 {
+		return -1, 0
+	}
+	entry = min(sq.songPos, len(sq.song)-1)
+	return entry, min(sq.repeat, sq.song[entry].Repeats-1)
+}
+
+// FormatSong writes a song order as pattern numbers from 1, with repeats after an x,
+// such as "1x4 2 3x2"
+func FormatSong(order []SongEntry) string {
+	if len(order) == 0 {
+		return "empty"
+	}
+	parts := make([]string, len(order))
+	for i, e := range order {
+		parts[i] = strconv.Itoa(e.Pattern + 1)
+		if e.Repeats > 1 {
+			parts[i] += "x" + strconv.Itoa(e.Repeats)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseSong reads a song order written as FormatSong does, separated by spaces or commas
+func ParseSong(text string) ([]SongEntry, error) {
+	var order []SongEntry
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' }) {
+		number, repeats, found := strings.Cut(strings.ToLower(field), "x")
+		e := SongEntry{Repeats: 1}
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 1 || n > MaxPatterns {
+			return nil, fmt.Errorf("bad pattern %q, expected 1 to %d", field, MaxPatterns)
+		}
+		e.Pattern = n - 1
+		if found {
+			if e.Repeats, err = strconv.Atoi(repeats); err != nil || e.Repeats < 1 || e.Repeats > MaxRepeats {
+				return nil, fmt.Errorf("bad repeats in %q, expected 1 to %d", field, MaxRepeats)
+			}
+		}
+		order = append(order, e)
+	}
+	if len(order) > MaxSongEntries {
+		return nil, fmt.Errorf("at most %d entries in a song", MaxSongEntries)
+	}
+	return order, nil
+}
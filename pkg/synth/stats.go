@@ -0,0 +1,150 @@
+package synth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const StatsIdleGap = 10 * time.Second // Longest pause between played notes that still counts as playing
+
+// StatsRecord is what was played over some span: a session or every session together
+type StatsRecord struct {
+	Played   time.Duration            `json:"played"`   // Time spent playing, not counting pauses longer than StatsIdleGap
+	Notes    int                      `json:"notes"`    // Notes received from MIDI and the keyboard piano
+	Presets  map[string]time.Duration `json:"presets"`  // Time played with each preset
+	Sessions int                      `json:"sessions"` // Runs of the synth
+}
+
+// PresetTime is the time played with a preset
+type PresetTime struct {
+	Name   string
+	Played time.Duration
+}
+
+// Stats counts the notes played and the time spent playing, for a practice timer, and
+// keeps the totals of earlier sessions in a file
+type Stats struct {
+	mu       sync.Mutex
+	session  StatsRecord
+	previous StatsRecord // Totals of the sessions before this one
+	lastNote time.Time
+}
+
+// NewStats starts the statistics of a new session
+func NewStats() *Stats {
+	return &Stats{
+		session:  StatsRecord{Presets: make(map[string]time.Duration), Sessions: 1},
+		previous: StatsRecord{Presets: make(map[string]time.Duration)},
+	}
+}
+
+// StatsPath returns the file the statistics of earlier sessions are kept in
+func StatsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gosynth", "stats.json"), nil
+}
+
+// noteOn counts a received note, adding the time since the previous one to the time
+// played when the pause was short enough to still be playing
+func (st *Stats) noteOn(preset string, now time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.session.Notes++
+	if gap := now.Sub(st.lastNote); !st.lastNote.IsZero() && gap < StatsIdleGap {
+		st.session.Played += gap
+		st.session.Presets[preset] += gap
+	}
+	st.lastNote = now
+}
+
+// Session returns the statistics of this session
+func (st *Stats) Session() StatsRecord {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.session.merge(StatsRecord{})
+}
+
+// Total returns the statistics of every session, this one included
+func (st *Stats) Total() StatsRecord {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.previous.merge(st.session)
+}
+
+// merge returns the sum of two records
+func (r StatsRecord) merge(o StatsRecord) StatsRecord {
+	sum := StatsRecord{
+		Played:   r.Played + o.Played,
+		Notes:    r.Notes + o.Notes,
+		Presets:  make(map[string]time.Duration),
+		Sessions: r.Sessions + o.Sessions,
+	}
+	for name, played := range r.Presets {
+		sum.Presets[name] += played
+	}
+	for name, played := range o.Presets {
+		sum.Presets[name] += played
+	}
+	return sum
+}
+
+// TopPresets returns up to n presets played longest, longest first
+func (r StatsRecord) TopPresets(n int) []PresetTime {
+	var top []PresetTime
+	for name, played := range r.Presets {
+		top = append(top, PresetTime{Name: name, Played: played})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Played != top[j].Played {
+			return top[i].Played > top[j].Played
+		}
+		return top[i].Name < top[j].Name
+	})
+	return top[:min(n, len(top))]
+}
+
+// Load reads the totals of earlier sessions; a missing file starts from nothing
+func (st *Stats) Load() error {
+	path, err := StatsPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var previous StatsRecord
+	if err := json.Unmarshal(data, &previous); err != nil {
+		return err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.previous = previous.merge(StatsRecord{})
+	return nil
+}
+
+// Save writes the totals, this session included, to the statistics file
+func (st *Stats) Save() error {
+	path, err := StatsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st.Total(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -1,209 +1,290 @@
+// Package synth drives the sound engine from PortAudio and MIDI, and adds the
+// performance features around it: arpeggiator, sequencer, latch, split, clock,
+// control surfaces, presets and history.
+//
+// Building with the noportaudio tag replaces the audio device with a null backend
+// that renders and discards the output, so the package builds without PortAudio.
+// MIDI is only opened when the program registers a gomidi driver.
 package synth
 
 import (
-	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
 
-	"github.com/gordonklaus/portaudio"
-	"gitlab.com/gomidi/midi/v2"
-)
+	"gosynth/pkg/engine"
 
-const (
-	SampleRate      = 44100
-	MinModFreq      = 100.0 // Minimum modulation frequency in Hz
-	MaxModFreq      = 600.0 // Maximum modulation frequency in Hz
-	FreqSweepTime   = .300  // Time to finish 10Hz of sweep
-	ModulationIndex = 0.5   // Modulation intensity
-	ClipThreshold   = 0.6   // Threshold where soft clipping begins
-	ClipHardLimit   = 0.85  // Maximum amplitude after clipping
-	InitialVolume   = 0.75  // Initial volume level
-	AudioBufferSize = 2048  // Increased buffer size for more stability
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/drivers"
 )
 
-// SmoothValue represents a parameter value
-type SmoothValue struct {
-	value float64
-}
-
-func (sv *SmoothValue) Update() {
-	// No smoothing needed
-}
-
-func (sv *SmoothValue) Set(value float64) {
-	sv.value = value
-}
-
-func (sv *SmoothValue) Get() float64 {
-	return sv.value
+// audioStream is an open audio output backend pulling blocks from the engine
+type audioStream interface {
+	Close() error
 }
 
 // Synth represents the synthesizer state
 type Synth struct {
-	CarrierFreq SmoothValue
-	MinModFreq  SmoothValue
-	MaxModFreq  SmoothValue
-	SweepTime   SmoothValue
-	ModIndex    SmoothValue
-	Volume      SmoothValue
-	stream      *portaudio.Stream
+	*engine.Engine
+
+	NoMIDI      bool // Leave the MIDI ports closed even when a driver is registered
+	Arp         *Arpeggiator
+	routed      routedNotes // Notes routed to the voices or arpeggiator and not yet released
+	Latch       *Latch
+	Quantize    *Quantizer   // Scale filter on played notes
+	Chord       *ChordMemory // Chord shape played from each key
+	Macros      *MacroSet    // Knobs each moving several parameters
+	Split       *Split
+	MIDIOut     *MIDIOut
+	SeqOut      *NoteRoute // Where the sequencer plays its notes
+	ArpOut      *NoteRoute // Where the arpeggiator plays its notes
+	partsMu     sync.Mutex
+	parts       []*Part // Multitimbral parts besides the main synth
+	Seq         *Sequencer
+	Player      *MIDIPlayer   // Standard MIDI File playback
+	MIDIRec     *MIDIRecorder // Performance capture to a Standard MIDI File
+	Clock       *Clock
+	History     *History
+	Locks       *Locks        // Parameters preset loads leave alone
+	Programs    *ProgramMap   // Presets selected by MIDI program changes
+	Stats       *Stats        // Notes and playing time, this session and in total
+	Preview     *Preview      // Chord played on startup and preset load
+	Controllers []*Controller // Detected control surfaces
+	Config      Config        // Audio and MIDI ports used by Start, and the UI's startup options
+	audio       audioStream
 	stopMIDI    func()
-	buffer      []float32 // Add audio buffer
-	timeIndex   float64   // Move timeIndex into the struct
+	midiInputs  []string      // Input ports listened to
+	midiSeen    []string      // Every MIDI port found when the ports were last opened
+	configWatch *configWatch  // Config file as last read, once watched
+	osc         *OSCServer    // Remote control server, while running
+	http        *HTTPServer   // JSON API server, while running
+	stopWatch   chan struct{} // Closed to end the change watcher
+	watchDone   chan struct{}
+	clockOut    bool                   // Send MIDI clock to the output port
+	gmDrums     atomic.Bool            // Play MIDI channel 10 from the SoundFont drum kit
+	drumVoices  atomic.Bool            // Play the kick, snare and hi-hat notes of MIDI channel 10 from the drum synthesis
+	presetName  atomic.Pointer[string] // Last loaded or saved preset; set from the UI, API and MIDI goroutines
 }
 
 // NewSynth creates a new synthesizer instance
 func NewSynth() *Synth {
-	return &Synth{
-		CarrierFreq: SmoothValue{value: 440.0}, // Start with A4 note
-		MinModFreq:  SmoothValue{value: MinModFreq},
-		MaxModFreq:  SmoothValue{value: MaxModFreq},
-		SweepTime:   SmoothValue{value: FreqSweepTime},
-		ModIndex:    SmoothValue{value: ModulationIndex},
-		Volume:      SmoothValue{value: InitialVolume},
-		buffer:      make([]float32, AudioBufferSize),
-		timeIndex:   0,
-	}
-}
-
-// MIDINoteToFreq converts a MIDI note number to frequency
-func MIDINoteToFreq(note uint8) float64 {
-	return 440.0 * math.Pow(2, (float64(note)-69.0)/12.0)
+	s := &Synth{}
+	s.Clock = NewClock()
+	s.Clock.OnTick(s.sendClock)
+	s.Engine = engine.NewEngine(s.Clock)
+	s.SeqOut = NewNoteRoute()
+	s.ArpOut = NewNoteRoute()
+	s.Arp = NewArpeggiator(s.Clock, s.partNoteOn(s.ArpOut), s.partNoteOff(s.ArpOut))
+	s.Latch = NewLatch()
+	s.Quantize = NewQuantizer()
+	s.Chord = NewChordMemory()
+	s.Macros = NewMacroSet()
+	s.Split = NewSplit()
+	s.MIDIOut = &MIDIOut{}
+	s.MIDIRec = &MIDIRecorder{}
+	s.Seq = NewSequencer(s.Clock, s.recordNoteOn(s.SeqOut), s.recordNoteOff(s.SeqOut), s.QueueOneShot, s.QueueDrum)
+	s.Player = NewMIDIPlayer(s)
+	s.History = NewHistory()
+	s.Locks = NewLocks()
+	s.Programs = NewProgramMap()
+	s.Stats = NewStats()
+	s.Preview = NewPreview()
+	s.Config = DefaultConfig()
+	s.setPresetName(DefaultPresetName)
+	return s
 }
 
-// CalculateModulatorFreq returns the current modulator frequency based on time
-func (s *Synth) CalculateModulatorFreq(t float64) float64 {
-	// Calculate how many periods have passed
-	periods := t / s.SweepTime.Get()
-
-	// Calculate the frequency range
-	freqRange := s.MaxModFreq.Get() - s.MinModFreq.Get()
-
-	// Calculate the frequency increase (wrap around using modulo)
-	freqIncrease := math.Mod(periods*freqRange, freqRange)
-
-	// Calculate current frequency
-	return s.MinModFreq.Get() + freqIncrease
-}
-
-// SoftClip applies soft clipping to prevent harsh distortion
-func SoftClip(sample float64) float64 {
-	// Apply a hyperbolic tangent-based soft clipper
-	if math.Abs(sample) > ClipThreshold {
-		// Calculate how much the signal exceeds the threshold
-		excess := math.Abs(sample) - ClipThreshold
-
-		// Apply progressively stronger compression as the signal gets louder
-		compressionFactor := 1.0 - math.Min(1.0, excess/(ClipHardLimit-ClipThreshold))
-
-		// Determine the sign of the original sample
-		sign := 1.0
-		if sample < 0 {
-			sign = -1.0
-		}
+// Start initializes and starts the synthesizer
+func (s *Synth) Start() error {
+	s.openMIDI()
 
-		// Apply the compression
-		return sign * (ClipThreshold + excess*compressionFactor)
+	// Open the audio output, which pulls blocks from the engine, and any input mixed into them
+	audio, err := openAudio(s.RenderInput, s.Config.AudioDevice, s.Config.AudioInput, s.Config.BufferSize)
+	if err != nil {
+		return err
 	}
-	return sample
-}
-
-// AudioCallback processes audio samples
-func (s *Synth) AudioCallback(out []float32) {
-	// Process audio
-	for i := range out {
-		t := s.timeIndex + float64(i)/SampleRate
-
-		// Generate carrier signal
-		carrier := math.Sin(2 * math.Pi * s.CarrierFreq.Get() * t)
-
-		// Calculate modulator wave
-		modFreq := s.CalculateModulatorFreq(t)
-		modulator := math.Sin(2 * math.Pi * modFreq * t)
+	s.audio = audio
 
-		// Apply amplitude modulation
-		sample := carrier * (1 + s.ModIndex.Get()*modulator)
-
-		// Apply soft clipping to prevent distortion
-		sample = SoftClip(sample)
-
-		// Apply volume control and store in buffer
-		s.buffer[i] = float32(sample * s.Volume.Get())
-	}
+	// Start the shared tempo clock that the sequencer and arpeggiator follow
+	s.Clock.Start()
 
-	// Copy buffer to output
-	copy(out, s.buffer[:len(out)])
+	// Publish parameter and transport changes for the UI and extensions
+	s.stopWatch, s.watchDone = make(chan struct{}), make(chan struct{})
+	go s.watchChanges(s.stopWatch, s.watchDone)
 
-	s.timeIndex += float64(len(out)) / SampleRate
+	s.PlayPreview()
+	return nil
 }
 
-// Start initializes and starts the synthesizer
-func (s *Synth) Start() error {
-	// Initialize PortAudio
-	err := portaudio.Initialize()
-	if err != nil {
-		return err
+// openMIDI listens to the MIDI inputs and opens the first output, when the program
+// has registered a MIDI driver
+func (s *Synth) openMIDI() {
+	if s.NoMIDI || drivers.Get() == nil {
+		return
 	}
 
-	// Try to initialize MIDI, but continue even if it fails
+	// Try to initialize MIDI, but continue even if it fails. The configured input, or
+	// the first, is played; recognised control surfaces are listened to as well.
+	var stops []func()
+	s.midiInputs = nil
 	ports := midi.GetInPorts()
-	if len(ports) > 0 {
-		inPort, err := midi.InPort(0)
-		if err == nil {
-			// Set up MIDI message handling
-			stopListening, err := midi.ListenTo(inPort, func(msg midi.Message, timestampms int32) {
-				var channel, key, velocity uint8
-				if msg.GetNoteStart(&channel, &key, &velocity) {
-					s.CarrierFreq.Set(MIDINoteToFreq(key))
-				}
-			})
-			if err == nil {
-				s.stopMIDI = stopListening
-			}
+	played := 0
+	for i, port := range ports {
+		if s.Config.MIDIIn != "" && strings.Contains(port.String(), s.Config.MIDIIn) {
+			played = i
+			break
 		}
 	}
-
-	// Get default output device
-	defaultDevice, err := portaudio.DefaultOutputDevice()
-	if err != nil {
-		return err
+	for i, port := range ports {
+		profile := FindControllerProfile(port.String())
+		if i != played && profile == nil {
+			continue
+		}
+		var controller *Controller
+		if profile != nil {
+			controller = s.newController(profile, port.String())
+			s.Controllers = append(s.Controllers, controller)
+		}
+		if stop, err := midi.ListenTo(port, s.midiHandler(controller)); err == nil {
+			stops = append(stops, stop)
+			s.midiInputs = append(s.midiInputs, port.String())
+		}
 	}
 
-	// Set up high-priority audio stream with optimal buffer size
-	streamParams := portaudio.StreamParameters{
-		Output: portaudio.StreamDeviceParameters{
-			Device:   defaultDevice,
-			Channels: 1,
-			Latency:  defaultDevice.DefaultHighOutputLatency,
-		},
-		SampleRate:      SampleRate,
-		FramesPerBuffer: AudioBufferSize,
+	// A virtual input lets other programs, such as a DAW, play the synth without a
+	// hardware loopback, with drivers that can create one (rtmidi on ALSA and CoreMIDI)
+	virtual, ok := drivers.Get().(virtualInDriver)
+	if ok && s.Config.VirtualIn != "" {
+		if port, err := virtual.OpenVirtualIn(s.Config.VirtualIn); err == nil {
+			if stop, err := midi.ListenTo(port, s.midiHandler(nil)); err == nil {
+				stops = append(stops, stop)
+				s.midiInputs = append(s.midiInputs, s.Config.VirtualIn+" (virtual)")
+			}
+			stops = append(stops, func() { port.Close() })
+		}
+	}
+	s.stopMIDI = func() {
+		for _, stop := range stops {
+			stop()
+		}
 	}
 
-	// Open audio stream with optimized parameters
-	stream, err := portaudio.OpenStream(streamParams, func(out []float32) {
-		s.AudioCallback(out)
-	})
-	if err != nil {
-		return err
+	// Open the configured MIDI output, or the first, for notes routed to external gear.
+	// The virtual input may be listed as an output, which would play the synth from itself.
+	if s.Config.MIDIOut == "" || s.MIDIOut.OpenNamed(s.Config.MIDIOut) != nil {
+		for i, port := range midi.GetOutPorts() {
+			if s.Config.VirtualIn == "" || !strings.Contains(port.String(), s.Config.VirtualIn) {
+				s.MIDIOut.Open(i)
+				break
+			}
+		}
 	}
-	s.stream = stream
+	s.midiSeen = midiPortNames()
+}
 
-	return stream.Start()
+// virtualInDriver is a MIDI driver that can create a virtual input port
+type virtualInDriver interface {
+	OpenVirtualIn(name string) (drivers.In, error)
 }
 
-// Stop cleans up and stops the synthesizer
-func (s *Synth) Stop() error {
+// closeMIDI stops listening to the MIDI inputs and closes the control surfaces and output
+func (s *Synth) closeMIDI() {
 	if s.stopMIDI != nil {
 		s.stopMIDI()
+		s.stopMIDI = nil
+	}
+	for _, c := range s.Controllers {
+		c.Close()
 	}
-	if s.stream != nil {
-		if err := s.stream.Close(); err != nil {
-			return err
+	s.Controllers = nil
+	s.MIDIOut.Close()
+}
+
+// reopenMIDI opens the MIDI ports again, as after the configured ports change
+func (s *Synth) reopenMIDI() {
+	s.closeMIDI()
+	s.openMIDI()
+}
+
+// midiHandler returns the listener for an input port, mapping CCs through the
+// port's control surface when it has one
+func (s *Synth) midiHandler(surface *Controller) func(msg midi.Message, timestampms int32) {
+	return func(msg midi.Message, timestampms int32) {
+		if s.handleClockMessage(msg) {
+			return
+		}
+		s.MIDIRec.record(recordInput, msg)
+		var channel, key, velocity, controller, value, program uint8
+		switch {
+		case msg.GetNoteStart(&channel, &key, &velocity) && s.handleKillNote(key, true):
+		case msg.GetNoteEnd(&channel, &key) && s.handleKillNote(key, false):
+		case s.DrumVoices() && msg.GetNoteStart(&channel, &key, &velocity) && channel == DrumChannel && DrumVoiceFor(key) >= 0:
+			s.PlayDrum(DrumVoiceFor(key), velocity)
+		case s.DrumVoices() && msg.GetNoteEnd(&channel, &key) && channel == DrumChannel && DrumVoiceFor(key) >= 0:
+			// Drum voices play out their decay
+		case s.GMDrums() && msg.GetNoteStart(&channel, &key, &velocity) && channel == DrumChannel:
+			s.DrumNoteOn(key, velocity)
+		case s.GMDrums() && msg.GetNoteEnd(&channel, &key) && channel == DrumChannel:
+			s.DrumNoteOff(key)
+		case msg.GetNoteStart(&channel, &key, &velocity) && s.partFor(channel) != nil:
+			s.partFor(channel).Synth.NoteOn(key, velocity)
+		case msg.GetNoteEnd(&channel, &key) && s.partFor(channel) != nil:
+			s.partFor(channel).Synth.NoteOff(key)
+		case msg.GetNoteStart(&channel, &key, &velocity):
+			s.NoteOn(key, velocity)
+		case msg.GetNoteEnd(&channel, &key):
+			s.NoteOff(key)
+		case msg.GetControlChange(&channel, &controller, &value):
+			switch {
+			case controller == SustainCC && s.partFor(channel) != nil:
+				s.partFor(channel).Synth.SetSustain(value >= 64)
+			case controller == SustainCC:
+				s.SetSustain(value >= 64)
+			case (controller == AllNotesOffCC || controller == AllSoundOffCC) && s.partFor(channel) != nil:
+				s.partFor(channel).Synth.releaseAll()
+			case controller == AllNotesOffCC || controller == AllSoundOffCC:
+				s.releaseAll()
+			case controller == ResetControllersCC && s.partFor(channel) != nil:
+				s.partFor(channel).Synth.resetControllers()
+			case controller == ResetControllersCC:
+				s.resetControllers()
+			case surface != nil && surface.handleCC(controller, value):
+			case controller >= MacroCC && controller < MacroCC+Macros && s.partFor(channel) != nil:
+				s.partFor(channel).Synth.SetMacro(int(controller-MacroCC), float64(value)/127)
+			case controller >= MacroCC && controller < MacroCC+Macros:
+				s.SetMacro(int(controller-MacroCC), float64(value)/127)
+			case controller == BankSelectCC || controller == BankSelectLSBCC:
+				s.Programs.setBank(controller == BankSelectLSBCC, value)
+			}
+		case msg.GetProgramChange(&channel, &program) && s.partFor(channel) != nil:
+			s.ProgramChange(s.partFor(channel).Synth, program)
+		case msg.GetProgramChange(&channel, &program):
+			s.ProgramChange(s, program)
 		}
 	}
-	return portaudio.Terminate()
 }
 
-// GetTimeIndex returns the current time index
-func (s *Synth) GetTimeIndex() float64 {
-	return s.timeIndex
+// Stop cleans up and stops the synthesizer. Stopping it again does nothing.
+func (s *Synth) Stop() error {
+	s.Arp.SetEnabled(false)
+	s.Seq.Stop()
+	s.Player.Stop()
+	s.Clock.Stop()
+	if s.stopWatch != nil {
+		close(s.stopWatch)
+		<-s.watchDone
+		s.stopWatch, s.watchDone = nil, nil
+	}
+	s.StopOSC()
+	s.StopHTTP()
+	s.SetClockOut(false)
+	s.closeMIDI()
+	s.StopRecording()
+	s.StopMIDIRecording()
+	if s.audio != nil {
+		audio := s.audio
+		s.audio = nil
+		return audio.Close()
+	}
+	return nil
 }
@@ -0,0 +1,181 @@
+package synth
+
+import (
+	"sync"
+	"time"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+const SustainCC = 64 // MIDI controller number of the sustain pedal
+
+// NoteOn handles a played note, sending split notes to the MIDI output and the rest through
+// the scale filter, chord memory and the latch
+func (s *Synth) NoteOn(note, velocity uint8) {
+	s.Stats.noteOn(s.PresetName(), time.Now())
+	if s.MIDIOut.Connected() {
+		if channel, ok := s.Split.noteOn(note); ok {
+			s.MIDIOut.Send(midi.NoteOn(channel, note, velocity))
+			return
+		}
+	}
+	note, ok := s.Quantize.noteOn(note)
+	if !ok {
+		return
+	}
+	s.Chord.order.Lock()
+	defer s.Chord.order.Unlock()
+	notes, press := s.Chord.noteOn(note)
+	for _, n := range notes {
+		if n.delay > 0 {
+			strummed := n.note
+			time.AfterFunc(n.delay, func() {
+				s.Chord.order.Lock()
+				defer s.Chord.order.Unlock()
+				s.chordNoteOn(note, strummed, velocity, press)
+			})
+			continue
+		}
+		s.chordNoteOn(note, n.note, velocity, press)
+	}
+}
+
+// chordNoteOn starts one note of a key's chord through the latch, unless the key has
+// moved on since the press it belongs to; the caller holds the chord memory's order
+func (s *Synth) chordNoteOn(key, note, velocity uint8, press int) {
+	if !s.Chord.start(key, note, press) {
+		return
+	}
+	released, play := s.Latch.NoteOn(note)
+	for _, r := range released {
+		s.routeNoteOff(r)
+	}
+	if play {
+		s.routeNoteOn(note, velocity)
+	}
+}
+
+// NoteOff handles a released note, unless the scale filter blocked it or the latch is holding it
+func (s *Synth) NoteOff(note uint8) {
+	if channel, ok := s.Split.noteOff(note); ok {
+		s.MIDIOut.Send(midi.NoteOff(channel, note))
+		return
+	}
+	note, ok := s.Quantize.noteOff(note)
+	if !ok {
+		return
+	}
+	s.Chord.order.Lock()
+	defer s.Chord.order.Unlock()
+	for _, n := range s.Chord.noteOff(note) {
+		if s.Latch.NoteOff(n) {
+			s.routeNoteOff(n)
+		}
+	}
+}
+
+// SetLatch engages or disengages the latch, releasing notes it was holding
+func (s *Synth) SetLatch(enabled bool) {
+	for _, released := range s.Latch.SetEnabled(enabled) {
+		s.routeNoteOff(released)
+	}
+}
+
+// routedNotes are the notes routed on and not yet off, past the latch and chord memory,
+// so the arpeggiator can take over the notes already sounding when it starts
+type routedNotes struct {
+	mu       sync.Mutex
+	velocity [128]uint8 // 0 while a note isn't routed
+}
+
+// set records a note routed on, or off at velocity 0
+func (r *routedNotes) set(note, velocity uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.velocity[note&0x7f] = velocity
+}
+
+// notes returns the notes routed on, lowest first, with their velocities
+func (r *routedNotes) notes() (notes, velocities []uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for note, velocity := range r.velocity {
+		if velocity > 0 {
+			notes, velocities = append(notes, uint8(note)), append(velocities, velocity)
+		}
+	}
+	return notes, velocities
+}
+
+// clear forgets every routed note
+func (r *routedNotes) clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.velocity[:])
+}
+
+// SetArp starts or stops the arpeggiator. Notes sounding when it starts are released
+// from the voices and handed to it as held keys, so they arpeggiate and their note-offs
+// reach it; otherwise their voices would never be released.
+func (s *Synth) SetArp(enabled bool) {
+	if enabled == s.Arp.Enabled() {
+		return
+	}
+	s.Arp.SetEnabled(enabled)
+	if !enabled {
+		return
+	}
+	notes, velocities := s.routed.notes()
+	for i, note := range notes {
+		s.playNoteOff(note)
+		s.Arp.NoteOn(note, velocities[i])
+	}
+}
+
+// routeNoteOn sends a note to the arpeggiator when enabled, or straight to the voices
+func (s *Synth) routeNoteOn(note, velocity uint8) {
+	s.routed.set(note, max(1, velocity))
+	if s.Arp.Enabled() {
+		s.Arp.NoteOn(note, velocity)
+		return
+	}
+	s.playNoteOn(note, velocity)
+}
+
+// routeNoteOff releases a note in the arpeggiator when enabled, or in the voices
+func (s *Synth) routeNoteOff(note uint8) {
+	s.routed.set(note, 0)
+	if s.Arp.Enabled() {
+		s.Arp.NoteOff(note)
+		return
+	}
+	s.playNoteOff(note)
+}
+
+// playNoteOn queues a note start for the voice engine
+func (s *Synth) playNoteOn(note, velocity uint8) {
+	// The carrier frequency follows the last played note, as it always has
+	if freq := s.Tuning.NoteFreq(note); freq > 0 {
+		s.CarrierFreq.Set(freq)
+	}
+	s.QueueNoteOn(note, velocity, false)
+}
+
+// playNoteOff queues a note release for the voice engine
+func (s *Synth) playNoteOff(note uint8) {
+	s.QueueNoteOff(note, false)
+}
+
+// SetSustain applies a sustain pedal change; while arpeggiating the pedal holds the pattern
+func (s *Synth) SetSustain(down bool) {
+	if s.Arp.Enabled() {
+		s.Arp.SetHold(down)
+		return
+	}
+	s.QueueSustain(down)
+}
+
+// SustainDown reports whether the sustain pedal is currently held
+func (s *Synth) SustainDown() bool {
+	return s.Engine.SustainDown() || s.Arp.Hold()
+}
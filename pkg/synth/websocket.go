@@ -0,0 +1,301 @@
+package synth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gosynth/pkg/engine"
+)
+
+const (
+	LiveFrameRate      = 20              // Meter and waveform frames sent per second on /live
+	LiveScopeFrames    = 882             // Output frames across a waveform frame, 20 ms as on the scope
+	LiveWaveformPoints = 147             // Points a waveform frame is downsampled to
+	liveWriteTimeout   = 5 * time.Second // A client taking longer to accept a frame is dropped
+	liveMaxPayload     = 1 << 12         // Largest frame read from a client
+	websocketGUID      = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// WebSocket opcodes used by the live stream
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xa
+)
+
+// LiveMessage is a JSON message sent on the /live WebSocket. Type says which fields are set:
+// "param" for a parameter change, "transport" for the sequencer starting or stopping, and
+// "frame" for the output meter and waveform, sent LiveFrameRate times a second.
+type LiveMessage struct {
+	Type     string     `json:"type"`
+	Param    string     `json:"param,omitempty"`
+	Value    *float64   `json:"value,omitempty"`   // Set for a param message, even when 0
+	Playing  *bool      `json:"playing,omitempty"` // Set for a transport message, even when stopped
+	Meter    *LiveMeter `json:"meter,omitempty"`
+	Waveform []float32  `json:"waveform,omitempty"` // Output samples from -1 to 1, oldest first
+}
+
+// LiveMeter is the output level in a frame message
+type LiveMeter struct {
+	RMS      float64 `json:"rms"`
+	Peak     float64 `json:"peak"`
+	Centroid float64 `json:"centroid"` // Spectral centroid in Hz
+	Clipped  bool    `json:"clipped"`
+}
+
+// liveConns tracks the WebSocket connections of a server, which outlive http.Server.Close
+type liveConns struct {
+	mu    sync.Mutex
+	conns map[net.Conn]bool
+}
+
+// add tracks a connection, reporting false when the server is already closing
+func (l *liveConns) add(conn net.Conn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns == nil {
+		return false
+	}
+	l.conns[conn] = true
+	return true
+}
+
+func (l *liveConns) remove(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.conns, conn)
+}
+
+// closeAll closes every connection and refuses new ones
+func (l *liveConns) closeAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+}
+
+// apiLive upgrades GET /live to a WebSocket and streams LiveMessages until the client
+// goes away. Messages from the client other than pings and closes are ignored. Browsers
+// let any page open a WebSocket, so handshakes from pages not served from this machine
+// are refused; clients other than browsers send no Origin and are let in.
+func (s *Synth) apiLive(live *liveConns) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") || key == "" {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: "expected a WebSocket upgrade"})
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && !localOrigin(origin) {
+			writeJSON(w, http.StatusForbidden, apiError{Error: "origin " + origin + " isn't local"})
+			return
+		}
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			writeJSON(w, http.StatusInternalServerError, apiError{Error: "connection can't be upgraded"})
+			return
+		}
+		conn, rw, err := hijacker.Hijack()
+		if err != nil {
+			return
+		}
+		if !live.add(conn) {
+			conn.Close()
+			return
+		}
+		defer live.remove(conn)
+		defer conn.Close()
+
+		sum := sha1.Sum([]byte(key + websocketGUID))
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+		if rw.Flush() != nil {
+			return
+		}
+
+		ws := &wsConn{conn: conn}
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			ws.readControl(rw.Reader)
+		}()
+		s.streamLive(ws, closed)
+	}
+}
+
+// streamLive sends parameter and transport changes as they're published on the bus, and
+// the bus's latest meter frame with the waveform at LiveFrameRate, until the client closes
+// or a write fails
+func (s *Synth) streamLive(ws *wsConn, closed <-chan struct{}) {
+	events, unsubscribe := s.Bus.Subscribe()
+	defer unsubscribe()
+	ticker := time.NewTicker(time.Second / LiveFrameRate)
+	defer ticker.Stop()
+	var a engine.Analysis // Latest meter frame
+	for {
+		var msg LiveMessage
+		select {
+		case <-closed:
+			return
+		case ev := <-events:
+			switch ev.Kind {
+			case engine.EventParam:
+				msg = LiveMessage{Type: "param", Param: ev.Param, Value: &ev.Value}
+			case engine.EventTransport:
+				msg = LiveMessage{Type: "transport", Playing: &ev.Playing}
+			case engine.EventMeter:
+				a = ev.Meter
+				continue
+			default:
+				continue
+			}
+		case <-ticker.C:
+			trace, _ := s.ScopeTrace(LiveScopeFrames, true)
+			msg = LiveMessage{
+				Type:     "frame",
+				Meter:    &LiveMeter{RMS: a.RMS, Peak: a.Peak, Centroid: a.Centroid, Clipped: a.Clipped},
+				Waveform: downsample(trace, LiveWaveformPoints),
+			}
+		}
+		data, err := json.Marshal(msg)
+		if err != nil || ws.write(wsText, data) != nil {
+			return
+		}
+	}
+}
+
+// downsample picks evenly spaced samples from a trace
+func downsample(trace []float32, points int) []float32 {
+	if len(trace) <= points {
+		return trace
+	}
+	out := make([]float32, points)
+	for i := range out {
+		out[i] = trace[i*len(trace)/points]
+	}
+	return out
+}
+
+// localOrigin reports whether a browser's Origin header names a page served from this
+// machine
+func localOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return localHost(u.Hostname())
+}
+
+// headerHas reports whether a comma-separated header lists a token, ignoring case
+func headerHas(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wsConn writes WebSocket frames from the stream and the control reader
+type wsConn struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// write sends an unfragmented, unmasked frame, as a server does
+func (c *wsConn) write(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+// readControl reads client frames, answering pings, until the client closes the
+// connection or sends something malformed
+func (c *wsConn) readControl(r *bufio.Reader) {
+	for {
+		opcode, payload, err := readFrame(r)
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsClose:
+			c.write(wsClose, payload[:min(len(payload), 2)])
+			return
+		case wsPing:
+			if c.write(wsPong, payload) != nil {
+				return
+			}
+		}
+	}
+}
+
+// readFrame reads one masked client frame
+func readFrame(r *bufio.Reader) (opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode = head[0] & 0x0f
+	size := uint64(head[1] & 0x7f)
+	switch size {
+	case 126:
+		var n uint16
+		err = binary.Read(r, binary.BigEndian, &n)
+		size = uint64(n)
+	case 127:
+		err = binary.Read(r, binary.BigEndian, &size)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if head[1]&0x80 == 0 || size > liveMaxPayload {
+		return 0, nil, errors.New("websocket: unmasked or oversized client frame")
+	}
+	// Control frames are short and never fragmented
+	if opcode&0x8 != 0 && (size > 125 || head[0]&0x80 == 0) {
+		return 0, nil, errors.New("websocket: fragmented or oversized control frame")
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
@@ -0,0 +1,227 @@
+package synth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gosynth/pkg/engine"
+)
+
+// clientFrame builds a masked frame as a browser sends it. A size field of 126 or 127
+// forces that length encoding; 0 picks the shortest.
+func clientFrame(fin bool, opcode byte, payload []byte, sizeField byte) []byte {
+	head := opcode
+	if fin {
+		head |= 0x80
+	}
+	frame := []byte{head}
+	n := len(payload)
+	switch {
+	case sizeField == 127 || sizeField == 0 && n > 0xffff:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	case sizeField == 126 || sizeField == 0 && n >= 126:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|byte(n))
+	}
+	mask := []byte{0x37, 0xfa, 0x21, 0x3d}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// TestReadFrame checks unmasking, the extended lengths, the size cap and the rules for
+// control frames
+func TestReadFrame(t *testing.T) {
+	unmasked := []byte{0x81, 2, 'h', 'i'}
+	for _, tc := range []struct {
+		name   string
+		frame  []byte
+		opcode byte
+		size   int
+		hasErr bool
+	}{
+		{"short text", clientFrame(true, wsText, []byte("hello"), 0), wsText, 5, false},
+		{"16-bit length", clientFrame(true, wsText, bytes.Repeat([]byte("a"), 300), 0), wsText, 300, false},
+		{"64-bit length", clientFrame(true, wsText, bytes.Repeat([]byte("b"), 1000), 127), wsText, 1000, false},
+		{"largest payload", clientFrame(true, wsText, make([]byte, liveMaxPayload), 0), wsText, liveMaxPayload, false},
+		{"oversized payload", clientFrame(true, wsText, make([]byte, liveMaxPayload+1), 0), 0, 0, true},
+		{"oversized 64-bit length", clientFrame(true, wsText, make([]byte, 70000), 0), 0, 0, true},
+		{"unmasked", unmasked, 0, 0, true},
+		{"ping", clientFrame(true, wsPing, make([]byte, 125), 0), wsPing, 125, false},
+		{"oversized ping", clientFrame(true, wsPing, make([]byte, 126), 0), 0, 0, true},
+		{"fragmented close", clientFrame(false, wsClose, []byte{3, 232}, 0), 0, 0, true},
+		{"fragmented text", clientFrame(false, wsText, []byte("part"), 0), wsText, 4, false},
+		{"truncated payload", clientFrame(true, wsText, []byte("hello"), 0)[:8], 0, 0, true},
+		{"truncated length", []byte{0x81, 0x80 | 126, 1}, 0, 0, true},
+	} {
+		opcode, payload, err := readFrame(bufio.NewReader(bytes.NewReader(tc.frame)))
+		if (err != nil) != tc.hasErr {
+			t.Errorf("%s: error %v, want error %v", tc.name, err, tc.hasErr)
+			continue
+		}
+		if !tc.hasErr && (opcode != tc.opcode || len(payload) != tc.size) {
+			t.Errorf("%s: opcode %d with %d bytes, want %d with %d", tc.name, opcode, len(payload), tc.opcode, tc.size)
+		}
+	}
+
+	// The payload comes back unmasked
+	_, payload, _ := readFrame(bufio.NewReader(bytes.NewReader(clientFrame(true, wsText, []byte("hello"), 0))))
+	if string(payload) != "hello" {
+		t.Errorf("unmasked payload %q, want hello", payload)
+	}
+}
+
+// TestWriteFrame checks the length encodings of the unmasked frames the server sends
+func TestWriteFrame(t *testing.T) {
+	for _, tc := range []struct {
+		size int
+		head []byte
+	}{
+		{5, []byte{0x81, 5}},
+		{125, []byte{0x81, 125}},
+		{126, []byte{0x81, 126, 0, 126}},
+		{0xffff, []byte{0x81, 126, 0xff, 0xff}},
+		{0x10000, []byte{0x81, 127, 0, 0, 0, 0, 0, 1, 0, 0}},
+	} {
+		server, client := net.Pipe()
+		ws := &wsConn{conn: server}
+		go func(size int) {
+			ws.write(wsText, make([]byte, size))
+			server.Close()
+		}(tc.size)
+		frame, err := io.ReadAll(client)
+		client.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(frame, tc.head) || len(frame) != len(tc.head)+tc.size {
+			t.Errorf("%d bytes: frame starts % x and is %d long, want % x and %d", tc.size, frame[:min(len(frame), 10)], len(frame), tc.head, len(tc.head)+tc.size)
+		}
+	}
+}
+
+// TestLiveOrigin checks that browsers on other sites can't open the stream, while local
+// pages and clients without an Origin can
+func TestLiveOrigin(t *testing.T) {
+	s := NewSynth()
+	live := &liveConns{conns: make(map[net.Conn]bool)}
+	server := httptest.NewServer(s.apiLive(live))
+	defer server.Close()
+	defer live.closeAll()
+
+	for _, tc := range []struct {
+		origin string
+		status int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{"http://localhost:3000", http.StatusSwitchingProtocols},
+		{"http://127.0.0.1:8080", http.StatusSwitchingProtocols},
+		{"http://[::1]", http.StatusSwitchingProtocols},
+		{"https://example.com", http.StatusForbidden},
+		{"http://localhost.example.com", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	} {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("origin %q: status %d, want %d", tc.origin, resp.StatusCode, tc.status)
+		}
+		if tc.status == http.StatusSwitchingProtocols && resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+			t.Errorf("origin %q: accept key %q", tc.origin, resp.Header.Get("Sec-WebSocket-Accept"))
+		}
+	}
+}
+
+// readServerFrame reads an unmasked frame sent by the server
+func readServerFrame(r io.Reader) ([]byte, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return nil, err
+		}
+		n = binary.BigEndian.Uint64(ext)
+	}
+	payload := make([]byte, n)
+	_, err := io.ReadFull(r, payload)
+	return payload, err
+}
+
+// TestLiveMessages checks that a parameter set to 0 and the sequencer stopping are sent
+// with their value and playing fields, rather than leaving them out
+func TestLiveMessages(t *testing.T) {
+	s := NewSynth()
+	server, client := net.Pipe()
+	defer client.Close()
+	closed := make(chan struct{})
+	defer close(closed)
+	go s.streamLive(&wsConn{conn: server}, closed)
+
+	// The first meter frame shows the stream is subscribed to the bus
+	if _, err := readServerFrame(client); err != nil {
+		t.Fatal(err)
+	}
+	s.Bus.Publish(engine.Event{Kind: engine.EventParam, Param: "reverbMix", Value: 0})
+	s.Bus.Publish(engine.Event{Kind: engine.EventTransport, Playing: false})
+
+	var got []map[string]any
+	for len(got) < 2 {
+		payload, err := readServerFrame(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msg map[string]any
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg["type"] != "frame" {
+			got = append(got, msg)
+		}
+	}
+	if v, ok := got[0]["value"]; got[0]["type"] != "param" || !ok || v != 0.0 {
+		t.Errorf("param message %v, want reverbMix with a value of 0", got[0])
+	}
+	if v, ok := got[1]["playing"]; got[1]["type"] != "transport" || !ok || v != false {
+		t.Errorf("transport message %v, want playing false", got[1])
+	}
+	if _, ok := got[1]["value"]; ok {
+		t.Errorf("transport message %v has a value", got[1])
+	}
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"gosynth/pkg/engine"
+)
+
+// selectedAudition returns the module of the selected row: the effect it belongs to
+// on the effects page, the drone layer for its rows, otherwise the voices, whose own
+// effects are on the effects page too
+func (m Model) selectedAudition() engine.Audition {
+	switch m.page {
+	case pageEffects:
+		items, owners := m.effectItems()
+		if len(items) == 0 {
+			return engine.AuditionOff
+		}
+		if strings.HasPrefix(items[m.selected[pageEffects]].label, "Voice ") {
+			return engine.AuditionVoices
+		}
+		for a := engine.AuditionChorus; a <= engine.AuditionReverb; a++ {
+			if a.Effect() == owners[m.selected[pageEffects]] {
+				return a
+			}
+		}
+		return engine.AuditionOff
+	case pageOscillators:
+		if items, selected := m.pageItems(); strings.HasPrefix(items[*selected].label, "Drone ") {
+			return engine.AuditionLayer
+		}
+	}
+	return engine.AuditionVoices
+}
+
+// toggleAudition solos the selected row's module, or plays everything again when
+// it is already being auditioned
+func (m *Model) toggleAudition() {
+	a := m.selectedAudition()
+	switch {
+	case a == engine.AuditionOff:
+		m.status = "Select a chain effect, the drone layer or a voice row to audition"
+		return
+	case a == m.synth.Audition():
+		a = engine.AuditionOff
+	case a.Effect() != "" && !m.synth.FX.Enabled(a.Effect()):
+		m.status = fmt.Sprintf("Switch the %s on to audition it", a)
+		return
+	}
+	m.synth.SetAudition(a)
+	m.status = fmt.Sprintf("Audition: %s", a)
+}
+
+// renderAudition shows the auditioned module, lit while one is soloed
+func (m Model) renderAudition(baseStyle lipgloss.Style) string {
+	a := m.synth.Audition()
+	label := fmt.Sprintf(" F4 audition %s", a)
+	if a == engine.AuditionOff {
+		return baseStyle.Render(label)
+	}
+	return baseStyle.Foreground(lipgloss.Color("#ffff00")).Render(label)
+}
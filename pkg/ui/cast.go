@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gosynth/pkg/engine"
+)
+
+// castRecorder writes rendered frames to an asciicast v2 file
+type castRecorder struct {
+	file  *os.File
+	start time.Time
+	last  string // Last frame written, to skip unchanged frames
+}
+
+// newCastRecorder creates an asciicast file for a terminal of the given size
+func newCastRecorder(path string, width, height int) (*castRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &castRecorder{file: file, start: time.Now()}
+	header, err := json.Marshal(map[string]any{
+		"version":   2,
+		"width":     width,
+		"height":    height,
+		"timestamp": c.start.Unix(),
+		"title":     "gosynth",
+	})
+	if err == nil {
+		_, err = fmt.Fprintf(file, "%s\n", header)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// frame records a rendered screen as an output event that redraws the terminal
+func (c *castRecorder) frame(screen string) error {
+	if screen == c.last {
+		return nil
+	}
+	c.last = screen
+	event, err := json.Marshal([]any{
+		time.Since(c.start).Seconds(),
+		"o",
+		"\x1b[H\x1b[2J" + strings.ReplaceAll(screen, "\n", "\r\n"),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.file, "%s\n", event)
+	return err
+}
+
+// close finishes the asciicast file
+func (c *castRecorder) close() error {
+	return c.file.Close()
+}
+
+// toggleRecording starts or stops recording the session to matching .cast, .wav and .mid
+// files
+func (m *Model) toggleRecording() {
+	if m.cast != nil {
+		m.stopRecording()
+		return
+	}
+
+	dir, err := engine.RecordingDir()
+	if err == nil {
+		err = os.MkdirAll(dir, 0o755)
+	}
+	if err != nil {
+		m.status = fmt.Sprintf("Recording failed: %v", err)
+		return
+	}
+	base := filepath.Join(dir, "session-"+time.Now().Format("20060102-150405"))
+
+	cast, err := newCastRecorder(base+".cast", m.width, m.height)
+	if err != nil {
+		m.status = fmt.Sprintf("Recording failed: %v", err)
+		return
+	}
+	if err := m.synth.StartRecording(base + ".wav"); err != nil {
+		cast.close()
+		m.status = fmt.Sprintf("Recording failed: %v", err)
+		return
+	}
+	if err := m.synth.StartMIDIRecording(base + ".mid"); err != nil {
+		cast.close()
+		m.synth.StopRecording()
+		m.status = fmt.Sprintf("Recording failed: %v", err)
+		return
+	}
+	m.cast = cast
+	m.status = fmt.Sprintf("Recording to %s.cast, .wav and .mid", base)
+}
+
+// stopRecording finishes the recordings if they are running
+func (m *Model) stopRecording() {
+	if m.cast == nil {
+		return
+	}
+	castErr := m.cast.close()
+	wavErr := m.synth.StopRecording()
+	_, midErr := m.synth.StopMIDIRecording()
+	m.cast = nil
+	switch {
+	case castErr != nil:
+		m.status = fmt.Sprintf("Finishing recording failed: %v", castErr)
+	case wavErr != nil:
+		m.status = fmt.Sprintf("Finishing recording failed: %v", wavErr)
+	case midErr != nil:
+		m.status = fmt.Sprintf("Finishing recording failed: %v", midErr)
+	default:
+		m.status = "Recording saved"
+	}
+}
@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gosynth/pkg/synth"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// theme is the set of colours the menu is drawn in, on the black background the
+// waveform and scope share
+type theme struct {
+	text     lipgloss.Color // Rows and help
+	selected lipgloss.Color // Selected row and current page
+	mod      lipgloss.Color // Modulation tracks
+}
+
+// themes lists the colour themes the config file can choose by name
+var themes = map[string]theme{
+	"green": {text: "#ffffff", selected: "#00ff00", mod: "#00aaff"},
+	"amber": {text: "#ffb000", selected: "#ffe08a", mod: "#ff7a00"},
+	"ice":   {text: "#d0e8ff", selected: "#00e5ff", mod: "#8a7dff"},
+	"mono":  {text: "#c0c0c0", selected: "#ffffff", mod: "#808080"},
+}
+
+// defaultTheme is used when the config file names none
+const defaultTheme = "green"
+
+// actionKeys are the global actions that can be bound to other keys in the config
+// file, with their default keys
+var actionKeys = map[string]string{
+	"next_page":     "tab",
+	"previous_page": "shift+tab",
+	"save":          "ctrl+s",
+	"save_new":      "ctrl+n",
+	"lock":          "ctrl+p",
+	"piano":         "ctrl+k",
+	"kill_delay":    "f1",
+	"kill_reverb":   "f2",
+	"kill_parts":    "f3",
+	"audition":      "f4",
+	"panic":         "f5",
+	"undo":          "ctrl+z",
+	"redo":          "ctrl+y",
+	"latch":         "ctrl+l",
+	"record":        "ctrl+r",
+	"quit":          "q",
+}
+
+// keyBindings maps keys bound in the config file to the default key of their action.
+// A default key whose action was moved elsewhere maps to nothing.
+type keyBindings map[string]string
+
+// newKeyBindings binds actions to keys by action name, reporting unknown action names
+func newKeyBindings(custom map[string]string) (keyBindings, error) {
+	b := keyBindings{}
+	var unknown []string
+	for action, key := range custom {
+		def, ok := actionKeys[action]
+		if !ok {
+			unknown = append(unknown, action)
+			continue
+		}
+		if _, bound := b[def]; !bound {
+			b[def] = ""
+		}
+		b[key] = def
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return b, fmt.Errorf("unknown key actions in the config: %s", strings.Join(unknown, ", "))
+	}
+	return b, nil
+}
+
+// resolve returns the default key of the action a pressed key is bound to
+func (b keyBindings) resolve(key string) string {
+	if def, ok := b[key]; ok {
+		return def
+	}
+	return key
+}
+
+// key returns the key an action is bound to, for the help
+func (b keyBindings) key(action string) string {
+	def := actionKeys[action]
+	for key, bound := range b {
+		if bound == def && key != def {
+			return key
+		}
+	}
+	return def
+}
+
+// useConfig takes the colours and key bindings from the synth's config, returning the
+// mistakes found there; they're reported rather than fatal
+func (m *Model) useConfig() string {
+	var problem string
+	colors, ok := themes[m.synth.Config.Theme]
+	if !ok {
+		if m.synth.Config.Theme != "" {
+			problem = fmt.Sprintf("Unknown theme %q in the config, using %s", m.synth.Config.Theme, defaultTheme)
+		}
+		colors = themes[defaultTheme]
+	}
+	keys, err := newKeyBindings(m.synth.Config.Keys)
+	if err != nil {
+		problem = err.Error()
+	}
+	m.theme, m.keys = colors, keys
+	return problem
+}
+
+// configMsg asks for the config file to be checked for edits
+type configMsg struct{}
+
+// checkConfig schedules the next check of the config file
+func checkConfig() tea.Cmd {
+	return tea.Tick(synth.ConfigCheckInterval, func(time.Time) tea.Msg {
+		return configMsg{}
+	})
+}
+
+// reloadConfig applies edits to the config file, saying which took effect and which
+// wait for a restart
+func (m *Model) reloadConfig() {
+	reload, edited := m.synth.ReloadConfig()
+	if !edited {
+		return
+	}
+	if reload.Err != nil {
+		m.status = fmt.Sprintf("Config not reloaded, keeping the current settings: %v", reload.Err)
+		return
+	}
+	problem := m.useConfig()
+	var parts []string
+	if len(reload.Applied) > 0 {
+		parts = append(parts, "applied "+strings.Join(reload.Applied, ", "))
+	}
+	if len(reload.Restart) > 0 {
+		parts = append(parts, "restart gosynth to apply "+strings.Join(reload.Restart, ", "))
+	}
+	if len(reload.Failed) > 0 {
+		parts = append(parts, "couldn't apply "+strings.Join(reload.Failed, ", "))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "nothing to change")
+	}
+	if problem != "" {
+		parts = append(parts, problem)
+	}
+	m.status = "Config reloaded: " + strings.Join(parts, "; ")
+	m.buffer = "" // Clear buffer to force redraw
+}
@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gosynth/pkg/engine"
+)
+
+// drumItems are the drum synthesis rows of the oscillators page: the level, the kick's
+// pitch, then each drum's decay
+var drumItems = func() []menuItem {
+	items := []menuItem{
+		{
+			label: "Drum Level",
+			param: "drumLevel",
+			value: func(m Model) string { return fmt.Sprintf("%.2f", m.synth.Drums.Level.Get()) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Drums.Level.Set(math.Max(0, math.Min(1, m.synth.Drums.Level.Get()+dir*0.05)))
+			},
+		},
+		{
+			label: "Kick Pitch",
+			param: "kickPitch",
+			value: func(m Model) string { return fmt.Sprintf("%.0f Hz", m.synth.Drums.KickPitch.Get()) },
+			adjust: func(m *Model, dir float64) {
+				v := &m.synth.Drums.KickPitch
+				v.Set(math.Max(engine.MinKickPitch, math.Min(engine.MaxKickPitch, v.Get()+dir)))
+			},
+		},
+	}
+	for kind := engine.DrumKind(0); kind < engine.DrumKinds; kind++ {
+		kind := kind
+		items = append(items, menuItem{
+			label: strings.ToUpper(kind.String()[:1]) + kind.String()[1:] + " Decay",
+			param: kind.String() + "Decay",
+			value: func(m Model) string { return fmt.Sprintf("%.0f ms", m.synth.Drums.Decay[kind].Get()*1000) },
+			adjust: func(m *Model, dir float64) {
+				v := &m.synth.Drums.Decay[kind]
+				v.Set(math.Max(engine.MinDrumDecay, math.Min(engine.MaxDrumDecay, v.Get()+dir*0.01)))
+			},
+		})
+	}
+	return items
+}()
@@ -0,0 +1,413 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"gosynth/pkg/engine"
+)
+
+// effectRows are the rows of each effect on the effects page, shown in chain order
+var effectRows = map[string][]menuItem{
+	engine.EffectChorus: {
+		{
+			label: "Chorus",
+			value: func(m Model) string { return onOff(m.synth.FX.Enabled(engine.EffectChorus)) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.FX.SetEnabled(engine.EffectChorus, !m.synth.FX.Enabled(engine.EffectChorus))
+			},
+		},
+		{
+			label: "Chorus Sync",
+			value: func(m Model) string { return onOff(m.synth.Chorus.Synced()) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Chorus.SetSynced(!m.synth.Chorus.Synced())
+			},
+		},
+		{
+			label: "Chorus Rate",
+			param: "chorusRate",
+			value: func(m Model) string {
+				if m.synth.Chorus.Synced() {
+					return fmt.Sprintf("%s (%.2f Hz)", engine.Divisions[m.synth.Chorus.Div()].Name, m.synth.Chorus.Hz())
+				}
+				return fmt.Sprintf("%.1f Hz", m.synth.Chorus.Rate.Get())
+			},
+			adjust: func(m *Model, dir float64) {
+				if m.synth.Chorus.Synced() {
+					// Right moves to shorter divisions, speeding up as the unsynced rate does
+					m.synth.Chorus.SetDiv(m.synth.Chorus.Div() + sign(dir))
+					return
+				}
+				m.synth.Chorus.Rate.Set(math.Max(0.1, math.Min(5, m.synth.Chorus.Rate.Get()+dir*0.1)))
+			},
+		},
+		{
+			label: "Chorus Depth",
+			param: "chorusDepth",
+			value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.Chorus.Depth.Get()*100) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Chorus.Depth.Set(math.Max(0, math.Min(1, m.synth.Chorus.Depth.Get()+dir*0.05)))
+			},
+		},
+		{
+			label: "Chorus Mix",
+			param: "chorusMix",
+			value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.Chorus.Mix.Get()*100) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Chorus.Mix.Set(math.Max(0, math.Min(1, m.synth.Chorus.Mix.Get()+dir*0.05)))
+			},
+		},
+	},
+	engine.EffectDelay: {
+		{
+			label: "Delay",
+			value: func(m Model) string { return onOff(m.synth.FX.Enabled(engine.EffectDelay)) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.FX.SetEnabled(engine.EffectDelay, !m.synth.FX.Enabled(engine.EffectDelay))
+			},
+		},
+		{
+			label: "Delay Sync",
+			value: func(m Model) string { return onOff(m.synth.Delay.Synced()) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Delay.SetSynced(!m.synth.Delay.Synced())
+			},
+		},
+		{
+			label: "Delay Time",
+			param: "delayTime",
+			value: func(m Model) string {
+				if m.synth.Delay.Synced() {
+					return fmt.Sprintf("%s (%.0f ms)", engine.Divisions[m.synth.Delay.Div()].Name, m.synth.Delay.Seconds()*1000)
+				}
+				return fmt.Sprintf("%.0f ms", m.synth.Delay.Time.Get()*1000)
+			},
+			adjust: func(m *Model, dir float64) {
+				if m.synth.Delay.Synced() {
+					// Left moves to longer divisions, matching the unsynced direction
+					m.synth.Delay.SetDiv(m.synth.Delay.Div() - sign(dir))
+					return
+				}
+				m.synth.Delay.Time.Set(math.Max(0.01, math.Min(engine.MaxDelayTime, m.synth.Delay.Time.Get()+dir*0.01)))
+			},
+		},
+		{
+			label: "Delay Feedback",
+			param: "delayFeedback",
+			value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.Delay.Feedback.Get()*100) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Delay.Feedback.Set(math.Max(0, math.Min(0.95, m.synth.Delay.Feedback.Get()+dir*0.05)))
+			},
+		},
+		{
+			label: "Delay Mix",
+			param: "delayMix",
+			value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.Delay.Mix.Get()*100) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Delay.Mix.Set(math.Max(0, math.Min(1, m.synth.Delay.Mix.Get()+dir*0.05)))
+			},
+		},
+	},
+	engine.EffectReverb: {
+		{
+			label: "Reverb",
+			value: func(m Model) string { return onOff(m.synth.FX.Enabled(engine.EffectReverb)) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.FX.SetEnabled(engine.EffectReverb, !m.synth.FX.Enabled(engine.EffectReverb))
+			},
+		},
+		{
+			label: "Reverb Size",
+			param: "reverbSize",
+			value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.Reverb.Size.Get()*100) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Reverb.Size.Set(math.Max(0, math.Min(1, m.synth.Reverb.Size.Get()+dir*0.05)))
+			},
+		},
+		{
+			label: "Reverb Damping",
+			param: "reverbDamping",
+			value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.Reverb.Damping.Get()*100) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Reverb.Damping.Set(math.Max(0, math.Min(1, m.synth.Reverb.Damping.Get()+dir*0.05)))
+			},
+		},
+		{
+			label: "Reverb Mix",
+			param: "reverbMix",
+			value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.Reverb.Mix.Get()*100) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Reverb.Mix.Set(math.Max(0, math.Min(1, m.synth.Reverb.Mix.Get()+dir*0.05)))
+			},
+		},
+	},
+}
+
+// voiceRows are the rows of the effects run inside each voice, shown before the chain
+var voiceRows = []menuItem{
+	{
+		label: "Voice Drive",
+		param: "voiceDrive",
+		value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.VoiceFX.Drive.Get()*100) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.VoiceFX.Drive.Set(math.Max(0, math.Min(1, m.synth.VoiceFX.Drive.Get()+dir*0.05)))
+		},
+	},
+	{
+		label: "Voice Filter Drift",
+		param: "voiceDrift",
+		value: func(m Model) string {
+			if m.synth.VoiceFX.Drift.Get() == 0 {
+				return "off"
+			}
+			return fmt.Sprintf("%.0f%% (±%.2f oct)", m.synth.VoiceFX.Drift.Get()*100, m.synth.VoiceFX.Drift.Get()*engine.VoiceDriftOctaves)
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.VoiceFX.Drift.Set(math.Max(0, math.Min(1, m.synth.VoiceFX.Drift.Get()+dir*0.05)))
+		},
+	},
+	{
+		label: "Voice Chorus",
+		param: "voiceChorus",
+		value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.VoiceFX.Chorus.Get()*100) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.VoiceFX.Chorus.Set(math.Max(0, math.Min(1, m.synth.VoiceFX.Chorus.Get()+dir*0.05)))
+		},
+	},
+}
+
+// masterRows are the master bus rows, shown after the chain
+var masterRows = []menuItem{
+	{
+		label: "Mix Trim",
+		param: "mixTrim",
+		value: func(m Model) string { return fmt.Sprintf("%+.1f dB", m.synth.MixTrim.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.MixTrim.Set(math.Max(-engine.MaxTrim, math.Min(engine.MaxTrim, m.synth.MixTrim.Get()+dir*0.5)))
+		},
+	},
+	{
+		label: "FX Trim",
+		param: "fxTrim",
+		value: func(m Model) string { return fmt.Sprintf("%+.1f dB", m.synth.FXTrim.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.FXTrim.Set(math.Max(-engine.MaxTrim, math.Min(engine.MaxTrim, m.synth.FXTrim.Get()+dir*0.5)))
+		},
+	},
+	{
+		label: "Gain Staging",
+		value: func(m Model) string {
+			mix, fx, ok := m.synth.SuggestedTrims()
+			if !ok {
+				return "play to measure headroom"
+			}
+			var peaks []string
+			for _, st := range []engine.Stage{engine.StageMix, engine.StageFX, engine.StageMaster} {
+				peaks = append(peaks, fmt.Sprintf("%s %s", st, formatDB(m.synth.StagePeak(st))))
+			}
+			return fmt.Sprintf("peaks %s; → trims mix %+.1f, fx %+.1f dB, ← remeasures", strings.Join(peaks, ", "), mix, fx)
+		},
+		adjust: func(m *Model, dir float64) {
+			if dir < 0 {
+				m.synth.ResetStagePeaks()
+				return
+			}
+			m.synth.ApplyTrims()
+		},
+	},
+	{
+		label: "Compressor",
+		value: func(m Model) string { return onOff(m.synth.Comp.Enabled()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Comp.SetEnabled(!m.synth.Comp.Enabled())
+		},
+	},
+	{
+		label: "Comp Threshold",
+		param: "compThreshold",
+		value: func(m Model) string { return fmt.Sprintf("%.0f dB", m.synth.Comp.Threshold.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Comp.Threshold.Set(math.Max(-60, math.Min(0, m.synth.Comp.Threshold.Get()+dir)))
+		},
+	},
+	{
+		label: "Comp Ratio",
+		param: "compRatio",
+		value: func(m Model) string {
+			if m.synth.Comp.Ratio.Get() >= engine.MaxCompRatio {
+				return "limit"
+			}
+			return fmt.Sprintf("%.1f:1", m.synth.Comp.Ratio.Get())
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Comp.Ratio.Set(math.Max(1, math.Min(engine.MaxCompRatio, m.synth.Comp.Ratio.Get()+dir*0.5)))
+		},
+	},
+	{
+		label: "Comp Attack",
+		param: "compAttack",
+		value: func(m Model) string { return fmt.Sprintf("%.1f ms", m.synth.Comp.Attack.Get()*1000) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Comp.Attack.Set(math.Max(0.0001, math.Min(0.2, m.synth.Comp.Attack.Get()+dir*0.0005)))
+		},
+	},
+	{
+		label: "Comp Release",
+		param: "compRelease",
+		value: func(m Model) string { return fmt.Sprintf("%.0f ms", m.synth.Comp.Release.Get()*1000) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Comp.Release.Set(math.Max(0.01, math.Min(2, m.synth.Comp.Release.Get()+dir*0.01)))
+		},
+	},
+	{
+		label: "Comp Makeup",
+		param: "compMakeup",
+		value: func(m Model) string { return fmt.Sprintf("%.1f dB", m.synth.Comp.Makeup.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Comp.Makeup.Set(math.Max(0, math.Min(24, m.synth.Comp.Makeup.Get()+dir*0.5)))
+		},
+	},
+	{
+		label: "Input Level",
+		value: func(m Model) string {
+			if m.synth.Input.Channels() == 0 {
+				return "no input (set audio_input)"
+			}
+			return fmt.Sprintf("%.2f (%s peak)", m.synth.Input.Level.Get(), formatDB(m.synth.Input.Peak()))
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Input.Level.Set(math.Max(0, math.Min(1, math.Round((m.synth.Input.Level.Get()+dir*0.05)*100)/100)))
+		},
+	},
+	outputRow("Mono Sum", func(o *engine.OutputUtils) *bool { return &o.MonoSum }),
+	outputRow("Swap L/R", func(o *engine.OutputUtils) *bool { return &o.SwapLR }),
+	outputRow("Invert Left", func(o *engine.OutputUtils) *bool { return &o.InvertLeft }),
+	outputRow("Invert Right", func(o *engine.OutputUtils) *bool { return &o.InvertRight }),
+}
+
+// outputRow returns a row switching one of the master output utilities
+func outputRow(label string, field func(o *engine.OutputUtils) *bool) menuItem {
+	return menuItem{
+		label: label,
+		value: func(m Model) string {
+			output := m.synth.Output()
+			return onOff(*field(&output))
+		},
+		adjust: func(m *Model, dir float64) {
+			output := m.synth.Output()
+			*field(&output) = !*field(&output)
+			m.synth.SetOutput(output)
+		},
+	}
+}
+
+// effectItems returns the effects page rows in chain order, then the master bus rows,
+// with the chain effect each row belongs to ("" for the master bus)
+func (m Model) effectItems() ([]menuItem, []string) {
+	var items []menuItem
+	var owners []string
+	for _, item := range voiceRows {
+		items = append(items, item)
+		owners = append(owners, "")
+	}
+	for _, name := range m.synth.FX.Names() {
+		for _, item := range effectRows[name] {
+			items = append(items, item)
+			owners = append(owners, name)
+		}
+	}
+	for _, item := range masterRows {
+		items = append(items, item)
+		owners = append(owners, "")
+	}
+	return items, owners
+}
+
+// renderGainReduction draws the compressor's gain reduction as a bar of up to 24 dB,
+// glowing with the output peak
+func (m Model) renderGainReduction(baseStyle lipgloss.Style) string {
+	reduction := m.synth.Comp.GainReduction()
+	bar := strings.Repeat("█", int(math.Min(reduction, 24)))
+	glow := baseStyle.Foreground(lipgloss.Color(meterColor(m.meter.Peak)))
+	return baseStyle.Render("Gain reduction: ") + glow.Render(fmt.Sprintf("%-24s", bar)) +
+		baseStyle.Render(fmt.Sprintf(" %4.1f dB", reduction))
+}
+
+// renderVoiceFXWarning warns while voice effects are on and the callback is near its
+// deadline or fewer than MaxVoices voices fit the CPU budget, or returns ""
+func (m Model) renderVoiceFXWarning(baseStyle lipgloss.Style) string {
+	if !m.synth.VoiceFX.Active() {
+		return ""
+	}
+	load := m.synth.CPULoad()
+	polyphony := m.synth.MaxPolyphony()
+	if load < engine.VoiceFXWarnLoad && (polyphony < 0 || polyphony >= engine.MaxVoices) {
+		return ""
+	}
+	warning := fmt.Sprintf("⚠ Voice effects: load %.0f%%", load*100)
+	if polyphony >= 0 {
+		warning += fmt.Sprintf(", ~%d of %d voices fit the CPU budget", polyphony, engine.MaxVoices)
+	}
+	return baseStyle.Foreground(lipgloss.Color("#ffaa00")).Render(warning)
+}
+
+// renderLevel draws the output's RMS level as a VU bar from -60 dB to full scale with
+// a mark at the peak, and a clip light latched while the volume is unchanged
+func (m Model) renderLevel(baseStyle lipgloss.Style) string {
+	const width, floor = 40, -60.0
+	position := func(level float64) int {
+		if level <= 0 {
+			return 0
+		}
+		return clamp(int(math.Round((20*math.Log10(level)-floor)/-floor*width)), 0, width)
+	}
+	rms, peak := position(m.meter.RMS), position(m.meter.Peak)
+	bar := []rune(strings.Repeat("█", rms) + strings.Repeat("·", width-rms))
+	if peak > 0 {
+		bar[peak-1] = '│'
+	}
+	level := baseStyle.Foreground(lipgloss.Color(meterColor(m.meter.Peak)))
+	clip := baseStyle.Render("  clip")
+	if m.clipped {
+		clip = baseStyle.Foreground(lipgloss.Color("#ff0000")).Render("  CLIP")
+	}
+	return baseStyle.Render("Level: ") + level.Render(string(bar)) +
+		baseStyle.Render(fmt.Sprintf(" %s RMS, %s peak", formatDB(m.meter.RMS), formatDB(m.meter.Peak))) + clip
+}
+
+// formatDB formats a peak level in decibels below full scale
+func formatDB(level float64) string {
+	if level <= 0 {
+		return "-inf dB"
+	}
+	return fmt.Sprintf("%.1f dB", 20*math.Log10(level))
+}
+
+// meterColor shades a meter from dim green through yellow to red as the peak nears full scale
+func meterColor(peak float64) string {
+	level := math.Min(1, peak)
+	r, g, b := hslToRGB((1-level)/3, 1.0, 0.25+level*0.35)
+	return fmt.Sprintf("#%02x%02x%02x", int(r*255), int(g*255), int(b*255))
+}
+
+// moveEffect shifts the effect of the selected row along the chain, keeping it selected
+func (m *Model) moveEffect(dir int) {
+	items, owners := m.effectItems()
+	if len(items) == 0 {
+		return
+	}
+	label := items[m.selected[pageEffects]].label
+	m.recordEdit("effect order")
+	m.synth.FX.Move(owners[m.selected[pageEffects]], dir)
+
+	items, _ = m.effectItems()
+	for i, item := range items {
+		if item.label == label {
+			m.selected[pageEffects] = i
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gosynth/pkg/engine"
+	"gosynth/pkg/synth"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startEntry begins typing a value into the selected row, if it takes typed values
+func (m *Model) startEntry() {
+	if items, selected := m.pageItems(); len(items) > 0 && items[*selected].enter != nil {
+		m.entering = true
+		m.entry = ""
+	}
+}
+
+// handleEntryKey edits the typed value: Enter sets it, Esc cancels
+func (m *Model) handleEntryKey(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.entering = false
+		items, selected := m.pageItems()
+		if len(items) == 0 || items[*selected].enter == nil {
+			return
+		}
+		m.recordEdit(items[*selected].label)
+		if err := items[*selected].enter(m, m.entry); err != nil {
+			m.status = err.Error()
+		}
+	case tea.KeyEsc:
+		m.entering = false
+	case tea.KeyBackspace:
+		if text := []rune(m.entry); len(text) > 0 {
+			m.entry = string(text[:len(text)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.entry += string(msg.Runes)
+	}
+}
+
+// parseFrequency reads a typed frequency: a note name such as C#3 or Db3, converted to
+// its pitch, or a number of Hz with or without the unit
+func parseFrequency(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if text != "" && unicode.IsLetter(rune(text[0])) {
+		note, err := synth.ParseNote(text)
+		if err != nil {
+			return 0, err
+		}
+		return engine.MIDINoteToFreq(note), nil
+	}
+	number := strings.TrimSpace(strings.TrimSuffix(strings.ToLower(text), "hz"))
+	freq, err := strconv.ParseFloat(number, 64)
+	if err != nil || freq <= 0 {
+		return 0, fmt.Errorf("%q is not a note name (such as C#3) or a frequency in Hz", text)
+	}
+	return freq, nil
+}
+
+// nearestNote returns the note nearest a frequency, tuned from A4 at reference Hz, and
+// how many cents the frequency is above it; false outside the MIDI notes
+func nearestNote(freq, reference float64) (uint8, float64, bool) {
+	exact := 69 + 12*math.Log2(freq/reference)
+	note := math.Round(exact)
+	if note < 0 || note > 127 {
+		return 0, 0, false
+	}
+	return uint8(note), (exact - note) * 100, true
+}
+
+// freqNote names the note nearest a frequency and how far from it the frequency is,
+// such as "A4 (+3 cents)"
+func freqNote(freq float64) string {
+	note, cents, ok := nearestNote(freq, engine.ReferencePitch)
+	if !ok {
+		return ""
+	}
+	if cents = math.Round(cents); cents == 0 {
+		return noteName(note)
+	}
+	return fmt.Sprintf("%s (%+.0f cents)", noteName(note), cents)
+}
+
+// frequencyValue shows a frequency in Hz with its nearest note
+func frequencyValue(freq float64) string {
+	return fmt.Sprintf("%.1f Hz %s", freq, freqNote(freq))
+}
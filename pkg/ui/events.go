@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"gosynth/pkg/engine"
+)
+
+// busMsg carries an event from the engine's bus into the UI
+type busMsg engine.Event
+
+// waitEvent returns a command that delivers the next event on the bus
+func waitEvent(events <-chan engine.Event) tea.Cmd {
+	return func() tea.Msg {
+		return busMsg(<-events)
+	}
+}
+
+// handleEvent updates the state the UI keeps from the bus
+func (m *Model) handleEvent(ev engine.Event) {
+	switch ev.Kind {
+	case engine.EventMeter:
+		m.meter = ev.Meter
+		m.clipped = m.clipped || ev.Meter.Clipped
+	case engine.EventParam:
+		// The clip light stays on until the volume is changed
+		if ev.Param == "volume" {
+			m.clipped = false
+		}
+	case engine.EventNoteOn:
+		if !ev.Drum {
+			m.sounding[ev.Note] = true
+		}
+	case engine.EventNoteOff:
+		delete(m.sounding, ev.Note)
+	}
+}
+
+// renderNotes lists the notes being played, lowest first
+func (m Model) renderNotes() string {
+	notes := make([]int, 0, len(m.sounding))
+	for note := range m.sounding {
+		notes = append(notes, int(note))
+	}
+	sort.Ints(notes)
+	names := make([]string, len(notes))
+	for i, note := range notes {
+		names[i] = noteName(uint8(note))
+	}
+	return "Notes: " + strings.Join(names, " ")
+}
@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"gosynth/pkg/engine"
+)
+
+// filterItems are the rows of the filter page: cutoff, resonance, key tracking and the
+// filter envelope with its amount
+var filterItems = []menuItem{
+	{
+		label: "Cutoff",
+		param: "filterCutoff",
+		value: func(m Model) string {
+			cutoff := m.synth.Filter.Cutoff.Get()
+			switch {
+			case cutoff >= engine.FilterMaxCutoff:
+				return "open"
+			case cutoff >= 1000:
+				return fmt.Sprintf("%.2f kHz", cutoff/1000)
+			}
+			return fmt.Sprintf("%.0f Hz", cutoff)
+		},
+		adjust: func(m *Model, dir float64) {
+			// A semitone a step, so alt moves an octave
+			cutoff := m.synth.Filter.Cutoff.Get() * math.Exp2(dir/12)
+			m.synth.Filter.Cutoff.Set(math.Max(engine.FilterMinCutoff, math.Min(engine.FilterMaxCutoff, cutoff)))
+		},
+	},
+	{
+		label: "Resonance",
+		param: "filterResonance",
+		value: func(m Model) string { return fmt.Sprintf("%.2f", m.synth.Filter.Resonance.Get()) },
+		adjust: func(m *Model, dir float64) {
+			res := math.Round((m.synth.Filter.Resonance.Get()+dir*0.05)*100) / 100
+			m.synth.Filter.Resonance.Set(math.Max(0, math.Min(engine.FilterMaxRes, res)))
+		},
+	},
+	{
+		label: "Key Tracking",
+		param: "filterKeyTrack",
+		value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.Filter.KeyTrack.Get()*100) },
+		adjust: func(m *Model, dir float64) {
+			track := math.Round((m.synth.Filter.KeyTrack.Get()+dir*0.05)*100) / 100
+			m.synth.Filter.KeyTrack.Set(math.Max(0, math.Min(1, track)))
+		},
+	},
+	{
+		label: "Envelope Amount",
+		param: "filterEnvAmount",
+		value: func(m Model) string { return fmt.Sprintf("%+.1f oct", m.synth.Filter.EnvAmount.Get()) },
+		adjust: func(m *Model, dir float64) {
+			amount := math.Round((m.synth.Filter.EnvAmount.Get()+dir*0.5)*10) / 10
+			m.synth.Filter.EnvAmount.Set(math.Max(-engine.FilterEnvOctaves, math.Min(engine.FilterEnvOctaves, amount)))
+		},
+	},
+	{
+		label: "Filter Attack",
+		param: "filterAttack",
+		value: func(m Model) string { return fmt.Sprintf("%.2f s", m.synth.Filter.Attack.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Filter.Attack.Set(math.Max(0, math.Min(2, m.synth.Filter.Attack.Get()+dir*0.01)))
+		},
+	},
+	{
+		label: "Filter Decay",
+		param: "filterDecay",
+		value: func(m Model) string { return fmt.Sprintf("%.2f s", m.synth.Filter.Decay.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Filter.Decay.Set(math.Max(0, math.Min(2, m.synth.Filter.Decay.Get()+dir*0.01)))
+		},
+	},
+	{
+		label: "Filter Sustain",
+		param: "filterSustain",
+		value: func(m Model) string { return fmt.Sprintf("%.2f", m.synth.Filter.Sustain.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Filter.Sustain.Set(math.Max(0, math.Min(1, m.synth.Filter.Sustain.Get()+dir*0.05)))
+		},
+	},
+	{
+		label: "Filter Release",
+		param: "filterRelease",
+		value: func(m Model) string { return fmt.Sprintf("%.2f s", m.synth.Filter.Release.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Filter.Release.Set(math.Max(0, math.Min(5, m.synth.Filter.Release.Get()+dir*0.05)))
+		},
+	},
+}
+
+const (
+	filterPlotHeight = 10   // Rows of the filter response plot
+	filterPlotTop    = 24.0 // Gain at the top of the plot in dB, above the highest resonant peak
+	filterPlotRange  = 60.0 // dB from the top of the plot to its bottom
+)
+
+// drawFilterResponse plots the filter's gain over the same log frequency axis as the
+// spectrum, for the cutoff and resonance as set, so it follows them as they change
+func (m Model) drawFilterResponse(baseStyle lipgloss.Style) string {
+	span := spectrumHighest / spectrumLowest
+	heights := make([]float64, waveformWidth)
+	for x := range heights {
+		freq := spectrumLowest * math.Pow(span, (float64(x)+0.5)/waveformWidth)
+		gain := m.synth.Filter.Response(freq)
+		heights[x] = math.Max(0, math.Min(1, (gain-filterPlotTop+filterPlotRange)/filterPlotRange)) * filterPlotHeight
+	}
+
+	var result strings.Builder
+	border := borderStyle.Foreground(lipgloss.Color("#004400"))
+	result.WriteString(border.Render("╔"+strings.Repeat("═", waveformWidth)+"╗") + "\n")
+	for row := filterPlotHeight - 1; row >= 0; row-- {
+		result.WriteString(border.Render("║"))
+		for _, height := range heights {
+			fill := clamp(int((height-float64(row))*8), 0, 8)
+			if fill == 0 {
+				result.WriteString(spaceStyle.Render(" "))
+				continue
+			}
+			color := meterColor(float64(row+1) / filterPlotHeight)
+			result.WriteString(spaceStyle.Foreground(lipgloss.Color(color)).Render(string(spectrumBlocks[fill])))
+		}
+		top := filterPlotTop - filterPlotRange*float64(filterPlotHeight-1-row)/filterPlotHeight
+		result.WriteString(border.Render(fmt.Sprintf("║ %+.0f", top)) + "\n")
+	}
+	result.WriteString(border.Render("╚"+strings.Repeat("═", waveformWidth)+"╝") + "\n")
+	result.WriteString(baseStyle.Render(frequencyAxis()) + "\n")
+	return result.String()
+}
@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+
+	"gosynth/pkg/engine"
+)
+
+// fmItems are the rows of the FM page: the mode, algorithm and feedback, then the ratio,
+// level and envelope of each operator
+var fmItems = func() []menuItem {
+	items := []menuItem{
+		{
+			label: "FM Voices",
+			value: func(m Model) string { return onOff(m.synth.FM.Enabled()) },
+			adjust: func(m *Model, dir float64) {
+				m.synth.FM.SetEnabled(!m.synth.FM.Enabled())
+			},
+		},
+		{
+			label: "Algorithm",
+			value: func(m Model) string {
+				a := m.synth.FM.Algorithm()
+				return fmt.Sprintf("%d: %s", int(a)+1, a)
+			},
+			adjust: func(m *Model, dir float64) {
+				m.synth.FM.SetAlgorithm(m.synth.FM.Algorithm().Next(sign(dir)))
+			},
+		},
+		{
+			label: "Feedback (op 4)",
+			param: "fmFeedback",
+			value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.FM.Feedback.Get()*100) },
+			adjust: func(m *Model, dir float64) {
+				fb := &m.synth.FM.Feedback
+				fb.Set(math.Max(0, math.Min(1, math.Round((fb.Get()+dir*0.05)*100)/100)))
+			},
+		},
+	}
+	for i := 0; i < engine.FMOperators; i++ {
+		i := i
+		name := fmt.Sprintf("Op %d", i+1)
+		param := fmt.Sprintf("op%d", i+1)
+		op := func(m Model) *engine.FMOperator { return &m.synth.FM.Operators[i] }
+		items = append(items,
+			menuItem{
+				label: name + " Ratio",
+				param: param + "Ratio",
+				value: func(m Model) string { return fmt.Sprintf("%.2f", op(m).Ratio.Get()) },
+				adjust: func(m *Model, dir float64) {
+					// Steps of a half reach the harmonic ratios; shift moves in twentieths to detune
+					r := &op(*m).Ratio
+					r.Set(math.Max(engine.FMMinRatio, math.Min(engine.FMMaxRatio, math.Round((r.Get()+dir*0.5)*100)/100)))
+				},
+			},
+			menuItem{
+				label: name + " Level",
+				param: param + "Level",
+				value: func(m Model) string { return fmt.Sprintf("%.2f", op(m).Level.Get()) },
+				adjust: func(m *Model, dir float64) {
+					l := &op(*m).Level
+					l.Set(math.Max(0, math.Min(1, math.Round((l.Get()+dir*0.05)*100)/100)))
+				},
+			},
+			menuItem{
+				label: name + " Attack",
+				param: param + "Attack",
+				value: func(m Model) string { return fmt.Sprintf("%.2f s", op(m).Attack.Get()) },
+				adjust: func(m *Model, dir float64) {
+					a := &op(*m).Attack
+					a.Set(math.Max(0, math.Min(2, a.Get()+dir*0.01)))
+				},
+			},
+			menuItem{
+				label: name + " Decay",
+				param: param + "Decay",
+				value: func(m Model) string { return fmt.Sprintf("%.2f s", op(m).Decay.Get()) },
+				adjust: func(m *Model, dir float64) {
+					d := &op(*m).Decay
+					d.Set(math.Max(0, math.Min(2, d.Get()+dir*0.01)))
+				},
+			},
+			menuItem{
+				label: name + " Sustain",
+				param: param + "Sustain",
+				value: func(m Model) string { return fmt.Sprintf("%.2f", op(m).Sustain.Get()) },
+				adjust: func(m *Model, dir float64) {
+					s := &op(*m).Sustain
+					s.Set(math.Max(0, math.Min(1, s.Get()+dir*0.05)))
+				},
+			},
+			menuItem{
+				label: name + " Release",
+				param: param + "Release",
+				value: func(m Model) string { return fmt.Sprintf("%.2f s", op(m).Release.Get()) },
+				adjust: func(m *Model, dir float64) {
+					r := &op(*m).Release
+					r.Set(math.Max(0, math.Min(5, r.Get()+dir*0.05)))
+				},
+			},
+		)
+	}
+	return items
+}()
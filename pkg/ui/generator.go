@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"gosynth/pkg/engine"
+)
+
+// generatorItems are the rows of the signal generator page
+var generatorItems = []menuItem{
+	{
+		label: "Signal",
+		value: func(m Model) string {
+			g := m.synth.Generator
+			if g.Signal() == engine.TestSweep {
+				return fmt.Sprintf("%s (%.0f Hz)", g.Signal(), g.SweepFreq())
+			}
+			return g.Signal().String()
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Generator.SetSignal(m.synth.Generator.Signal().Next(sign(dir)))
+		},
+	},
+	frequencyRow("Tone Frequency", func(m Model) *engine.SmoothValue { return &m.synth.Generator.Freq }),
+	{
+		label: "Level",
+		value: func(m Model) string { return fmt.Sprintf("%.1f dBFS", m.synth.Generator.Level.Get()) },
+		adjust: func(m *Model, dir float64) {
+			level := math.Round((m.synth.Generator.Level.Get()+dir)*10) / 10
+			m.synth.Generator.Level.Set(math.Max(engine.SignalMinLevel, math.Min(0, level)))
+		},
+		enter: func(m *Model, text string) error {
+			number := strings.TrimSpace(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(text)), "dbfs"))
+			level, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return fmt.Errorf("%q is not a level in dBFS", text)
+			}
+			m.synth.Generator.Level.Set(math.Max(engine.SignalMinLevel, math.Min(0, level)))
+			return nil
+		},
+	},
+	{
+		label: "Channels",
+		value: func(m Model) string { return m.synth.Generator.Channels().String() },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Generator.SetChannels(m.synth.Generator.Channels().Next(sign(dir)))
+		},
+	},
+	frequencyRow("Sweep Start", func(m Model) *engine.SmoothValue { return &m.synth.Generator.SweepFrom }),
+	frequencyRow("Sweep End", func(m Model) *engine.SmoothValue { return &m.synth.Generator.SweepTo }),
+	{
+		label: "Sweep Time",
+		value: func(m Model) string { return fmt.Sprintf("%.0f s", m.synth.Generator.SweepTime.Get()) },
+		adjust: func(m *Model, dir float64) {
+			length := m.synth.Generator.SweepTime.Get() + float64(steps(dir))
+			m.synth.Generator.SweepTime.Set(math.Max(1, math.Min(engine.MaxSweepTime, length)))
+		},
+	},
+}
+
+// frequencyRow returns a row setting one of the generator's frequencies in semitone
+// steps, or typed as a note or in Hz
+func frequencyRow(label string, value func(m Model) *engine.SmoothValue) menuItem {
+	return menuItem{
+		label: label,
+		value: func(m Model) string { return frequencyValue(value(m).Get()) },
+		adjust: func(m *Model, dir float64) {
+			freq := value(*m)
+			freq.Set(semitones(freq.Get(), dir, engine.SignalMinFreq, engine.SignalMaxFreq))
+		},
+		enter: func(m *Model, text string) error {
+			freq, err := parseFrequency(text)
+			if err != nil {
+				return err
+			}
+			value(*m).Set(math.Max(engine.SignalMinFreq, math.Min(engine.SignalMaxFreq, freq)))
+			return nil
+		},
+	}
+}
+
+// renderGenerator warns that a test signal is playing in place of the synth, on every
+// page, or returns nothing while the generator is off
+func (m Model) renderGenerator(baseStyle lipgloss.Style) string {
+	g := m.synth.Generator
+	if g.Signal() == engine.TestOff {
+		return ""
+	}
+	return baseStyle.Foreground(lipgloss.Color("#ff0000")).Render(fmt.Sprintf(
+		"Test signal: %s at %.1f dBFS on %s, the synth is muted", g.Signal(), g.Level.Get(), g.Channels())) + "\n"
+}
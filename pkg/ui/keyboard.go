@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	pianoVelocity   = 100                    // Velocity used for computer-keyboard notes
+	pianoMinOctave  = 0                      // Lowest selectable keyboard octave
+	pianoMaxOctave  = 8                      // Highest selectable keyboard octave
+	pianoFirstHold  = 600 * time.Millisecond // Covers the terminal's initial key-repeat delay
+	pianoRepeatHold = 150 * time.Millisecond // Covers the interval between auto-repeats
+)
+
+// pianoKeys maps a row of computer keys to semitone offsets from C, laid out like a piano
+var pianoKeys = map[string]int{
+	"a": 0, "w": 1, "s": 2, "e": 3, "d": 4, "f": 5, "t": 6, "g": 7,
+	"y": 8, "h": 9, "u": 10, "j": 11, "k": 12, "o": 13, "l": 14, "p": 15,
+	";": 16, "'": 17,
+}
+
+// pianoReleaseMsg fires when a computer-keyboard note has not been repeated recently.
+// Terminals do not report key releases, so held keys are detected from auto-repeat.
+type pianoReleaseMsg struct {
+	note uint8
+	seq  int
+}
+
+// handlePianoKey plays a note for a mapped key, returning false if the key is not a piano key
+func (m *Model) handlePianoKey(key string) (tea.Cmd, bool) {
+	switch key {
+	case "z":
+		if m.octave > pianoMinOctave {
+			m.octave--
+		}
+		return nil, true
+	case "x":
+		if m.octave < pianoMaxOctave {
+			m.octave++
+		}
+		return nil, true
+	}
+
+	offset, ok := pianoKeys[key]
+	if !ok {
+		return nil, false
+	}
+	note := (m.octave+1)*12 + offset
+	if note > 127 {
+		return nil, true
+	}
+
+	// A repeat of a held key only extends the hold; a fresh press starts the note
+	hold := pianoRepeatHold
+	if _, held := m.pianoHeld[uint8(note)]; !held {
+		m.synth.NoteOn(uint8(note), pianoVelocity)
+		hold = pianoFirstHold
+	}
+	m.pianoSeq++
+	m.pianoHeld[uint8(note)] = m.pianoSeq
+
+	msg := pianoReleaseMsg{note: uint8(note), seq: m.pianoSeq}
+	return tea.Tick(hold, func(time.Time) tea.Msg { return msg }), true
+}
+
+// handlePianoRelease ends a note once its key has stopped repeating
+func (m *Model) handlePianoRelease(msg pianoReleaseMsg) {
+	if seq, held := m.pianoHeld[msg.note]; held && seq == msg.seq {
+		delete(m.pianoHeld, msg.note)
+		m.synth.NoteOff(msg.note)
+	}
+}
+
+// releasePianoNotes ends every note started from the computer keyboard
+func (m *Model) releasePianoNotes() {
+	for note := range m.pianoHeld {
+		m.synth.NoteOff(note)
+		delete(m.pianoHeld, note)
+	}
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"fmt"
+
+	"gosynth/pkg/synth"
+)
+
+// macroItems returns the rows of the macro knobs: each knob's position, then the
+// parameters it moves
+func macroItems() []menuItem {
+	var items []menuItem
+	for macro := 0; macro < synth.Macros; macro++ {
+		macro := macro
+		name := fmt.Sprintf("Macro %d", macro+1)
+		items = append(items,
+			menuItem{
+				label: name,
+				param: fmt.Sprintf("macro%d", macro+1),
+				value: func(m Model) string {
+					return fmt.Sprintf("%.0f%% (CC %d)", m.synth.Macros.Value[macro].Get()*100, synth.MacroCC+macro)
+				},
+				adjust: func(m *Model, dir float64) {
+					m.synth.SetMacro(macro, m.synth.Macros.Value[macro].Get()+dir*0.05)
+				},
+				mod: func(m Model) (float64, float64) {
+					return m.synth.Macros.Value[macro].Get(), m.synth.Macros.Value[macro].Current()
+				},
+			},
+			menuItem{
+				label: name + " Targets",
+				value: func(m Model) string { return synth.FormatMacro(m.synth.Macros.Targets(macro)) },
+				adjust: func(m *Model, dir float64) {
+					m.synth.Macros.Invert(macro)
+				},
+				enter: func(m *Model, text string) error {
+					targets, err := synth.ParseMacro(text)
+					if err != nil {
+						return err
+					}
+					return m.synth.SetMacroTargets(macro, targets)
+				},
+			},
+		)
+	}
+	return items
+}
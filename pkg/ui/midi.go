@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"gosynth/pkg/synth"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// midiScanMsg asks for the MIDI ports to be checked for devices plugged in or out
+type midiScanMsg struct{}
+
+// scanMIDI schedules the next check of the MIDI ports
+func scanMIDI() tea.Cmd {
+	return tea.Tick(synth.MIDIScanInterval, func(time.Time) tea.Msg {
+		return midiScanMsg{}
+	})
+}
+
+// rescanMIDI reconnects the MIDI ports when devices were plugged in or out
+func (m *Model) rescanMIDI() {
+	if m.synth.RescanMIDI() {
+		m.status = "MIDI devices changed: " + m.midiSummary()
+		m.buffer = "" // Clear buffer to force redraw
+	}
+}
+
+// midiSummary names the connected MIDI inputs and output
+func (m Model) midiSummary() string {
+	if m.synth.NoMIDI {
+		return "off"
+	}
+	in := "none"
+	if inputs := m.synth.MIDIInputs(); len(inputs) > 0 {
+		in = strings.Join(inputs, ", ")
+	}
+	out := "none"
+	if m.synth.MIDIOut.Connected() {
+		out = m.synth.MIDIOut.Name()
+	}
+	return "in " + in + ", out " + out
+}
+
+// renderMIDI shows the connected MIDI devices
+func (m Model) renderMIDI(baseStyle lipgloss.Style) string {
+	return baseStyle.Render("MIDI: " + m.midiSummary())
+}
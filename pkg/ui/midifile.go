@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"gosynth/pkg/synth"
+)
+
+// midiFileItems are the MIDI file player rows of the settings page
+var midiFileItems = []menuItem{
+	{
+		label: "MIDI File",
+		value: func(m Model) string {
+			if name := m.synth.Player.Name(); name != "" {
+				return name
+			}
+			return "none"
+		},
+		adjust: func(m *Model, dir float64) {
+			m.loadAdjacentMIDIFile(sign(dir))
+		},
+		enter: func(m *Model, text string) error {
+			if err := m.synth.Player.Load(text); err != nil {
+				return err
+			}
+			m.status = "Loaded " + m.synth.Player.Name()
+			return nil
+		},
+	},
+	{
+		label: "MIDI File Patches",
+		value: func(m Model) string {
+			if m.synth.Player.MapPatches() {
+				return "from programs and track names"
+			}
+			return "off, as set"
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Player.SetMapPatches(!m.synth.Player.MapPatches())
+		},
+	},
+	{
+		label: "MIDI File Playback",
+		value: func(m Model) string {
+			player := m.synth.Player
+			switch {
+			case player.Name() == "":
+				return "no file"
+			case player.Playing():
+				return fmt.Sprintf("playing %s / %s", formatMinutes(player.Position()), formatMinutes(player.Length()))
+			}
+			return fmt.Sprintf("stopped (%s)", formatMinutes(player.Length()))
+		},
+		adjust: func(m *Model, dir float64) {
+			if m.synth.Player.Playing() {
+				m.synth.Player.Stop()
+			} else {
+				m.synth.Player.Play()
+			}
+		},
+	},
+}
+
+// loadAdjacentMIDIFile loads the previous or next .mid file in the MIDI file directory
+func (m *Model) loadAdjacentMIDIFile(dir int) {
+	names, err := synth.ListMIDIFiles()
+	if err != nil {
+		m.status = fmt.Sprintf("Listing MIDI files failed: %v", err)
+		return
+	}
+	if len(names) == 0 {
+		midiDir, _ := synth.MIDIFileDir()
+		m.status = fmt.Sprintf("No MIDI files in %s", midiDir)
+		return
+	}
+	idx := -1
+	for i, name := range names {
+		if name == m.synth.Player.Name() {
+			idx = i
+		}
+	}
+	if idx < 0 && dir < 0 {
+		idx = 0
+	}
+	idx = (idx + dir + len(names)) % len(names)
+	if err := m.synth.Player.Load(names[idx]); err != nil {
+		m.status = fmt.Sprintf("Loading %s failed: %v", names[idx], err)
+		return
+	}
+	m.status = "Loaded " + names[idx]
+}
+
+// formatMinutes writes a duration as minutes and seconds, such as 2:05
+func formatMinutes(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
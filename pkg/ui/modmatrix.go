@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+
+	"gosynth/pkg/engine"
+)
+
+// modItems returns the modulation page rows: source, destination, curve and amount for
+// each slot, with a steps row while the slot's curve is stepped, then the macro knobs, the
+// LFO and the sweep rows
+func (m Model) modItems() []menuItem {
+	var items []menuItem
+	for slot := 0; slot < engine.ModSlots; slot++ {
+		slot := slot
+		name := fmt.Sprintf("Mod %d", slot+1)
+		routing := m.synth.Mod.Routing(slot)
+		items = append(items,
+			menuItem{
+				label: name + " Source",
+				value: func(m Model) string { return m.synth.Mod.Routing(slot).Source.String() },
+				adjust: func(m *Model, dir float64) {
+					m.synth.Mod.EditRouting(slot, func(r *engine.ModRouting) { r.Source = r.Source.Next(sign(dir)) })
+				},
+			},
+			menuItem{
+				label: name + " Destination",
+				value: func(m Model) string { return m.synth.Mod.Routing(slot).Dest.String() },
+				adjust: func(m *Model, dir float64) {
+					m.synth.Mod.EditRouting(slot, func(r *engine.ModRouting) { r.Dest = r.Dest.Next(sign(dir)) })
+				},
+			},
+			menuItem{
+				label: name + " Curve",
+				value: func(m Model) string { return m.synth.Mod.Routing(slot).Curve.String() },
+				adjust: func(m *Model, dir float64) {
+					m.synth.Mod.EditRouting(slot, func(r *engine.ModRouting) { r.Curve = r.Curve.Next(sign(dir)) })
+				},
+			},
+		)
+		if routing.Curve == engine.CurveSteps {
+			items = append(items, menuItem{
+				label: name + " Steps",
+				value: func(m Model) string { return fmt.Sprintf("%d", m.synth.Mod.Routing(slot).Steps) },
+				adjust: func(m *Model, dir float64) {
+					m.synth.Mod.EditRouting(slot, func(r *engine.ModRouting) { r.Steps += steps(dir) })
+				},
+			})
+		}
+		items = append(items, menuItem{
+			label: name + " Amount",
+			value: func(m Model) string {
+				r := m.synth.Mod.Routing(slot)
+				if r.Dest == engine.ModPitch {
+					return fmt.Sprintf("%+.0f%% (%+.1f st)", r.Amount*100, r.Amount*engine.ModPitchMax)
+				}
+				return fmt.Sprintf("%+.0f%%", r.Amount*100)
+			},
+			adjust: func(m *Model, dir float64) {
+				m.synth.Mod.EditRouting(slot, func(r *engine.ModRouting) {
+					r.Amount = math.Round((r.Amount+dir*0.05)*100) / 100
+				})
+			},
+		})
+	}
+	items = append(items, macroItems()...)
+	items = append(items, lfoItems...)
+	return append(items, m.sweepItems()...)
+}
+
+// lfoItems are the LFO rows of the modulation page
+var lfoItems = []menuItem{
+	{
+		label: "LFO Shape",
+		value: func(m Model) string { return m.synth.LFO.Shape().String() },
+		adjust: func(m *Model, dir float64) {
+			m.synth.LFO.SetShape(m.synth.LFO.Shape().Next(sign(dir)))
+		},
+	},
+	{
+		label: "LFO Sync",
+		value: func(m Model) string { return onOff(m.synth.LFO.Synced()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.LFO.SetSynced(!m.synth.LFO.Synced())
+		},
+	},
+	{
+		label: "LFO Rate",
+		param: "lfoRate",
+		value: func(m Model) string {
+			if m.synth.LFO.Synced() {
+				return fmt.Sprintf("%s (%.2f Hz)", engine.Divisions[m.synth.LFO.Div()].Name, m.synth.LFO.Hz())
+			}
+			return fmt.Sprintf("%.2f Hz", m.synth.LFO.Rate.Get())
+		},
+		adjust: func(m *Model, dir float64) {
+			if m.synth.LFO.Synced() {
+				// Right moves to shorter divisions, speeding up as the unsynced rate does
+				m.synth.LFO.SetDiv(m.synth.LFO.Div() + sign(dir))
+				return
+			}
+			// Steps grow with the rate, so slow and fast rates are both easy to set
+			rate := m.synth.LFO.Rate.Get() * math.Exp2(dir/12)
+			m.synth.LFO.Rate.Set(math.Max(engine.LFOMinRate, math.Min(engine.LFOMaxRate, rate)))
+		},
+	},
+}
+
+// renderModSummary lists each slot's routing on one line, then the sweep
+func (m Model) renderModSummary() []string {
+	routings := m.synth.Mod.Routings()
+	lines := make([]string, len(routings))
+	for i, r := range routings {
+		lines[i] = fmt.Sprintf("Mod %d: %s", i+1, r)
+	}
+	return append(lines, m.renderSweep())
+}
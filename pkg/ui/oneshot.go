@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+
+	"gosynth/pkg/engine"
+)
+
+// oneShotItems are the one-shot sample rows of the oscillators page: the level, then
+// each slot, browsing the WAV files in the sample directory
+var oneShotItems = func() []menuItem {
+	items := []menuItem{{
+		label: "One-Shot Level",
+		param: "oneShotLevel",
+		value: func(m Model) string { return fmt.Sprintf("%.2f", m.synth.OneShots.Level.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.OneShots.Level.Set(math.Max(0, math.Min(1, m.synth.OneShots.Level.Get()+dir*0.05)))
+		},
+	}}
+	for slot := 0; slot < engine.OneShotSlots; slot++ {
+		slot := slot
+		items = append(items, menuItem{
+			label: fmt.Sprintf("One-Shot %d", slot+1),
+			value: func(m Model) string {
+				sample := m.synth.OneShots.Sample(slot)
+				if sample == nil {
+					return "empty"
+				}
+				return fmt.Sprintf("%s (%.1f s)", sample.Name, sample.Duration())
+			},
+			adjust: func(m *Model, dir float64) {
+				m.loadAdjacentOneShot(slot, sign(dir))
+			},
+		})
+	}
+	return items
+}()
+
+// loadAdjacentOneShot loads the previous or next WAV file into a one-shot slot, with
+// "empty" between the last file and the first
+func (m *Model) loadAdjacentOneShot(slot, dir int) {
+	names, err := engine.ListSamples()
+	if err != nil {
+		m.status = fmt.Sprintf("Listing samples failed: %v", err)
+		return
+	}
+	if len(names) == 0 {
+		sampleDir, _ := engine.SampleDir()
+		m.status = fmt.Sprintf("No WAV samples in %s", sampleDir)
+		return
+	}
+
+	// Index 0 is empty, followed by the sample files
+	idx := 0
+	if sample := m.synth.OneShots.Sample(slot); sample != nil {
+		for i, name := range names {
+			if name == sample.Name {
+				idx = i + 1
+			}
+		}
+	}
+	idx = (idx + dir + len(names) + 1) % (len(names) + 1)
+	if idx == 0 {
+		m.synth.OneShots.Clear(slot)
+		m.status = fmt.Sprintf("One-shot %d empty", slot+1)
+		return
+	}
+	name := names[idx-1]
+	if err := m.synth.OneShots.Load(slot, name); err != nil {
+		m.status = fmt.Sprintf("Loading sample %s failed: %v", name, err)
+		return
+	}
+	m.status = fmt.Sprintf("Loaded %s into one-shot %d", name, slot+1)
+}
+
+// shotName returns a step's one-shot as shown on the sequencer page
+func (m Model) shotName(shot int) string {
+	if shot == 0 {
+		return "no one-shot"
+	}
+	if sample := m.synth.OneShots.Sample(shot - 1); sample != nil {
+		return fmt.Sprintf("one-shot %d (%s)", shot, sample.Name)
+	}
+	return fmt.Sprintf("one-shot %d (empty)", shot)
+}
@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"gosynth/pkg/synth"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Pages of the UI, cycled with tab and shift+tab
+const (
+	pageOscillators = iota
+	pageFM
+	pageFilter
+	pageEnvelopes
+	pageSequencer
+	pageEffects
+	pageMod
+	pageSpectrum
+	pageStats
+	pageParts
+	pageSettings
+	pageGenerator
+	pageTuner
+	pageCount
+)
+
+// uiPage describes a page: its name in the header, its parameter rows and any keys
+// it handles ahead of the global ones
+type uiPage struct {
+	name  string
+	items func(m *Model) []menuItem       // Parameter rows, or nil for a page that draws its own
+	keys  func(m *Model, key string) bool // Handles a page key, reporting whether it was used
+	help  []string                        // Control lines for the page's own keys
+}
+
+// pages lists every page in tab order
+var pages = [pageCount]uiPage{
+	pageOscillators: {
+		name: "Oscillators",
+		items: func(m *Model) []menuItem {
+			items := append(oscillatorItems[:len(oscillatorItems):len(oscillatorItems)], tuningItems...)
+			items = append(items, oneShotItems...)
+			return append(items, drumItems...)
+		},
+	},
+	pageFM: {
+		name:  "FM",
+		items: func(m *Model) []menuItem { return fmItems },
+		help:  []string{"Operators only modulate lower-numbered ones; carriers are heard, scaled to one carrier's level"},
+	},
+	pageFilter: {
+		name:  "Filter",
+		items: func(m *Model) []menuItem { return filterItems },
+	},
+	pageEnvelopes: {
+		name: "Envelopes",
+		items: func(m *Model) []menuItem {
+			items := append(envelopeItems[:len(envelopeItems):len(envelopeItems)], monoItems...)
+			return append(items, polyphonyItems...)
+		},
+	},
+	pageSequencer: {
+		name: "Sequencer",
+		keys: (*Model).handleSequencerKey,
+		help: []string{
+			"Use ←→ to move the cursor, ↑↓ (shift for octaves) to set the note",
+			"Enter toggles the step, [ ] velocity, 9 0 gate, < > nudge it off the grid, o p one-shot, 1 2 3 kick, snare and hat, , . length, - = BPM",
+			"g picks the notes, a drum or a one-shot slot to fill with a Euclidean rhythm, h j set its hits, r t rotate it",
+			"{ } choose the pattern, c copies it to the next one, m switches song mode (the order is set on the settings page)",
+			"u i lay a groove template over the pattern, changing its timing and accents as it plays but not its steps",
+			"Space starts and stops the sequencer",
+		},
+	},
+	pageEffects: {
+		name: "Effects",
+		items: func(m *Model) []menuItem {
+			items, _ := m.effectItems()
+			return items
+		},
+		keys: func(m *Model, key string) bool {
+			switch key {
+			case "[", "]":
+				m.moveEffect(map[string]int{"[": -1, "]": 1}[key])
+				return true
+			}
+			return false
+		},
+		help: []string{"Use [ ] to move the selected effect earlier or later in the chain"},
+	},
+	pageMod: {
+		name:  "Modulation",
+		items: func(m *Model) []menuItem { return m.modItems() },
+	},
+	pageSpectrum: {
+		name:  "Spectrum",
+		items: func(m *Model) []menuItem { return spectrumItems },
+	},
+	pageStats: {
+		name: "Stats",
+		help: []string{fmt.Sprintf("Playing time stops counting after a pause of more than %s between notes", synth.StatsIdleGap)},
+	},
+	pageParts: {
+		name:  "Parts",
+		items: func(m *Model) []menuItem { return m.partItems() },
+		help:  []string{"Each part plays notes from its MIDI channel; other channels play the main synth"},
+	},
+	pageSettings: {
+		name: "Settings",
+		items: func(m *Model) []menuItem {
+			return append(settingsItems[:len(settingsItems):len(settingsItems)], midiFileItems...)
+		},
+	},
+	pageGenerator: {
+		name:  "Generator",
+		items: func(m *Model) []menuItem { return generatorItems },
+		help: []string{
+			"The test signal replaces the synth's output past the volume, at its exact level; set it off to play again",
+			"The sweep rises logarithmically from its start to its end frequency and repeats",
+		},
+	},
+	pageTuner: {
+		name: "Tuner",
+		help: []string{"The tuner follows the audio input; the needle is green within 5 cents of the note"},
+	},
+}
+
+// pageItems returns the rows of the current page and its selection. Rows can come and
+// go, such as the stepped curve and sweep point rows, so the selection is kept on the page.
+func (m *Model) pageItems() ([]menuItem, *int) {
+	selected := &m.selected[m.page]
+	if pages[m.page].items == nil {
+		return nil, selected
+	}
+	items := pages[m.page].items(m)
+	*selected = max(0, min(*selected, len(items)-1))
+	return items, selected
+}
+
+// renderPageTabs draws the page names across the top, highlighting the current page
+func (m Model) renderPageTabs(baseStyle, selectedStyle lipgloss.Style) string {
+	tabs := make([]string, pageCount)
+	for i, page := range pages {
+		if i == m.page {
+			tabs[i] = selectedStyle.Render("[" + page.name + "]")
+		} else {
+			tabs[i] = baseStyle.Faint(true).Render(" " + page.name + " ")
+		}
+	}
+	return strings.Join(tabs, baseStyle.Render(" "))
+}
@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gosynth/pkg/synth"
+)
+
+// partItems returns the parts page rows: the number of parts, then the preset, MIDI
+// channel, level and pan of each
+func (m Model) partItems() []menuItem {
+	items := []menuItem{{
+		label: "Parts",
+		value: func(m Model) string { return fmt.Sprintf("%d of %d", len(m.synth.Parts()), synth.MaxParts) },
+		adjust: func(m *Model, dir float64) {
+			if dir < 0 {
+				m.synth.RemovePart()
+				return
+			}
+			if _, err := m.synth.AddPart(); err != nil {
+				m.status = fmt.Sprintf("Adding a part failed: %v", err)
+			}
+		},
+	}}
+	for i, part := range m.synth.Parts() {
+		part := part
+		name := fmt.Sprintf("Part %d", i+1)
+		items = append(items,
+			menuItem{
+				label:  name + " Preset",
+				value:  func(m Model) string { return part.Synth.PresetName() },
+				adjust: func(m *Model, dir float64) { m.loadPartPreset(name, part, sign(dir)) },
+			},
+			menuItem{
+				label: name + " Channel",
+				value: func(m Model) string { return fmt.Sprintf("%d", m.synth.PartChannel(part)+1) },
+				adjust: func(m *Model, dir float64) {
+					channel := int(m.synth.PartChannel(part)) + steps(dir)
+					m.synth.SetPartChannel(part, uint8(clamp(channel, 0, 15)))
+				},
+			},
+			menuItem{
+				label: name + " Level",
+				value: func(m Model) string { return fmt.Sprintf("%.0f%%", part.Level.Get()*100) },
+				adjust: func(m *Model, dir float64) {
+					part.Level.Set(math.Max(0, math.Min(math.Round((part.Level.Get()+dir*0.05)*100)/100, 1)))
+				},
+			},
+			menuItem{
+				label: name + " Pan",
+				value: func(m Model) string { return panName(part.Pan.Get()) },
+				adjust: func(m *Model, dir float64) {
+					part.Pan.Set(math.Max(-1, math.Min(math.Round((part.Pan.Get()+dir*0.05)*100)/100, 1)))
+				},
+			},
+		)
+	}
+	return items
+}
+
+// loadPartPreset loads the saved preset before or after a part's current one into it
+func (m *Model) loadPartPreset(name string, part *synth.Part, dir int) {
+	preset, ok := m.adjacentPreset(part.Synth.PresetName(), dir)
+	if !ok {
+		return
+	}
+	err := part.Synth.LoadPreset(preset)
+	var issues synth.PresetIssues
+	if err != nil && !errors.As(err, &issues) {
+		m.status = fmt.Sprintf("Loading preset %s into %s failed: %v", preset, name, err)
+		return
+	}
+	m.status = fmt.Sprintf("Loaded preset %s into %s", preset, name)
+	if len(issues) > 0 {
+		m.status += presetIssuesStatus(issues)
+	}
+}
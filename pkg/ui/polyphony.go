@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gosynth/pkg/engine"
+)
+
+// polyphonyItems are the voice limit rows of the envelopes page: the voices playing, the
+// limit and stealing policy, then for each part the voices kept for it and the most it may
+// play at once
+var polyphonyItems = func() []menuItem {
+	items := []menuItem{
+		{
+			label: "Voices Playing",
+			value: func(m Model) string {
+				return fmt.Sprintf("%d of %d", m.synth.SoundingVoices(), m.synth.Polyphony.Voices())
+			},
+			adjust: func(m *Model, dir float64) {},
+		},
+		{
+			label: "Max Polyphony",
+			value: func(m Model) string {
+				return fmt.Sprintf("%d of %d", m.synth.Polyphony.Voices(), engine.MaxVoices)
+			},
+			adjust: func(m *Model, dir float64) {
+				m.synth.Polyphony.SetVoices(m.synth.Polyphony.Voices() + steps(dir))
+			},
+		},
+		{
+			label: "Voice Stealing",
+			value: func(m Model) string { return m.synth.Polyphony.Steal().String() },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Polyphony.SetSteal(m.synth.Polyphony.Steal().Next(sign(dir)))
+			},
+		},
+	}
+	for part := engine.VoicePart(0); part < engine.PartCount; part++ {
+		part := part
+		name := strings.ToUpper(part.String()[:1]) + part.String()[1:]
+		items = append(items,
+			menuItem{
+				label: name + " Reserved Voices",
+				value: func(m Model) string {
+					if n := m.synth.Polyphony.Part(part).Reserve; n > 0 {
+						return fmt.Sprintf("%d", n)
+					}
+					return "none"
+				},
+				adjust: func(m *Model, dir float64) {
+					m.synth.Polyphony.SetReserve(part, m.synth.Polyphony.Part(part).Reserve+steps(dir))
+				},
+			},
+			menuItem{
+				label: name + " Max Voices",
+				value: func(m Model) string {
+					return fmt.Sprintf("%d of %d", m.synth.Polyphony.Part(part).Max, engine.MaxVoices)
+				},
+				adjust: func(m *Model, dir float64) {
+					m.synth.Polyphony.SetMax(part, m.synth.Polyphony.Part(part).Max+steps(dir))
+				},
+			},
+		)
+	}
+	return items
+}()
+
+// monoItems are the mono mode rows of the envelopes page
+var monoItems = []menuItem{
+	{
+		label: "Mono",
+		value: func(m Model) string { return onOff(m.synth.Mono.Enabled()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Mono.SetEnabled(!m.synth.Mono.Enabled())
+		},
+	},
+	{
+		label: "Note Priority",
+		value: func(m Model) string { return m.synth.Mono.Priority().String() },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Mono.SetPriority(m.synth.Mono.Priority().Next(sign(dir)))
+		},
+	},
+	{
+		label: "Portamento",
+		param: "portamento",
+		value: func(m Model) string {
+			if t := m.synth.Mono.Portamento.Get(); t > 0 {
+				return fmt.Sprintf("%.2f s", t)
+			}
+			return "off"
+		},
+		adjust: func(m *Model, dir float64) {
+			t := m.synth.Mono.Portamento.Get() + dir*0.01
+			m.synth.Mono.Portamento.Set(math.Max(0, math.Min(engine.MaxPortamento, math.Round(t*100)/100)))
+		},
+	},
+}
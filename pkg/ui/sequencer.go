@@ -0,0 +1,282 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"gosynth/pkg/engine"
+	"gosynth/pkg/synth"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const stepsPerRow = 16 // Steps shown on each line of the grid
+
+// euclid is the Euclidean rhythm generator's track and rhythm, kept between fills
+type euclid struct {
+	track  int // 0 for the notes, then the drum voices and one-shot slots
+	hits   int
+	rotate int
+}
+
+// handleSequencerKey edits the pattern under the cursor, returning false for keys it does not use
+func (m *Model) handleSequencerKey(key string) bool {
+	seq := m.synth.Seq
+	pattern := seq.Pattern()
+
+	switch key {
+	case "left":
+		m.cursor = (m.cursor - 1 + pattern.Length) % pattern.Length
+	case "right":
+		m.cursor = (m.cursor + 1) % pattern.Length
+	case "up", "down", "shift+up", "shift+down":
+		m.recordEdit(fmt.Sprintf("step %d note", m.cursor))
+		semitones := map[string]int{"up": 1, "down": -1, "shift+up": 12, "shift+down": -12}[key]
+		seq.EditStep(m.cursor, func(step *synth.Step) {
+			step.Note = uint8(clamp(int(step.Note)+semitones, 0, 127))
+			step.Active = true
+		})
+	case "enter":
+		m.recordEdit(fmt.Sprintf("step %d toggle", m.cursor))
+		seq.EditStep(m.cursor, func(step *synth.Step) { step.Active = !step.Active })
+	case "[", "]":
+		m.recordEdit(fmt.Sprintf("step %d velocity", m.cursor))
+		delta := map[string]int{"[": -8, "]": 8}[key]
+		seq.EditStep(m.cursor, func(step *synth.Step) {
+			step.Velocity = uint8(clamp(int(step.Velocity)+delta, 1, 127))
+		})
+	case "9", "0":
+		m.recordEdit(fmt.Sprintf("step %d gate", m.cursor))
+		delta := map[string]float64{"9": -0.1, "0": 0.1}[key]
+		seq.EditStep(m.cursor, func(step *synth.Step) {
+			step.Gate = math.Max(0.1, math.Min(1.0, step.Gate+delta))
+		})
+	case "<", ">":
+		m.recordEdit(fmt.Sprintf("step %d nudge", m.cursor))
+		delta := map[string]int{"<": -1, ">": 1}[key]
+		seq.EditStep(m.cursor, func(step *synth.Step) {
+			step.Nudge = clamp(step.Nudge+delta, -synth.MaxNudge, synth.MaxNudge)
+		})
+	case "u", "i":
+		m.stepGroove(map[string]int{"u": -1, "i": 1}[key], pattern.Groove)
+	case "o", "p":
+		m.recordEdit(fmt.Sprintf("step %d one-shot", m.cursor))
+		delta := map[string]int{"o": -1, "p": 1}[key]
+		seq.EditStep(m.cursor, func(step *synth.Step) {
+			step.Shot = (step.Shot + delta + engine.OneShotSlots + 1) % (engine.OneShotSlots + 1)
+		})
+	case ",", ".":
+		m.recordEdit("length")
+		length := pattern.Length + map[string]int{",": -1, ".": 1}[key]
+		seq.SetLength(clamp(length, 1, synth.MaxSteps))
+		m.cursor = clamp(m.cursor, 0, clamp(length, 1, synth.MaxSteps)-1)
+	case "1", "2", "3":
+		kind := engine.DrumKind(key[0] - '1')
+		m.recordEdit(fmt.Sprintf("step %d %s", m.cursor, kind))
+		seq.EditStep(m.cursor, func(step *synth.Step) { step.Drums ^= 1 << kind })
+	case "{", "}":
+		m.synth.Seq.SelectPattern((m.synth.Seq.Selected() + map[string]int{"{": -1, "}": 1}[key] + synth.MaxPatterns) % synth.MaxPatterns)
+		m.cursor = clamp(m.cursor, 0, seq.Pattern().Length-1)
+	case "c":
+		to := (seq.Selected() + 1) % synth.MaxPatterns
+		m.recordEdit(fmt.Sprintf("copy pattern %d", to))
+		seq.CopyPattern(to)
+		seq.SelectPattern(to)
+		m.status = fmt.Sprintf("Copied to pattern %d", to+1)
+	case "m":
+		m.recordEdit("song mode")
+		seq.SetSongMode(!seq.SongMode())
+	case "g":
+		m.euclid.track = (m.euclid.track + 1) % synth.EuclidTracks
+	case "h", "j":
+		m.euclid.hits = clamp(m.euclid.hits+map[string]int{"h": -1, "j": 1}[key], 0, pattern.Length)
+		m.fillEuclid()
+	case "r", "t":
+		m.euclid.rotate = (m.euclid.rotate + map[string]int{"r": -1, "t": 1}[key] + pattern.Length) % pattern.Length
+		m.fillEuclid()
+	case "-", "=":
+		m.recordEdit("bpm")
+		delta := map[string]float64{"-": -1, "=": 1}[key]
+		clock := m.synth.Clock
+		clock.BPM.Set(math.Max(20, math.Min(300, clock.BPM.Get()+delta)))
+	case " ":
+		if seq.Playing() {
+			seq.Stop()
+		} else {
+			seq.Play()
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// stepGroove lays the previous or next groove template over the pattern, going through
+// none between the last and the first
+func (m *Model) stepGroove(dir int, current *synth.Groove) {
+	names, err := synth.ListGrooves()
+	if err != nil {
+		m.status = fmt.Sprintf("Listing grooves failed: %v", err)
+	}
+	index := -1
+	for i, name := range names {
+		if current != nil && (name == current.Name || strings.TrimSuffix(name, filepath.Ext(name)) == current.Name) {
+			index = i
+		}
+	}
+	index = (index+1+dir+len(names)+1)%(len(names)+1) - 1
+	m.recordEdit("groove")
+	if index < 0 {
+		m.synth.Seq.SetGroove(nil)
+		return
+	}
+	groove, err := synth.LoadGroove(names[index])
+	if err != nil {
+		m.status = fmt.Sprintf("Loading groove failed: %v", err)
+		return
+	}
+	m.synth.Seq.SetGroove(groove)
+}
+
+// grooveName names the pattern's groove template for the header
+func grooveName(p synth.Pattern) string {
+	if p.Groove == nil {
+		return "none"
+	}
+	return p.Groove.Name
+}
+
+// fillEuclid refills the generator's track with its rhythm over the pattern's length
+func (m *Model) fillEuclid() {
+	m.recordEdit(fmt.Sprintf("euclid track %d", m.euclid.track))
+	m.synth.Seq.FillEuclid(m.euclid.track, m.euclid.hits, m.euclid.rotate)
+}
+
+// euclidTrackName names a generator track
+func euclidTrackName(track int) string {
+	if track == 0 {
+		return "notes"
+	}
+	if drum := synth.EuclidDrum(track); drum >= 0 {
+		return drum.String()
+	}
+	return fmt.Sprintf("one-shot %d", synth.EuclidShot(track))
+}
+
+// drumLetters abbreviates a step's drum hits for the grid, such as "KS", or "--" for none
+func drumLetters(step synth.Step) string {
+	letters := ""
+	for kind := engine.DrumKind(0); kind < engine.DrumKinds; kind++ {
+		if step.HasDrum(kind) {
+			letters += strings.ToUpper(kind.String()[:1])
+		}
+	}
+	if letters == "" {
+		return "--"
+	}
+	return letters
+}
+
+// nudgeText shows how many ticks a step plays off the grid, such as "+3", or "--" on it
+func nudgeText(step synth.Step) string {
+	if step.Nudge == 0 {
+		return "--"
+	}
+	return fmt.Sprintf("%+d", step.Nudge)
+}
+
+// drumNames lists a step's drum hits for the cursor line
+func drumNames(step synth.Step) string {
+	var names []string
+	for kind := engine.DrumKind(0); kind < engine.DrumKinds; kind++ {
+		if step.HasDrum(kind) {
+			names = append(names, kind.String())
+		}
+	}
+	if len(names) == 0 {
+		return "no drums"
+	}
+	return strings.Join(names, "+")
+}
+
+// renderSequencer draws the step grid with the cursor and playhead
+func (m Model) renderSequencer(baseStyle, selectedStyle lipgloss.Style) string {
+	seq := m.synth.Seq
+	pattern := seq.Pattern()
+	current := seq.CurrentStep()
+
+	activeStyle := baseStyle.Foreground(lipgloss.Color("#00ffff"))
+	playheadStyle := baseStyle.Foreground(lipgloss.Color("#ff00ff"))
+
+	var s strings.Builder
+	transport := "stopped"
+	if seq.Playing() {
+		transport = "playing"
+	}
+	s.WriteString(baseStyle.Render(fmt.Sprintf("Sequencer: %s  BPM: %.0f  Swing: %.0f%%  Groove: %s  Length: %d  Pattern: %d/%d", transport, m.synth.Clock.BPM.Get(), m.synth.Clock.Swing.Get(), grooveName(pattern), pattern.Length, seq.Selected()+1, synth.MaxPatterns)) + "\n")
+	song := seq.Song()
+	switch entry, repeat := seq.SongPosition(); {
+	case !seq.SongMode():
+		s.WriteString(baseStyle.Render("Song: off, looping the pattern") + "\n\n")
+	case entry >= 0:
+		s.WriteString(baseStyle.Render(fmt.Sprintf("Song: %s  (entry %d/%d, pass %d/%d)", synth.FormatSong(song), entry+1, len(song), repeat+1, song[entry].Repeats)) + "\n\n")
+	default:
+		s.WriteString(baseStyle.Render("Song: "+synth.FormatSong(song)) + "\n\n")
+	}
+
+	for row := 0; row < pattern.Length; row += stepsPerRow {
+		end := row + stepsPerRow
+		if end > pattern.Length {
+			end = pattern.Length
+		}
+
+		var numbers, notes, velocities, gates, nudges, shots, drums, marks strings.Builder
+		for i := row; i < end; i++ {
+			step := pattern.Steps[i]
+			numbers.WriteString(fmt.Sprintf("%-5d", i+1))
+			if step.Active {
+				notes.WriteString(fmt.Sprintf("%-5s", noteName(step.Note)))
+			} else {
+				notes.WriteString("--   ")
+			}
+			velocities.WriteString(fmt.Sprintf("%-5s", string(getWaveformChar(float64(step.Velocity)/127))))
+			gates.WriteString(fmt.Sprintf("%-5s", fmt.Sprintf("%.0f%%", step.Gate*100)))
+			nudges.WriteString(fmt.Sprintf("%-5s", nudgeText(step)))
+			if step.Shot > 0 {
+				shots.WriteString(fmt.Sprintf("%-5d", step.Shot))
+			} else {
+				shots.WriteString("--   ")
+			}
+			drums.WriteString(fmt.Sprintf("%-5s", drumLetters(step)))
+			switch {
+			case i == m.cursor && i == current:
+				marks.WriteString("^*   ")
+			case i == m.cursor:
+				marks.WriteString("^    ")
+			case i == current:
+				marks.WriteString("*    ")
+			default:
+				marks.WriteString("     ")
+			}
+		}
+
+		s.WriteString(baseStyle.Render("Step  "+numbers.String()) + "\n")
+		s.WriteString(baseStyle.Render("Note  ") + activeStyle.Render(notes.String()) + "\n")
+		s.WriteString(baseStyle.Render("Vel   "+velocities.String()) + "\n")
+		s.WriteString(baseStyle.Render("Gate  "+gates.String()) + "\n")
+		s.WriteString(baseStyle.Render("Nudge "+nudges.String()) + "\n")
+		s.WriteString(baseStyle.Render("Shot  ") + activeStyle.Render(shots.String()) + "\n")
+		s.WriteString(baseStyle.Render("Drum  ") + activeStyle.Render(drums.String()) + "\n")
+		s.WriteString(baseStyle.Render("      ") + playheadStyle.Render(marks.String()) + "\n\n")
+	}
+
+	step := pattern.Steps[m.cursor]
+	s.WriteString(selectedStyle.Render(fmt.Sprintf("> Step %d: %s, velocity %d, gate %.0f%%, nudge %+d/%d ticks, %s, %s, %s",
+		m.cursor+1, noteName(step.Note), step.Velocity, step.Gate*100, step.Nudge, synth.TicksPerStep, onOff(step.Active), m.shotName(step.Shot), drumNames(step))) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("Euclid: %s, %d hits over %d steps, rotated %d",
+		euclidTrackName(m.euclid.track), m.euclid.hits, pattern.Length, m.euclid.rotate)) + "\n")
+
+	return s.String()
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"gosynth/pkg/engine"
+)
+
+const (
+	spectrumHeight  = 16      // Rows of the spectrum display
+	spectrumLowest  = 20.0    // Frequency at the left edge in Hz
+	spectrumHighest = 20000.0 // Frequency at the right edge in Hz
+)
+
+// spectrumBlocks are the partial cells at the top of a bar, in eighths
+var spectrumBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// spectrumItems are the rows of the spectrum page
+var spectrumItems = []menuItem{
+	{
+		label: "Spectrum Floor",
+		value: func(m Model) string { return fmt.Sprintf("%.0f dB", m.spectrumFloor) },
+		adjust: func(m *Model, dir float64) {
+			m.spectrumFloor = math.Max(engine.SpectrumFloor, math.Min(-24, m.spectrumFloor+dir*6))
+		},
+	},
+	{
+		label: "Input Overlay",
+		value: func(m Model) string {
+			if m.synth.Input.Channels() == 0 {
+				return onOff(m.spectrumInput) + " (no audio input)"
+			}
+			return onOff(m.spectrumInput)
+		},
+		adjust: func(m *Model, dir float64) {
+			m.spectrumInput = !m.spectrumInput
+		},
+	},
+	{
+		label: "Spectrum Hold",
+		value: func(m Model) string { return onOff(m.spectrumHold) },
+		adjust: func(m *Model, dir float64) {
+			m.spectrumHold = !m.spectrumHold
+		},
+	},
+}
+
+// spectrumColumn returns the highest level of the FFT bins under a display column,
+// whose frequencies are spaced logarithmically
+func spectrumColumn(levels []float64, column int) float64 {
+	span := spectrumHighest / spectrumLowest
+	low := spectrumLowest * math.Pow(span, float64(column)/waveformWidth)
+	high := spectrumLowest * math.Pow(span, float64(column+1)/waveformWidth)
+	binWidth := float64(engine.SampleRate) / engine.SpectrumSize
+	first := clamp(int(math.Round(low/binWidth)), 0, len(levels)-1)
+	last := clamp(int(math.Round(high/binWidth)), first, len(levels)-1)
+	level := levels[first]
+	for _, l := range levels[first : last+1] {
+		level = math.Max(level, l)
+	}
+	return level
+}
+
+// frequencyAxis labels the decades along the log frequency axis of a display as wide as
+// the waveform, inside its border
+func frequencyAxis() string {
+	axis := []rune(strings.Repeat(" ", waveformWidth+2))
+	for _, f := range []float64{20, 50, 100, 200, 500, 1000, 2000, 5000, 10000, 20000} {
+		label := fmt.Sprintf("%.0f", f)
+		if f >= 1000 {
+			label = fmt.Sprintf("%.0fk", f/1000)
+		}
+		x := 1 + int(math.Log(f/spectrumLowest)/math.Log(spectrumHighest/spectrumLowest)*waveformWidth) // Past the border
+		x = clamp(x, 0, len(axis)-len(label))
+		copy(axis[x:], []rune(label))
+	}
+	return string(axis) + " Hz"
+}
+
+// spectrumHeights scales the levels of FFT bins to the rows of each display column,
+// from the floor up to full scale
+func (m Model) spectrumHeights(levels []float64) []float64 {
+	heights := make([]float64, waveformWidth)
+	if len(levels) > 0 {
+		for x := range heights {
+			level := spectrumColumn(levels, x)
+			heights[x] = math.Max(0, math.Min(1, (level-m.spectrumFloor)/-m.spectrumFloor)) * spectrumHeight
+		}
+	}
+	return heights
+}
+
+// drawSpectrum renders the output's frequency content as bars over a log frequency
+// axis, scaled in dB from the floor up to full scale, with the audio input's traced
+// over it as a line for matching a patch to the material played along with
+func (m Model) drawSpectrum(baseStyle lipgloss.Style) string {
+	heights := m.spectrumHeights(m.spectrum)
+	var input []float64
+	if m.inputSpectrum != nil {
+		input = m.spectrumHeights(m.inputSpectrum)
+	}
+	inputStyle := spaceStyle.Foreground(lipgloss.Color("#00ffff"))
+
+	var result strings.Builder
+	border := borderStyle.Foreground(lipgloss.Color("#004400"))
+	result.WriteString(border.Render("╔"+strings.Repeat("═", waveformWidth)+"╗") + "\n")
+	for row := spectrumHeight - 1; row >= 0; row-- {
+		result.WriteString(border.Render("║"))
+		for x, height := range heights {
+			if input != nil && input[x] > 0 && min(int(input[x]), spectrumHeight-1) == row {
+				result.WriteString(inputStyle.Render("─"))
+				continue
+			}
+			fill := clamp(int((height-float64(row))*8), 0, 8)
+			if fill == 0 {
+				result.WriteString(spaceStyle.Render(" "))
+				continue
+			}
+			color := meterColor(float64(row+1) / spectrumHeight)
+			result.WriteString(spaceStyle.Foreground(lipgloss.Color(color)).Render(string(spectrumBlocks[fill])))
+		}
+		result.WriteString(border.Render(fmt.Sprintf("║ %.0f", m.spectrumFloor*(1-float64(row+1)/spectrumHeight))) + "\n")
+	}
+	result.WriteString(border.Render("╚"+strings.Repeat("═", waveformWidth)+"╝") + "\n")
+	result.WriteString(baseStyle.Render(frequencyAxis()) + "\n")
+	if input != nil {
+		legend := "─ audio input"
+		if note, cents, ok := nearestNote(m.pitch.Freq, m.synth.Tuning.Reference()); ok && m.pitchHeld > 0 {
+			legend += fmt.Sprintf(", playing %s %+.0f cents (%.1f Hz)", noteName(note), cents, m.pitch.Freq)
+		}
+		result.WriteString(inputStyle.Render(legend) + "\n")
+	}
+	return result.String()
+}
@@ -0,0 +1,33 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const statsTopPresets = 5 // Presets listed on the stats page
+
+// renderStats draws the practice timer of this session and the totals of every session
+func (m Model) renderStats(baseStyle lipgloss.Style) string {
+	session, total := m.synth.Stats.Session(), m.synth.Stats.Total()
+
+	var s strings.Builder
+	s.WriteString(baseStyle.Render(fmt.Sprintf("Practice timer: %s played this session, %d notes",
+		session.Played.Round(time.Second), session.Notes)) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("All sessions: %s played over %d sessions, %d notes",
+		total.Played.Round(time.Second), total.Sessions, total.Notes)) + "\n\n")
+
+	top := total.TopPresets(statsTopPresets)
+	if len(top) == 0 {
+		s.WriteString(baseStyle.Render("Most played presets: none yet, play some notes") + "\n")
+		return s.String()
+	}
+	s.WriteString(baseStyle.Render("Most played presets:") + "\n")
+	for i, preset := range top {
+		s.WriteString(baseStyle.Render(fmt.Sprintf("  %d. %-20s %s", i+1, preset.Name, preset.Played.Round(time.Second))) + "\n")
+	}
+	return s.String()
+}
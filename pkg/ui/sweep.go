@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gosynth/pkg/engine"
+)
+
+// sweepItems returns the sweep rows of the modulation page: the loop mode and point
+// count, then position, level and curve for each point. The first point always starts
+// the cycle, so it has no position row.
+func (m Model) sweepItems() []menuItem {
+	items := []menuItem{
+		{
+			label: "Sweep Loop",
+			value: func(m Model) string { return m.synth.Sweep.Loop().String() },
+			adjust: func(m *Model, dir float64) {
+				m.synth.Sweep.SetLoop(m.synth.Sweep.Loop().Next(sign(dir)))
+			},
+		},
+		{
+			label: "Sweep Points",
+			value: func(m Model) string { return fmt.Sprintf("%d", len(m.synth.Sweep.Points())) },
+			adjust: func(m *Model, dir float64) {
+				if dir > 0 {
+					m.synth.Sweep.AddPoint()
+				} else {
+					m.synth.Sweep.RemovePoint()
+				}
+			},
+		},
+	}
+	for i := range m.synth.Sweep.Points() {
+		i := i
+		name := fmt.Sprintf("Point %d", i+1)
+		if i > 0 {
+			items = append(items, menuItem{
+				label: name + " Position",
+				value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.sweepPoint(i).Pos*100) },
+				adjust: func(m *Model, dir float64) {
+					m.synth.Sweep.EditPoint(i, func(p *engine.SweepPoint) { p.Pos = math.Round((p.Pos+dir*0.05)*100) / 100 })
+				},
+			})
+		}
+		items = append(items,
+			menuItem{
+				label: name + " Level",
+				value: func(m Model) string {
+					level := m.sweepPoint(i).Level
+					freq := m.synth.MinModFreq.Get() + (m.synth.MaxModFreq.Get()-m.synth.MinModFreq.Get())*level
+					return fmt.Sprintf("%.0f%% (%.1f Hz)", level*100, freq)
+				},
+				adjust: func(m *Model, dir float64) {
+					m.synth.Sweep.EditPoint(i, func(p *engine.SweepPoint) { p.Level = math.Round((p.Level+dir*0.05)*100) / 100 })
+				},
+			},
+			menuItem{
+				label: name + " Curve",
+				value: func(m Model) string { return m.sweepPoint(i).Curve.String() },
+				adjust: func(m *Model, dir float64) {
+					m.synth.Sweep.EditPoint(i, func(p *engine.SweepPoint) { p.Curve = p.Curve.Next(sign(dir)) })
+				},
+			},
+		)
+	}
+	return items
+}
+
+// sweepPoint returns a sweep breakpoint, or a zero point if it was just removed
+func (m Model) sweepPoint(i int) engine.SweepPoint {
+	points := m.synth.Sweep.Points()
+	if i >= len(points) {
+		return engine.SweepPoint{}
+	}
+	return points[i]
+}
+
+// renderSweep draws one cycle of the sweep as a sparkline, with its current level
+func (m Model) renderSweep() string {
+	var line strings.Builder
+	for x := 0; x < waveformWidth; x++ {
+		level := m.synth.Sweep.LevelAt((float64(x) + 0.5) / waveformWidth)
+		line.WriteRune(spectrumBlocks[1+int(math.Round(level*7))])
+	}
+	return fmt.Sprintf("Sweep: %s %.0f%%", line.String(), m.synth.Modulation().Sweep*100)
+}
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	tunerWidth   = 41 // Cells of the tuning needle's scale, from -50 to +50 cents
+	tunerInTune  = 5  // Cents either side of a note the needle shows as in tune
+	tunerHoldFor = 15 // Frames a detected note stays shown after the input stops having a pitch
+)
+
+// updateTuner detects the pitch of the audio input for the tuner page, keeping the last
+// note shown for a moment after it stops so a decaying string doesn't flicker
+func (m *Model) updateTuner() {
+	if pitch, ok := m.synth.InputPitch(); ok {
+		m.pitch, m.pitchHeld = pitch, tunerHoldFor
+	} else if m.pitchHeld > 0 {
+		m.pitchHeld--
+	}
+}
+
+// renderTuner draws the note nearest the input's pitch and a needle on a scale of cents
+// either side of it, tuned from the reference pitch
+func (m Model) renderTuner(baseStyle lipgloss.Style) string {
+	reference := m.synth.Tuning.Reference()
+	var s strings.Builder
+	s.WriteString(baseStyle.Render(fmt.Sprintf("Reference: A4 = %.1f Hz", reference)) + "\n\n")
+	if m.synth.Input.Channels() == 0 {
+		s.WriteString(baseStyle.Render("No audio input: set audio_input in the config file, or start with -audio-input default") + "\n")
+		return s.String()
+	}
+	note, cents, ok := nearestNote(m.pitch.Freq, reference)
+	if m.pitchHeld == 0 || !ok {
+		s.WriteString(baseStyle.Render("Listening: play a single note") + "\n\n")
+		s.WriteString(baseStyle.Faint(true).Render(" "+strings.Repeat("·", tunerWidth)) + "\n")
+		return s.String()
+	}
+
+	style := baseStyle.Foreground(lipgloss.Color("#ffaa00"))
+	if math.Abs(cents) <= tunerInTune {
+		style = baseStyle.Foreground(lipgloss.Color("#00ff00"))
+	}
+	s.WriteString(style.Render(fmt.Sprintf("%-4s %+5.1f cents", noteName(note), cents)))
+	s.WriteString(baseStyle.Render(fmt.Sprintf("   %.2f Hz, clarity %.0f%%", m.pitch.Freq, m.pitch.Clarity*100)) + "\n\n")
+
+	// The needle sits on a scale from flat on the left to sharp on the right
+	scale := []rune(strings.Repeat("·", tunerWidth))
+	scale[tunerWidth/2] = '|'
+	needle := clamp(int(math.Round((cents+50)/100*(tunerWidth-1))), 0, tunerWidth-1)
+	scale[needle] = '▼'
+	s.WriteString(" " + style.Render(string(scale)) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("%-*s%s", tunerWidth/2+1, " -50", "0")+fmt.Sprintf("%*s", tunerWidth/2, "+50")) + "\n")
+	return s.String()
+}
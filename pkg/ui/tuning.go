@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"fmt"
+
+	"gosynth/pkg/engine"
+)
+
+// tuningItems are the microtuning rows of the oscillators page
+var tuningItems = []menuItem{
+	{
+		label: "Scale",
+		value: func(m Model) string {
+			if scale := m.synth.Tuning.Scale(); scale != "" {
+				return scale
+			}
+			return "equal temperament"
+		},
+		adjust: func(m *Model, dir float64) {
+			m.loadAdjacentTuning(sign(dir), false)
+		},
+	},
+	{
+		label: "Keyboard Map",
+		value: func(m Model) string {
+			if mapping := m.synth.Tuning.Mapping(); mapping != "" {
+				return mapping
+			}
+			return "default"
+		},
+		adjust: func(m *Model, dir float64) {
+			if m.synth.Tuning.Scale() == "" {
+				m.status = "Choose a scale before a keyboard mapping"
+				return
+			}
+			m.loadAdjacentTuning(sign(dir), true)
+		},
+	},
+}
+
+// loadAdjacentTuning steps through the default and the Scala scales, or keyboard mappings
+// when mapping is set, in the tunings directory
+func (m *Model) loadAdjacentTuning(dir int, mapping bool) {
+	list, kind, current := engine.ListScales, "scales", m.synth.Tuning.Scale()
+	if mapping {
+		list, kind, current = engine.ListMappings, "keyboard mappings", m.synth.Tuning.Mapping()
+	}
+	names, err := list()
+	if err != nil {
+		m.status = fmt.Sprintf("Listing %s failed: %v", kind, err)
+		return
+	}
+	if len(names) == 0 {
+		tuningDir, _ := engine.TuningDir()
+		m.status = fmt.Sprintf("No %s in %s", kind, tuningDir)
+		return
+	}
+
+	// Index 0 is the default, followed by the files
+	idx := 0
+	for i, name := range names {
+		if name == current {
+			idx = i + 1
+		}
+	}
+	idx = (idx + dir + len(names) + 1) % (len(names) + 1)
+	st := m.synth.Tuning.State()
+	name := ""
+	if idx > 0 {
+		name = names[idx-1]
+	}
+	if mapping {
+		st.Mapping = name
+	} else {
+		st.Scale = name
+		if name == "" {
+			st.Mapping = ""
+		}
+	}
+	if err := m.synth.Tuning.SetState(st); err != nil {
+		m.status = fmt.Sprintf("Loading %s failed: %v", name, err)
+		return
+	}
+	switch {
+	case st.Scale == "":
+		m.status = "Equal temperament"
+	case name == "":
+		m.status = "Default keyboard mapping"
+	default:
+		m.status = "Loaded " + name
+	}
+}
@@ -1,11 +1,13 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strings"
 	"time"
 
+	"gosynth/pkg/engine"
 	"gosynth/pkg/synth"
 
 	"github.com/charmbracelet/bubbles/spinner"
@@ -14,8 +16,20 @@ import (
 )
 
 const (
-	waveformWidth  = 100 // Width of the waveform display
-	waveformHeight = 20  // Height of the waveform display
+	waveformWidth    = 100         // Width of the waveform display
+	waveformHeight   = 20          // Height of the waveform display
+	editCoalesceTime = time.Second // Repeated edits of one row within this time are one undo step
+	scopeFrames      = 882         // Output frames across the oscilloscope, 20 ms
+)
+
+const (
+	fineStep   = 0.1 // Adjustment scale with shift held
+	coarseStep = 12  // Adjustment scale with alt held: an octave on frequency rows
+)
+
+const (
+	sleepStep = 5 * time.Minute // Sleep timer adjustment per arrow press
+	maxSleep  = 3 * time.Hour   // Longest sleep timer
 )
 
 // Model represents the application UI state
@@ -23,10 +37,52 @@ type Model struct {
 	spinner  spinner.Model
 	synth    *synth.Synth
 	realTime bool
-	selected int
-	buffer   string    // Add buffer for double buffering
-	lastDraw time.Time // Track last draw time
-	ready    bool      // Track if the model is ready for input
+	selected [pageCount]int // Selected row of each parameter page
+	buffer   string         // Add buffer for double buffering
+	lastDraw time.Time      // Track last draw time
+	ready    bool           // Track if the model is ready for input
+
+	piano     bool          // Computer-keyboard piano mode
+	octave    int           // Octave of the lowest piano key
+	pianoHeld map[uint8]int // Notes held from the keyboard, by latest press
+	pianoSeq  int           // Press counter used to match release timers
+
+	page   int    // Page currently shown
+	cursor int    // Step under the sequencer cursor
+	euclid euclid // Euclidean rhythm generator settings
+	status string // Result of the last preset action
+
+	entering bool   // Typing a value into the selected row
+	entry    string // Text typed so far
+
+	lastEdit     string    // Target of the last recorded edit, for coalescing undo steps
+	lastEditTime time.Time // When that edit was made
+
+	scopeTrace     []float32 // Output samples shown on the oscilloscope
+	scopeTrigger   bool      // Start the trace on a rising zero crossing
+	scopeTriggered bool      // Whether the shown trace found its trigger
+	scopeHold      bool      // Freeze the trace
+
+	spectrum      []float64 // Output level per FFT bin shown on the spectrum page
+	inputSpectrum []float64 // Audio input level per FFT bin overlaid on it, nil without an input
+	spectrumFloor float64   // Level at the bottom of the spectrum display, in dB
+	spectrumHold  bool      // Freeze the spectrum
+	spectrumInput bool      // Overlay the audio input's spectrum
+
+	pitch     engine.Pitch // Pitch of the audio input shown on the tuner page
+	pitchHeld int          // Frames the pitch stays shown, 0 once it has gone
+
+	events   <-chan engine.Event // Subscription to the engine's bus, for the life of the program
+	meter    engine.Analysis     // Levels of the latest output block
+	clipped  bool                // Latched when the output clips, until the volume changes
+	sounding map[uint8]bool      // Notes started and not yet released
+
+	width  int // Terminal size, for session recordings
+	height int
+	cast   *castRecorder // Session recording in progress, if any
+
+	theme theme       // Menu colours
+	keys  keyBindings // Global actions moved to other keys
 }
 
 // NewModel creates a new UI model
@@ -35,14 +91,39 @@ func NewModel(s *synth.Synth) Model {
 	sp.Spinner = spinner.Dot
 	sp.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 
-	return Model{
+	events, _ := s.Bus.Subscribe()
+	m := Model{
 		spinner:  sp,
 		synth:    s,
 		realTime: false,
-		selected: 0,
 		lastDraw: time.Now(),
 		ready:    false,
+
+		octave:    4,
+		pianoHeld: make(map[uint8]int),
+
+		scopeTrigger:  true,
+		spectrumFloor: -72,
+		spectrumInput: true,
+
+		events:   events,
+		sounding: make(map[uint8]bool),
+	}
+	m.status = m.useConfig()
+	return m
+}
+
+// Page returns the page shown, for saving the session
+func (m Model) Page() int {
+	return m.page
+}
+
+// WithPage returns the model showing a page, such as the one a restored session was on
+func (m Model) WithPage(page int) Model {
+	if page >= 0 && page < pageCount {
+		m.page = page
 	}
+	return m
 }
 
 // Init initializes the application
@@ -50,9 +131,12 @@ func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		m.spinner.Tick,
 		tea.EnterAltScreen,
+		waitEvent(m.events),
 		tea.Every(time.Second/30, func(time.Time) tea.Msg {
 			return frameMsg{}
 		}),
+		checkConfig(),
+		scanMIDI(),
 	)
 }
 
@@ -65,13 +149,41 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		// Mark the model as ready when we receive the first window size event
 		m.ready = true
+		m.width, m.height = msg.Width, msg.Height
 		return m, nil
 
 	case frameMsg:
+		// Once the sleep timer has faded to silence, end the session as if q was pressed
+		if m.synth.Asleep() {
+			m.stopRecording()
+			return m, tea.Sequence(
+				tea.ExitAltScreen,
+				tea.Quit,
+			)
+		}
+
 		// Only update if enough time has passed or if we're in real-time mode
 		if m.realTime || time.Since(m.lastDraw) > time.Second/30 {
 			m.lastDraw = time.Now()
+			if !m.scopeHold {
+				m.scopeTrace, m.scopeTriggered = m.synth.ScopeTrace(scopeFrames, m.scopeTrigger)
+			}
+			if m.page == pageSpectrum && !m.spectrumHold {
+				m.spectrum = m.synth.Spectrum()
+				m.inputSpectrum = nil
+				if m.spectrumInput {
+					m.inputSpectrum = m.synth.InputSpectrum()
+				}
+			}
+			if m.page == pageTuner || m.page == pageSpectrum && m.spectrumInput {
+				m.updateTuner()
+			}
 			m.buffer = m.render() // Pre-render the frame
+			if m.cast != nil {
+				if err := m.cast.frame(m.buffer); err != nil {
+					m.status = fmt.Sprintf("Recording frame failed: %v", err)
+				}
+			}
 			return m, tea.Batch(
 				m.spinner.Tick,
 				tea.Every(time.Second/30, func(time.Time) tea.Msg {
@@ -81,65 +193,123 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case busMsg:
+		m.handleEvent(engine.Event(msg))
+		return m, waitEvent(m.events)
+
+	case configMsg:
+		m.reloadConfig()
+		return m, checkConfig()
+
+	case midiScanMsg:
+		m.rescanMIDI()
+		return m, scanMIDI()
+
+	case pianoReleaseMsg:
+		m.handlePianoRelease(msg)
+		return m, nil
+
 	case tea.KeyMsg:
 		// Only handle keyboard input if the model is ready
 		if !m.ready {
 			return m, nil
 		}
 
-		switch msg.String() {
+		// While a value is typed, keys edit the text
+		if m.entering {
+			m.handleEntryKey(msg)
+			m.buffer = "" // Clear buffer to force redraw
+			return m, nil
+		}
+
+		// In piano mode the letter rows play notes
+		if m.piano {
+			if cmd, handled := m.handlePianoKey(msg.String()); handled {
+				m.buffer = "" // Clear buffer to force redraw
+				return m, cmd
+			}
+		}
+
+		// Pages may take keys of their own, such as the sequencer's grid editing
+		if keys := pages[m.page].keys; keys != nil && keys(&m, msg.String()) {
+			m.buffer = "" // Clear buffer to force redraw
+			return m, nil
+		}
+
+		key := m.keys.resolve(msg.String())
+		switch key {
+		case "tab":
+			m.page = (m.page + 1) % pageCount
+			m.buffer = "" // Clear buffer to force redraw
+		case "shift+tab":
+			m.page = (m.page + pageCount - 1) % pageCount
+			m.buffer = "" // Clear buffer to force redraw
+		case "ctrl+s":
+			m.savePreset(m.synth.PresetName())
+			m.buffer = "" // Clear buffer to force redraw
+		case "ctrl+n":
+			m.savePreset(nextPresetName())
+			m.buffer = "" // Clear buffer to force redraw
+		case "ctrl+p":
+			m.toggleLock()
+			m.buffer = "" // Clear buffer to force redraw
+		case "ctrl+k":
+			m.piano = !m.piano
+			if !m.piano {
+				m.releasePianoNotes()
+			}
+			m.buffer = "" // Clear buffer to force redraw
+		case "f1", "f2", "f3":
+			kill := map[string]engine.Kill{"f1": engine.KillDelay, "f2": engine.KillReverb, "f3": engine.KillParts}[key]
+			m.synth.SetKill(kill, !m.synth.Killed(kill))
+			m.buffer = "" // Clear buffer to force redraw
+		case "f4":
+			m.toggleAudition()
+			m.buffer = "" // Clear buffer to force redraw
+		case "f5":
+			m.synth.Panic()
+			m.status = "Panic: every note released and the controllers reset"
+			m.buffer = "" // Clear buffer to force redraw
+		case "ctrl+z":
+			m.undo()
+			m.buffer = "" // Clear buffer to force redraw
+		case "ctrl+y":
+			m.redo()
+			m.buffer = "" // Clear buffer to force redraw
+		case "ctrl+l":
+			m.synth.SetLatch(!m.synth.Latch.Enabled())
+			m.buffer = "" // Clear buffer to force redraw
+		case "ctrl+r":
+			m.toggleRecording()
+			m.buffer = "" // Clear buffer to force redraw
 		case "ctrl+c", "q":
+			m.stopRecording()
 			return m, tea.Sequence(
 				tea.ExitAltScreen,
 				tea.Quit,
 			)
+		case "enter":
+			m.startEntry()
+			m.buffer = "" // Clear buffer to force redraw
 		case "up":
-			if m.selected > 0 {
-				m.selected--
+			if _, selected := m.pageItems(); *selected > 0 {
+				*selected--
 				m.buffer = "" // Clear buffer to force redraw
 			}
 		case "down":
-			if m.selected < 6 {
-				m.selected++
+			if items, selected := m.pageItems(); *selected < len(items)-1 {
+				*selected++
 				m.buffer = "" // Clear buffer to force redraw
 			}
-		case "left", "right":
+		case "left", "right", "shift+left", "shift+right", "alt+left", "alt+right":
 			m.buffer = "" // Clear buffer to force redraw
-			if m.selected == 6 {
-				m.realTime = !m.realTime
-			} else {
-				switch msg.String() {
-				case "left":
-					switch m.selected {
-					case 0:
-						m.synth.CarrierFreq.Set(math.Max(20, m.synth.CarrierFreq.Get()-10))
-					case 1:
-						m.synth.MinModFreq.Set(math.Max(20, m.synth.MinModFreq.Get()-10))
-					case 2:
-						m.synth.MaxModFreq.Set(math.Max(m.synth.MinModFreq.Get()+10, m.synth.MaxModFreq.Get()-10))
-					case 3:
-						m.synth.SweepTime.Set(math.Max(0.01, m.synth.SweepTime.Get()-0.01))
-					case 4:
-						m.synth.ModIndex.Set(math.Max(0, m.synth.ModIndex.Get()-0.05))
-					case 5:
-						m.synth.Volume.Set(math.Max(0, m.synth.Volume.Get()-0.05))
-					}
-				case "right":
-					switch m.selected {
-					case 0:
-						m.synth.CarrierFreq.Set(math.Min(2000, m.synth.CarrierFreq.Get()+10))
-					case 1:
-						m.synth.MinModFreq.Set(math.Min(m.synth.MaxModFreq.Get()-10, m.synth.MinModFreq.Get()+10))
-					case 2:
-						m.synth.MaxModFreq.Set(math.Min(2000, m.synth.MaxModFreq.Get()+10))
-					case 3:
-						m.synth.SweepTime.Set(math.Min(1.0, m.synth.SweepTime.Get()+0.01))
-					case 4:
-						m.synth.ModIndex.Set(math.Min(1.0, m.synth.ModIndex.Get()+0.05))
-					case 5:
-						m.synth.Volume.Set(math.Min(1.0, m.synth.Volume.Get()+0.05))
-					}
-				}
+			if items, selected := m.pageItems(); len(items) > 0 {
+				m.recordEdit(items[*selected].label)
+				items[*selected].adjust(&m, map[string]float64{
+					"left": -1, "right": 1,
+					"shift+left": -fineStep, "shift+right": fineStep,
+					"alt+left": -coarseStep, "alt+right": coarseStep,
+				}[key])
 			}
 		}
 	}
@@ -149,6 +319,1143 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// recordEdit snapshots the engine for undo before an edit, treating repeated
+// edits of the same target in quick succession as one step
+func (m *Model) recordEdit(target string) {
+	if target == m.lastEdit && time.Since(m.lastEditTime) < editCoalesceTime {
+		m.lastEditTime = time.Now()
+		return
+	}
+	m.lastEdit = target
+	m.lastEditTime = time.Now()
+	if err := m.synth.Snapshot(); err != nil {
+		m.status = fmt.Sprintf("Saving history failed: %v", err)
+	}
+}
+
+// undo reverts the last edit or preset load
+func (m *Model) undo() {
+	m.lastEdit = ""
+	ok, err := m.synth.Undo()
+	switch {
+	case err != nil:
+		m.status = fmt.Sprintf("Saving history failed: %v", err)
+	case !ok:
+		m.status = "Nothing to undo"
+	default:
+		m.status = "Undone"
+	}
+}
+
+// redo reapplies the last undone edit or preset load
+func (m *Model) redo() {
+	m.lastEdit = ""
+	ok, err := m.synth.Redo()
+	switch {
+	case err != nil:
+		m.status = fmt.Sprintf("Saving history failed: %v", err)
+	case !ok:
+		m.status = "Nothing to redo"
+	default:
+		m.status = "Redone"
+	}
+}
+
+// savePreset stores the current patch and pattern under a name and reports the result
+func (m *Model) savePreset(name string) {
+	if err := m.synth.SavePreset(name); err != nil {
+		m.status = fmt.Sprintf("Saving preset %s failed: %v", name, err)
+		return
+	}
+	m.status = fmt.Sprintf("Saved preset %s", name)
+}
+
+// panName shows a pan position as L or R and a percentage, or C for the centre
+func panName(pan float64) string {
+	switch {
+	case pan < -0.005:
+		return fmt.Sprintf("L%.0f", -pan*100)
+	case pan > 0.005:
+		return fmt.Sprintf("R%.0f", pan*100)
+	}
+	return "C"
+}
+
+// adjacentPreset returns the saved preset before or after the named one, or sets the
+// status and returns false when there is none
+func (m *Model) adjacentPreset(current string, dir int) (string, bool) {
+	names, err := synth.ListPresets()
+	if err != nil {
+		m.status = fmt.Sprintf("Listing presets failed: %v", err)
+		return "", false
+	}
+	if len(names) == 0 {
+		m.status = "No saved presets (ctrl+s to save)"
+		return "", false
+	}
+	idx := -1
+	for i, name := range names {
+		if name == current {
+			idx = i
+		}
+	}
+	if idx == -1 && dir < 0 {
+		idx = 0
+	}
+	return names[(idx+dir+len(names))%len(names)], true
+}
+
+// loadAdjacentPreset loads the saved preset before or after the current one
+func (m *Model) loadAdjacentPreset(dir int) {
+	name, ok := m.adjacentPreset(m.synth.PresetName(), dir)
+	if !ok {
+		return
+	}
+	err := m.synth.LoadPreset(name)
+	var issues synth.PresetIssues
+	if err != nil && !errors.As(err, &issues) {
+		m.status = fmt.Sprintf("Loading preset %s failed: %v", name, err)
+		return
+	}
+	m.lastEdit = "" // Every preset load is its own undo step
+	m.status = fmt.Sprintf("Loaded preset %s", name)
+	if len(issues) > 0 {
+		m.status += presetIssuesStatus(issues)
+	}
+}
+
+// maxStatusIssues is how many preset problems the status lists before summing up the rest
+const maxStatusIssues = 4
+
+// presetIssuesStatus lists the problems of a loaded preset, one per line
+func presetIssuesStatus(issues synth.PresetIssues) string {
+	var b strings.Builder
+	if len(issues) == 1 {
+		b.WriteString(" with a problem:")
+	} else {
+		fmt.Fprintf(&b, " with %d problems:", len(issues))
+	}
+	for i, issue := range issues {
+		if i == maxStatusIssues {
+			fmt.Fprintf(&b, "\n  and %d more", len(issues)-i)
+			break
+		}
+		b.WriteString("\n  " + issue.String())
+	}
+	return b.String()
+}
+
+// toggleLock locks or unlocks the parameter of the selected row against preset loads
+func (m *Model) toggleLock() {
+	items, selected := m.pageItems()
+	if len(items) == 0 || items[*selected].param == "" {
+		m.status = "This row can't be locked"
+		return
+	}
+	item := items[*selected]
+	locked := !m.synth.Locks.Locked(item.param)
+	if err := m.synth.Locks.SetLocked(item.param, locked); err != nil {
+		m.status = fmt.Sprintf("Saving locks failed: %v", err)
+		return
+	}
+	if locked {
+		m.status = item.label + " is locked; preset loads keep its value"
+	} else {
+		m.status = item.label + " is unlocked"
+	}
+}
+
+// loadAdjacentSoundFont steps through "off" and the SoundFonts in the soundfonts directory
+func (m *Model) loadAdjacentSoundFont(dir int) {
+	names, err := engine.ListSoundFonts()
+	if err != nil {
+		m.status = fmt.Sprintf("Listing SoundFonts failed: %v", err)
+		return
+	}
+	if len(names) == 0 {
+		sfDir, _ := engine.SoundFontDir()
+		m.status = fmt.Sprintf("No SoundFonts in %s", sfDir)
+		return
+	}
+
+	// Index 0 is "off", followed by the font files
+	idx := 0
+	for i, name := range names {
+		if name == m.synth.Sampler.Name() {
+			idx = i + 1
+		}
+	}
+	idx = (idx + dir + len(names) + 1) % (len(names) + 1)
+	if idx == 0 {
+		m.synth.Sampler.Unload()
+		m.status = "SoundFont off"
+		return
+	}
+	name := names[idx-1]
+	if err := m.synth.Sampler.Load(name); err != nil {
+		m.status = fmt.Sprintf("Loading SoundFont %s failed: %v", name, err)
+		return
+	}
+	m.status = fmt.Sprintf("Loaded SoundFont %s (%d presets)", name, m.synth.Sampler.PresetCount())
+}
+
+// nextPresetName returns the first unused user-N preset name
+func nextPresetName() string {
+	names, _ := synth.ListPresets()
+	used := make(map[string]bool)
+	for _, name := range names {
+		used[name] = true
+	}
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("user-%d", i)
+		if !used[name] {
+			return name
+		}
+	}
+}
+
+// menuItem is a selectable parameter row in the menu
+type menuItem struct {
+	label  string
+	param  string // Preset parameter the row sets, which can be locked against preset loads
+	value  func(m Model) string
+	adjust func(m *Model, dir float64)        // dir is -1 for left, +1 for right, scaled by fineStep or coarseStep with shift or alt
+	mod    func(m Model) (base, live float64) // Positions 0 to 1 of the set and modulated values, if modulated
+	enter  func(m *Model, text string) error  // Sets the value from typed text, for rows that take it
+}
+
+// steps rounds an adjustment to whole steps for rows that count, moving at least one
+func steps(dir float64) int {
+	return int(math.Copysign(math.Max(1, math.Round(math.Abs(dir))), dir))
+}
+
+// sign turns an adjustment into one step for rows that pick from a list, whatever the modifier
+func sign(dir float64) int {
+	if dir < 0 {
+		return -1
+	}
+	return 1
+}
+
+// semitones moves a frequency by dir semitones within low and high, so a step is
+// the same pitch change at any frequency
+func semitones(freq, dir, low, high float64) float64 {
+	return math.Max(low, math.Min(high, freq*math.Exp2(dir/12)))
+}
+
+// modTrackWidth is the number of cells in a row's modulation track
+const modTrackWidth = 16
+
+// logPosition places a value on a logarithmic scale from low to high, as 0 to 1
+func logPosition(value, low, high float64) float64 {
+	return math.Log(math.Max(value, low)/low) / math.Log(high/low)
+}
+
+// renderModulation draws a track with a bar at the set value and a diamond at the
+// modulated value, which moves as the modulation runs
+func renderModulation(base, live float64) string {
+	track := []rune(strings.Repeat("─", modTrackWidth))
+	cell := func(pos float64) int {
+		return clamp(int(pos*float64(modTrackWidth-1)+0.5), 0, modTrackWidth-1)
+	}
+	track[cell(base)] = '│'
+	track[cell(live)] = '◆'
+	return string(track)
+}
+
+// oscillatorItems are the rows of the oscillators page: presets and random patches, the
+// carrier and modulator, output level and placement, the drone layer and SoundFont playback
+var oscillatorItems = []menuItem{
+	{
+		label: "Preset",
+		value: func(m Model) string { return m.synth.PresetName() },
+		adjust: func(m *Model, dir float64) {
+			m.loadAdjacentPreset(sign(dir))
+		},
+	},
+	{
+		label: "Preset Crossfade",
+		value: func(m Model) string { return fmt.Sprintf("%.1f s", m.synth.PresetFade.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.PresetFade.Set(math.Max(0, math.Min(engine.MaxPresetFade, m.synth.PresetFade.Get()+dir*0.1)))
+		},
+	},
+	{
+		label: "Randomize",
+		value: func(m Model) string { return "←/→ new patch" },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Randomize()
+			m.lastEdit = "" // Every patch is its own undo step
+			m.status = "Random patch; locked parameters kept, ctrl+z to go back"
+		},
+	},
+	{
+		label: "Carrier Frequency",
+		param: "carrierFreq",
+		value: func(m Model) string { return frequencyValue(m.synth.CarrierFreq.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.CarrierFreq.Set(semitones(m.synth.CarrierFreq.Get(), dir, 20, 2000))
+		},
+		enter: func(m *Model, text string) error {
+			freq, err := parseFrequency(text)
+			if err != nil {
+				return err
+			}
+			m.synth.CarrierFreq.Set(math.Max(20, math.Min(2000, freq)))
+			return nil
+		},
+		mod: func(m Model) (float64, float64) {
+			return logPosition(m.synth.CarrierFreq.Get(), 20, 2000), logPosition(m.synth.CarrierFreq.Current(), 20, 2000)
+		},
+	},
+	{
+		label: "Min Modulator Frequency",
+		param: "minModFreq",
+		value: func(m Model) string { return frequencyValue(m.synth.MinModFreq.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.MinModFreq.Set(semitones(m.synth.MinModFreq.Get(), dir, 20, m.synth.MaxModFreq.Get()-10))
+		},
+		enter: func(m *Model, text string) error {
+			freq, err := parseFrequency(text)
+			if err != nil {
+				return err
+			}
+			m.synth.MinModFreq.Set(math.Max(20, math.Min(m.synth.MaxModFreq.Get()-10, freq)))
+			return nil
+		},
+		mod: func(m Model) (float64, float64) {
+			return logPosition(m.synth.MinModFreq.Get(), 20, 2000), logPosition(m.synth.Modulation().ModFreq, 20, 2000)
+		},
+	},
+	{
+		label: "Max Modulator Frequency",
+		param: "maxModFreq",
+		value: func(m Model) string { return frequencyValue(m.synth.MaxModFreq.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.MaxModFreq.Set(semitones(m.synth.MaxModFreq.Get(), dir, m.synth.MinModFreq.Get()+10, 2000))
+		},
+		enter: func(m *Model, text string) error {
+			freq, err := parseFrequency(text)
+			if err != nil {
+				return err
+			}
+			m.synth.MaxModFreq.Set(math.Max(m.synth.MinModFreq.Get()+10, math.Min(2000, freq)))
+			return nil
+		},
+		mod: func(m Model) (float64, float64) {
+			return logPosition(m.synth.MaxModFreq.Get(), 20, 2000), logPosition(m.synth.Modulation().ModFreq, 20, 2000)
+		},
+	},
+	{
+		label: "Sweep Time",
+		param: "sweepTime",
+		value: func(m Model) string { return fmt.Sprintf("%.2f s", m.synth.SweepTime.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.SweepTime.Set(math.Max(0.01, math.Min(1.0, m.synth.SweepTime.Get()+dir*0.01)))
+		},
+	},
+	{
+		label: "Modulation Index",
+		param: "modIndex",
+		value: func(m Model) string { return fmt.Sprintf("%.2f", m.synth.ModIndex.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.ModIndex.Set(math.Max(0, math.Min(1.0, m.synth.ModIndex.Get()+dir*0.05)))
+		},
+		mod: func(m Model) (float64, float64) {
+			// The live marker shows how deep the modulator is swinging right now
+			return m.synth.ModIndex.Get(), m.synth.ModIndex.Current() * math.Abs(m.synth.Modulation().Modulator)
+		},
+	},
+	{
+		label: "Modulator Feedback",
+		param: "modFeedback",
+		value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.ModFeedback.Get()*100) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.ModFeedback.Set(math.Max(0, math.Min(1, math.Round((m.synth.ModFeedback.Get()+dir*0.05)*100)/100)))
+		},
+	},
+	{
+		label: "Volume",
+		param: "volume",
+		value: func(m Model) string { return fmt.Sprintf("%.2f", m.synth.Volume.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Volume.Set(math.Max(0, math.Min(1.0, m.synth.Volume.Get()+dir*0.05)))
+		},
+		mod: func(m Model) (float64, float64) {
+			return m.synth.Volume.Get(), m.synth.Volume.Current()
+		},
+	},
+	{
+		label: "Pan",
+		param: "pan",
+		value: func(m Model) string { return panName(m.synth.Pan.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Pan.Set(math.Max(-1, math.Min(1, m.synth.Pan.Get()+dir*0.05)))
+		},
+		mod: func(m Model) (float64, float64) {
+			return (m.synth.Pan.Get() + 1) / 2, (m.synth.Pan.Current() + 1) / 2
+		},
+	},
+	{
+		label: "Pan Spread",
+		param: "panSpread",
+		value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.PanSpread.Get()*100) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.PanSpread.Set(math.Max(0, math.Min(1, m.synth.PanSpread.Get()+dir*0.05)))
+		},
+	},
+	{
+		label: "Play Mode",
+		value: func(m Model) string {
+			if m.synth.Drone() {
+				return "drone"
+			}
+			if m.synth.SustainDown() {
+				return "voices (sustain)"
+			}
+			return "voices"
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.SetDrone(!m.synth.Drone())
+		},
+	},
+	{
+		label: "Binaural Beat",
+		param: "binauralBeat",
+		value: func(m Model) string {
+			beat := m.synth.Binaural.Get()
+			switch {
+			case beat <= 0:
+				return "off"
+			case !m.synth.Drone():
+				return fmt.Sprintf("%.1f Hz (drone only)", beat)
+			case m.synth.Output().MonoSum:
+				return fmt.Sprintf("%.1f Hz (mono sum on)", beat)
+			}
+			return fmt.Sprintf("%.1f Hz", beat)
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Binaural.Set(math.Max(0, math.Min(engine.MaxBinauralBeat, math.Round((m.synth.Binaural.Get()+dir*0.5)*10)/10)))
+		},
+	},
+	{
+		label: "Sub Level",
+		param: "subLevel",
+		value: func(m Model) string {
+			if level := m.synth.Sub.Level.Get(); level > 0 {
+				return fmt.Sprintf("%.2f", level)
+			}
+			return "off"
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Sub.Level.Set(math.Max(0, math.Min(1, math.Round((m.synth.Sub.Level.Get()+dir*0.05)*100)/100)))
+		},
+	},
+	{
+		label: "Sub Wave",
+		value: func(m Model) string { return m.synth.Sub.Shape().String() },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Sub.SetShape(m.synth.Sub.Shape().Next(sign(dir)))
+		},
+	},
+	{
+		label: "Sub Octave",
+		value: func(m Model) string { return fmt.Sprintf("-%d oct", m.synth.Sub.Octaves()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Sub.SetOctaves(m.synth.Sub.Octaves() + steps(dir))
+		},
+	},
+	{
+		label: "Noise Level",
+		param: "noiseLevel",
+		value: func(m Model) string {
+			if level := m.synth.Noise.Level.Get(); level > 0 {
+				return fmt.Sprintf("%.2f", level)
+			}
+			return "off"
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Noise.Level.Set(math.Max(0, math.Min(1, math.Round((m.synth.Noise.Level.Get()+dir*0.05)*100)/100)))
+		},
+	},
+	{
+		label: "Noise Color",
+		value: func(m Model) string { return m.synth.Noise.Color().String() },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Noise.SetColor(m.synth.Noise.Color().Next(sign(dir)))
+		},
+	},
+	{
+		label: "Noise Envelope",
+		param: "noiseEnvelope",
+		value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.Noise.Envelope.Get()*100) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Noise.Envelope.Set(math.Max(0, math.Min(1, math.Round((m.synth.Noise.Envelope.Get()+dir*0.05)*100)/100)))
+		},
+	},
+	{
+		label: "Noise Decay",
+		param: "noiseDecay",
+		value: func(m Model) string { return fmt.Sprintf("%.0f ms", m.synth.Noise.Decay.Get()*1000) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Noise.Decay.Set(math.Max(0.001, math.Min(engine.MaxNoiseDecay, m.synth.Noise.Decay.Get()+dir*0.005)))
+		},
+	},
+	{
+		label: "Drone Layer",
+		value: func(m Model) string { return onOff(m.synth.DroneLayer.Enabled()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.DroneLayer.SetEnabled(!m.synth.DroneLayer.Enabled())
+		},
+	},
+	{
+		label: "Drone Root",
+		value: func(m Model) string { return noteName(m.synth.DroneLayer.Root()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.DroneLayer.SetRoot(int(m.synth.DroneLayer.Root()) + steps(dir))
+		},
+	},
+	{
+		label: "Drone Chord",
+		value: func(m Model) string { return engine.DroneChords[m.synth.DroneLayer.Chord()].Name },
+		adjust: func(m *Model, dir float64) {
+			count := len(engine.DroneChords)
+			m.synth.DroneLayer.SetChord((m.synth.DroneLayer.Chord() + sign(dir) + count) % count)
+		},
+	},
+	{
+		label: "Drone Wave",
+		value: func(m Model) string { return m.synth.DroneLayer.Wave().String() },
+		adjust: func(m *Model, dir float64) {
+			m.synth.DroneLayer.SetWave(m.synth.DroneLayer.Wave().Next(sign(dir)))
+		},
+	},
+	{
+		label: "Drone Level",
+		param: "droneLevel",
+		value: func(m Model) string { return fmt.Sprintf("%.2f", m.synth.DroneLayer.Level.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.DroneLayer.Level.Set(math.Max(0, math.Min(1, m.synth.DroneLayer.Level.Get()+dir*0.05)))
+		},
+	},
+	{
+		label: "Drone Detune",
+		param: "droneDetune",
+		value: func(m Model) string { return fmt.Sprintf("%.0f cents", m.synth.DroneLayer.Detune.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.DroneLayer.Detune.Set(math.Max(0, math.Min(50, m.synth.DroneLayer.Detune.Get()+dir)))
+		},
+	},
+	{
+		label: "Drone Fade",
+		param: "droneFade",
+		value: func(m Model) string { return fmt.Sprintf("%.1f s", m.synth.DroneLayer.Fade.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.DroneLayer.Fade.Set(math.Max(0, math.Min(10, m.synth.DroneLayer.Fade.Get()+dir*0.5)))
+		},
+	},
+	{
+		label: "SoundFont",
+		value: func(m Model) string {
+			if !m.synth.Sampler.Loaded() {
+				return "off"
+			}
+			return m.synth.Sampler.Name()
+		},
+		adjust: func(m *Model, dir float64) {
+			m.loadAdjacentSoundFont(sign(dir))
+		},
+	},
+	{
+		label: "SF2 Preset",
+		value: func(m Model) string { return m.synth.Sampler.PresetName() },
+		adjust: func(m *Model, dir float64) {
+			sampler := m.synth.Sampler
+			if count := sampler.PresetCount(); count > 0 {
+				sampler.SelectPreset((sampler.Preset() + sign(dir) + count) % count)
+			}
+		},
+	},
+	{
+		label: "Sample Start (velocity)",
+		param: "sampleStartVelocity",
+		value: func(m Model) string { return fmt.Sprintf("%.0f ms", m.synth.Sampler.StartVelocity.Get()*1000) },
+		adjust: func(m *Model, dir float64) {
+			v := &m.synth.Sampler.StartVelocity
+			v.Set(math.Max(0, math.Min(engine.MaxStartOffset, v.Get()+dir*0.002)))
+		},
+	},
+	{
+		label: "Sample Start (random)",
+		param: "sampleStartRandom",
+		value: func(m Model) string { return fmt.Sprintf("%.0f ms", m.synth.Sampler.StartRandom.Get()*1000) },
+		adjust: func(m *Model, dir float64) {
+			v := &m.synth.Sampler.StartRandom
+			v.Set(math.Max(0, math.Min(engine.MaxStartOffset, v.Get()+dir*0.002)))
+		},
+	},
+	{
+		label: "GM Drum Map (ch 10)",
+		value: func(m Model) string { return onOff(m.synth.GMDrums()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.SetGMDrums(!m.synth.GMDrums())
+		},
+	},
+	{
+		label: "Drum Voices (ch 10)",
+		value: func(m Model) string { return onOff(m.synth.DrumVoices()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.SetDrumVoices(!m.synth.DrumVoices())
+		},
+	},
+}
+
+// envelopeItems are the rows of the envelopes page
+var envelopeItems = []menuItem{
+	{
+		label: "Delay",
+		param: "envDelay",
+		value: func(m Model) string { return fmt.Sprintf("%.2f s", m.synth.EnvDelay.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.EnvDelay.Set(math.Max(0, math.Min(2.0, m.synth.EnvDelay.Get()+dir*0.01)))
+		},
+	},
+	{
+		label: "Attack",
+		param: "attack",
+		value: func(m Model) string { return fmt.Sprintf("%.2f s", m.synth.Attack.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Attack.Set(math.Max(0, math.Min(2.0, m.synth.Attack.Get()+dir*0.01)))
+		},
+	},
+	{
+		label: "Attack Curve",
+		value: func(m Model) string { return m.synth.EnvCurves.State().Attack.String() },
+		adjust: func(m *Model, dir float64) {
+			m.editCurves(func(c *engine.EnvelopeCurves) { c.Attack = c.Attack.Next(sign(dir)) })
+		},
+	},
+	{
+		label: "Hold",
+		param: "envHold",
+		value: func(m Model) string { return fmt.Sprintf("%.2f s", m.synth.EnvHold.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.EnvHold.Set(math.Max(0, math.Min(2.0, m.synth.EnvHold.Get()+dir*0.01)))
+		},
+	},
+	{
+		label: "Decay",
+		param: "decay",
+		value: func(m Model) string { return fmt.Sprintf("%.2f s", m.synth.Decay.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Decay.Set(math.Max(0, math.Min(2.0, m.synth.Decay.Get()+dir*0.01)))
+		},
+	},
+	{
+		label: "Decay Curve",
+		value: func(m Model) string { return m.synth.EnvCurves.State().Decay.String() },
+		adjust: func(m *Model, dir float64) {
+			m.editCurves(func(c *engine.EnvelopeCurves) { c.Decay = c.Decay.Next(sign(dir)) })
+		},
+	},
+	{
+		label: "Sustain",
+		param: "sustain",
+		value: func(m Model) string { return fmt.Sprintf("%.2f", m.synth.Sustain.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Sustain.Set(math.Max(0, math.Min(1.0, m.synth.Sustain.Get()+dir*0.05)))
+		},
+		mod: func(m Model) (float64, float64) {
+			return m.synth.Sustain.Get(), m.synth.Modulation().Envelope
+		},
+	},
+	{
+		label: "Release",
+		param: "release",
+		value: func(m Model) string { return fmt.Sprintf("%.2f s", m.synth.Release.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Release.Set(math.Max(0, math.Min(5.0, m.synth.Release.Get()+dir*0.05)))
+		},
+	},
+	{
+		label: "Release Curve",
+		value: func(m Model) string { return m.synth.EnvCurves.State().Release.String() },
+		adjust: func(m *Model, dir float64) {
+			m.editCurves(func(c *engine.EnvelopeCurves) { c.Release = c.Release.Next(sign(dir)) })
+		},
+	},
+	{
+		label: "Pitch Env Amount",
+		param: "pitchEnvAmount",
+		value: func(m Model) string {
+			if amount := m.synth.PitchEnv.Amount.Get(); amount != 0 {
+				return fmt.Sprintf("%+.0f st", amount)
+			}
+			return "off"
+		},
+		adjust: func(m *Model, dir float64) {
+			amount := math.Round(m.synth.PitchEnv.Amount.Get() + dir)
+			m.synth.PitchEnv.Amount.Set(math.Max(-engine.PitchEnvMax, math.Min(engine.PitchEnvMax, amount)))
+		},
+	},
+	{
+		label: "Pitch Env Attack",
+		param: "pitchEnvAttack",
+		value: func(m Model) string { return fmt.Sprintf("%.3f s", m.synth.PitchEnv.Attack.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.PitchEnv.Attack.Set(math.Max(0, math.Min(2, m.synth.PitchEnv.Attack.Get()+dir*0.005)))
+		},
+	},
+	{
+		label: "Pitch Env Decay",
+		param: "pitchEnvDecay",
+		value: func(m Model) string { return fmt.Sprintf("%.3f s", m.synth.PitchEnv.Decay.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.PitchEnv.Decay.Set(math.Max(0, math.Min(2, m.synth.PitchEnv.Decay.Get()+dir*0.005)))
+		},
+	},
+}
+
+// editCurves changes the curves of the voice envelope
+func (m *Model) editCurves(edit func(c *engine.EnvelopeCurves)) {
+	curves := m.synth.EnvCurves.State()
+	edit(&curves)
+	m.synth.EnvCurves.SetState(curves)
+}
+
+// settingsItems are the rows of the settings page: tempo, swing and clock, song, arpeggiator, MIDI
+// output, reference pitch, CPU budget, sleep timer and display
+var settingsItems = []menuItem{
+	{
+		label: "Tempo",
+		param: "bpm",
+		value: func(m Model) string {
+			if m.synth.ClockSync() {
+				return fmt.Sprintf("%.0f BPM (MIDI clock)", m.synth.Clock.BPM.Get())
+			}
+			return fmt.Sprintf("%.0f BPM", m.synth.Clock.BPM.Get())
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Clock.BPM.Set(math.Max(20, math.Min(300, m.synth.Clock.BPM.Get()+dir)))
+		},
+	},
+	{
+		label: "Swing",
+		param: "swing",
+		value: func(m Model) string {
+			if swing := m.synth.Clock.Swing.Get(); swing > synth.NoSwing {
+				return fmt.Sprintf("%.0f%%", swing)
+			}
+			return "straight"
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Clock.Swing.Set(math.Max(synth.NoSwing, math.Min(synth.MaxSwing, m.synth.Clock.Swing.Get()+dir)))
+		},
+	},
+	{
+		label: "MIDI Clock In",
+		value: func(m Model) string { return onOff(m.synth.ClockSync()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.SetClockSync(!m.synth.ClockSync())
+		},
+	},
+	{
+		label: "MIDI Clock Out",
+		value: func(m Model) string { return onOff(m.synth.ClockOut()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.SetClockOut(!m.synth.ClockOut())
+		},
+	},
+	{
+		label: "Song Mode",
+		value: func(m Model) string { return onOff(m.synth.Seq.SongMode()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Seq.SetSongMode(!m.synth.Seq.SongMode())
+		},
+	},
+	{
+		label: "Song Order",
+		value: func(m Model) string { return synth.FormatSong(m.synth.Seq.Song()) },
+		adjust: func(m *Model, dir float64) {
+			// Add or remove the selected pattern at the end of the song
+			song := m.synth.Seq.Song()
+			if dir > 0 {
+				song = append(song, synth.SongEntry{Pattern: m.synth.Seq.Selected(), Repeats: 1})
+			} else if len(song) > 0 {
+				song = song[:len(song)-1]
+			}
+			m.synth.Seq.SetSong(song)
+		},
+		enter: func(m *Model, text string) error {
+			song, err := synth.ParseSong(text)
+			if err != nil {
+				return err
+			}
+			m.synth.Seq.SetSong(song)
+			return nil
+		},
+	},
+	{
+		label: "MIDI Out Port",
+		value: func(m Model) string {
+			if !m.synth.MIDIOut.Connected() {
+				return "none"
+			}
+			return m.synth.MIDIOut.Name()
+		},
+		adjust: func(m *Model, dir float64) {
+			if err := m.synth.MIDIOut.OpenNext(sign(dir)); err != nil {
+				m.status = fmt.Sprintf("Opening a MIDI output failed: %v", err)
+			}
+		},
+	},
+	{
+		label: "Sequencer Output",
+		value: func(m Model) string { return m.synth.SeqOut.Dest().String() },
+		adjust: func(m *Model, dir float64) {
+			m.synth.SeqOut.SetDest(m.synth.SeqOut.Dest().Next(sign(dir)))
+		},
+	},
+	{
+		label: "Sequencer Channel",
+		value: func(m Model) string { return fmt.Sprintf("%d", m.synth.SeqOut.Channel()+1) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.SeqOut.SetChannel(uint8(clamp(int(m.synth.SeqOut.Channel())+steps(dir), 0, 15)))
+		},
+	},
+	{
+		label: "Arpeggiator",
+		value: func(m Model) string { return onOff(m.synth.Arp.Enabled()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.SetArp(!m.synth.Arp.Enabled())
+		},
+	},
+	{
+		label: "Latch",
+		value: func(m Model) string { return onOff(m.synth.Latch.Enabled()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.SetLatch(!m.synth.Latch.Enabled())
+		},
+	},
+	{
+		label: "Latch Mode",
+		value: func(m Model) string { return m.synth.Latch.Mode().String() },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Latch.SetMode(m.synth.Latch.Mode().Next(sign(dir)))
+		},
+	},
+	{
+		label: "Chord Memory",
+		value: func(m Model) string { return onOff(m.synth.Chord.Enabled()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Chord.SetEnabled(!m.synth.Chord.Enabled())
+		},
+	},
+	{
+		label: "Chord Shape",
+		value: func(m Model) string {
+			return fmt.Sprintf("%s (%s)", m.synth.Chord.Name(), synth.FormatIntervals(m.synth.Chord.Intervals()))
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Chord.StepShape(sign(dir))
+		},
+		enter: func(m *Model, text string) error {
+			intervals, err := synth.ParseIntervals(text)
+			if err != nil {
+				return err
+			}
+			m.synth.Chord.SetIntervals(intervals)
+			return nil
+		},
+	},
+	{
+		label: "Chord Chance",
+		value: func(m Model) string {
+			var chances []string
+			for _, chance := range m.synth.Chord.Chances() {
+				chances = append(chances, fmt.Sprintf("%d%%", chance))
+			}
+			return strings.Join(chances, " ")
+		},
+		adjust: func(m *Model, dir float64) {
+			// Thin out or fill in the notes above the lowest together; Enter sets each
+			chances := m.synth.Chord.Chances()
+			for i := 1; i < len(chances); i++ {
+				chances[i] = clamp(chances[i]+steps(dir)*10, 0, 100)
+			}
+			m.synth.Chord.SetChances(chances)
+		},
+		enter: func(m *Model, text string) error {
+			chances, err := synth.ParseChances(text)
+			if err != nil {
+				return err
+			}
+			m.synth.Chord.SetChances(chances)
+			return nil
+		},
+	},
+	{
+		label: "Strum",
+		value: func(m Model) string {
+			strum, direction := m.synth.Chord.Strum()
+			if strum == 0 {
+				return "off"
+			}
+			return fmt.Sprintf("%d ms %s", strum.Milliseconds(), direction)
+		},
+		adjust: func(m *Model, dir float64) {
+			strum, direction := m.synth.Chord.Strum()
+			m.synth.Chord.SetStrum(strum+time.Duration(steps(dir))*5*time.Millisecond, direction)
+		},
+	},
+	{
+		label: "Strum Direction",
+		value: func(m Model) string {
+			_, direction := m.synth.Chord.Strum()
+			return direction.String()
+		},
+		adjust: func(m *Model, dir float64) {
+			strum, direction := m.synth.Chord.Strum()
+			m.synth.Chord.SetStrum(strum, direction.Next(sign(dir)))
+		},
+	},
+	{
+		label: "Scale Filter",
+		value: func(m Model) string { return m.synth.Quantize.Mode().String() },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Quantize.SetMode(m.synth.Quantize.Mode().Next(sign(dir)))
+		},
+	},
+	{
+		label: "Scale Key",
+		value: func(m Model) string {
+			if m.synth.Quantize.Scale() == synth.ScaleUser {
+				return "any (user scale)"
+			}
+			return synth.PitchClassNames(1 << m.synth.Quantize.Key())
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Quantize.SetKey(m.synth.Quantize.Key() + sign(dir))
+		},
+	},
+	{
+		label: "Scale",
+		value: func(m Model) string {
+			return fmt.Sprintf("%s (%s)", m.synth.Quantize.Scale(), m.synth.Quantize.Notes())
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Quantize.SetScale(m.synth.Quantize.Scale().Next(sign(dir)))
+		},
+		enter: func(m *Model, text string) error {
+			pitches, err := synth.ParsePitchClasses(text)
+			if err != nil {
+				return err
+			}
+			m.synth.Quantize.SetUserScale(pitches)
+			m.synth.Quantize.SetScale(synth.ScaleUser)
+			return nil
+		},
+	},
+	{
+		label: "Arp Mode",
+		value: func(m Model) string { return m.synth.Arp.Mode().String() },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Arp.SetMode(m.synth.Arp.Mode().Next(sign(dir)))
+		},
+	},
+	{
+		label: "Arp Sync",
+		value: func(m Model) string { return onOff(m.synth.Arp.Synced()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Arp.SetSynced(!m.synth.Arp.Synced())
+		},
+	},
+	{
+		label: "Arp Rate",
+		param: "arpRate",
+		value: func(m Model) string {
+			if m.synth.Arp.Synced() {
+				return engine.Divisions[m.synth.Arp.Div()].Name
+			}
+			return fmt.Sprintf("%.1f Hz", m.synth.Arp.Rate.Get())
+		},
+		adjust: func(m *Model, dir float64) {
+			if m.synth.Arp.Synced() {
+				// Right moves to shorter divisions, i.e. a faster rate
+				m.synth.Arp.SetDiv(m.synth.Arp.Div() + sign(dir))
+				return
+			}
+			m.synth.Arp.Rate.Set(math.Max(0.5, math.Min(32, m.synth.Arp.Rate.Get()+dir*0.5)))
+		},
+	},
+	{
+		label: "Arp Octaves",
+		param: "arpOctaves",
+		value: func(m Model) string { return fmt.Sprintf("%.0f", m.synth.Arp.Octaves.Get()) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Arp.Octaves.Set(math.Max(1, math.Min(4, m.synth.Arp.Octaves.Get()+dir)))
+		},
+	},
+	{
+		label: "Arp Gate",
+		param: "arpGate",
+		value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.Arp.Gate.Get()*100) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Arp.Gate.Set(math.Max(0.05, math.Min(1.0, m.synth.Arp.Gate.Get()+dir*0.05)))
+		},
+	},
+	{
+		label: "Arp Output",
+		value: func(m Model) string { return m.synth.ArpOut.Dest().String() },
+		adjust: func(m *Model, dir float64) {
+			m.synth.ArpOut.SetDest(m.synth.ArpOut.Dest().Next(sign(dir)))
+		},
+	},
+	{
+		label: "Arp Channel",
+		value: func(m Model) string { return fmt.Sprintf("%d", m.synth.ArpOut.Channel()+1) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.ArpOut.SetChannel(uint8(clamp(int(m.synth.ArpOut.Channel())+steps(dir), 0, 15)))
+		},
+	},
+	{
+		label: "MIDI Out Split",
+		value: func(m Model) string {
+			if !m.synth.MIDIOut.Connected() {
+				return onOff(m.synth.Split.Enabled()) + " (no output port)"
+			}
+			return onOff(m.synth.Split.Enabled()) + " -> " + m.synth.MIDIOut.Name()
+		},
+		adjust: func(m *Model, dir float64) {
+			m.synth.Split.SetEnabled(!m.synth.Split.Enabled())
+		},
+	},
+	{
+		label: "Split Low Note",
+		value: func(m Model) string {
+			low, _ := m.synth.Split.Range()
+			return noteName(low)
+		},
+		adjust: func(m *Model, dir float64) {
+			low, high := m.synth.Split.Range()
+			m.synth.Split.SetRange(uint8(clamp(int(low)+steps(dir), 0, int(high))), high)
+		},
+	},
+	{
+		label: "Split High Note",
+		value: func(m Model) string {
+			_, high := m.synth.Split.Range()
+			return noteName(high)
+		},
+		adjust: func(m *Model, dir float64) {
+			low, high := m.synth.Split.Range()
+			m.synth.Split.SetRange(low, uint8(clamp(int(high)+steps(dir), int(low), 127)))
+		},
+	},
+	{
+		label: "Split Channel",
+		value: func(m Model) string { return fmt.Sprintf("%d", m.synth.Split.Channel()+1) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.Split.SetChannel(uint8(clamp(int(m.synth.Split.Channel())+steps(dir), 0, 15)))
+		},
+	},
+	{
+		label: "Reference Pitch",
+		value: func(m Model) string { return fmt.Sprintf("A4 = %g Hz", m.synth.Tuning.Reference()) },
+		adjust: func(m *Model, dir float64) {
+			if err := m.synth.SaveReference(math.Round(m.synth.Tuning.Reference()) + float64(steps(dir))); err != nil {
+				m.status = fmt.Sprintf("Saving the reference pitch to the config failed: %v", err)
+			}
+		},
+	},
+	{
+		label: "CPU Budget",
+		value: func(m Model) string { return fmt.Sprintf("%.0f%%", m.synth.CPUBudget.Get()*100) },
+		adjust: func(m *Model, dir float64) {
+			m.synth.CPUBudget.Set(math.Max(0.1, math.Min(1, m.synth.CPUBudget.Get()+dir*0.05)))
+		},
+	},
+	{
+		label: "Voice Cost",
+		value: func(m Model) string {
+			load := fmt.Sprintf("load %.0f%%", m.synth.CPULoad()*100)
+			polyphony := m.synth.MaxPolyphony()
+			if polyphony < 0 {
+				return "play some voices to measure (" + load + ")"
+			}
+			return fmt.Sprintf("%.1f µs/voice, ~%d voices fit (%d playable, %s)",
+				float64(m.synth.VoiceCost().Nanoseconds())/1000, polyphony, engine.MaxVoices, load)
+		},
+		adjust: func(m *Model, dir float64) {},
+	},
+	{
+		label: "Sleep Timer",
+		value: func(m Model) string {
+			remaining, ok := m.synth.SleepRemaining()
+			if !ok {
+				return "off"
+			}
+			return fmt.Sprintf("fading out, silent in %s", remaining.Round(time.Second))
+		},
+		adjust: func(m *Model, dir float64) {
+			// Step from the time left, rounded up to a whole step
+			remaining, _ := m.synth.SleepRemaining()
+			n := int((remaining+sleepStep-1)/sleepStep) + steps(dir)
+			m.synth.StartSleep(time.Duration(clamp(n, 0, int(maxSleep/sleepStep))) * sleepStep)
+		},
+	},
+	{
+		label: "Scope Trigger",
+		value: func(m Model) string { return onOff(m.scopeTrigger) },
+		adjust: func(m *Model, dir float64) {
+			m.scopeTrigger = !m.scopeTrigger
+		},
+	},
+	{
+		label: "Scope Hold",
+		value: func(m Model) string { return onOff(m.scopeHold) },
+		adjust: func(m *Model, dir float64) {
+			m.scopeHold = !m.scopeHold
+		},
+	},
+	{
+		label: "Real-time display",
+		value: func(m Model) string { return fmt.Sprintf("%v", m.realTime) },
+		adjust: func(m *Model, dir float64) {
+			m.realTime = !m.realTime
+		},
+	},
+}
+
+// renderKills shows the kill switches, lit while engaged
+func (m Model) renderKills(baseStyle lipgloss.Style) string {
+	killStyle := baseStyle.Foreground(lipgloss.Color("#ff0000"))
+	parts := []string{baseStyle.Render("Kills:")}
+	for i, kill := range []engine.Kill{engine.KillDelay, engine.KillReverb, engine.KillParts} {
+		label := fmt.Sprintf(" F%d %s", i+1, kill)
+		if m.synth.Killed(kill) {
+			parts = append(parts, killStyle.Render(label))
+		} else {
+			parts = append(parts, baseStyle.Render(label))
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// onOff formats a toggle value
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// noteName formats a MIDI note number as a note name with octave, e.g. C4 for 60
+func noteName(note uint8) string {
+	names := [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+	return fmt.Sprintf("%s%d", names[note%12], int(note)/12-1)
+}
+
 // getWaveformChar returns an appropriate character based on intensity
 func getWaveformChar(value float64) rune {
 	switch {
@@ -225,9 +1532,9 @@ func hueToRGB(p, q, t float64) float64 {
 	return p
 }
 
-// getRainbowColor returns a color string based on time and intensity
+// getRainbowColor returns a color string based on a hue offset and intensity
 func getRainbowColor(intensity float64, timeOffset float64) string {
-	// Use time to shift the hue
+	// Use the offset to shift the hue
 	hue := math.Mod(timeOffset, 1.0)
 	// Fixed saturation for vibrant colors
 	saturation := 1.0
@@ -243,6 +1550,33 @@ func getRainbowColor(intensity float64, timeOffset float64) string {
 		int(b*255))
 }
 
+// Accent colors follow the output analysis: the hue tracks the spectral centroid from
+// red for dark sounds to violet for bright ones, and the lightness follows the level
+const (
+	accentLowFreq  = 50    // Centroid in Hz mapped to the first hue
+	accentHighFreq = 10000 // Centroid in Hz mapped to the last hue
+	accentHueRange = 0.8   // Stop short of wrapping back to red
+	accentSilence  = 1e-4  // RMS below which the accents fall back to the idle colors
+)
+
+// analysisHue maps a spectral centroid to a hue on a log frequency scale
+func analysisHue(centroid float64) float64 {
+	if centroid <= 0 {
+		return 0
+	}
+	pos := math.Log(centroid/accentLowFreq) / math.Log(accentHighFreq/accentLowFreq)
+	return math.Max(0, math.Min(1, pos)) * accentHueRange
+}
+
+// accentColor returns a border color lit by the output level, or fallback when silent
+func accentColor(a engine.Analysis, fallback string) string {
+	if a.RMS < accentSilence {
+		return fallback
+	}
+	r, g, b := hslToRGB(analysisHue(a.Centroid), 1.0, 0.15+math.Min(1, a.RMS*4)*0.45)
+	return fmt.Sprintf("#%02x%02x%02x", int(r*255), int(g*255), int(b*255))
+}
+
 // drawWaveform renders the waveform visualization
 func (m Model) drawWaveform() string {
 	// Create a buffer for the waveform with double vertical resolution
@@ -264,75 +1598,38 @@ func (m Model) drawWaveform() string {
 		intensities[centerY][x] = 0.2
 	}
 
-	// Calculate and draw the waveforms with interpolation
-	points := waveformWidth * 4 // Calculate more points for smoother rendering
-	lastCarrierY := -1
-	lastFinalY := -1
-
-	// Use current timeIndex for animated display if realTime is true
-	displayTime := 0.0
-	if m.realTime {
-		displayTime = m.synth.GetTimeIndex()
-	}
-
-	for i := 0; i < points; i++ {
-		x := i * waveformWidth / points
-		t := displayTime + float64(i)/float64(points)*0.02 // Show 0.02 seconds of waveform
-
-		// Generate carrier signal
-		carrier := math.Sin(2 * math.Pi * m.synth.CarrierFreq.Get() * t)
-
-		// Calculate modulator wave
-		modFreq := m.synth.CalculateModulatorFreq(t)
-		modulator := math.Sin(2 * math.Pi * modFreq * t)
-
-		// Apply amplitude modulation
-		final := carrier * (1 + m.synth.ModIndex.Get()*modulator)
-
-		// Map the waves to y coordinates with higher resolution
-		carrierY := int(carrier*float64(waveformHeight/3)) + centerY
-		finalY := int(final*float64(waveformHeight/3)) + centerY
-
-		// Ensure y coordinates are within bounds
-		carrierY = clamp(carrierY, 0, waveformHeight-1)
-		finalY = clamp(finalY, 0, waveformHeight-1)
-
-		// Calculate intensities
-		carrierIntensity := math.Abs(carrier) * 0.7
-		finalIntensity := math.Abs(final)
-
-		// Interpolate between last points if they exist
-		if lastCarrierY != -1 && x > 0 {
-			interpolatePointsWithIntensity(buffer, intensities, x-1, lastCarrierY, x, carrierY, '·', carrierIntensity)
+	// Draw the trace of the real output, interpolating between columns
+	lastY := -1
+	for i, sample := range m.scopeTrace {
+		x := i * waveformWidth / len(m.scopeTrace)
+		level := float64(sample)
+		y := clamp(centerY-int(math.Round(level*float64(centerY-1))), 0, waveformHeight-1)
+		if lastY != -1 && x > 0 {
+			interpolatePointsWithIntensity(buffer, intensities, x-1, lastY, x, y, getWaveformChar(math.Abs(level)), math.Abs(level))
 		}
-		if lastFinalY != -1 && x > 0 {
-			// Use intensity-based characters for the modulated wave
-			intensity := math.Abs(final)
-			char := getWaveformChar(intensity)
-			interpolatePointsWithIntensity(buffer, intensities, x-1, lastFinalY, x, finalY, char, finalIntensity)
-		}
-
-		lastCarrierY = carrierY
-		lastFinalY = finalY
+		lastY = y
 	}
 
 	// Convert buffer to string with a fancier border and colors
 	var result strings.Builder
 	result.WriteString("\n")
 
-	// Top border
-	result.WriteString(borderStyle.Render("╔" + strings.Repeat("═", waveformWidth) + "╗\n"))
+	// Color the display from the sound itself: the border glows with the level
+	// and the waveform hue follows the brightness
+	analysis := m.meter
+	border := borderStyle.Foreground(lipgloss.Color(accentColor(analysis, "#004400")))
+	hueOffset := analysisHue(analysis.Centroid)
 
-	// Calculate time-based hue offset
-	timeHueOffset := math.Mod(m.synth.GetTimeIndex()*0.2, 1.0) // Adjust speed of color change here
+	// Top border
+	result.WriteString(border.Render("╔" + strings.Repeat("═", waveformWidth) + "╗\n"))
 
 	// Waveform content
 	for y, line := range buffer {
-		result.WriteString(borderStyle.Render("║"))
+		result.WriteString(border.Render("║"))
 		for x, char := range line {
 			intensity := intensities[y][x]
 			if char != ' ' {
-				color := getRainbowColor(intensity, timeHueOffset+float64(x)/float64(waveformWidth)*0.5)
+				color := getRainbowColor(intensity, hueOffset+float64(x)/float64(waveformWidth)*0.2)
 				style := lipgloss.NewStyle().
 					Background(lipgloss.Color("#000000")).
 					Foreground(lipgloss.Color(color))
@@ -341,14 +1638,24 @@ func (m Model) drawWaveform() string {
 				result.WriteString(spaceStyle.Render(" "))
 			}
 		}
-		result.WriteString(borderStyle.Render("║") + "\n")
+		result.WriteString(border.Render("║") + "\n")
 	}
 
 	// Bottom border
-	result.WriteString(borderStyle.Render("╚" + strings.Repeat("═", waveformWidth) + "╝\n"))
+	result.WriteString(border.Render("╚" + strings.Repeat("═", waveformWidth) + "╝\n"))
 
 	// Legend
-	result.WriteString(waveformStyle.Render("\nWaveform Display (modulated: ░▒▓█)") + "\n")
+	trigger := "off"
+	if m.scopeTrigger {
+		trigger = "waiting"
+		if m.scopeTriggered {
+			trigger = "locked"
+		}
+	}
+	if m.scopeHold {
+		trigger += ", held"
+	}
+	result.WriteString(waveformStyle.Render(fmt.Sprintf("\nOutput oscilloscope (trigger %s, level: ░▒▓█)", trigger)) + "\n")
 
 	return result.String()
 }
@@ -405,11 +1712,15 @@ func (m Model) render() string {
 	// Create base styles for menu items
 	baseStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("#ffffff")).
+		Foreground(m.theme.text).
 		Background(lipgloss.Color("#000000"))
 
 	selectedStyle := baseStyle.
-		Foreground(lipgloss.Color("#00ff00"))
+		Foreground(m.theme.selected)
+
+	modStyle := lipgloss.NewStyle().
+		Foreground(m.theme.mod).
+		Background(lipgloss.Color("#000000"))
 
 	// Create container style for the entire app
 	containerStyle := lipgloss.NewStyle().
@@ -419,69 +1730,108 @@ func (m Model) render() string {
 
 	var s strings.Builder
 
-	s.WriteString(baseStyle.Render("Gosynth synthesizer - Use keyboard arrows or MIDI controller") + "\n\n")
-
-	// Carrier Frequency
-	if m.selected == 0 {
-		s.WriteString(selectedStyle.Render(fmt.Sprintf("> Carrier Frequency: %.1f Hz", m.synth.CarrierFreq.Get())) + "\n")
-	} else {
-		s.WriteString(baseStyle.Render(fmt.Sprintf("  Carrier Frequency: %.1f Hz", m.synth.CarrierFreq.Get())) + "\n")
-	}
-
-	// Min Modulator Frequency
-	if m.selected == 1 {
-		s.WriteString(selectedStyle.Render("> Min Modulator Frequency: "))
-	} else {
-		s.WriteString(baseStyle.Render("  Min Modulator Frequency: "))
-	}
-	s.WriteString(baseStyle.Render(fmt.Sprintf("%.1f Hz", m.synth.MinModFreq.Get())) + "\n")
-
-	// Max Modulator Frequency
-	if m.selected == 2 {
-		s.WriteString(selectedStyle.Render("> Max Modulator Frequency: "))
-	} else {
-		s.WriteString(baseStyle.Render("  Max Modulator Frequency: "))
+	s.WriteString(baseStyle.Render("Gosynth synthesizer - Use keyboard arrows or MIDI controller"))
+	if m.cast != nil {
+		s.WriteString(baseStyle.Foreground(lipgloss.Color("#ff0000")).Render("  ● REC"))
 	}
-	s.WriteString(baseStyle.Render(fmt.Sprintf("%.1f Hz", m.synth.MaxModFreq.Get())) + "\n")
-
-	// Sweep Time
-	if m.selected == 3 {
-		s.WriteString(selectedStyle.Render("> Sweep Time: "))
+	s.WriteString("\n")
+	if m.piano {
+		s.WriteString(selectedStyle.Render(fmt.Sprintf("Keyboard piano: on (octave %d)", m.octave)) + "\n\n")
 	} else {
-		s.WriteString(baseStyle.Render("  Sweep Time: "))
+		s.WriteString(baseStyle.Render("Keyboard piano: off") + "\n\n")
 	}
-	s.WriteString(baseStyle.Render(fmt.Sprintf("%.2f s", m.synth.SweepTime.Get())) + "\n")
-
-	// Modulation Index
-	if m.selected == 4 {
-		s.WriteString(selectedStyle.Render("> Modulation Index: "))
-	} else {
-		s.WriteString(baseStyle.Render("  Modulation Index: "))
+	s.WriteString(m.renderPageTabs(baseStyle, selectedStyle) + "\n\n")
+	s.WriteString(m.renderKills(baseStyle) + m.renderAudition(baseStyle) + "\n")
+	s.WriteString(m.renderGenerator(baseStyle))
+	s.WriteString(baseStyle.Render(m.renderNotes()) + "\n")
+	s.WriteString(m.renderLevel(baseStyle) + "\n")
+	s.WriteString(m.renderMIDI(baseStyle) + "\n")
+	for _, c := range m.synth.Controllers {
+		s.WriteString(baseStyle.Render(fmt.Sprintf("Control surface: %s on %s", c.Profile.Name, c.Port)) + "\n")
 	}
-	s.WriteString(baseStyle.Render(fmt.Sprintf("%.2f", m.synth.ModIndex.Get())) + "\n")
 
-	// Volume
-	if m.selected == 5 {
-		s.WriteString(selectedStyle.Render("> Volume: "))
+	if m.page == pageSequencer {
+		s.WriteString(m.renderSequencer(baseStyle, selectedStyle))
 	} else {
-		s.WriteString(baseStyle.Render("  Volume: "))
+		// Parameter rows
+		items, selected := m.pageItems()
+		if m.page == pageEffects {
+			s.WriteString(baseStyle.Render("Effects chain: "+strings.Join(m.synth.FX.Names(), " → ")+" → compressor") + "\n")
+			s.WriteString(m.renderGainReduction(baseStyle) + "\n")
+			if warning := m.renderVoiceFXWarning(baseStyle); warning != "" {
+				s.WriteString(warning + "\n")
+			}
+			s.WriteString("\n")
+		}
+		if m.page == pageFilter {
+			s.WriteString(m.drawFilterResponse(baseStyle) + "\n")
+		}
+		if m.page == pageSpectrum {
+			s.WriteString(m.drawSpectrum(baseStyle) + "\n")
+		}
+		if m.page == pageStats {
+			s.WriteString(m.renderStats(baseStyle))
+		}
+		if m.page == pageTuner {
+			s.WriteString(m.renderTuner(baseStyle))
+		}
+		if m.page == pageMod {
+			for _, line := range m.renderModSummary() {
+				s.WriteString(baseStyle.Render(line) + "\n")
+			}
+			s.WriteString("\n")
+		}
+		for i, item := range items {
+			value := item.value(m)
+			label := item.label
+			if item.param != "" && m.synth.Locks.Locked(item.param) {
+				label += " [locked]"
+			}
+			if i == *selected {
+				s.WriteString(selectedStyle.Render("> " + label + ": "))
+				if m.entering {
+					value = m.entry + "_"
+				}
+			} else {
+				s.WriteString(baseStyle.Render("  " + label + ": "))
+			}
+			if item.mod == nil {
+				s.WriteString(baseStyle.Render(value) + "\n")
+				continue
+			}
+			base, live := item.mod(m)
+			s.WriteString(baseStyle.Render(fmt.Sprintf("%-26s", value)))
+			s.WriteString(modStyle.Render(renderModulation(base, live)) + "\n")
+		}
 	}
-	s.WriteString(baseStyle.Render(fmt.Sprintf("%.2f", m.synth.Volume.Get())) + "\n")
-
-	// Real-time toggle
-	if m.selected == 6 {
-		s.WriteString(selectedStyle.Render("> Real-time display: "))
-	} else {
-		s.WriteString(baseStyle.Render("  Real-time display: "))
+	s.WriteString("\n")
+	if m.status != "" {
+		s.WriteString(baseStyle.Render(m.status) + "\n")
 	}
-	s.WriteString(baseStyle.Render(fmt.Sprintf("%v", m.realTime)) + "\n\n")
 
 	// Update instructions to include both MIDI and keyboard controls
 	s.WriteString(baseStyle.Render("\nControls:") + "\n")
-	s.WriteString(baseStyle.Render("- Use ↑↓ to select parameter") + "\n")
-	s.WriteString(baseStyle.Render("- Use ←→ to adjust value") + "\n")
-	s.WriteString(baseStyle.Render("- MIDI keyboard will control carrier frequency") + "\n")
-	s.WriteString(baseStyle.Render("- Press q to quit") + "\n")
+	if pages[m.page].items != nil {
+		s.WriteString(baseStyle.Render("- Use ↑↓ to select parameter") + "\n")
+		s.WriteString(baseStyle.Render("- Use ←→ to adjust value, shift for fine steps, alt for coarse") + "\n")
+		s.WriteString(baseStyle.Render("- Press Enter on a frequency to type a note (C#3) or Hz, Esc to cancel") + "\n")
+	}
+	for _, line := range pages[m.page].help {
+		s.WriteString(baseStyle.Render("- "+line) + "\n")
+	}
+	k := m.keys.key // Keys can be rebound in the config file
+	s.WriteString(baseStyle.Render(fmt.Sprintf("- %s and %s switch to the next and previous page", k("next_page"), k("previous_page"))) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("- Press %s to save the preset and pattern, %s to save as new", k("save"), k("save_new"))) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("- Press %s to lock the selected parameter against preset loads", k("lock"))) + "\n")
+	s.WriteString(baseStyle.Render("- MIDI keyboard plays notes (sustain pedal supported in voices mode)") + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("- Press %s for keyboard piano (a w s e d f t g y h u j k, z/x octave)", k("piano"))) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("- Press %s to latch the last chord, or hold notes in the latch's hold mode", k("latch"))) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("- Press %s to record the session to asciicast and WAV files", k("record"))) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("- Press %s/%s/%s to kill the delay, reverb or all non-drum parts", k("kill_delay"), k("kill_reverb"), k("kill_parts"))) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("- Press %s to audition the selected row's voices, drone layer or effect on its own", k("audition"))) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("- Press %s to release every stuck note (MIDI panic)", k("panic"))) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("- Press %s to undo an edit or preset load, %s to redo", k("undo"), k("redo"))) + "\n")
+	s.WriteString(baseStyle.Render(fmt.Sprintf("- Press %s to quit", k("quit"))) + "\n")
 
 	// Add waveform visualization
 	s.WriteString(m.drawWaveform())